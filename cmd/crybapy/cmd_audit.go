@@ -77,6 +77,7 @@ func doAudit(config *auditConfig) error {
 	fmt.Printf("Total.......................: %d\n", stats.Total)
 	fmt.Printf("Confirmed...................: %d\n", stats.Confirmed)
 	fmt.Printf("False Confirmed.............: %d\n", stats.FalseConfirmed)
+	fmt.Printf("Reorged.....................: %d\n", stats.Reorged)
 	fmt.Printf("Overpaid. ..................: %d\n", stats.Overpaid)
 	fmt.Printf("Unstarted...................: %d\n", stats.Unstarted)
 	fmt.Printf("Pending.....................: %d\n", stats.Pending)
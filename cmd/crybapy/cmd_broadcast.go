@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+type broadcastConfig struct {
+	*rootConfig
+	PayerConfig
+	Name             string
+	SignedPlanPath   string
+	SkipConfirmation bool
+}
+
+func newBroadcastCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &broadcastConfig{
+		rootConfig: rootConfig,
+		PayerConfig: PayerConfig{
+			Offline: true,
+		},
+	}
+	cmd := &cobra.Command{
+		Use:   "broadcast NAME SIGNEDPLANFILE",
+		Short: "Sends the transactions signed with the sign command",
+		Long: "Records the signed transactions in SIGNEDPLANFILE in the payout database, sends them " +
+			"and waits until they are confirmed, like run --drain. Broadcasting the same plan again " +
+			"skips the transactions already recorded and resends those dropped by the node.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.Name = args[0]
+			config.SignedPlanPath = args[1]
+			return checkCmd(doBroadcast(config))
+		},
+	}
+	cmd.Flags().BoolVarP(
+		&config.SkipConfirmation,
+		"skip-confirmation", "",
+		false,
+		"Skip confirmation")
+	RegisterFlags(cmd, &config.PayerConfig)
+	return cmd
+}
+
+func doBroadcast(config *broadcastConfig) error {
+	chainID, err := payerChainID(config.PayerConfig, config.ChainID)
+	if err != nil {
+		return err
+	}
+
+	plan, err := payouts.ReadOfflinePlan(config.SignedPlanPath)
+	if err != nil {
+		return err
+	}
+
+	promptConfirm := promptConfirm
+	if config.SkipConfirmation {
+		promptConfirm = func(label string) error {
+			fmt.Printf("Skipping confirmation to %s!\n", label)
+			return nil
+		}
+	}
+
+	runDir := filepath.Join(config.DataDir, config.Name)
+
+	log, err := openLog(runDir)
+	if err != nil {
+		return err
+	}
+
+	// The owner defaults to the one the plan was made for.
+	if config.Owner == "" {
+		config.Owner = plan.Owner.String()
+	}
+	p, err := CreatePayer(config.Ctx, log, config.PayerConfig, config.NodeAddress, config.ChainID, plan.Spender.String())
+	if err != nil {
+		return err
+	}
+	ethPayer, ok := p.(*eth.Payer)
+	if !ok {
+		return errs.New("offline signing is not supported by the %s payer", p)
+	}
+
+	dbPath := payouts.DBPathFromDir(runDir)
+	db, err := pipelinedb.OpenDB(context.Background(), dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	fmt.Printf("Broadcasting %q payout...\n", config.Name)
+	payoutsConfig := payouts.Config{
+		Drain:         true,
+		PromptConfirm: promptConfirm,
+	}
+	if err := payouts.BroadcastOfflinePlan(config.Ctx, log, payoutsConfig, db, ethPayer, chainID, plan); err != nil {
+		return err
+	}
+
+	fmt.Println("Waiting for the transactions to be confirmed...")
+	if err := payouts.Run(config.Ctx, log, payoutsConfig, db, p); err != nil {
+		return err
+	}
+
+	if err := db.Close(); err != nil {
+		return errs.New("failed to close database: %v", err)
+	}
+
+	fmt.Println("Broadcast complete.")
+	return nil
+}
@@ -14,13 +14,16 @@ type importConfig struct {
 
 	// CSVPath is the path to the CSV file containing payout data
 	CSVPath string
+
+	// GroupSize is the maximum number of payouts in a payout group
+	GroupSize int
 }
 
 func newImportCommand(rootConfig *rootConfig) *cobra.Command {
 	config := &importConfig{
 		rootConfig: rootConfig,
 	}
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "import CSVPATH",
 		Short: "Imports a payout from a CSV file",
 		Args:  cobra.ExactArgs(1),
@@ -29,11 +32,17 @@ func newImportCommand(rootConfig *rootConfig) *cobra.Command {
 			return checkCmd(doImport(config))
 		},
 	}
+	cmd.Flags().IntVarP(
+		&config.GroupSize,
+		"group-size", "",
+		1,
+		"Number of payouts per payout group. Groups with more than one payout are paid in a single transaction through the disperse contract (eth, polygon and zksync-era only).")
+	return cmd
 }
 
 func doImport(config *importConfig) error {
 	fmt.Printf("Importing payouts from %q...\n", config.CSVPath)
-	if err := payouts.Import(config.Ctx, config.DataDir, config.CSVPath); err != nil {
+	if err := payouts.Import(config.Ctx, config.DataDir, config.CSVPath, config.GroupSize); err != nil {
 		return errs.New("import failed: %v\n", err)
 	}
 	fmt.Println("Import complete.")
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+type nonceCommandConfig struct {
+	*rootConfig
+	PayerConfig
+}
+
+func newNonceCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &nonceCommandConfig{
+		rootConfig:  rootConfig,
+		PayerConfig: PayerConfig{},
+	}
+	cmd := &cobra.Command{
+		Use:   "nonce",
+		Short: "Inspects and repairs the nonces of a spender",
+		Long: "Inspects and repairs the nonces of a spender of an eth or polygon payout. " +
+			"The payout must not be running while the nonces are repaired.",
+	}
+	cmd.AddCommand(newNonceStatusCommand(config))
+	cmd.AddCommand(newNonceCancelCommand(config))
+	cmd.AddCommand(newNonceFillGapsCommand(config))
+	return cmd
+}
+
+// openNonceDB opens the database of the payout and creates the payer of the
+// spender. Only the eth and polygon payers manage nonces.
+func openNonceDB(config *nonceCommandConfig, log *zap.Logger, name string, spenderKeyPath string, readOnly bool) (*pipelinedb.DB, *eth.Payer, error) {
+	p, err := CreatePayer(config.Ctx, log, config.PayerConfig, config.NodeAddress, config.ChainID, spenderKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	ethPayer, ok := p.(*eth.Payer)
+	if !ok {
+		return nil, nil, errs.New("nonce commands are not supported by the %s payer", p)
+	}
+
+	dbPath := payouts.DBPathFromDir(filepath.Join(config.DataDir, name))
+	db, err := pipelinedb.OpenDB(context.Background(), dbPath, readOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, ethPayer, nil
+}
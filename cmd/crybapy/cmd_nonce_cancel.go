@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/payouts"
+)
+
+const defaultCancelWait = 10 * time.Minute
+
+type nonceCancelConfig struct {
+	*nonceCommandConfig
+	Wait time.Duration
+}
+
+func newNonceCancelCommand(parentConfig *nonceCommandConfig) *cobra.Command {
+	config := &nonceCancelConfig{
+		nonceCommandConfig: parentConfig,
+	}
+	cmd := &cobra.Command{
+		Use:   "cancel NAME SPENDERKEYPATH NONCE",
+		Short: "Replaces the transaction pending with the nonce with a zero-value self-transfer",
+		Long: "Replaces the transaction pending with the nonce with a zero-value transfer from the " +
+			"spender to itself, with fees bumped above the replaced transaction. If the nonce " +
+			"belongs to a payout group, waits until either transaction is confirmed. If the " +
+			"cancellation wins, the payout group is sent again with a new nonce on the next run.",
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nonce, err := strconv.ParseUint(args[2], 10, 64)
+			if err != nil {
+				return usageErr.New("invalid nonce %q\n", args[2])
+			}
+			return checkCmd(doNonceCancel(config, args[0], args[1], nonce))
+		},
+	}
+	cmd.Flags().DurationVarP(
+		&config.Wait,
+		"wait", "",
+		defaultCancelWait,
+		"How long to wait for the cancellation to be confirmed")
+	RegisterFlags(cmd, &config.PayerConfig)
+	return cmd
+}
+
+func doNonceCancel(config *nonceCancelConfig, name, spenderKeyPath string, nonce uint64) error {
+	log, err := openLog(filepath.Join(config.DataDir, name))
+	if err != nil {
+		return err
+	}
+
+	db, p, err := openNonceDB(config.nonceCommandConfig, log, name, spenderKeyPath, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx, cancel := context.WithTimeout(config.Ctx, config.Wait)
+	defer cancel()
+
+	if err := payouts.CancelNonce(ctx, log, db, p, nonce, promptConfirm); err != nil {
+		return err
+	}
+	fmt.Printf("Nonce %d cancelled.\n", nonce)
+	return nil
+}
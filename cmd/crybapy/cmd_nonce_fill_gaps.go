@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/payouts"
+)
+
+func newNonceFillGapsCommand(config *nonceCommandConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fill-gaps NAME SPENDERKEYPATH",
+		Short: "Fixes the nonces that hold up the payout",
+		Long: "Fills nonce gaps left by dropped transactions with zero-value self-transfers, " +
+			"sends dropped transactions of the payout again, and marks payout groups whose nonce " +
+			"was used by a transaction sent by another tool to be sent again with a new nonce.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkCmd(doNonceFillGaps(config, args[0], args[1]))
+		},
+	}
+	RegisterFlags(cmd, &config.PayerConfig)
+	return cmd
+}
+
+func doNonceFillGaps(config *nonceCommandConfig, name, spenderKeyPath string) error {
+	log, err := openLog(filepath.Join(config.DataDir, name))
+	if err != nil {
+		return err
+	}
+
+	db, p, err := openNonceDB(config, log, name, spenderKeyPath, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	status, err := payouts.FetchNonceStatus(config.Ctx, db, p)
+	if err != nil {
+		return err
+	}
+	if err := payouts.PrintNonceStatus(os.Stdout, status); err != nil {
+		return err
+	}
+
+	fixed, err := payouts.FillNonceGaps(config.Ctx, log, db, p, status, promptConfirm)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d nonces fixed.\n", fixed)
+	return nil
+}
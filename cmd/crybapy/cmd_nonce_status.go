@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/payouts"
+)
+
+func newNonceStatusCommand(config *nonceCommandConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status NAME SPENDERKEYPATH",
+		Short: "Compares the nonces of the spender on chain to the payout database",
+		Long: "Prints the next nonce of the spender according to the latest block and according " +
+			"to the node including pending transactions, along with the unfinished nonce groups " +
+			"of the spender in the payout database and any gaps between them.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkCmd(doNonceStatus(config, args[0], args[1]))
+		},
+	}
+	RegisterFlags(cmd, &config.PayerConfig)
+	return cmd
+}
+
+func doNonceStatus(config *nonceCommandConfig, name, spenderKeyPath string) error {
+	log, err := openConsoleLog()
+	if err != nil {
+		return err
+	}
+
+	db, p, err := openNonceDB(config, log, name, spenderKeyPath, true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	status, err := payouts.FetchNonceStatus(config.Ctx, db, p)
+	if err != nil {
+		return err
+	}
+	return payouts.PrintNonceStatus(os.Stdout, status)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipeline"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+type planConfig struct {
+	*rootConfig
+	PayerConfig
+	PriceGuardConfig
+	SpendLimitsConfig
+	Name                    string
+	Spender                 string
+	PlanPath                string
+	CoinMarketCapAPIURL     string
+	CoinMarketCapAPIKeyPath string
+	Count                   int
+	Price                   string
+	SkipConfirmation        bool
+}
+
+func newPlanCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &planConfig{
+		rootConfig: rootConfig,
+		PayerConfig: PayerConfig{
+			Offline: true,
+		},
+	}
+	cmd := &cobra.Command{
+		Use:   "plan NAME SPENDERADDRESS PLANFILE",
+		Short: "Plans unsigned transactions for the next payout groups, to be signed offline",
+		Long: "Writes the unsigned transactions paying out the next payout groups to PLANFILE, " +
+			"to be signed with the sign command on an offline host holding the spender key and " +
+			"sent with the broadcast command. The spender must have no unfinished transactions. " +
+			"Only applies to eth and polygon type payment.",
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.Name = args[0]
+			config.Spender = args[1]
+			config.PlanPath = args[2]
+			return checkCmd(doPlan(config))
+		},
+	}
+	cmd.Flags().StringVarP(
+		&config.CoinMarketCapAPIURL,
+		"coinmarkcap-api-url", "",
+		coinmarketcap.ProductionAPIURL,
+		"CoinMarketCap API URL")
+	cmd.Flags().StringVarP(
+		&config.CoinMarketCapAPIKeyPath,
+		"coinmarkcap-api-key-path", "",
+		filepath.Join(homeDir, ".coinmarketcapkey"),
+		"Path on disk to the CoinMarketCap API key")
+	cmd.Flags().IntVarP(
+		&config.Count,
+		"count", "",
+		pipeline.DefaultLimit,
+		"How many payout groups to plan transactions for")
+	cmd.Flags().StringVarP(
+		&config.Price,
+		"price", "",
+		"",
+		"Token price in USD to lock for the payout instead of a CoinMarketCap quote. Requires confirmation. Must match the locked price if one has already been locked.")
+	cmd.Flags().BoolVarP(
+		&config.SkipConfirmation,
+		"skip-confirmation", "",
+		false,
+		"Skip confirmation")
+	RegisterFlags(cmd, &config.PayerConfig)
+	registerPriceGuardFlags(cmd, &config.PriceGuardConfig)
+	registerSpendLimitsFlags(cmd, &config.SpendLimitsConfig)
+	return cmd
+}
+
+func doPlan(config *planConfig) error {
+	if config.Count <= 0 {
+		return usageErr.New("--count must be more than zero\n")
+	}
+	chainID, err := payerChainID(config.PayerConfig, config.ChainID)
+	if err != nil {
+		return err
+	}
+	price, err := convertPrice(config.Price)
+	if err != nil {
+		return err
+	}
+	symbol, err := quoteSymbol(config.PayerConfig)
+	if err != nil {
+		return err
+	}
+	if price != nil && config.SkipConfirmation {
+		return usageErr.New("--price requires confirmation and cannot be combined with --skip-confirmation\n")
+	}
+
+	spendLimits, err := newSpendLimits(config.SpendLimitsConfig)
+	if err != nil {
+		return err
+	}
+
+	var quoter coinmarketcap.Quoter
+	if price == nil {
+		coinMarketCapAPIKey, err := loadFirstLine(config.CoinMarketCapAPIKeyPath)
+		if err != nil {
+			return errs.New("failed to load CoinMarketCap key: %v\n", err)
+		}
+
+		quoter, err = coinmarketcap.NewCachingClient(config.CoinMarketCapAPIURL, coinMarketCapAPIKey, time.Second*5)
+		if err != nil {
+			return errs.New("failed instantiate coinmarketcap client: %v\n", err)
+		}
+	}
+
+	guard, err := newPriceGuard(quoter, config.PriceGuardConfig)
+	if err != nil {
+		return err
+	}
+	if price != nil {
+		if err := guard.CheckPrice(*price); err != nil {
+			return err
+		}
+	}
+
+	promptConfirm := promptConfirm
+	if config.SkipConfirmation {
+		promptConfirm = func(label string) error {
+			fmt.Printf("Skipping confirmation to %s!\n", label)
+			return nil
+		}
+	}
+
+	runDir := filepath.Join(config.DataDir, config.Name)
+
+	log, err := openLog(runDir)
+	if err != nil {
+		return err
+	}
+
+	p, err := CreatePayer(config.Ctx, log, config.PayerConfig, config.NodeAddress, config.ChainID, config.Spender)
+	if err != nil {
+		return err
+	}
+	ethPayer, ok := p.(*eth.Payer)
+	if !ok {
+		return errs.New("offline signing is not supported by the %s payer", p)
+	}
+
+	dbPath := payouts.DBPathFromDir(runDir)
+	db, err := pipelinedb.OpenDB(context.Background(), dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	fmt.Printf("Planning %q payout...\n", config.Name)
+	plan, err := payouts.PlanOffline(config.Ctx, log, payouts.Config{
+		Quoter:        guard,
+		Symbol:        symbol,
+		Price:         price,
+		SpendLimits:   spendLimits,
+		PromptConfirm: promptConfirm,
+	}, db, ethPayer, chainID, config.Count)
+	if err != nil {
+		return err
+	}
+
+	if err := payouts.WriteOfflinePlan(config.PlanPath, plan); err != nil {
+		return err
+	}
+
+	if err := db.Close(); err != nil {
+		return errs.New("failed to close database: %v", err)
+	}
+
+	fmt.Printf("Planned %d transactions in %s. Sign them with the sign command on the host holding the spender key.\n", len(plan.Transactions), config.PlanPath)
+	return nil
+}
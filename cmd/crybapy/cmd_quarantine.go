@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+type quarantineConfig struct {
+	*rootConfig
+	Name string
+}
+
+func newQuarantineCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &quarantineConfig{
+		rootConfig: rootConfig,
+	}
+	cmd := &cobra.Command{
+		Use:   "quarantine NAME",
+		Short: "Lists the payouts in quarantined payout groups",
+		Long: "Lists the payouts in payout groups that were quarantined after their transaction failed " +
+			"when running with --quarantine, as CSV, along with the failed transaction and the reason.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.Name = args[0]
+			return checkCmd(doQuarantine(config))
+		},
+	}
+	return cmd
+}
+
+func doQuarantine(config *quarantineConfig) error {
+	dbPath := payouts.DBPathFromDir(filepath.Join(config.DataDir, config.Name))
+	db, err := pipelinedb.OpenDB(context.Background(), dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	count, err := payouts.QuarantineReport(config.Ctx, db, os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d payout groups quarantined.\n", count)
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+type repriceConfig struct {
+	*rootConfig
+	PriceGuardConfig
+	Name                    string
+	CoinMarketCapAPIURL     string
+	CoinMarketCapAPIKeyPath string
+	Price                   string
+	PayerConfig
+}
+
+func newRepriceCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &repriceConfig{
+		rootConfig: rootConfig,
+	}
+	cmd := &cobra.Command{
+		Use:   "reprice NAME",
+		Short: "Replaces the price locked for a payout",
+		Long: "Replaces the STORJ (or native coin) price locked for a payout with a fresh CoinMarketCap quote or an operator provided price. " +
+			"The coin or token is taken from the same --type, --token-symbol and --native-symbol flags as the run, and must match the locked price. " +
+			"Transactions that have already been sent keep the price they were sent with.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.Name = args[0]
+			return checkCmd(doReprice(config))
+		},
+	}
+	cmd.Flags().StringVarP(
+		&config.CoinMarketCapAPIURL,
+		"coinmarkcap-api-url", "",
+		coinmarketcap.ProductionAPIURL,
+		"CoinMarketCap API URL")
+	cmd.Flags().StringVarP(
+		&config.CoinMarketCapAPIKeyPath,
+		"coinmarkcap-api-key-path", "",
+		filepath.Join(homeDir, ".coinmarketcapkey"),
+		"Path on disk to the CoinMarketCap API key")
+	cmd.Flags().StringVarP(
+		&config.Price,
+		"price", "",
+		"",
+		"Price in USD to lock instead of a CoinMarketCap quote")
+	registerQuoteSymbolFlags(cmd, &config.PayerConfig)
+	registerPriceGuardFlags(cmd, &config.PriceGuardConfig)
+	return cmd
+}
+
+func doReprice(config *repriceConfig) error {
+	price, err := convertPrice(config.Price)
+	if err != nil {
+		return err
+	}
+
+	symbol, err := quoteSymbol(config.PayerConfig)
+	if err != nil {
+		return err
+	}
+
+	var quoter coinmarketcap.Quoter
+	if price == nil {
+		coinMarketCapAPIKey, err := loadFirstLine(config.CoinMarketCapAPIKeyPath)
+		if err != nil {
+			return errs.New("failed to load CoinMarketCap key: %v\n", err)
+		}
+
+		quoter, err = coinmarketcap.NewCachingClient(config.CoinMarketCapAPIURL, coinMarketCapAPIKey, time.Second*5)
+		if err != nil {
+			return errs.New("failed instantiate coinmarketcap client: %v\n", err)
+		}
+	}
+
+	guard, err := newPriceGuard(quoter, config.PriceGuardConfig)
+	if err != nil {
+		return err
+	}
+	if price != nil {
+		if err := guard.CheckPrice(*price); err != nil {
+			return err
+		}
+	}
+
+	dbPath := payouts.DBPathFromDir(filepath.Join(config.DataDir, config.Name))
+	db, err := pipelinedb.OpenDB(context.Background(), dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := setPriceReference(config.Ctx, guard, db, config.DataDir, config.Name, symbol); err != nil {
+		return err
+	}
+
+	fmt.Printf("Repricing %q payout...\n", config.Name)
+	err = payouts.Reprice(config.Ctx, payouts.Config{
+		Quoter:        guard,
+		Symbol:        symbol,
+		Price:         price,
+		PromptConfirm: promptConfirm,
+	}, db)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Close(); err != nil {
+		return errs.New("failed to close database: %v", err)
+	}
+
+	fmt.Println("Price locked.")
+	return nil
+}
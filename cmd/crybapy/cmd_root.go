@@ -54,11 +54,18 @@ func newRootCommand() *cobra.Command {
 
 	cmd.AddCommand(newImportCommand(config))
 	cmd.AddCommand(newRunCommand(config))
+	cmd.AddCommand(newPlanCommand(config))
+	cmd.AddCommand(newSignCommand(config))
+	cmd.AddCommand(newBroadcastCommand(config))
+	cmd.AddCommand(newRepriceCommand(config))
 	cmd.AddCommand(newStatCommand(config))
 	cmd.AddCommand(newAuditCommand(config))
+	cmd.AddCommand(newQuarantineCommand(config))
+	cmd.AddCommand(newNonceCommand(config))
 	cmd.AddCommand(newPriceCommand(config))
 	cmd.AddCommand(newZkSyncCommand(config))
 	cmd.AddCommand(newPayerCommand(config))
+	cmd.AddCommand(newSignerCommand(config))
 	return cmd
 }
 
@@ -3,15 +3,21 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
 	"time"
 
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
 	"github.com/zeebo/errs"
+	"go.uber.org/zap"
 
 	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/metrics"
+	"storj.io/crypto-batch-payment/pkg/payer"
 	"storj.io/crypto-batch-payment/pkg/payouts"
 	"storj.io/crypto-batch-payment/pkg/pipeline"
 )
@@ -19,15 +25,25 @@ import (
 type runConfig struct {
 	*rootConfig
 	PayerConfig
+	PriceGuardConfig
+	SpendLimitsConfig
 	Name                    string
 	SpenderKeyPath          string
+	ExtraSpenderKeyPaths    []string
 	CoinMarketCapAPIURL     string
 	CoinMarketCapAPIKeyPath string
 	QuoteCacheExpiry        time.Duration
 	PipelineLimit           int
 	TxDelay                 time.Duration
+	ReplaceAfter            time.Duration
+	RetryBudget             time.Duration
+	Quarantine              bool
+	MaxQuarantined          int
+	Price                   string
 	SkipConfirmation        bool
 	Drain                   bool
+	Simulate                bool
+	MetricsAddr             string
 }
 
 func newRunCommand(rootConfig *rootConfig) *cobra.Command {
@@ -37,7 +53,10 @@ func newRunCommand(rootConfig *rootConfig) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "run NAME SPENDERKEYPATH",
 		Short: "Runs payout",
-		Args:  cobra.ExactArgs(2),
+		Long: "Runs the payout with the spender key in SPENDERKEYPATH. For safe type payment, SPENDERKEYPATH " +
+			"is the address of the Safe instead and the transfers are written to Safe Transaction Builder " +
+			"batches in --safe-batch-dir, to be executed by the owners of the Safe.",
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config.Name = args[0]
 			config.SpenderKeyPath = args[1]
@@ -72,6 +91,31 @@ func newRunCommand(rootConfig *rootConfig) *cobra.Command {
 		"tx-delay", "",
 		pipeline.DefaultTxDelay,
 		"How long to wait between sending individual transactions")
+	cmd.Flags().DurationVarP(
+		&config.ReplaceAfter,
+		"replace-after", "",
+		pipeline.DefaultReplaceAfter,
+		"How long a transaction can stay pending before it is replaced with one paying a higher fee (0 disables). Only applies to eth and polygon type payment.")
+	cmd.Flags().DurationVarP(
+		&config.RetryBudget,
+		"retry-budget", "",
+		pipeline.DefaultRetryBudget,
+		"How long to retry node and price provider calls that fail with transient errors (timeouts, rate limits, server errors) before halting (negative disables)")
+	cmd.Flags().BoolVarP(
+		&config.Quarantine,
+		"quarantine", "",
+		false,
+		"Quarantine payout groups whose transaction failed, unless due to an insufficient balance or allowance, and continue with the rest instead of halting")
+	cmd.Flags().IntVarP(
+		&config.MaxQuarantined,
+		"max-quarantined", "",
+		pipeline.DefaultMaxQuarantined,
+		"How many payout groups can be quarantined before halting")
+	cmd.Flags().StringVarP(
+		&config.Price,
+		"price", "",
+		"",
+		"Token price (or native coin price for native type payment) in USD to lock for the payout instead of a CoinMarketCap quote. Requires confirmation. Must match the locked price if one has already been locked.")
 	cmd.Flags().BoolVarP(
 		&config.SkipConfirmation,
 		"skip-confirmation", "",
@@ -82,11 +126,46 @@ func newRunCommand(rootConfig *rootConfig) *cobra.Command {
 		"drain", "",
 		false,
 		"Drain existing transactions only")
+	cmd.Flags().BoolVarP(
+		&config.Simulate,
+		"simulate", "",
+		false,
+		"Simulate every transfer against the pending state of the chain without signing or sending anything")
+	cmd.Flags().StringVarP(
+		&config.MetricsAddr,
+		"metrics-addr", "",
+		"",
+		"Address (e.g. localhost:9100) to serve metrics on at /metrics while the payout runs. Disabled if empty.")
+	cmd.Flags().StringArrayVarP(
+		&config.ExtraSpenderKeyPaths,
+		"extra-spender-key", "",
+		nil,
+		"Path on disk to another spender key to send payouts from in parallel with its own nonces. Can be repeated. Requires --owner, and each spender needs an allowance from the owner. Only applies to eth, polygon and rollup type payment.")
 	RegisterFlags(cmd, &config.PayerConfig)
+	registerPriceGuardFlags(cmd, &config.PriceGuardConfig)
+	registerSpendLimitsFlags(cmd, &config.SpendLimitsConfig)
 	return cmd
 }
 
 func doRun(config *runConfig) error {
+	price, err := convertPrice(config.Price)
+	if err != nil {
+		return err
+	}
+	if price != nil && config.SkipConfirmation {
+		return usageErr.New("--price requires confirmation and cannot be combined with --skip-confirmation\n")
+	}
+
+	symbol, err := quoteSymbol(config.PayerConfig)
+	if err != nil {
+		return err
+	}
+
+	spendLimits, err := newSpendLimits(config.SpendLimitsConfig)
+	if err != nil {
+		return err
+	}
+
 	coinMarketCapAPIKey, err := loadFirstLine(config.CoinMarketCapAPIKeyPath)
 	if err != nil {
 		return errs.New("failed to load CoinMarketCap key: %v\n", err)
@@ -97,6 +176,16 @@ func doRun(config *runConfig) error {
 		return errs.New("failed instantiate coinmarketcap client: %v\n", err)
 	}
 
+	guard, err := newPriceGuard(quoter, config.PriceGuardConfig)
+	if err != nil {
+		return err
+	}
+	if price != nil {
+		if err := guard.CheckPrice(*price); err != nil {
+			return err
+		}
+	}
+
 	promptConfirm := promptConfirm
 	if config.SkipConfirmation {
 		promptConfirm = func(label string) error {
@@ -118,6 +207,11 @@ func doRun(config *runConfig) error {
 		return err
 	}
 
+	lanes, err := createLanes(config, log, payer)
+	if err != nil {
+		return err
+	}
+
 	dbPath := payouts.DBPathFromDir(runDir)
 	db, err := pipelinedb.OpenDB(context.Background(), dbPath, false)
 	if err != nil {
@@ -125,12 +219,28 @@ func doRun(config *runConfig) error {
 	}
 	defer func() { _ = db.Close() }()
 
+	if err := setPriceReference(config.Ctx, guard, db, config.DataDir, config.Name, symbol); err != nil {
+		return err
+	}
+
 	payoutsConfig := payouts.Config{
-		Quoter:        quoter,
-		PipelineLimit: config.PipelineLimit,
-		TxDelay:       config.TxDelay,
-		Drain:         config.Drain,
-		PromptConfirm: promptConfirm,
+		Quoter:         guard,
+		Symbol:         symbol,
+		Price:          price,
+		PipelineLimit:  config.PipelineLimit,
+		TxDelay:        config.TxDelay,
+		Drain:          config.Drain,
+		ReplaceAfter:   config.ReplaceAfter,
+		RetryBudget:    config.RetryBudget,
+		Quarantine:     config.Quarantine,
+		MaxQuarantined: config.MaxQuarantined,
+		SpendLimits:    spendLimits,
+		Lanes:          lanes,
+		PromptConfirm:  promptConfirm,
+	}
+
+	if config.Simulate {
+		return doSimulate(config, log, payoutsConfig, db, payer)
 	}
 
 	err = payouts.Preview(config.Ctx, payoutsConfig, db, payer)
@@ -138,7 +248,32 @@ func doRun(config *runConfig) error {
 		return err
 	}
 
+	stopMetrics := func() {}
+	if config.MetricsAddr != "" {
+		listener, err := net.Listen("tcp", config.MetricsAddr)
+		if err != nil {
+			return errs.New("failed to listen for metrics on %q: %v\n", config.MetricsAddr, err)
+		}
+		ctx, cancel := context.WithCancel(config.Ctx)
+		defer cancel()
+		stopMetrics = cancel
+		go func() {
+			if err := metrics.Serve(ctx, log, listener, metrics.NewHandler(log, db, payer, lanes)); err != nil {
+				log.Warn("Metrics server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	payoutsConfig.Pause = watchPause(config.Ctx, log, runDir)
+	fmt.Printf("Create %s or send SIGUSR1 to pause; remove it or send SIGUSR2 to resume.\n", filepath.Join(runDir, pauseFileName))
+
 	err = payouts.Run(config.Ctx, log, payoutsConfig, db, payer)
+	stopMetrics()
+	if err != nil {
+		return err
+	}
+
+	stats, err := db.Stats(config.Ctx)
 	if err != nil {
 		return err
 	}
@@ -148,5 +283,73 @@ func doRun(config *runConfig) error {
 	}
 
 	fmt.Println("Payouts complete.")
+	if stats.QuarantinedGroups > 0 {
+		fmt.Printf("%d payout groups were quarantined. Run \"crybapy quarantine %s\" to list them.\n", stats.QuarantinedGroups, config.Name)
+	}
 	return nil
 }
+
+// doSimulate simulates the transfers of the payout groups left to send and
+// writes the transfers that would fail as CSV.
+func doSimulate(config *runConfig, log *zap.Logger, payoutsConfig payouts.Config, db *pipelinedb.DB, payer payer.Payer) error {
+	fmt.Fprintln(os.Stderr, "Simulating transfers...")
+	priceLock, err := payouts.Simulate(config.Ctx, log, payoutsConfig, db, payer)
+	if err != nil {
+		return err
+	}
+
+	total, failed, err := payouts.SimulationReport(config.Ctx, db, os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d transfers would fail at a %s price of $%s.\n", failed, total, payoutsConfig.Symbol, priceLock.Price)
+	if failed > 0 {
+		return errs.New("simulation failed")
+	}
+	return nil
+}
+
+// createLanes creates a pipeline lane for the spender key and each of the
+// extra spender keys. It returns no lanes if there are no extra spender keys.
+func createLanes(config *runConfig, log *zap.Logger, spenderPayer payer.Payer) ([]pipeline.Lane, error) {
+	if len(config.ExtraSpenderKeyPaths) == 0 {
+		return nil, nil
+	}
+	if config.Owner == "" {
+		return nil, usageErr.New("--extra-spender-key requires --owner since all spenders pay from the owner using an allowance\n")
+	}
+	pt, err := payer.TypeFromString(config.PayerType)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if pt != payer.Eth && pt != payer.Polygon && pt != payer.Rollup {
+		return nil, usageErr.New("--extra-spender-key is not supported for %s type payment\n", pt)
+	}
+
+	spenderAddress, err := loadSpenderAddress(config.PayerConfig, config.SpenderKeyPath, "spender")
+	if err != nil {
+		return nil, err
+	}
+	lanes := []pipeline.Lane{{Spender: spenderAddress, Payer: spenderPayer}}
+	seen := map[common.Address]bool{spenderAddress: true}
+
+	for _, keyPath := range config.ExtraSpenderKeyPaths {
+		address, err := loadSpenderAddress(config.PayerConfig, keyPath, "extra spender")
+		if err != nil {
+			return nil, err
+		}
+		if seen[address] {
+			return nil, usageErr.New("spender %s is used more than once\n", address)
+		}
+		seen[address] = true
+
+		lanePayer, err := CreatePayer(config.Ctx, log, config.PayerConfig, config.NodeAddress, config.ChainID, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		lanes = append(lanes, pipeline.Lane{Spender: address, Payer: lanePayer})
+	}
+
+	fmt.Printf("Sending from %d spenders in parallel.\n", len(lanes))
+	return lanes, nil
+}
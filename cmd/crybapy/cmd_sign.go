@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+)
+
+type signConfig struct {
+	*rootConfig
+	PlanPath             string
+	SpenderKeyPath       string
+	SignedPlanPath       string
+	SpenderKeyPassphrase string
+}
+
+func newSignCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &signConfig{
+		rootConfig: rootConfig,
+	}
+	cmd := &cobra.Command{
+		Use:   "sign PLANFILE SPENDERKEYPATH SIGNEDPLANFILE",
+		Short: "Signs the transactions planned with the plan command",
+		Long: "Checks that each transaction in PLANFILE pays out its payouts and nothing else, " +
+			"then signs them with the spender key after confirmation and writes them to SIGNEDPLANFILE. " +
+			"It does not need the payout database or the node, so it can run on an offline host.",
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.PlanPath = args[0]
+			config.SpenderKeyPath = args[1]
+			config.SignedPlanPath = args[2]
+			return checkCmd(doSign(config))
+		},
+	}
+	registerSpenderKeyPassphrase(cmd, &config.SpenderKeyPassphrase)
+	return cmd
+}
+
+func doSign(config *signConfig) error {
+	plan, err := payouts.ReadOfflinePlan(config.PlanPath)
+	if err != nil {
+		return err
+	}
+
+	spenderKey, _, err := loadETHKey(config.SpenderKeyPath, "spender", config.SpenderKeyPassphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := payouts.SignOfflinePlan(config.Ctx, plan, eth.NewKeySigner(spenderKey, plan.ChainID), promptConfirm); err != nil {
+		return err
+	}
+
+	if err := payouts.WriteOfflinePlan(config.SignedPlanPath, plan); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed %d transactions in %s. Send them with the broadcast command.\n", len(plan.Transactions), config.SignedPlanPath)
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+const defaultSignerAddr = "127.0.0.1:8550"
+
+type signerConfig struct {
+	*rootConfig
+	SpenderKeyPath       string
+	SpenderKeyPassphrase string
+	ListenAddr           string
+	Socket               string
+	AuthTokenFile        string
+	ContractAddress      string
+	DisperseAddress      string
+}
+
+func newSignerCommand(rootConfig *rootConfig) *cobra.Command {
+	config := &signerConfig{
+		rootConfig: rootConfig,
+	}
+	cmd := &cobra.Command{
+		Use:   "signer SPENDERKEYPATH",
+		Short: "Serve a stand-in for Clef that signs transactions with the spender key, for use with --external-signer",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.SpenderKeyPath = args[0]
+			return checkCmd(doSigner(config))
+		},
+	}
+	cmd.Flags().StringVarP(
+		&config.ListenAddr,
+		"listen-addr", "",
+		defaultSignerAddr,
+		"Address to serve the signer JSON-RPC API on over HTTP. Requires --auth-token-file.")
+	cmd.Flags().StringVarP(
+		&config.Socket,
+		"socket", "",
+		"",
+		"Path of a unix socket to serve the signer JSON-RPC API on instead of --listen-addr. Only the owner can connect to it.")
+	cmd.Flags().StringVarP(
+		&config.AuthTokenFile,
+		"auth-token-file", "",
+		"",
+		"File holding the auth token requests over HTTP must carry as a bearer token. It must only be readable by its owner.")
+	cmd.Flags().StringVarP(
+		&config.ContractAddress,
+		"contract", "",
+		storjtoken.DefaultContractAddress.String(),
+		"Address of the ERC-20 token contract, the only token whose transfers are signed (STORJ by default)")
+	cmd.Flags().StringVarP(
+		&config.DisperseAddress,
+		"disperse-contract", "",
+		"",
+		"Address of the disperse contract whose calls are signed, if any")
+	registerSpenderKeyPassphrase(cmd, &config.SpenderKeyPassphrase)
+	return cmd
+}
+
+func doSigner(config *signerConfig) error {
+	chainID, err := convertInt(config.ChainID, 0, "chain-id")
+	if err != nil {
+		return err
+	}
+
+	spenderKey, _, err := loadETHKey(config.SpenderKeyPath, "spender", config.SpenderKeyPassphrase)
+	if err != nil {
+		return err
+	}
+
+	log, err := openConsoleLog()
+	if err != nil {
+		return err
+	}
+
+	contractAddress, err := convertAddress(config.ContractAddress, "contract")
+	if err != nil {
+		return err
+	}
+	var disperseAddress *common.Address
+	if config.DisperseAddress != "" {
+		address, err := convertAddress(config.DisperseAddress, "disperse-contract")
+		if err != nil {
+			return err
+		}
+		disperseAddress = &address
+	}
+
+	listener, authToken, err := listenSigner(config)
+	if err != nil {
+		return err
+	}
+
+	service := eth.NewSignerService(log, eth.NewKeySigner(spenderKey, chainID), chainID, contractAddress, disperseAddress)
+	return service.Serve(config.Ctx, listener, authToken)
+}
+
+// listenSigner listens on the unix socket, if any, or else on the TCP address,
+// which requires an auth token.
+func listenSigner(config *signerConfig) (net.Listener, string, error) {
+	if config.Socket != "" {
+		listener, err := net.Listen("unix", config.Socket)
+		if err != nil {
+			return nil, "", errs.New("failed to listen for signer on %q: %v\n", config.Socket, err)
+		}
+		// Only the owner may connect, like to the IPC endpoint of geth.
+		if err := os.Chmod(config.Socket, 0600); err != nil {
+			_ = listener.Close()
+			return nil, "", errs.Wrap(err)
+		}
+		return listener, "", nil
+	}
+
+	if config.AuthTokenFile == "" {
+		return nil, "", usageErr.New("--auth-token-file is required to serve the signer on --listen-addr; use --socket otherwise\n")
+	}
+	authToken, err := eth.LoadAuthToken(config.AuthTokenFile)
+	if err != nil {
+		return nil, "", err
+	}
+	listener, err := net.Listen("tcp", config.ListenAddr)
+	if err != nil {
+		return nil, "", errs.New("failed to listen for signer on %q: %v\n", config.ListenAddr, err)
+	}
+	return listener, authToken, nil
+}
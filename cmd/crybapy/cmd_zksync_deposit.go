@@ -24,6 +24,8 @@ type zkSyncDepositConfig struct {
 	SpenderKeyPath string
 	GasTipCap      int64
 	ZkNodeAddress  string
+
+	SpenderKeyPassphrase string
 }
 
 func newZkSyncDepositCommand(zkSyncConfig *zkSyncConfig) *cobra.Command {
@@ -51,6 +53,7 @@ func newZkSyncDepositCommand(zkSyncConfig *zkSyncConfig) *cobra.Command {
 		"zk-node-address", "",
 		"https://api.zksync.io",
 		"ZkSync api address")
+	registerSpenderKeyPassphrase(cmd, &config.SpenderKeyPassphrase)
 	return cmd
 }
 
@@ -63,7 +66,7 @@ func doZkSyncDeposit(config *zkSyncDepositConfig) error {
 		return errs.New("Please specify both zkSync and ethereum rpc API address.")
 	}
 
-	spenderKey, spenderAddress, err := loadETHKey(config.SpenderKeyPath, "spender")
+	spenderKey, spenderAddress, err := loadETHKey(config.SpenderKeyPath, "spender", config.SpenderKeyPassphrase)
 	if err != nil {
 		return err
 	}
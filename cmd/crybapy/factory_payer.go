@@ -2,37 +2,76 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
 
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
 	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/ethkey"
 	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/rollup"
+	"storj.io/crypto-batch-payment/pkg/safe"
 	"storj.io/crypto-batch-payment/pkg/storjtoken"
 	"storj.io/crypto-batch-payment/pkg/zksyncera"
 )
 
+// polygonMaxGas is the default max gas price for polygon type payment, in
+// Wei.
+const polygonMaxGas = "500" + "000" + "000" + "000"
+
 type PayerConfig struct {
 	PayerType string
 
 	ContractAddress string
 	Owner           string
 
+	TokenSymbol   string
+	TokenDecimals int32
+
+	DisperseAddress string
+
 	MaxGas string
 	MaxFee string
 
+	Confirmations uint64
+
 	GasTipCap string
 
 	PaymasterAddress string
 	PaymasterPayload string
+
+	SpenderKeyPassphrase        string
+	ExternalSigner              string
+	ExternalSignerAuthTokenFile string
+
+	SafeBatchDir string
+
+	RollupStack string
+
+	NativeSymbol string
+
+	// Offline, if true, creates the payer for a spender whose key is kept
+	// offline. Spender keys are then given as spender addresses and nothing
+	// can be signed.
+	Offline bool
+
+	// flags are the flags of the command, which tell whether a flag was
+	// set or is left to the default of the payment type.
+	flags *pflag.FlagSet
 }
 
 func RegisterFlags(cmd *cobra.Command, config *PayerConfig) {
+	config.flags = cmd.Flags()
+	registerQuoteSymbolFlags(cmd, config)
 	cmd.Flags().StringVarP(
 		&config.GasTipCap,
 		"gas-tip-cap", "",
@@ -47,22 +86,32 @@ func RegisterFlags(cmd *cobra.Command, config *PayerConfig) {
 		&config.ContractAddress,
 		"contract", "",
 		storjtoken.DefaultContractAddress.String(),
-		"Address of the STORJ contract on the network")
+		"Address of the ERC-20 token contract on the network (STORJ by default, bridged STORJ for polygon type payment)")
+	cmd.Flags().Int32VarP(
+		&config.TokenDecimals,
+		"token-decimals", "",
+		-1,
+		"Decimals the ERC-20 token contract is expected to have. The payer refuses a contract with other decimals (-1 skips the check).")
+	cmd.Flags().StringVarP(
+		&config.DisperseAddress,
+		"disperse-contract", "",
+		"",
+		"Address of the disperse contract used to pay out multi-payout groups in a single transaction. Only applies to eth, polygon and zksync-era type payment.")
 	cmd.Flags().StringVarP(
 		&config.MaxGas,
 		"max-gas", "",
 		"70"+"000"+"000"+"000",
-		"Max gas price we're willing to consider in Wei (tip + base fee). Default: 70 GWei. Only applies to Eth type payment.")
-	cmd.Flags().StringVarP(
-		&config.PayerType,
-		"type", "",
-		payer.Eth.String(),
-		"Type of the payment (eth,zksync-era,zksync,zkwithdraw,sim,polygon)")
+		"Max gas price we're willing to consider in Wei (tip + base fee). Default: 70 GWei, or 500 GWei for polygon type payment. Only applies to eth and polygon type payment.")
+	cmd.Flags().Uint64VarP(
+		&config.Confirmations,
+		"confirmations", "",
+		eth.DefaultConfirmations,
+		"Number of blocks a transaction must be buried under (including its own) before it is considered final. Only applies to eth and polygon type payment.")
 	cmd.Flags().StringVarP(
 		&config.MaxFee,
 		"max-fee", "",
 		"",
-		"Max fee we're willing to consider. Only applies to zksync or zkwithdraw type payment.")
+		"Max fee in wei we're willing to pay per payout. Transactions paying out a group may cost up to the max fee times the group size. Only applies to zksync-era type payment.")
 	cmd.Flags().StringVarP(
 		&config.PaymasterAddress,
 		"paymaster-address", "",
@@ -73,6 +122,73 @@ func RegisterFlags(cmd *cobra.Command, config *PayerConfig) {
 		"paymaster-payload", "",
 		"",
 		"Payload for the paymaster to be used.")
+	cmd.Flags().StringVarP(
+		&config.SafeBatchDir,
+		"safe-batch-dir", "",
+		"",
+		"Directory to write the Safe Transaction Builder batches to. Only applies to safe type payment, for which the spender is the address of the Safe.")
+	cmd.Flags().StringVarP(
+		&config.RollupStack,
+		"rollup-stack", "",
+		"",
+		"Stack of the rollup (op-stack for Optimism and Base, or arbitrum), which decides how the L1 data fee is estimated. Only applies to rollup type payment.")
+	registerSpenderKeyPassphrase(cmd, &config.SpenderKeyPassphrase)
+	cmd.Flags().StringVarP(
+		&config.ExternalSigner,
+		"external-signer", "",
+		"",
+		"JSON-RPC endpoint of an external signer, like Clef, holding the spender keys. Spender keys are then given as spender addresses. Only applies to eth, polygon, rollup and native type payment.")
+	cmd.Flags().StringVarP(
+		&config.ExternalSignerAuthTokenFile,
+		"external-signer-auth-token-file", "",
+		"",
+		"File holding the auth token of the external signer, sent as a bearer token with every request over HTTP. It must only be readable by its owner.")
+}
+
+// registerQuoteSymbolFlags registers the flags that decide what the payouts
+// are paid in, for commands that need to know the quote symbol but do not
+// create a payer.
+func registerQuoteSymbolFlags(cmd *cobra.Command, config *PayerConfig) {
+	cmd.Flags().StringVarP(
+		&config.PayerType,
+		"type", "",
+		payer.Eth.String(),
+		"Type of the payment (eth,zksync-era,zksync,zkwithdraw,sim,polygon,rollup,safe,native)")
+	cmd.Flags().StringVarP(
+		&config.TokenSymbol,
+		"token-symbol", "",
+		coinmarketcap.STORJ,
+		"Symbol of the ERC-20 token, quoted to convert the USD amounts (e.g. USDC)")
+	cmd.Flags().StringVarP(
+		&config.NativeSymbol,
+		"native-symbol", "",
+		coinmarketcap.ETH,
+		"Symbol of the native coin of the chain (e.g. ETH or MATIC), quoted to convert the USD amounts. Only applies to native type payment.")
+}
+
+// quoteSymbol returns the symbol of the coin or token the payouts are paid
+// in, which is quoted to convert the USD amounts.
+func quoteSymbol(config PayerConfig) (coinmarketcap.Symbol, error) {
+	pt, err := payer.TypeFromString(config.PayerType)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	symbol, flag := config.TokenSymbol, "--token-symbol"
+	if pt == payer.Native {
+		symbol, flag = config.NativeSymbol, "--native-symbol"
+	}
+	if symbol == "" {
+		return "", usageErr.New("%s is required for %s type payment\n", flag, pt)
+	}
+	return coinmarketcap.Symbol(strings.ToUpper(symbol)), nil
+}
+
+func registerSpenderKeyPassphrase(cmd *cobra.Command, passphrase *string) {
+	cmd.Flags().StringVarP(
+		passphrase,
+		"spender-key-passphrase", "",
+		string(ethkey.PassphrasePrompt),
+		"Where to read the passphrase of encrypted spender keystores from (prompt, fd:<N> or env:<NAME>). Plain hex keys need no passphrase.")
 }
 
 func registerNodeAddress(cmd *cobra.Command, addr *string) {
@@ -82,32 +198,61 @@ func registerNodeAddress(cmd *cobra.Command, addr *string) {
 		"/home/storj/.ethereum/geth.ipc",
 		"Address of the ETH node to use")
 }
-func CreatePayer(ctx context.Context, log *zap.Logger, config PayerConfig, nodeAddress string, chain string, spenderKeyPath string) (paymentPayer payer.Payer, err error) {
-	spenderKey, spenderAddress, err := loadETHKey(spenderKeyPath, "spender")
+
+// CreatePayer creates the payer of the configured type. Spender is the path
+// to the spender key or, with --external-signer or an offline spender key,
+// the spender address. For safe type payment it is the address of the Safe.
+func CreatePayer(ctx context.Context, log *zap.Logger, config PayerConfig, nodeAddress string, chain string, spender string) (paymentPayer payer.Payer, err error) {
+	pt, err := payer.TypeFromString(config.PayerType)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	config, chain = withTypeDefaults(config, pt, chain)
+	chainID, err := convertInt(chain, 0, "chain-id")
+	if err != nil {
+		return nil, err
+	}
+
+	spenderSigner, spenderKey, err := loadSpender(ctx, config, pt, spender, chainID)
 	if err != nil {
 		return nil, err
 	}
+
 	var maxGas big.Int
 	_, ok := maxGas.SetString(config.MaxGas, 10)
 	if !ok {
 		return nil, errs.New("invalid max gas setting")
 	}
 
-	owner := spenderAddress
+	owner := spenderSigner.Address()
 	if config.Owner != "" {
 		owner, err = convertAddress(config.Owner, "owner")
 		if err != nil {
 			return nil, err
 		}
 	}
+	if pt == payer.Safe && owner != spenderSigner.Address() {
+		return nil, usageErr.New("--owner is not supported for safe type payment since the Safe pays from its own balance\n")
+	}
+	if pt == payer.Native && owner != spenderSigner.Address() {
+		return nil, usageErr.New("--owner is not supported for native type payment since the spender pays from its own balance\n")
+	}
+	if pt == payer.Native && config.DisperseAddress != "" {
+		return nil, usageErr.New("--disperse-contract is not supported for native type payment\n")
+	}
 
 	contractAddress, err := convertAddress(config.ContractAddress, "contract")
 	if err != nil {
 		return nil, err
 	}
-	chainID, err := convertInt(chain, 0, "chain-id")
-	if err != nil {
-		return nil, err
+	var disperseAddress *common.Address
+	if config.DisperseAddress != "" {
+		a, err := convertAddress(config.DisperseAddress, "disperse-contract")
+		if err != nil {
+			return nil, err
+		}
+		disperseAddress = &a
 	}
 
 	var maxFee *big.Int
@@ -135,10 +280,6 @@ func CreatePayer(ctx context.Context, log *zap.Logger, config PayerConfig, nodeA
 		}
 	}
 
-	pt, err := payer.TypeFromString(config.PayerType)
-	if err != nil {
-		return nil, errs.Wrap(err)
-	}
 	switch pt {
 	case payer.Eth, payer.Polygon:
 		var client *ethclient.Client
@@ -148,14 +289,117 @@ func CreatePayer(ctx context.Context, log *zap.Logger, config PayerConfig, nodeA
 		}
 		defer client.Close()
 
+		var fees eth.FeeStrategy
+		if gasTipCap != nil {
+			fees = eth.NewFixedFees(gasTipCap)
+		}
+		if pt == payer.Polygon {
+			// Polygon PoS validators never include transactions tipping
+			// less than the minimum.
+			if fees == nil {
+				fees = eth.NewFixedFees(big.NewInt(eth.PolygonMinGasTipCap))
+			}
+			fees = eth.NewMinTipFees(fees, big.NewInt(eth.PolygonMinGasTipCap))
+		}
+
 		paymentPayer, err = eth.NewPayer(ctx,
 			client,
 			contractAddress,
 			owner,
-			spenderKey,
+			spenderSigner,
+			fees,
+			&maxGas,
+			disperseAddress,
+			config.Confirmations,
+		)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	case payer.Native:
+		var client *ethclient.Client
+		client, err = ethclient.Dial(nodeAddress)
+		if err != nil {
+			return paymentPayer, errs.New("Failed to dial node %q: %v\n", nodeAddress, err)
+		}
+
+		var fees eth.FeeStrategy
+		if gasTipCap != nil {
+			fees = eth.NewFixedFees(gasTipCap)
+		}
+
+		paymentPayer, err = eth.NewNativePayer(ctx,
+			client,
+			spenderSigner,
+			fees,
+			&maxGas,
+			config.Confirmations,
+		)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	case payer.Rollup:
+		stack, err := rollup.StackFromString(config.RollupStack)
+		if err != nil {
+			return nil, usageErr.New("--rollup-stack must be op-stack or arbitrum for rollup type payment\n")
+		}
+		var client *ethclient.Client
+		client, err = ethclient.Dial(nodeAddress)
+		if err != nil {
+			return paymentPayer, errs.New("Failed to dial node %q: %v\n", nodeAddress, err)
+		}
+
+		var fees eth.FeeStrategy
+		if gasTipCap != nil {
+			fees = eth.NewFixedFees(gasTipCap)
+		}
+
+		oracle, err := rollup.NewL1FeeOracle(stack, client)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		paymentPayer, err = rollup.NewPayer(ctx,
+			client,
+			oracle,
 			chainID,
-			gasTipCap,
+			contractAddress,
+			owner,
+			spenderSigner,
+			fees,
 			&maxGas,
+			disperseAddress,
+			config.Confirmations,
+		)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	case payer.Safe:
+		if config.SafeBatchDir == "" {
+			return nil, usageErr.New("--safe-batch-dir is required for safe type payment\n")
+		}
+		var client *ethclient.Client
+		client, err = ethclient.Dial(nodeAddress)
+		if err != nil {
+			return paymentPayer, errs.New("Failed to dial node %q: %v\n", nodeAddress, err)
+		}
+		if chainID.Sign() == 0 {
+			chainID, err = client.ChainID(ctx)
+			if err != nil {
+				return nil, errs.Wrap(err)
+			}
+		}
+
+		symbol, err := quoteSymbol(config)
+		if err != nil {
+			return nil, err
+		}
+		paymentPayer, err = safe.NewPayer(ctx,
+			client,
+			contractAddress,
+			string(symbol),
+			owner,
+			chainID,
+			config.SafeBatchDir,
+			config.Confirmations,
 		)
 		if err != nil {
 			return nil, errs.Wrap(err)
@@ -175,6 +419,7 @@ func CreatePayer(ctx context.Context, log *zap.Logger, config PayerConfig, nodeA
 			int(chainID.Int64()),
 			paymasterAddress,
 			paymasterPayload,
+			disperseAddress,
 			maxFee)
 		if err != nil {
 			return nil, errs.Wrap(err)
@@ -187,5 +432,109 @@ func CreatePayer(ctx context.Context, log *zap.Logger, config PayerConfig, nodeA
 	default:
 		return nil, errs.New("unsupported payer type: %v", config.PayerType)
 	}
+
+	// Paying with a token of other decimals than expected would pay out
+	// orders of magnitude too much or too little.
+	if config.TokenDecimals >= 0 && pt != payer.Native && pt != payer.Sim {
+		decimals, err := paymentPayer.GetTokenDecimals(ctx)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		if decimals != config.TokenDecimals {
+			return nil, errs.New("token contract %s has %d decimals; expected %d", contractAddress, decimals, config.TokenDecimals)
+		}
+	}
 	return paymentPayer, nil
 }
+
+// withTypeDefaults returns the config and chain ID with the flags that were
+// not set changed from the Ethereum defaults to those of the payment type.
+func withTypeDefaults(config PayerConfig, pt payer.Type, chain string) (PayerConfig, string) {
+	if pt != payer.Polygon || config.flags == nil {
+		return config, chain
+	}
+	if !config.flags.Changed("max-gas") {
+		config.MaxGas = polygonMaxGas
+	}
+	if !config.flags.Changed("contract") {
+		config.ContractAddress = storjtoken.PolygonContractAddress.String()
+	}
+	if !config.flags.Changed("chain-id") {
+		chain = storjtoken.PolygonChainID.String()
+	}
+	return config, chain
+}
+
+// payerChainID returns the chain ID of the configured payment type, unless
+// --chain-id is set.
+func payerChainID(config PayerConfig, chain string) (*big.Int, error) {
+	pt, err := payer.TypeFromString(config.PayerType)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	_, chain = withTypeDefaults(config, pt, chain)
+	return convertInt(chain, 0, "chain-id")
+}
+
+// loadSpender returns the signer of the spender. With --external-signer,
+// spender is the address of the spender and its key stays with the external
+// signer, so no key is returned. The same goes for an offline spender key
+// and a Safe, except that nothing can be signed. Otherwise spender is the
+// path to the key.
+func loadSpender(ctx context.Context, config PayerConfig, pt payer.Type, spender string, chainID *big.Int) (eth.Signer, *ecdsa.PrivateKey, error) {
+	if pt == payer.Safe {
+		// The owners of the Safe sign its transactions, so the spender is
+		// only the address of the Safe.
+		address, err := convertAddress(spender, "safe")
+		if err != nil {
+			return nil, nil, err
+		}
+		return eth.NewOfflineSigner(address), nil, nil
+	}
+	if config.Offline {
+		if pt != payer.Eth && pt != payer.Polygon {
+			return nil, nil, usageErr.New("offline signing is not supported for %s type payment\n", pt)
+		}
+		address, err := convertAddress(spender, "spender")
+		if err != nil {
+			return nil, nil, err
+		}
+		return eth.NewOfflineSigner(address), nil, nil
+	}
+	if config.ExternalSigner == "" {
+		spenderKey, _, err := loadETHKey(spender, "spender", config.SpenderKeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		return eth.NewKeySigner(spenderKey, chainID), spenderKey, nil
+	}
+
+	if pt != payer.Eth && pt != payer.Polygon && pt != payer.Rollup && pt != payer.Native {
+		return nil, nil, usageErr.New("--external-signer is not supported for %s type payment\n", pt)
+	}
+	address, err := convertAddress(spender, "spender")
+	if err != nil {
+		return nil, nil, err
+	}
+	var authToken string
+	if config.ExternalSignerAuthTokenFile != "" {
+		authToken, err = eth.LoadAuthToken(config.ExternalSignerAuthTokenFile)
+		if err != nil {
+			return nil, nil, errs.Wrap(err)
+		}
+	}
+	signer, err := eth.NewExternalSigner(ctx, config.ExternalSigner, authToken, address, chainID)
+	if err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+	return signer, nil, nil
+}
+
+// loadSpenderAddress returns the address of the spender without asking for
+// a passphrase or dialing the external signer.
+func loadSpenderAddress(config PayerConfig, spender, which string) (common.Address, error) {
+	if config.ExternalSigner != "" || config.Offline {
+		return convertAddress(spender, which)
+	}
+	return loadETHKeyAddress(spender, which)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+func TestWithTypeDefaults(t *testing.T) {
+	parse := func(t *testing.T, args ...string) (PayerConfig, string) {
+		var config PayerConfig
+		var chain string
+		cmd := &cobra.Command{}
+		cmd.Flags().StringVar(&chain, "chain-id", storjtoken.DefaultChainID.String(), "")
+		RegisterFlags(cmd, &config)
+		require.NoError(t, cmd.ParseFlags(args))
+		return config, chain
+	}
+
+	t.Run("eth", func(t *testing.T) {
+		config, chain := parse(t, "--type", "eth")
+		config, chain = withTypeDefaults(config, payer.Eth, chain)
+		require.Equal(t, "70000000000", config.MaxGas)
+		require.Equal(t, storjtoken.DefaultContractAddress.String(), config.ContractAddress)
+		require.Equal(t, "1", chain)
+	})
+
+	t.Run("polygon", func(t *testing.T) {
+		config, chain := parse(t, "--type", "polygon")
+		config, chain = withTypeDefaults(config, payer.Polygon, chain)
+		require.Equal(t, "500000000000", config.MaxGas)
+		require.Equal(t, storjtoken.PolygonContractAddress.String(), config.ContractAddress)
+		require.Equal(t, "137", chain)
+	})
+
+	t.Run("polygon with flags", func(t *testing.T) {
+		contract := "0x1111111111111111111111111111111111111111"
+		config, chain := parse(t, "--type", "polygon", "--max-gas", "600000000000", "--contract", contract, "--chain-id", "80002")
+		config, chain = withTypeDefaults(config, payer.Polygon, chain)
+		require.Equal(t, "600000000000", config.MaxGas)
+		require.Equal(t, contract, config.ContractAddress)
+		require.Equal(t, "80002", chain)
+
+		// The Ethereum defaults can be given explicitly too.
+		config, chain = parse(t, "--type", "polygon", "--chain-id", "1")
+		_, chain = withTypeDefaults(config, payer.Polygon, chain)
+		require.Equal(t, "1", chain)
+	})
+}
@@ -11,13 +11,14 @@ import (
 	"time"
 
 	cryptohopper "storj.io/crypto-batch-payment/pkg"
+	"storj.io/crypto-batch-payment/pkg/ethkey"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/manifoldco/promptui"
+	"github.com/shopspring/decimal"
 	"github.com/zeebo/errs"
 )
 
@@ -25,21 +26,17 @@ var (
 	usageErr = errs.Class("usage")
 )
 
-func loadETHKey(path, which string) (*ecdsa.PrivateKey, common.Address, error) {
-	fi, err := os.Stat(path)
-	if err != nil {
-		return nil, common.Address{}, errs.New("unable to stat %s key: %v\n", which, err)
-	}
-
-	if (fi.Mode() & 0177) != 0 {
-		return nil, common.Address{}, errs.New("%s mode %#o is too permissive (set to 0600)\n", path, fi.Mode())
+func loadETHKey(path, which, passphrase string) (*ecdsa.PrivateKey, common.Address, error) {
+	if err := ethkey.Passphrase(passphrase).Validate(); err != nil {
+		return nil, common.Address{}, usageErr.Wrap(err)
 	}
+	return ethkey.Load(path, which, ethkey.Passphrase(passphrase))
+}
 
-	key, err := crypto.LoadECDSA(path)
-	if err != nil {
-		return nil, common.Address{}, errs.New("unable to load %s key: %v\n", which, err)
-	}
-	return key, crypto.PubkeyToAddress(key.PublicKey), nil
+// loadETHKeyAddress returns the address of a key without decrypting it, so
+// that the passphrase of an encrypted keystore is only asked for once.
+func loadETHKeyAddress(path, which string) (common.Address, error) {
+	return ethkey.Address(path, which)
 }
 
 func dialNode(address string) (*ethclient.Client, error) {
@@ -72,6 +69,35 @@ func convertInt(s string, base int, which string) (*big.Int, error) {
 	return i, nil
 }
 
+// convertPrice parses an operator provided price. An empty string
+// means no price was provided.
+func convertPrice(s string) (*decimal.Decimal, error) {
+	if s == "" {
+		return nil, nil
+	}
+	price, err := decimal.NewFromString(s)
+	if err != nil {
+		return nil, usageErr.New("invalid price %q\n", s)
+	}
+	if !price.IsPositive() {
+		return nil, usageErr.New("price must be more than zero; got %q\n", s)
+	}
+	return &price, nil
+}
+
+// convertLimit parses an optional non-negative decimal flag value. An empty
+// string means the limit is disabled and is returned as zero.
+func convertLimit(s, flag string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	v, err := decimal.NewFromString(s)
+	if err != nil || v.IsNegative() {
+		return decimal.Zero, usageErr.New("invalid --%s %q\n", flag, s)
+	}
+	return v, nil
+}
+
 func promptConfirm(label string) error {
 	_, err := (&promptui.Prompt{
 		Label:     label,
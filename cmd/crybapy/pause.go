@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// pauseFileName is the name of the control file in the run directory
+	// that pauses the payout while it exists.
+	pauseFileName = "PAUSE"
+
+	// pauseFilePollInterval is how often to check for the control file.
+	pauseFilePollInterval = time.Second
+)
+
+// watchPause returns a channel that pauses (true) and resumes (false) the
+// pipeline. Creating the control file in the run directory or sending
+// SIGUSR1 pauses. Removing the control file or sending SIGUSR2 resumes.
+func watchPause(ctx context.Context, log *zap.Logger, runDir string) <-chan bool {
+	pauseFile := filepath.Join(runDir, pauseFileName)
+
+	sigCh := make(chan os.Signal, 1)
+	notifyPauseSignals(sigCh)
+
+	pauseCh := make(chan bool)
+	go func() {
+		defer signal.Stop(sigCh)
+
+		ticker := time.NewTicker(pauseFilePollInterval)
+		defer ticker.Stop()
+
+		var fileExists bool
+		for {
+			var paused bool
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-sigCh:
+				paused = isPauseSignal(sig)
+				log.Info("Pause control signal received", zap.String("signal", sig.String()), zap.Bool("paused", paused))
+			case <-ticker.C:
+				_, err := os.Stat(pauseFile)
+				exists := err == nil
+				if exists == fileExists {
+					continue
+				}
+				fileExists = exists
+				paused = exists
+				log.Info("Pause control file changed", zap.String("path", pauseFile), zap.Bool("paused", paused))
+			}
+
+			select {
+			case pauseCh <- paused:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return pauseCh
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func notifyPauseSignals(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+}
+
+func isPauseSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR1
+}
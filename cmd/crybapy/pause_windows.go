@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+)
+
+// notifyPauseSignals is a no-op since there are no user signals on Windows.
+// The pause control file still works.
+func notifyPauseSignals(ch chan<- os.Signal) {}
+
+func isPauseSignal(sig os.Signal) bool {
+	return false
+}
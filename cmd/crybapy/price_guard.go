@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+type PriceGuardConfig struct {
+	MaxQuoteAge       time.Duration
+	MaxPriceDeviation string
+	MinPrice          string
+	MaxPrice          string
+}
+
+func registerPriceGuardFlags(cmd *cobra.Command, config *PriceGuardConfig) {
+	cmd.Flags().DurationVarP(
+		&config.MaxQuoteAge,
+		"max-quote-age", "",
+		coinmarketcap.DefaultMaxQuoteAge,
+		"Reject price quotes last updated longer ago than this (0 disables)")
+	cmd.Flags().StringVarP(
+		&config.MaxPriceDeviation,
+		"max-price-deviation", "",
+		"",
+		"Reject price quotes that deviate more than this fraction (e.g. 0.1 for 10%) from the price locked for the payout or, if none is locked yet, the latest price locked by another payout in the data directory (empty disables)")
+	cmd.Flags().StringVarP(
+		&config.MinPrice,
+		"min-price", "",
+		"",
+		"Reject prices below this USD value (empty disables)")
+	cmd.Flags().StringVarP(
+		&config.MaxPrice,
+		"max-price", "",
+		"",
+		"Reject prices above this USD value (empty disables)")
+}
+
+func newPriceGuard(quoter coinmarketcap.Quoter, config PriceGuardConfig) (_ *coinmarketcap.Guard, err error) {
+	guardConfig := coinmarketcap.GuardConfig{
+		MaxAge: config.MaxQuoteAge,
+	}
+	if guardConfig.MaxDeviation, err = convertLimit(config.MaxPriceDeviation, "max-price-deviation"); err != nil {
+		return nil, err
+	}
+	if guardConfig.MinPrice, err = convertLimit(config.MinPrice, "min-price"); err != nil {
+		return nil, err
+	}
+	if guardConfig.MaxPrice, err = convertLimit(config.MaxPrice, "max-price"); err != nil {
+		return nil, err
+	}
+	return coinmarketcap.NewGuard(quoter, guardConfig), nil
+}
+
+// setPriceReference sets the price that quotes are checked for deviation
+// from: the price locked for the payout or, if none is locked yet, the latest
+// price locked by another payout in the data directory.
+func setPriceReference(ctx context.Context, guard *coinmarketcap.Guard, db *pipelinedb.DB, dataDir, name string, symbol coinmarketcap.Symbol) error {
+	lock, err := db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		lock, err = payouts.PreviousPriceLock(ctx, dataDir, name, symbol)
+		if err != nil {
+			return err
+		}
+	}
+	if lock != nil {
+		guard.SetReference(symbol, lock.Price)
+	}
+	return nil
+}
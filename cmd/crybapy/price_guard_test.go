@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/ethtest"
+	"storj.io/crypto-batch-payment/pkg/payouts"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+func TestRunPriceDeviation(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	symbol := coinmarketcap.Symbol(coinmarketcap.STORJ)
+
+	newDB := func(t *testing.T, name string) *pipelinedb.DB {
+		runDir := filepath.Join(dataDir, name)
+		require.NoError(t, os.MkdirAll(runDir, 0755))
+		db, err := pipelinedb.NewDB(ctx, payouts.DBPathFromDir(runDir))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+	lockPrice := func(t *testing.T, db *pipelinedb.DB, price string, at time.Time) {
+		require.NoError(t, db.LockPrice(ctx, pipelinedb.PriceLock{
+			Symbol:    string(symbol),
+			Price:     decimal.RequireFromString(price),
+			Source:    pipelinedb.PriceSourceCoinMarketCap,
+			Timestamp: at,
+		}))
+	}
+
+	// Payouts run earlier locked a price. The latest one is the reference
+	// for the first quote of a new payout.
+	older := newDB(t, "older")
+	lockPrice(t, older, "2.00", time.Now().Add(-2*time.Hour))
+	require.NoError(t, older.Close())
+	previous := newDB(t, "previous")
+	lockPrice(t, previous, "1.00", time.Now().Add(-time.Hour))
+	require.NoError(t, previous.Close())
+	db := newDB(t, "current")
+
+	quoteFor := func(t *testing.T, price string) error {
+		var config runConfig
+		cmd := &cobra.Command{}
+		registerPriceGuardFlags(cmd, &config.PriceGuardConfig)
+		require.NoError(t, cmd.ParseFlags([]string{"--max-price-deviation", "0.1"}))
+
+		quoter := ethtest.NewQuoter()
+		quoter.SetQuote(symbol, &coinmarketcap.Quote{
+			LastUpdated: time.Now(),
+			Price:       decimal.RequireFromString(price),
+		})
+		guard, err := newPriceGuard(quoter, config.PriceGuardConfig)
+		require.NoError(t, err)
+		require.NoError(t, setPriceReference(ctx, guard, db, dataDir, "current", symbol))
+
+		_, err = guard.GetQuote(ctx, symbol)
+		return err
+	}
+
+	require.NoError(t, quoteFor(t, "1.05"))
+	err := quoteFor(t, "1.50")
+	require.True(t, coinmarketcap.ErrPriceDeviation.Has(err), "unexpected error: %v", err)
+
+	// Once the payout has locked a price, quotes are checked against it.
+	lockPrice(t, db, "1.50", time.Now())
+	require.NoError(t, quoteFor(t, "1.50"))
+	err = quoteFor(t, "1.05")
+	require.True(t, coinmarketcap.ErrPriceDeviation.Has(err), "unexpected error: %v", err)
+}
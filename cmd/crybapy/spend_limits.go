@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"storj.io/crypto-batch-payment/pkg/pipeline"
+)
+
+type SpendLimitsConfig struct {
+	MaxTotalSTORJ string
+	MaxTotalUSD   string
+	MaxPayoutUSD  string
+	MaxPayeeUSD   string
+}
+
+func registerSpendLimitsFlags(cmd *cobra.Command, config *SpendLimitsConfig) {
+	cmd.Flags().StringVarP(
+		&config.MaxTotalSTORJ,
+		"max-total-storj", "",
+		"",
+		"Halt before the tokens (STORJ, or the --token-symbol token) paid out in total would exceed this amount (empty disables)")
+	cmd.Flags().StringVarP(
+		&config.MaxTotalUSD,
+		"max-total-usd", "",
+		"",
+		"Halt before the USD paid out in total would exceed this amount (empty disables)")
+	cmd.Flags().StringVarP(
+		&config.MaxPayoutUSD,
+		"max-payout-usd", "",
+		"",
+		"Halt before sending a single payout of more than this USD amount (empty disables)")
+	cmd.Flags().StringVarP(
+		&config.MaxPayeeUSD,
+		"max-payee-usd", "",
+		"",
+		"Halt before the USD paid out to a single payee address would exceed this amount (empty disables)")
+}
+
+func newSpendLimits(config SpendLimitsConfig) (limits pipeline.SpendLimits, err error) {
+	if limits.MaxTokens, err = convertLimit(config.MaxTotalSTORJ, "max-total-storj"); err != nil {
+		return pipeline.SpendLimits{}, err
+	}
+	if limits.MaxUSD, err = convertLimit(config.MaxTotalUSD, "max-total-usd"); err != nil {
+		return pipeline.SpendLimits{}, err
+	}
+	if limits.MaxPayoutUSD, err = convertLimit(config.MaxPayoutUSD, "max-payout-usd"); err != nil {
+		return pipeline.SpendLimits{}, err
+	}
+	if limits.MaxPayeeUSD, err = convertLimit(config.MaxPayeeUSD, "max-payee-usd"); err != nil {
+		return pipeline.SpendLimits{}, err
+	}
+	return limits, nil
+}
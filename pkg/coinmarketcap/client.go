@@ -29,6 +29,7 @@ type Symbol string
 
 const (
 	STORJ = "STORJ"
+	ETH   = "ETH"
 )
 
 type Quoter interface {
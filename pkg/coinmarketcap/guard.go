@@ -0,0 +1,151 @@
+package coinmarketcap
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
+)
+
+const (
+	// DefaultMaxQuoteAge is the default maximum age of a quote.
+	DefaultMaxQuoteAge = 15 * time.Minute
+)
+
+var (
+	// ErrStaleQuote is returned when a quote is older than the max age.
+	ErrStaleQuote = errs.Class("stale quote")
+
+	// ErrPriceOutOfBounds is returned when a price falls outside of the
+	// configured min/max bounds.
+	ErrPriceOutOfBounds = errs.Class("price out of bounds")
+
+	// ErrPriceDeviation is returned when a price deviates too far from the
+	// reference price.
+	ErrPriceDeviation = errs.Class("price deviation")
+
+	// ErrCircuitOpen is returned for every quote of a symbol once a quote
+	// for that symbol has tripped the circuit breaker.
+	ErrCircuitOpen = errs.Class("price circuit breaker open")
+)
+
+// GuardConfig configures the sanity checks a Guard applies to quotes. Zero
+// values disable the corresponding check.
+type GuardConfig struct {
+	// MaxAge is the maximum age of a quote, based on its LastUpdated time.
+	MaxAge time.Duration
+
+	// MaxDeviation is the maximum fraction (e.g. 0.1 for 10%) a price can
+	// move away from the reference price.
+	MaxDeviation decimal.Decimal
+
+	// MinPrice is the lowest acceptable price.
+	MinPrice decimal.Decimal
+
+	// MaxPrice is the highest acceptable price.
+	MaxPrice decimal.Decimal
+}
+
+// Guard is a Quoter that rejects quotes that fail sanity checks before they
+// can be used to convert USD into tokens. Stale quotes are rejected. Quotes
+// that are out of bounds or deviate too far from the reference price trip a
+// circuit breaker that rejects all further quotes for the symbol until it is
+// reset.
+type Guard struct {
+	quoter Quoter
+	config GuardConfig
+
+	mu         sync.Mutex
+	references map[Symbol]decimal.Decimal
+	tripped    map[Symbol]error
+
+	now func() time.Time
+}
+
+var _ Quoter = (*Guard)(nil)
+
+func NewGuard(quoter Quoter, config GuardConfig) *Guard {
+	return &Guard{
+		quoter:     quoter,
+		config:     config,
+		references: make(map[Symbol]decimal.Decimal),
+		tripped:    make(map[Symbol]error),
+		now:        time.Now,
+	}
+}
+
+// SetReference sets the price that quotes for the symbol are compared to
+// when checking deviation. If unset, the first accepted quote becomes the
+// reference, so the first quote itself is never checked for deviation.
+func (g *Guard) SetReference(symbol Symbol, price decimal.Decimal) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.references[symbol] = price
+}
+
+// Reset closes the circuit breaker for the symbol.
+func (g *Guard) Reset(symbol Symbol) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.tripped, symbol)
+}
+
+// CheckPrice checks a price that did not come from a quote (e.g. one provided
+// by an operator) against the min/max bounds.
+func (g *Guard) CheckPrice(price decimal.Decimal) error {
+	if !g.config.MinPrice.IsZero() && price.LessThan(g.config.MinPrice) {
+		return ErrPriceOutOfBounds.New("$%s is below the minimum of $%s", price, g.config.MinPrice)
+	}
+	if !g.config.MaxPrice.IsZero() && price.GreaterThan(g.config.MaxPrice) {
+		return ErrPriceOutOfBounds.New("$%s is above the maximum of $%s", price, g.config.MaxPrice)
+	}
+	return nil
+}
+
+func (g *Guard) GetQuote(ctx context.Context, symbol Symbol) (*Quote, error) {
+	g.mu.Lock()
+	tripped := g.tripped[symbol]
+	g.mu.Unlock()
+	if tripped != nil {
+		return nil, ErrCircuitOpen.New("%s: %v", symbol, tripped)
+	}
+
+	quote, err := g.quoter.GetQuote(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.config.MaxAge > 0 {
+		if age := g.now().Sub(quote.LastUpdated); age > g.config.MaxAge {
+			return nil, ErrStaleQuote.New("%s quote is %s old; max age is %s", symbol, age.Truncate(time.Second), g.config.MaxAge)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.CheckPrice(quote.Price); err != nil {
+		g.tripped[symbol] = err
+		return nil, err
+	}
+
+	reference, ok := g.references[symbol]
+	if !ok {
+		g.references[symbol] = quote.Price
+		return quote, nil
+	}
+
+	if !g.config.MaxDeviation.IsZero() && reference.IsPositive() {
+		deviation := quote.Price.Sub(reference).Abs().Div(reference)
+		if deviation.GreaterThan(g.config.MaxDeviation) {
+			err := ErrPriceDeviation.New("%s price $%s deviates %s%% from reference price $%s; max deviation is %s%%",
+				symbol, quote.Price, deviation.Shift(2).StringFixed(2), reference, g.config.MaxDeviation.Shift(2))
+			g.tripped[symbol] = err
+			return nil, err
+		}
+	}
+
+	return quote, nil
+}
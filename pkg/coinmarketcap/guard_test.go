@@ -0,0 +1,115 @@
+package coinmarketcap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var quote *Quote
+	setQuote := func(price string, age time.Duration) {
+		quote = &Quote{
+			Price:       decimal.RequireFromString(price),
+			LastUpdated: now.Add(-age),
+		}
+	}
+	quoter := QuoterFunc(func(ctx context.Context, symbol Symbol) (*Quote, error) {
+		return quote, nil
+	})
+
+	newGuard := func() *Guard {
+		guard := NewGuard(quoter, GuardConfig{
+			MaxAge:       time.Minute * 10,
+			MaxDeviation: decimal.RequireFromString("0.1"),
+			MinPrice:     decimal.RequireFromString("0.05"),
+			MaxPrice:     decimal.RequireFromString("5"),
+		})
+		guard.now = func() time.Time { return now }
+		return guard
+	}
+
+	t.Run("stale quotes are rejected", func(t *testing.T) {
+		guard := newGuard()
+
+		setQuote("0.5", time.Minute*11)
+		_, err := guard.GetQuote(ctx, STORJ)
+		require.True(t, ErrStaleQuote.Has(err), err)
+
+		// Staleness does not trip the breaker.
+		setQuote("0.5", time.Minute)
+		got, err := guard.GetQuote(ctx, STORJ)
+		require.NoError(t, err)
+		require.Equal(t, "0.5", got.Price.String())
+	})
+
+	t.Run("out of bounds trips the breaker", func(t *testing.T) {
+		guard := newGuard()
+
+		setQuote("50", 0)
+		_, err := guard.GetQuote(ctx, STORJ)
+		require.True(t, ErrPriceOutOfBounds.Has(err), err)
+
+		setQuote("0.5", 0)
+		_, err = guard.GetQuote(ctx, STORJ)
+		require.True(t, ErrCircuitOpen.Has(err), err)
+
+		guard.Reset(STORJ)
+		_, err = guard.GetQuote(ctx, STORJ)
+		require.NoError(t, err)
+	})
+
+	t.Run("deviation from first quote trips the breaker", func(t *testing.T) {
+		guard := newGuard()
+
+		setQuote("0.5", 0)
+		_, err := guard.GetQuote(ctx, STORJ)
+		require.NoError(t, err)
+
+		setQuote("0.55", 0)
+		_, err = guard.GetQuote(ctx, STORJ)
+		require.NoError(t, err)
+
+		setQuote("0.56", 0)
+		_, err = guard.GetQuote(ctx, STORJ)
+		require.EqualError(t, err, "price deviation: STORJ price $0.56 deviates 12.00% from reference price $0.5; max deviation is 10%")
+
+		setQuote("0.5", 0)
+		_, err = guard.GetQuote(ctx, STORJ)
+		require.True(t, ErrCircuitOpen.Has(err), err)
+	})
+
+	t.Run("deviation from explicit reference", func(t *testing.T) {
+		guard := newGuard()
+		guard.SetReference(STORJ, decimal.RequireFromString("1"))
+
+		setQuote("0.5", 0)
+		_, err := guard.GetQuote(ctx, STORJ)
+		require.True(t, ErrPriceDeviation.Has(err), err)
+	})
+
+	t.Run("operator prices are checked against bounds", func(t *testing.T) {
+		guard := newGuard()
+		require.NoError(t, guard.CheckPrice(decimal.RequireFromString("0.5")))
+		require.EqualError(t, guard.CheckPrice(decimal.RequireFromString("0.01")), "price out of bounds: $0.01 is below the minimum of $0.05")
+		require.EqualError(t, guard.CheckPrice(decimal.RequireFromString("43.21")), "price out of bounds: $43.21 is above the maximum of $5")
+	})
+
+	t.Run("zero config disables checks", func(t *testing.T) {
+		guard := NewGuard(quoter, GuardConfig{})
+
+		setQuote("0.5", time.Hour*24)
+		_, err := guard.GetQuote(ctx, STORJ)
+		require.NoError(t, err)
+
+		setQuote("500", time.Hour*24)
+		_, err = guard.GetQuote(ctx, STORJ)
+		require.NoError(t, err)
+	})
+}
@@ -3,18 +3,23 @@ package config
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/zeebo/errs"
 
 	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
 )
 
 type CoinMarketCap struct {
-	APIURL      string   `toml:"api_url"`
-	APIKeyPath  Path     `toml:"api_key_path"`
-	CacheExpiry Duration `toml:"cache_expiry"`
+	APIURL            string          `toml:"api_url"`
+	APIKeyPath        Path            `toml:"api_key_path"`
+	CacheExpiry       Duration        `toml:"cache_expiry"`
+	MaxQuoteAge       Duration        `toml:"max_quote_age"`
+	MaxPriceDeviation decimal.Decimal `toml:"max_price_deviation"`
+	MinPrice          decimal.Decimal `toml:"min_price"`
+	MaxPrice          decimal.Decimal `toml:"max_price"`
 }
 
-func (c CoinMarketCap) NewQuoter() (coinmarketcap.Quoter, error) {
+func (c CoinMarketCap) NewQuoter() (*coinmarketcap.Guard, error) {
 	apiKey, err := loadFirstLine(string(c.APIKeyPath))
 	if err != nil {
 		return nil, errs.New("failed to load CoinMarketCap key: %v\n", err)
@@ -25,5 +30,14 @@ func (c CoinMarketCap) NewQuoter() (coinmarketcap.Quoter, error) {
 		return nil, errs.New("failed instantiate coinmarketcap client: %v\n", err)
 	}
 
-	return quoter, nil
+	return coinmarketcap.NewGuard(quoter, c.GuardConfig()), nil
+}
+
+func (c CoinMarketCap) GuardConfig() coinmarketcap.GuardConfig {
+	return coinmarketcap.GuardConfig{
+		MaxAge:       time.Duration(c.MaxQuoteAge),
+		MaxDeviation: c.MaxPriceDeviation,
+		MinPrice:     c.MinPrice,
+		MaxPrice:     c.MaxPrice,
+	}
 }
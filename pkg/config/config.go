@@ -8,17 +8,22 @@ import (
 	"time"
 
 	"github.com/pelletier/go-toml/v2"
+	"github.com/shopspring/decimal"
 
 	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
 	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/payouts"
 	"storj.io/crypto-batch-payment/pkg/pipeline"
 )
 
 type Config struct {
 	Pipeline      Pipeline      `toml:"pipeline"`
 	CoinMarketCap CoinMarketCap `toml:"coinmarketcap"`
+	Token         Token         `toml:"token"`
 	Eth           *Eth          `toml:"eth"`
 	ZkSyncEra     *ZkSyncEra    `toml:"zksync-era"`
+	Polygon       *Polygon      `toml:"polygon"`
+	Rollup        *Rollup       `toml:"rollup"`
 }
 
 func (c *Config) NewPayers(ctx context.Context) (_ Payers, err error) {
@@ -35,6 +40,9 @@ func (c *Config) NewPayers(ctx context.Context) (_ Payers, err error) {
 			return nil, fmt.Errorf("failed to init eth payer: %w", err)
 		}
 		payers.Add(payer.Eth, p)
+		if err := c.Token.checkDecimals(ctx, p); err != nil {
+			return nil, fmt.Errorf("failed to init eth payer: %w", err)
+		}
 	}
 
 	if c.ZkSyncEra != nil {
@@ -43,6 +51,28 @@ func (c *Config) NewPayers(ctx context.Context) (_ Payers, err error) {
 			return nil, fmt.Errorf("failed to init zksync-era payer: %w", err)
 		}
 		payers.Add(payer.ZkSyncEra, p)
+		if err := c.Token.checkDecimals(ctx, p); err != nil {
+			return nil, fmt.Errorf("failed to init zksync-era payer: %w", err)
+		}
+	}
+
+	if c.Polygon != nil {
+		p, err := c.Polygon.newPayer(ctx, c.Token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init polygon payer: %w", err)
+		}
+		payers.Add(payer.Polygon, p)
+	}
+
+	if c.Rollup != nil {
+		p, err := c.Rollup.NewPayer(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init rollup payer: %w", err)
+		}
+		payers.Add(payer.Rollup, p)
+		if err := c.Token.checkDecimals(ctx, p); err != nil {
+			return nil, fmt.Errorf("failed to init rollup payer: %w", err)
+		}
 	}
 
 	return payers, nil
@@ -72,12 +102,55 @@ func (c *Config) NewAuditors(ctx context.Context) (_ Auditors, err error) {
 		auditors.Add(payer.ZkSyncEra, p)
 	}
 
+	if c.Polygon != nil {
+		p, err := c.Polygon.NewAuditor(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init polygon auditor: %w", err)
+		}
+		auditors.Add(payer.Polygon, p)
+	}
+
+	if c.Rollup != nil {
+		p, err := c.Rollup.NewAuditor(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init rollup auditor: %w", err)
+		}
+		auditors.Add(payer.Rollup, p)
+	}
+
 	return auditors, nil
 }
 
+// PayoutsConfig returns the payouts configuration for paying out in the
+// configured token, quoted by the given quoter.
+func (c *Config) PayoutsConfig(quoter coinmarketcap.Quoter) payouts.Config {
+	return payouts.Config{
+		Quoter:        quoter,
+		Symbol:        c.Token.QuoteSymbol(),
+		PipelineLimit: c.Pipeline.DepthLimit,
+		TxDelay:       time.Duration(c.Pipeline.TxDelay),
+		ReplaceAfter:  time.Duration(c.Pipeline.ReplaceAfter),
+		SpendLimits:   c.Pipeline.SpendLimits(),
+	}
+}
+
 type Pipeline struct {
-	DepthLimit int      `toml:"depth_limit"`
-	TxDelay    Duration `toml:"tx_delay"`
+	DepthLimit    int             `toml:"depth_limit"`
+	TxDelay       Duration        `toml:"tx_delay"`
+	ReplaceAfter  Duration        `toml:"replace_after"`
+	MaxTotalSTORJ decimal.Decimal `toml:"max_total_storj"`
+	MaxTotalUSD   decimal.Decimal `toml:"max_total_usd"`
+	MaxPayoutUSD  decimal.Decimal `toml:"max_payout_usd"`
+	MaxPayeeUSD   decimal.Decimal `toml:"max_payee_usd"`
+}
+
+func (c Pipeline) SpendLimits() pipeline.SpendLimits {
+	return pipeline.SpendLimits{
+		MaxTokens:    c.MaxTotalSTORJ,
+		MaxUSD:       c.MaxTotalUSD,
+		MaxPayoutUSD: c.MaxPayoutUSD,
+		MaxPayeeUSD:  c.MaxPayeeUSD,
+	}
 }
 
 func Load(path string) (Config, error) {
@@ -92,20 +165,28 @@ func Parse(data []byte) (Config, error) {
 	const (
 		defaultPipelineDepthLimit       = pipeline.DefaultLimit
 		defaultPipelineTxDelay          = Duration(pipeline.DefaultTxDelay)
+		defaultPipelineReplaceAfter     = Duration(pipeline.DefaultReplaceAfter)
 		defaultCoinMarketCapAPIURL      = coinmarketcap.ProductionAPIURL
 		defaultCoinMarketCapKeyPath     = "~/.coinmarketcap"
 		defaultCoinMarketCapCacheExpiry = time.Second * 5
+		defaultCoinMarketCapMaxQuoteAge = coinmarketcap.DefaultMaxQuoteAge
+		defaultTokenSymbol              = coinmarketcap.STORJ
 	)
 
 	config := Config{
 		Pipeline: Pipeline{
-			DepthLimit: defaultPipelineDepthLimit,
-			TxDelay:    defaultPipelineTxDelay,
+			DepthLimit:   defaultPipelineDepthLimit,
+			TxDelay:      defaultPipelineTxDelay,
+			ReplaceAfter: defaultPipelineReplaceAfter,
 		},
 		CoinMarketCap: CoinMarketCap{
 			APIURL:      defaultCoinMarketCapAPIURL,
 			APIKeyPath:  ToPath(defaultCoinMarketCapKeyPath),
 			CacheExpiry: Duration(defaultCoinMarketCapCacheExpiry),
+			MaxQuoteAge: Duration(defaultCoinMarketCapMaxQuoteAge),
+		},
+		Token: Token{
+			Symbol: defaultTokenSymbol,
 		},
 	}
 
@@ -1,6 +1,8 @@
 package config_test
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"os/user"
 	"path/filepath"
@@ -8,9 +10,11 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
 	"storj.io/crypto-batch-payment/pkg/config"
 )
 
@@ -27,31 +31,88 @@ func TestLoad_Defaults(t *testing.T) {
 
 	assert.Equal(t, config.Config{
 		Pipeline: config.Pipeline{
-			DepthLimit: 16,
-			TxDelay:    0,
+			DepthLimit:   16,
+			TxDelay:      0,
+			ReplaceAfter: 0,
 		},
 		CoinMarketCap: config.CoinMarketCap{
 			APIURL:      "https://pro-api.coinmarketcap.com",
 			APIKeyPath:  homePath(".coinmarketcap"),
 			CacheExpiry: 5000000000,
+			MaxQuoteAge: config.Duration(15 * time.Minute),
+		},
+		Token: config.Token{
+			Symbol: "STORJ",
 		},
 		Eth: &config.Eth{
-			NodeAddress:          "https://someaddress.test",
-			SpenderKeyPath:       homePath("some.key"),
-			ERC20ContractAddress: common.HexToAddress("0x1111111111111111111111111111111111111111"),
-			ChainID:              0,
-			Owner:                nil,
-			MaxGas:               nil,
-			GasTipCap:            nil,
+			NodeAddress:                 "https://someaddress.test",
+			SpenderKeyPath:              homePath("some.key"),
+			SpenderKeyPassphrase:        "",
+			ExternalSigner:              "",
+			ExternalSignerAuthTokenPath: "",
+			Spender:                     nil,
+			ERC20ContractAddress:        common.HexToAddress("0x1111111111111111111111111111111111111111"),
+			DisperseContractAddress:     nil,
+			ChainID:                     0,
+			Owner:                       nil,
+			MaxGas:                      nil,
+			GasTipCap:                   nil,
+			Confirmations:               0,
+			FeeStrategy:                 "",
+			InfuraAPIKeyPath:            "",
+			FeeHistoryBlocks:            0,
+			FeeHistoryPercentile:        0,
 		},
 		ZkSyncEra: &config.ZkSyncEra{
-			NodeAddress:          "https://mainnet.era.zksync.io",
-			SpenderKeyPath:       homePath("some.key"),
-			ERC20ContractAddress: common.HexToAddress("0x2222222222222222222222222222222222222222"),
-			ChainID:              0,
-			MaxFee:               nil,
-			PaymasterAddress:     nil,
-			PaymasterPayload:     nil,
+			NodeAddress:             "https://mainnet.era.zksync.io",
+			SpenderKeyPath:          homePath("some.key"),
+			SpenderKeyPassphrase:    "",
+			ERC20ContractAddress:    common.HexToAddress("0x2222222222222222222222222222222222222222"),
+			ChainID:                 0,
+			MaxFee:                  nil,
+			PaymasterAddress:        nil,
+			PaymasterPayload:        nil,
+			DisperseContractAddress: nil,
+		},
+		Polygon: &config.Polygon{
+			NodeAddress:                 "https://polygon-rpc.test",
+			SpenderKeyPath:              homePath("some.key"),
+			SpenderKeyPassphrase:        "",
+			ExternalSigner:              "",
+			ExternalSignerAuthTokenPath: "",
+			Spender:                     nil,
+			ERC20ContractAddress:        nil,
+			DisperseContractAddress:     nil,
+			ChainID:                     0,
+			Owner:                       nil,
+			MaxGas:                      nil,
+			GasTipCap:                   nil,
+			MinGasTipCap:                nil,
+			Confirmations:               0,
+			FeeStrategy:                 "",
+			InfuraAPIKeyPath:            "",
+			FeeHistoryBlocks:            0,
+			FeeHistoryPercentile:        0,
+		},
+		Rollup: &config.Rollup{
+			NodeAddress:                 "https://mainnet.base.test",
+			Stack:                       "op-stack",
+			SpenderKeyPath:              homePath("some.key"),
+			SpenderKeyPassphrase:        "",
+			ExternalSigner:              "",
+			ExternalSignerAuthTokenPath: "",
+			Spender:                     nil,
+			ERC20ContractAddress:        common.HexToAddress("0x3333333333333333333333333333333333333333"),
+			DisperseContractAddress:     nil,
+			ChainID:                     8453,
+			Owner:                       nil,
+			MaxGas:                      nil,
+			GasTipCap:                   nil,
+			Confirmations:               0,
+			FeeStrategy:                 "",
+			InfuraAPIKeyPath:            "",
+			FeeHistoryBlocks:            0,
+			FeeHistoryPercentile:        0,
 		},
 	}, cfg)
 }
@@ -62,35 +123,120 @@ func TestLoad_Overrides(t *testing.T) {
 
 	assert.Equal(t, config.Config{
 		Pipeline: config.Pipeline{
-			DepthLimit: 24,
-			TxDelay:    config.Duration(time.Minute),
+			DepthLimit:    24,
+			TxDelay:       config.Duration(time.Minute),
+			ReplaceAfter:  config.Duration(10 * time.Minute),
+			MaxTotalSTORJ: decimal.RequireFromString("1000000"),
+			MaxTotalUSD:   decimal.RequireFromString("250000"),
+			MaxPayoutUSD:  decimal.RequireFromString("5000"),
+			MaxPayeeUSD:   decimal.RequireFromString("10000"),
 		},
 		CoinMarketCap: config.CoinMarketCap{
-			APIURL:      "https://override.test",
-			APIKeyPath:  "override",
-			CacheExpiry: 5000000000,
+			APIURL:            "https://override.test",
+			APIKeyPath:        "override",
+			CacheExpiry:       5000000000,
+			MaxQuoteAge:       config.Duration(time.Minute),
+			MaxPriceDeviation: decimal.RequireFromString("0.1"),
+			MinPrice:          decimal.RequireFromString("0.05"),
+			MaxPrice:          decimal.RequireFromString("5"),
+		},
+		Token: config.Token{
+			Symbol:   "USDC",
+			Decimals: ptrOf(int32(6)),
 		},
 		Eth: &config.Eth{
-			NodeAddress:          "https://override.test",
-			SpenderKeyPath:       "override",
-			ERC20ContractAddress: common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e"),
-			ChainID:              12345,
-			Owner:                ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
-			MaxGas:               big.NewInt(80_000_000_000),
-			GasTipCap:            big.NewInt(2_000_000_000),
+			NodeAddress:                 "https://override.test",
+			SpenderKeyPath:              "override",
+			SpenderKeyPassphrase:        "env:OVERRIDE",
+			ExternalSigner:              "http://localhost:8550",
+			ExternalSignerAuthTokenPath: "override",
+			Spender:                     ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+			ERC20ContractAddress:        common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e"),
+			DisperseContractAddress:     ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+			ChainID:                     12345,
+			Owner:                       ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
+			MaxGas:                      big.NewInt(80_000_000_000),
+			GasTipCap:                   big.NewInt(2_000_000_000),
+			Confirmations:               12,
+			FeeStrategy:                 "fee-history",
+			InfuraAPIKeyPath:            "override",
+			FeeHistoryBlocks:            10,
+			FeeHistoryPercentile:        75,
 		},
 		ZkSyncEra: &config.ZkSyncEra{
-			NodeAddress:          "https://override.test",
-			SpenderKeyPath:       "override",
-			ERC20ContractAddress: common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e"),
-			ChainID:              12345,
-			MaxFee:               big.NewInt(5678),
-			PaymasterAddress:     ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
-			PaymasterPayload:     []byte("\x01\x23"),
+			NodeAddress:             "https://override.test",
+			SpenderKeyPath:          "override",
+			SpenderKeyPassphrase:    "env:OVERRIDE",
+			ERC20ContractAddress:    common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e"),
+			ChainID:                 12345,
+			MaxFee:                  big.NewInt(5678),
+			PaymasterAddress:        ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
+			PaymasterPayload:        []byte("\x01\x23"),
+			DisperseContractAddress: ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+		},
+		Polygon: &config.Polygon{
+			NodeAddress:                 "https://override.test",
+			SpenderKeyPath:              "override",
+			SpenderKeyPassphrase:        "env:OVERRIDE",
+			ExternalSigner:              "http://localhost:8550",
+			ExternalSignerAuthTokenPath: "override",
+			Spender:                     ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+			ERC20ContractAddress:        ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
+			DisperseContractAddress:     ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+			ChainID:                     12345,
+			Owner:                       ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
+			MaxGas:                      big.NewInt(600_000_000_000),
+			GasTipCap:                   big.NewInt(40_000_000_000),
+			MinGasTipCap:                big.NewInt(35_000_000_000),
+			Confirmations:               128,
+			FeeStrategy:                 "fee-history",
+			InfuraAPIKeyPath:            "override",
+			FeeHistoryBlocks:            10,
+			FeeHistoryPercentile:        75,
+		},
+		Rollup: &config.Rollup{
+			NodeAddress:                 "https://override.test",
+			Stack:                       "arbitrum",
+			SpenderKeyPath:              "override",
+			SpenderKeyPassphrase:        "env:OVERRIDE",
+			ExternalSigner:              "http://localhost:8550",
+			ExternalSignerAuthTokenPath: "override",
+			Spender:                     ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+			ERC20ContractAddress:        common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e"),
+			DisperseContractAddress:     ptrOf(common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")),
+			ChainID:                     12345,
+			Owner:                       ptrOf(common.HexToAddress("0xe66652d41EE7e81d3fcAe1dF7F9B9f9411ac835e")),
+			MaxGas:                      big.NewInt(2_000_000_000),
+			GasTipCap:                   big.NewInt(0),
+			Confirmations:               20,
+			FeeStrategy:                 "fee-history",
+			InfuraAPIKeyPath:            "override",
+			FeeHistoryBlocks:            10,
+			FeeHistoryPercentile:        75,
 		},
 	}, cfg)
 }
 
+func TestPayoutsConfig(t *testing.T) {
+	cfg, err := config.Load("./testdata/override.toml")
+	require.NoError(t, err)
+
+	quoter := coinmarketcap.QuoterFunc(func(ctx context.Context, symbol coinmarketcap.Symbol) (*coinmarketcap.Quote, error) {
+		return nil, errors.New("unexpected quote")
+	})
+	payoutsConfig := cfg.PayoutsConfig(quoter)
+	assert.NotNil(t, payoutsConfig.Quoter)
+	assert.Equal(t, coinmarketcap.Symbol("USDC"), payoutsConfig.Symbol)
+	assert.Equal(t, 24, payoutsConfig.PipelineLimit)
+	assert.Equal(t, time.Minute, payoutsConfig.TxDelay)
+	assert.Equal(t, 10*time.Minute, payoutsConfig.ReplaceAfter)
+	assert.Equal(t, cfg.Pipeline.SpendLimits(), payoutsConfig.SpendLimits)
+
+	cfg, err = config.Load("./testdata/defaults.toml")
+	require.NoError(t, err)
+	assert.Equal(t, coinmarketcap.Symbol(coinmarketcap.STORJ), cfg.PayoutsConfig(quoter).Symbol)
+}
+
 func ptrOf[T any](t T) *T {
 	return &t
 }
@@ -4,28 +4,47 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/zeebo/errs"
 
 	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/ethkey"
+	"storj.io/crypto-batch-payment/pkg/infura"
+	"storj.io/crypto-batch-payment/pkg/payer"
 )
 
 const (
-	defaultEthChainID = 1
-	defaultMaxGas     = "70_000_000_000"
-	defaultGasTipCap  = "1_000_000_000"
+	defaultEthChainID       = 1
+	defaultMaxGas           = "70_000_000_000"
+	defaultGasTipCap        = "1_000_000_000"
+	defaultFeeStrategy      = "fixed"
+	infuraGasCacheExpiry    = 10 * time.Second
+	infuraFeeStrategyPrefix = "infura-"
 )
 
 type Eth struct {
-	NodeAddress          string          `toml:"node_address"`
-	SpenderKeyPath       Path            `toml:"spender_key_path"`
-	ERC20ContractAddress common.Address  `toml:"erc20_contract_address"`
-	ChainID              int             `toml:"chain_id"`
-	Owner                *common.Address `toml:"owner"`
-	MaxGas               *big.Int        `toml:"max_gas"`
-	GasTipCap            *big.Int        `toml:"gas_tip_cap"`
+	NodeAddress                 string            `toml:"node_address"`
+	SpenderKeyPath              Path              `toml:"spender_key_path"`
+	SpenderKeyPassphrase        ethkey.Passphrase `toml:"spender_key_passphrase"`
+	ExternalSigner              string            `toml:"external_signer"`
+	ExternalSignerAuthTokenPath Path              `toml:"external_signer_auth_token_path"`
+	Spender                     *common.Address   `toml:"spender"`
+	ERC20ContractAddress        common.Address    `toml:"erc20_contract_address"`
+	DisperseContractAddress     *common.Address   `toml:"disperse_contract_address"`
+	ChainID                     int               `toml:"chain_id"`
+	Owner                       *common.Address   `toml:"owner"`
+	MaxGas                      *big.Int          `toml:"max_gas"`
+	GasTipCap                   *big.Int          `toml:"gas_tip_cap"`
+	Confirmations               uint64            `toml:"confirmations"`
+	FeeStrategy                 string            `toml:"fee_strategy"`
+	InfuraAPIKeyPath            Path              `toml:"infura_api_key_path"`
+	FeeHistoryBlocks            uint64            `toml:"fee_history_blocks"`
+	FeeHistoryPercentile        float64           `toml:"fee_history_percentile"`
 }
 
 func (c Eth) NewPayer(ctx context.Context) (_ Payer, err error) {
@@ -47,13 +66,37 @@ func (c Eth) NewPayer(ctx context.Context) (_ Payer, err error) {
 	if c.GasTipCap == nil {
 		c.GasTipCap, _ = new(big.Int).SetString(defaultGasTipCap, 0)
 	}
+	if c.FeeStrategy == "" {
+		c.FeeStrategy = defaultFeeStrategy
+	}
+
+	return c.newPayer(ctx, nil)
+}
+
+// newPayer returns the eth payer once the defaults are applied. If
+// minGasTipCap is set, every transaction tips at least as much.
+func (c Eth) newPayer(ctx context.Context, minGasTipCap *big.Int) (_ *payerWrapper, err error) {
+	return c.newPayerWith(ctx, minGasTipCap, c.newEthPayer)
+}
+
+// payerFunc creates a payer built on the eth payer, given the node client,
+// owner, signer and fee strategy of the configuration.
+type payerFunc func(ctx context.Context, client *ethclient.Client, owner common.Address, signer eth.Signer, fees eth.FeeStrategy) (payer.Payer, error)
 
-	spenderKey, spenderAddress, err := loadSpenderKey(string(c.SpenderKeyPath))
+// newPayerWith sets up the signer, node client and fee strategy once the
+// defaults are applied and creates the payer with them.
+func (c Eth) newPayerWith(ctx context.Context, minGasTipCap *big.Int, newPayer payerFunc) (_ *payerWrapper, err error) {
+	signer, closeSigner, err := c.newSigner(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			closeSigner()
+		}
+	}()
 
-	owner := spenderAddress
+	owner := signer.Address()
 	if c.Owner != nil {
 		owner = *c.Owner
 	}
@@ -68,23 +111,93 @@ func (c Eth) NewPayer(ctx context.Context) (_ Payer, err error) {
 		}
 	}()
 
+	fees, err := c.newFeeStrategy(client)
+	if err != nil {
+		return nil, err
+	}
+	if minGasTipCap != nil {
+		fees = eth.NewMinTipFees(fees, minGasTipCap)
+	}
+
+	p, err := newPayer(ctx, client, owner, signer, fees)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	return &payerWrapper{
+		Payer: p,
+		closeFunc: func() {
+			client.Close()
+			closeSigner()
+		},
+	}, nil
+}
+
+func (c Eth) newEthPayer(ctx context.Context, client *ethclient.Client, owner common.Address, signer eth.Signer, fees eth.FeeStrategy) (payer.Payer, error) {
 	ethPayer, err := eth.NewPayer(ctx,
 		client,
 		c.ERC20ContractAddress,
 		owner,
-		spenderKey,
-		big.NewInt(int64(c.ChainID)),
-		c.GasTipCap,
+		signer,
+		fees,
 		c.MaxGas,
+		c.DisperseContractAddress,
+		c.Confirmations,
 	)
 	if err != nil {
-		return nil, errs.Wrap(err)
+		return nil, err
 	}
+	return ethPayer, nil
+}
 
-	return &payerWrapper{
-		Payer:     ethPayer,
-		closeFunc: client.Close,
-	}, nil
+// newSigner returns the signer of the spender. With external_signer, the
+// spender key is held by the external signer and the spender is configured
+// by address. Otherwise it is loaded from spender_key_path.
+func (c Eth) newSigner(ctx context.Context) (_ eth.Signer, closeFunc func(), err error) {
+	chainID := big.NewInt(int64(c.ChainID))
+	if c.ExternalSigner == "" {
+		spenderKey, _, err := loadSpenderKey(string(c.SpenderKeyPath), c.SpenderKeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		return eth.NewKeySigner(spenderKey, chainID), func() {}, nil
+	}
+
+	if c.Spender == nil {
+		return nil, nil, errors.New("spender is not configured; it is required with external_signer")
+	}
+	var authToken string
+	if c.ExternalSignerAuthTokenPath != "" {
+		authToken, err = eth.LoadAuthToken(string(c.ExternalSignerAuthTokenPath))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	signer, err := eth.NewExternalSigner(ctx, c.ExternalSigner, authToken, *c.Spender, chainID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, signer.Close, nil
+}
+
+// newFeeStrategy returns the configured fee strategy. One of "fixed",
+// "infura-low", "infura-medium", "infura-high" or "fee-history".
+func (c Eth) newFeeStrategy(client ethereum.FeeHistoryReader) (eth.FeeStrategy, error) {
+	switch {
+	case c.FeeStrategy == "fixed":
+		return eth.NewFixedFees(c.GasTipCap), nil
+	case strings.HasPrefix(c.FeeStrategy, infuraFeeStrategyPrefix):
+		apiKey, err := loadFirstLine(string(c.InfuraAPIKeyPath))
+		if err != nil {
+			return nil, errs.New("failed to load Infura key: %v", err)
+		}
+		level := eth.InfuraLevel(strings.TrimPrefix(c.FeeStrategy, infuraFeeStrategyPrefix))
+		return eth.NewInfuraFees(infura.NewCache(infura.NewClient(apiKey), infuraGasCacheExpiry), c.ChainID, level)
+	case c.FeeStrategy == "fee-history":
+		return eth.NewFeeHistoryFees(client, c.FeeHistoryBlocks, c.FeeHistoryPercentile)
+	default:
+		return nil, errs.New("unsupported fee_strategy %q", c.FeeStrategy)
+	}
 }
 
 func (c Eth) NewAuditor(ctx context.Context) (_ Auditor, err error) {
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/ethkey"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+const (
+	defaultPolygonMaxGas    = "500_000_000_000"
+	defaultPolygonGasTipCap = "30_000_000_000"
+)
+
+type Polygon struct {
+	NodeAddress                 string            `toml:"node_address"`
+	SpenderKeyPath              Path              `toml:"spender_key_path"`
+	SpenderKeyPassphrase        ethkey.Passphrase `toml:"spender_key_passphrase"`
+	ExternalSigner              string            `toml:"external_signer"`
+	ExternalSignerAuthTokenPath Path              `toml:"external_signer_auth_token_path"`
+	Spender                     *common.Address   `toml:"spender"`
+	ERC20ContractAddress        *common.Address   `toml:"erc20_contract_address"`
+	DisperseContractAddress     *common.Address   `toml:"disperse_contract_address"`
+	ChainID                     int               `toml:"chain_id"`
+	Owner                       *common.Address   `toml:"owner"`
+	MaxGas                      *big.Int          `toml:"max_gas"`
+	GasTipCap                   *big.Int          `toml:"gas_tip_cap"`
+	MinGasTipCap                *big.Int          `toml:"min_gas_tip_cap"`
+	Confirmations               uint64            `toml:"confirmations"`
+	FeeStrategy                 string            `toml:"fee_strategy"`
+	InfuraAPIKeyPath            Path              `toml:"infura_api_key_path"`
+	FeeHistoryBlocks            uint64            `toml:"fee_history_blocks"`
+	FeeHistoryPercentile        float64           `toml:"fee_history_percentile"`
+}
+
+func (c Polygon) NewPayer(ctx context.Context) (_ Payer, err error) {
+	return c.newPayer(ctx, Token{Symbol: coinmarketcap.STORJ})
+}
+
+// newPayer returns a payer paying in the token, whose decimals are checked.
+// STORJ on Polygon is known to have 18.
+func (c Polygon) newPayer(ctx context.Context, token Token) (_ Payer, err error) {
+	// Check for required parameters
+	if c.NodeAddress == "" {
+		return nil, errors.New("node_address is not configured")
+	}
+
+	// Apply defaults
+	if c.ERC20ContractAddress == nil {
+		c.ERC20ContractAddress = &storjtoken.PolygonContractAddress
+	}
+	if c.ChainID == 0 {
+		c.ChainID = int(storjtoken.PolygonChainID.Int64())
+	}
+	if c.MaxGas == nil {
+		c.MaxGas, _ = new(big.Int).SetString(defaultPolygonMaxGas, 0)
+	}
+	if c.GasTipCap == nil {
+		c.GasTipCap, _ = new(big.Int).SetString(defaultPolygonGasTipCap, 0)
+	}
+	if c.MinGasTipCap == nil {
+		c.MinGasTipCap = big.NewInt(eth.PolygonMinGasTipCap)
+	}
+	if c.FeeStrategy == "" {
+		c.FeeStrategy = defaultFeeStrategy
+	}
+
+	if c.GasTipCap.Cmp(c.MinGasTipCap) < 0 {
+		return nil, errs.New("gas_tip_cap %s is below the min_gas_tip_cap of %s", c.GasTipCap, c.MinGasTipCap)
+	}
+
+	p, err := c.eth().newPayer(ctx, c.MinGasTipCap)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			p.Close()
+		}
+	}()
+
+	if token.QuoteSymbol() != coinmarketcap.STORJ || token.Decimals != nil {
+		if err := token.checkDecimals(ctx, p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	// Paying with the 8 decimal STORJ token of Ethereum by mistake would
+	// pay out 10^10 times too little, so insist on the bridged token.
+	decimals, err := p.GetTokenDecimals(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if decimals != storjtoken.PolygonDecimals {
+		return nil, errs.New("erc20_contract_address %s has %d decimals; STORJ on Polygon has %d",
+			c.ERC20ContractAddress, decimals, storjtoken.PolygonDecimals)
+	}
+	return p, nil
+}
+
+func (c Polygon) NewAuditor(ctx context.Context) (_ Auditor, err error) {
+	return c.eth().NewAuditor(ctx)
+}
+
+// eth returns the configuration of the eth payer paying on Polygon.
+func (c Polygon) eth() Eth {
+	e := Eth{
+		NodeAddress:                 c.NodeAddress,
+		SpenderKeyPath:              c.SpenderKeyPath,
+		SpenderKeyPassphrase:        c.SpenderKeyPassphrase,
+		ExternalSigner:              c.ExternalSigner,
+		ExternalSignerAuthTokenPath: c.ExternalSignerAuthTokenPath,
+		Spender:                     c.Spender,
+		DisperseContractAddress:     c.DisperseContractAddress,
+		ChainID:                     c.ChainID,
+		Owner:                       c.Owner,
+		MaxGas:                      c.MaxGas,
+		GasTipCap:                   c.GasTipCap,
+		Confirmations:               c.Confirmations,
+		FeeStrategy:                 c.FeeStrategy,
+		InfuraAPIKeyPath:            c.InfuraAPIKeyPath,
+		FeeHistoryBlocks:            c.FeeHistoryBlocks,
+		FeeHistoryPercentile:        c.FeeHistoryPercentile,
+	}
+	if c.ERC20ContractAddress != nil {
+		e.ERC20ContractAddress = *c.ERC20ContractAddress
+	}
+	return e
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/ethkey"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/rollup"
+)
+
+const (
+	defaultRollupMaxGas    = "1_000_000_000"
+	defaultRollupGasTipCap = "1_000_000"
+)
+
+type Rollup struct {
+	NodeAddress                 string            `toml:"node_address"`
+	Stack                       string            `toml:"stack"`
+	SpenderKeyPath              Path              `toml:"spender_key_path"`
+	SpenderKeyPassphrase        ethkey.Passphrase `toml:"spender_key_passphrase"`
+	ExternalSigner              string            `toml:"external_signer"`
+	ExternalSignerAuthTokenPath Path              `toml:"external_signer_auth_token_path"`
+	Spender                     *common.Address   `toml:"spender"`
+	ERC20ContractAddress        common.Address    `toml:"erc20_contract_address"`
+	DisperseContractAddress     *common.Address   `toml:"disperse_contract_address"`
+	ChainID                     int               `toml:"chain_id"`
+	Owner                       *common.Address   `toml:"owner"`
+	MaxGas                      *big.Int          `toml:"max_gas"`
+	GasTipCap                   *big.Int          `toml:"gas_tip_cap"`
+	Confirmations               uint64            `toml:"confirmations"`
+	FeeStrategy                 string            `toml:"fee_strategy"`
+	InfuraAPIKeyPath            Path              `toml:"infura_api_key_path"`
+	FeeHistoryBlocks            uint64            `toml:"fee_history_blocks"`
+	FeeHistoryPercentile        float64           `toml:"fee_history_percentile"`
+}
+
+func (c Rollup) NewPayer(ctx context.Context) (_ Payer, err error) {
+	// Check for required parameters. Rollups have little in common, so
+	// there is no default chain.
+	if c.NodeAddress == "" {
+		return nil, errors.New("node_address is not configured")
+	}
+	if c.Stack == "" {
+		return nil, errors.New("stack is not configured")
+	}
+	if c.ERC20ContractAddress == zeroAddress {
+		return nil, errors.New("erc20_contract_address is not configured")
+	}
+	if c.ChainID == 0 {
+		return nil, errors.New("chain_id is not configured")
+	}
+	stack, err := rollup.StackFromString(c.Stack)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply defaults
+	if c.MaxGas == nil {
+		c.MaxGas, _ = new(big.Int).SetString(defaultRollupMaxGas, 0)
+	}
+	if c.GasTipCap == nil {
+		c.GasTipCap, _ = new(big.Int).SetString(defaultRollupGasTipCap, 0)
+	}
+	if c.FeeStrategy == "" {
+		c.FeeStrategy = defaultFeeStrategy
+	}
+
+	return c.eth().newPayerWith(ctx, nil, func(ctx context.Context, client *ethclient.Client, owner common.Address, signer eth.Signer, fees eth.FeeStrategy) (payer.Payer, error) {
+		oracle, err := rollup.NewL1FeeOracle(stack, client)
+		if err != nil {
+			return nil, err
+		}
+		rollupPayer, err := rollup.NewPayer(ctx,
+			client,
+			oracle,
+			big.NewInt(int64(c.ChainID)),
+			c.ERC20ContractAddress,
+			owner,
+			signer,
+			fees,
+			c.MaxGas,
+			c.DisperseContractAddress,
+			c.Confirmations,
+		)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		return rollupPayer, nil
+	})
+}
+
+func (c Rollup) NewAuditor(ctx context.Context) (_ Auditor, err error) {
+	// Rollup transactions have receipts like Ethereum ones.
+	return c.eth().NewAuditor(ctx)
+}
+
+// eth returns the configuration of the eth payer the rollup payer is built
+// on.
+func (c Rollup) eth() Eth {
+	return Eth{
+		NodeAddress:                 c.NodeAddress,
+		SpenderKeyPath:              c.SpenderKeyPath,
+		SpenderKeyPassphrase:        c.SpenderKeyPassphrase,
+		ExternalSigner:              c.ExternalSigner,
+		ExternalSignerAuthTokenPath: c.ExternalSignerAuthTokenPath,
+		Spender:                     c.Spender,
+		ERC20ContractAddress:        c.ERC20ContractAddress,
+		DisperseContractAddress:     c.DisperseContractAddress,
+		ChainID:                     c.ChainID,
+		Owner:                       c.Owner,
+		MaxGas:                      c.MaxGas,
+		GasTipCap:                   c.GasTipCap,
+		Confirmations:               c.Confirmations,
+		FeeStrategy:                 c.FeeStrategy,
+		InfuraAPIKeyPath:            c.InfuraAPIKeyPath,
+		FeeHistoryBlocks:            c.FeeHistoryBlocks,
+		FeeHistoryPercentile:        c.FeeHistoryPercentile,
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+)
+
+type Token struct {
+	Symbol   string `toml:"symbol"`
+	Decimals *int32 `toml:"decimals"`
+}
+
+// QuoteSymbol returns the symbol quoted to convert the USD amounts of the
+// payouts to the token.
+func (c Token) QuoteSymbol() coinmarketcap.Symbol {
+	return coinmarketcap.Symbol(strings.ToUpper(c.Symbol))
+}
+
+// checkDecimals checks that the payer pays in a token with the configured
+// decimals, if any.
+func (c Token) checkDecimals(ctx context.Context, p Payer) error {
+	if c.Decimals == nil {
+		return nil
+	}
+	decimals, err := p.GetTokenDecimals(ctx)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	if decimals != *c.Decimals {
+		return errs.New("token has %d decimals; %s is configured with %d", decimals, c.QuoteSymbol(), *c.Decimals)
+	}
+	return nil
+}
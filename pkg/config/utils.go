@@ -3,41 +3,20 @@ package config
 import (
 	"bufio"
 	"crypto/ecdsa"
-	"errors"
-	"io/fs"
 	"os"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/ethkey"
 )
 
 var (
 	zeroAddress common.Address
 )
 
-func loadSpenderKey(path string) (*ecdsa.PrivateKey, common.Address, error) {
-	return loadETHKey(path, "spender_key_path")
-}
-
-func loadETHKey(path, which string) (*ecdsa.PrivateKey, common.Address, error) {
-	fi, err := os.Stat(path)
-	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return nil, common.Address{}, errs.New("%s: %s not found\n", which, path)
-		}
-		return nil, common.Address{}, errs.New("unable to stat %s key: %v\n", which, err)
-	}
-
-	if (fi.Mode() & 0177) != 0 {
-		return nil, common.Address{}, errs.New("%s mode %#o is too permissive (set to 0600)\n", path, fi.Mode())
-	}
-
-	key, err := crypto.LoadECDSA(path)
-	if err != nil {
-		return nil, common.Address{}, errs.New("unable to load %s key: %v\n", which, err)
-	}
-	return key, crypto.PubkeyToAddress(key.PublicKey), nil
+func loadSpenderKey(path string, passphrase ethkey.Passphrase) (*ecdsa.PrivateKey, common.Address, error) {
+	return ethkey.Load(path, "spender_key_path", passphrase)
 }
 
 func loadFirstLine(p string) (_ string, err error) {
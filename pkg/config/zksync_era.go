@@ -7,6 +7,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 
+	"storj.io/crypto-batch-payment/pkg/ethkey"
 	"storj.io/crypto-batch-payment/pkg/zksyncera"
 )
 
@@ -15,13 +16,15 @@ const (
 )
 
 type ZkSyncEra struct {
-	NodeAddress          string          `toml:"node_address"`
-	SpenderKeyPath       Path            `toml:"spender_key_path"`
-	ERC20ContractAddress common.Address  `toml:"erc20_contract_address"`
-	ChainID              int             `toml:"chain_id"`
-	MaxFee               *big.Int        `toml:"max_fee"`
-	PaymasterAddress     *common.Address `toml:"paymaster_address"`
-	PaymasterPayload     HexString       `toml:"paymaster_payload"`
+	NodeAddress             string            `toml:"node_address"`
+	SpenderKeyPath          Path              `toml:"spender_key_path"`
+	SpenderKeyPassphrase    ethkey.Passphrase `toml:"spender_key_passphrase"`
+	ERC20ContractAddress    common.Address    `toml:"erc20_contract_address"`
+	ChainID                 int               `toml:"chain_id"`
+	MaxFee                  *big.Int          `toml:"max_fee"`
+	PaymasterAddress        *common.Address   `toml:"paymaster_address"`
+	PaymasterPayload        HexString         `toml:"paymaster_payload"`
+	DisperseContractAddress *common.Address   `toml:"disperse_contract_address"`
 }
 
 func (c ZkSyncEra) NewPayer(ctx context.Context) (_ Payer, err error) {
@@ -38,7 +41,7 @@ func (c ZkSyncEra) NewPayer(ctx context.Context) (_ Payer, err error) {
 		c.ChainID = defaultZksyncEraChainID
 	}
 
-	spenderKey, _, err := loadSpenderKey(string(c.SpenderKeyPath))
+	spenderKey, _, err := loadSpenderKey(string(c.SpenderKeyPath), c.SpenderKeyPassphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +53,7 @@ func (c ZkSyncEra) NewPayer(ctx context.Context) (_ Payer, err error) {
 		c.ChainID,
 		c.PaymasterAddress,
 		c.PaymasterPayload,
+		c.DisperseContractAddress,
 		c.MaxFee)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,92 @@
+package contract
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/crypto-batch-payment/pkg/ethtest"
+)
+
+func TestDisperse(t *testing.T) {
+	alice := ethtest.NewAccount()
+
+	alloc := core.DefaultGenesisBlock().Alloc
+	alloc[alice.Address] = types.Account{Balance: initialBalance}
+
+	backend := simulated.NewBackend(alloc)
+	client := backend.Client()
+
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+
+	auth, err := bind.NewKeyedTransactorWithChainID(alice.Key, chainID)
+	require.NoError(t, err)
+
+	initialSupply := big.NewInt(100000000000)
+	tokenAddress, _, token, err := DeployToken(auth, client, alice.Address, "Storj", "STORJ", initialSupply, big.NewInt(8))
+	require.NoError(t, err)
+	disperseAddress, _, disperse, err := DeployDisperse(auth, client)
+	require.NoError(t, err)
+	backend.Commit()
+
+	requireBalance := func(address common.Address, want int64) {
+		got, err := token.BalanceOf(nil, address)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(want).String(), got.String())
+	}
+
+	requireStatus := func(tx *types.Transaction, want uint64) *types.Receipt {
+		backend.Commit()
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		require.NoError(t, err)
+		require.Equal(t, want, receipt.Status)
+		return receipt
+	}
+
+	// The contract does not hold an allowance yet so the transfer must fail.
+	// The gas limit is fixed since estimation would fail.
+	recipients := []common.Address{ethtest.NewAccount().Address, ethtest.NewAccount().Address, ethtest.NewAccount().Address}
+	values := []*big.Int{big.NewInt(100), big.NewInt(200), big.NewInt(300)}
+
+	fixedGas := *auth
+	fixedGas.GasLimit = DisperseGasLimit(len(recipients))
+
+	tx, err := disperse.DisperseTokenSimple(&fixedGas, tokenAddress, recipients, values)
+	require.NoError(t, err)
+	requireStatus(tx, types.ReceiptStatusFailed)
+
+	tx, err = token.Approve(auth, disperseAddress, big.NewInt(600))
+	require.NoError(t, err)
+	requireStatus(tx, types.ReceiptStatusSuccessful)
+
+	// Mismatched lengths are rejected.
+	tx, err = disperse.DisperseTokenSimple(&fixedGas, tokenAddress, recipients, values[:2])
+	require.NoError(t, err)
+	requireStatus(tx, types.ReceiptStatusFailed)
+
+	tx, err = disperse.DisperseTokenSimple(&fixedGas, tokenAddress, recipients, values)
+	require.NoError(t, err)
+	receipt := requireStatus(tx, types.ReceiptStatusSuccessful)
+	require.Len(t, receipt.Logs, len(recipients))
+	require.LessOrEqual(t, receipt.GasUsed, DisperseGasLimit(len(recipients)))
+
+	requireBalance(alice.Address, initialSupply.Int64()-600)
+	for i, recipient := range recipients {
+		requireBalance(recipient, values[i].Int64())
+	}
+
+	// The allowance is used up so the same batch must now fail as a whole.
+	tx, err = disperse.DisperseTokenSimple(&fixedGas, tokenAddress, recipients, values)
+	require.NoError(t, err)
+	requireStatus(tx, types.ReceiptStatusFailed)
+	for i, recipient := range recipients {
+		requireBalance(recipient, values[i].Int64())
+	}
+}
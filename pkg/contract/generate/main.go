@@ -5,8 +5,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/asm"
 )
 
 const cleanupAbi = false
@@ -47,26 +49,67 @@ func generate() error {
 		}
 	}
 
-	err := abigen()
+	err := assemble("Disperse.evm", "Disperse.bin")
+	if err != nil {
+		return fmt.Errorf("failed to assemble Disperse.evm: %w", err)
+	}
+
+	err = abigen("Token", "CentrallyIssuedToken", "token.go")
 	if err != nil {
 		return fmt.Errorf("failed to generate abi: %w", err)
 	}
 
+	err = abigen("Disperse", "Disperse", "disperse.go")
+	if err != nil {
+		return fmt.Errorf("failed to generate abi: %w", err)
+	}
+
+	return nil
+}
+
+// assemble compiles the EVM assembly in src into deployable bytecode. The
+// assembled code is treated as the runtime code and prefixed with a prologue
+// that copies it into memory and returns it from the constructor.
+func assemble(src, dst string) error {
+	source, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+
+	compiler := asm.NewCompiler(false)
+	compiler.Feed(asm.Lex(source, false))
+	runtime, errs := compiler.Compile()
+	if len(errs) > 0 {
+		return combine(errs...)
+	}
+
+	size := len(runtime) / 2
+	if size > 0xffff {
+		return fmt.Errorf("runtime code too large: %d bytes", size)
+	}
+
+	// PUSH2 size, DUP1, PUSH1 0x0c, PUSH1 0x00, CODECOPY, PUSH1 0x00, RETURN
+	prologue := fmt.Sprintf("61%04x80600c6000396000f3", size)
+
+	err = os.WriteFile(dst, []byte(prologue+strings.ToLower(runtime)), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
 	return nil
 }
 
-func abigen() error {
-	abi, err := os.ReadFile("CentrallyIssuedToken.abi")
+func abigen(typ, name, out string) error {
+	abi, err := os.ReadFile(name + ".abi")
 	if err != nil {
 		return fmt.Errorf("failed to read: %w", err)
 	}
-	bin, err := os.ReadFile("CentrallyIssuedToken.bin")
+	bin, err := os.ReadFile(name + ".bin")
 	if err != nil {
 		return fmt.Errorf("failed to read: %w", err)
 	}
 
 	code, err := bind.Bind(
-		[]string{"Token"},
+		[]string{typ},
 		[]string{string(abi)},
 		[]string{string(bin)},
 		nil,
@@ -79,7 +122,7 @@ func abigen() error {
 		return fmt.Errorf("failed to generate abi: %w", err)
 	}
 
-	err = os.WriteFile("token.go", []byte(code), 0600)
+	err = os.WriteFile(out, []byte(code), 0600)
 	if err != nil {
 		return fmt.Errorf("failed to write: %w", err)
 	}
@@ -4,4 +4,13 @@ const (
 	TokenDeployGasLimit       = 1200000
 	TokenTransferGasLimit     = 70000
 	TokenTransferFromGasLimit = 70000
+
+	DisperseBaseGasLimit     = 40000
+	DisperseTransferGasLimit = 50000
 )
+
+// DisperseGasLimit returns the gas limit for a disperse transaction paying
+// out to the given number of recipients.
+func DisperseGasLimit(recipients int) uint64 {
+	return DisperseBaseGasLimit + uint64(recipients)*DisperseTransferGasLimit
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/zeebo/errs"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	batchpayment "storj.io/crypto-batch-payment/pkg"
@@ -13,7 +14,8 @@ import (
 )
 
 var (
-	_ payer.Auditor = &Auditor{}
+	_ payer.Auditor         = &Auditor{}
+	_ payer.FinalityChecker = &Auditor{}
 )
 
 // Auditor audits eth transactions.
@@ -55,6 +57,14 @@ func (e *Auditor) CheckConfirmedTransactionState(ctx context.Context, hash strin
 	return TxStateFromReceipt(receipt), nil
 }
 
+func (e *Auditor) IsCanonical(ctx context.Context, hash string, blockHash common.Hash) (bool, error) {
+	txHash, err := batchpayment.HashFromString(hash)
+	if err != nil {
+		return false, err
+	}
+	return IsCanonical(ctx, e.client, txHash, blockHash)
+}
+
 func (e *Auditor) Close() {
 	e.client.Close()
 }
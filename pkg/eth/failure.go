@@ -0,0 +1,103 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/zeebo/errs/v2"
+
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+var _ payer.FailureDiagnoser = &Payer{}
+
+// balanceRevertReasons are fragments of revert reasons returned by ERC-20
+// contracts when the balance or allowance does not cover a transfer.
+var balanceRevertReasons = []string{
+	"exceeds balance",
+	"exceeds allowance",
+	"insufficient balance",
+	"insufficient allowance",
+}
+
+// DiagnoseFailure checks the balance and allowance of the owner against the
+// tokens transferred by the failed transaction, including the allowance of
+// the disperse contract for multitransfers. If they cover the transfer,
+// the transaction is replayed against the state before the block it failed
+// in to recover the revert reason. For native payouts, the balance of the
+// spender is checked against the value and gas instead.
+func (e *Payer) DiagnoseFailure(ctx context.Context, tx pipelinedb.Transaction, receipt *types.Receipt) (reason string, balanceRelated bool, err error) {
+	if e.native {
+		return e.diagnoseNativeFailure(ctx, tx)
+	}
+
+	callOpts := &bind.CallOpts{Context: ctx}
+
+	balance, err := e.contract.BalanceOf(callOpts, e.owner)
+	if err != nil {
+		return "", false, errs.Wrap(err)
+	}
+	if balance.Cmp(tx.Tokens) < 0 {
+		return fmt.Sprintf("owner token balance (%s) does not cover the transfer (%s)", balance, tx.Tokens), true, nil
+	}
+
+	var ethTx types.Transaction
+	if err := ethTx.UnmarshalJSON(tx.Raw); err != nil {
+		return "", false, errs.Wrap(err)
+	}
+
+	switch {
+	case e.disperse != nil && ethTx.To() != nil && *ethTx.To() == e.disperseAddr:
+		// The disperse contract pulls the tokens with transferFrom and
+		// reverts without a reason, so its allowance has to be checked.
+		allowance, err := e.contract.Allowance(callOpts, e.owner, e.disperseAddr)
+		if err != nil {
+			return "", false, errs.Wrap(err)
+		}
+		if allowance.Cmp(tx.Tokens) < 0 {
+			return fmt.Sprintf("disperse contract allowance (%s) does not cover the transfer (%s)", allowance, tx.Tokens), true, nil
+		}
+	case e.owner != e.from:
+		allowance, err := e.contract.Allowance(callOpts, e.owner, e.from)
+		if err != nil {
+			return "", false, errs.Wrap(err)
+		}
+		if allowance.Cmp(tx.Tokens) < 0 {
+			return fmt.Sprintf("spender allowance (%s) does not cover the transfer (%s)", allowance, tx.Tokens), true, nil
+		}
+	}
+
+	if receipt != nil && receipt.GasUsed >= ethTx.Gas() {
+		return fmt.Sprintf("transaction ran out of gas (limit %d)", ethTx.Gas()), false, nil
+	}
+
+	var blockNumber *big.Int
+	if receipt != nil && receipt.BlockNumber != nil && receipt.BlockNumber.Sign() > 0 {
+		blockNumber = new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	}
+	_, err = e.client.CallContract(ctx, ethereum.CallMsg{
+		From:  e.from,
+		To:    ethTx.To(),
+		Gas:   ethTx.Gas(),
+		Value: ethTx.Value(),
+		Data:  ethTx.Data(),
+	}, blockNumber)
+	if err == nil {
+		return "transaction reverted", false, nil
+	}
+
+	reason = err.Error()
+	lower := strings.ToLower(reason)
+	for _, fragment := range balanceRevertReasons {
+		if strings.Contains(lower, fragment) {
+			return reason, true, nil
+		}
+	}
+	return reason, false, nil
+}
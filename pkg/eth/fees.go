@@ -0,0 +1,198 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/infura"
+)
+
+const (
+	// DefaultFeeHistoryBlocks is the default number of blocks the fee
+	// history strategy looks back over.
+	DefaultFeeHistoryBlocks = 20
+
+	// DefaultFeeHistoryPercentile is the default percentile of priority fees
+	// paid in each block that the fee history strategy tips at.
+	DefaultFeeHistoryPercentile = 50
+
+	// PolygonMinGasTipCap is the minimum tip Polygon PoS validators accept.
+	// Transactions tipping less are never included.
+	PolygonMinGasTipCap = 25 * params.GWei
+)
+
+// FeeStrategy decides the EIP-1559 fees of new transactions. The payer caps
+// the fee cap at the max gas price.
+type FeeStrategy interface {
+	// SuggestFees returns the tip and fee cap for a transaction to be
+	// included after the block with the given header. A nil fee cap leaves
+	// it to the payer.
+	SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error)
+}
+
+// NewFixedFees returns a strategy that always tips the given amount and
+// leaves the fee cap to the payer, which makes it the max gas price.
+func NewFixedFees(gasTipCap *big.Int) FeeStrategy {
+	return fixedFees{gasTipCap: gasTipCap}
+}
+
+type fixedFees struct {
+	gasTipCap *big.Int
+}
+
+func (f fixedFees) SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error) {
+	return new(big.Int).Set(f.gasTipCap), nil, nil
+}
+
+// InfuraLevel is one of the fee levels suggested by the Infura gas API.
+type InfuraLevel string
+
+const (
+	InfuraLow    InfuraLevel = "low"
+	InfuraMedium InfuraLevel = "medium"
+	InfuraHigh   InfuraLevel = "high"
+)
+
+// NewInfuraFees returns a strategy that uses the fees suggested by the
+// Infura gas API at the given level.
+func NewInfuraFees(client infura.Client, chainID int, level InfuraLevel) (FeeStrategy, error) {
+	switch level {
+	case InfuraLow, InfuraMedium, InfuraHigh:
+	default:
+		return nil, errs.New("unsupported infura fee level %q", level)
+	}
+	return &infuraFees{
+		client:  client,
+		chainID: chainID,
+		level:   level,
+	}, nil
+}
+
+type infuraFees struct {
+	client  infura.Client
+	chainID int
+	level   InfuraLevel
+}
+
+func (f *infuraFees) SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error) {
+	fees, err := f.client.GetSuggestedGasFees(ctx, f.chainID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var values infura.RecommendedGasValues
+	switch f.level {
+	case InfuraLow:
+		values = fees.Low
+	case InfuraMedium:
+		values = fees.Medium
+	case InfuraHigh:
+		values = fees.High
+	}
+
+	// The gas API reports fees in gwei.
+	gasTipCap = values.SuggestedMaxPriorityFeePerGas.Shift(9).BigInt()
+	gasFeeCap = values.SuggestedMaxFeePerGas.Shift(9).BigInt()
+	if gasTipCap.Sign() <= 0 || gasFeeCap.Sign() <= 0 {
+		return nil, nil, errs.New("infura suggested invalid %s fees (tip=%s gwei, fee cap=%s gwei)",
+			f.level, values.SuggestedMaxPriorityFeePerGas, values.SuggestedMaxFeePerGas)
+	}
+	return gasTipCap, gasFeeCap, nil
+}
+
+// NewFeeHistoryFees returns a strategy that tips the median of the given
+// percentile of priority fees paid over the last blocks, as reported by
+// eth_feeHistory.
+func NewFeeHistoryFees(client ethereum.FeeHistoryReader, blocks uint64, percentile float64) (FeeStrategy, error) {
+	if blocks == 0 {
+		blocks = DefaultFeeHistoryBlocks
+	}
+	if percentile == 0 {
+		percentile = DefaultFeeHistoryPercentile
+	}
+	if percentile < 0 || percentile > 100 {
+		return nil, errs.New("fee history percentile must be between 0 and 100; got %v", percentile)
+	}
+	return &feeHistoryFees{
+		client:     client,
+		blocks:     blocks,
+		percentile: percentile,
+	}, nil
+}
+
+type feeHistoryFees struct {
+	client     ethereum.FeeHistoryReader
+	blocks     uint64
+	percentile float64
+}
+
+func (f *feeHistoryFees) SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error) {
+	history, err := f.client.FeeHistory(ctx, f.blocks, head.Number, []float64{f.percentile})
+	if err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+
+	var rewards []*big.Int
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			rewards = append(rewards, reward[0])
+		}
+	}
+	if len(rewards) == 0 {
+		return nil, nil, errs.New("no priority fees in the fee history of the last %d blocks", f.blocks)
+	}
+	slices.SortFunc(rewards, func(a, b *big.Int) int { return a.Cmp(b) })
+	gasTipCap = new(big.Int).Set(rewards[len(rewards)/2])
+
+	// Block producers have no incentive to include a transaction that does
+	// not tip, so always tip something.
+	if gasTipCap.Sign() == 0 {
+		gasTipCap.SetInt64(params.Wei)
+	}
+
+	// The last base fee in the history is the one of the next block.
+	baseFee := head.BaseFee
+	if len(history.BaseFee) > 0 {
+		baseFee = history.BaseFee[len(history.BaseFee)-1]
+	}
+	return gasTipCap, doubleBaseFeePlusTip(baseFee, gasTipCap), nil
+}
+
+// NewMinTipFees returns a strategy that tips at least the given minimum,
+// whatever the given strategy suggests. The fee cap, if any, is raised by as
+// much as the tip.
+func NewMinTipFees(fees FeeStrategy, minGasTipCap *big.Int) FeeStrategy {
+	return minTipFees{fees: fees, minGasTipCap: minGasTipCap}
+}
+
+type minTipFees struct {
+	fees         FeeStrategy
+	minGasTipCap *big.Int
+}
+
+func (f minTipFees) SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error) {
+	gasTipCap, gasFeeCap, err = f.fees.SuggestFees(ctx, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gasTipCap.Cmp(f.minGasTipCap) < 0 {
+		if gasFeeCap != nil {
+			gasFeeCap = new(big.Int).Add(gasFeeCap, new(big.Int).Sub(f.minGasTipCap, gasTipCap))
+		}
+		gasTipCap = new(big.Int).Set(f.minGasTipCap)
+	}
+	return gasTipCap, gasFeeCap, nil
+}
+
+// doubleBaseFeePlusTip returns a fee cap leaving room for the base fee to
+// double, which is also room for bumping the fee of the transaction later.
+func doubleBaseFeePlusTip(baseFee, gasTipCap *big.Int) *big.Int {
+	gasFeeCap := new(big.Int).Mul(baseFee, big.NewInt(2))
+	return gasFeeCap.Add(gasFeeCap, gasTipCap)
+}
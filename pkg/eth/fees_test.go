@@ -0,0 +1,95 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+)
+
+func TestFixedFees(t *testing.T) {
+	gasTipCap := big.NewInt(10)
+	tip, feeCap, err := NewFixedFees(gasTipCap).SuggestFees(context.Background(), &types.Header{BaseFee: big.NewInt(100)})
+	require.NoError(t, err)
+	require.Equal(t, gasTipCap, tip)
+
+	// The fee cap is left to the payer.
+	require.Nil(t, feeCap)
+}
+
+func TestMinTipFees(t *testing.T) {
+	ctx := context.Background()
+	head := &types.Header{BaseFee: big.NewInt(100)}
+
+	for _, tc := range []struct {
+		name              string
+		gasTipCap         int64
+		expectedGasTipCap int64
+		expectedGasFeeCap int64
+	}{
+		{
+			name:              "below minimum",
+			gasTipCap:         10,
+			expectedGasTipCap: 25,
+			expectedGasFeeCap: 225,
+		},
+		{
+			name:              "at minimum",
+			gasTipCap:         25,
+			expectedGasTipCap: 25,
+			expectedGasFeeCap: 225,
+		},
+		{
+			name:              "above minimum",
+			gasTipCap:         40,
+			expectedGasTipCap: 40,
+			expectedGasFeeCap: 240,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gasTipCap := big.NewInt(tc.gasTipCap)
+			gasFeeCap := doubleBaseFeePlusTip(head.BaseFee, gasTipCap)
+			fees := NewMinTipFees(suggestedFees{gasTipCap: gasTipCap, gasFeeCap: gasFeeCap}, big.NewInt(25))
+
+			tip, feeCap, err := fees.SuggestFees(ctx, head)
+			require.NoError(t, err)
+			require.Equal(t, big.NewInt(tc.expectedGasTipCap), tip)
+			require.Equal(t, big.NewInt(tc.expectedGasFeeCap), feeCap)
+
+			// The suggestion of the wrapped strategy is left alone.
+			require.Equal(t, big.NewInt(tc.gasTipCap), gasTipCap)
+			require.Equal(t, doubleBaseFeePlusTip(head.BaseFee, gasTipCap), gasFeeCap)
+		})
+	}
+
+	t.Run("fee cap left to the payer", func(t *testing.T) {
+		fees := NewMinTipFees(NewFixedFees(big.NewInt(10)), big.NewInt(25))
+		tip, feeCap, err := fees.SuggestFees(ctx, head)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(25), tip)
+		require.Nil(t, feeCap)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		fees := NewMinTipFees(failingFees{}, big.NewInt(25))
+		_, _, err := fees.SuggestFees(ctx, head)
+		require.EqualError(t, err, "no fees")
+	})
+}
+
+type suggestedFees struct {
+	gasTipCap, gasFeeCap *big.Int
+}
+
+func (f suggestedFees) SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error) {
+	return f.gasTipCap, f.gasFeeCap, nil
+}
+
+type failingFees struct{}
+
+func (failingFees) SuggestFees(ctx context.Context, head *types.Header) (gasTipCap, gasFeeCap *big.Int, err error) {
+	return nil, nil, errs.New("no fees")
+}
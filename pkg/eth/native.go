@@ -0,0 +1,194 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs/v2"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+const (
+	// NativeTransferGasLimit is the gas of a plain value transfer to an
+	// account without code.
+	NativeTransferGasLimit = params.TxGas
+
+	// NativeDecimals are the decimals of the native coin of Ethereum and of
+	// the chains built like it, like ETH and MATIC.
+	NativeDecimals = 18
+)
+
+// NewNativePayer returns a payer that pays out in the native coin of the
+// chain (e.g. ETH or MATIC) with plain value transfers from the spender,
+// instead of in an ERC-20 token. The USD amounts are converted at the price
+// of the native coin. Every payout is sent in a transaction of its own.
+func NewNativePayer(ctx context.Context,
+	client Client,
+	signer Signer,
+	fees FeeStrategy,
+	maxGas *big.Int,
+	confirmations uint64) (*Payer, error) {
+
+	if confirmations == 0 {
+		confirmations = DefaultConfirmations
+	}
+
+	// Without a fee strategy, tip whatever the node suggests right now for
+	// every transaction.
+	if fees == nil {
+		suggestedGasTip, err := client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		fees = NewFixedFees(suggestedGasTip)
+	}
+
+	return &Payer{
+		owner:         signer.Address(),
+		fees:          fees,
+		maxGas:        maxGas,
+		client:        client,
+		signer:        signer,
+		from:          signer.Address(),
+		tokenDecimals: NativeDecimals,
+		confirmations: confirmations,
+		native:        true,
+	}, nil
+}
+
+// nativeBalance returns the pending balance of the spender less the gas of a
+// value transfer at the max gas price, which is reserved so that the spender
+// can always pay for the transaction it is about to send.
+func (e *Payer) nativeBalance(ctx context.Context) (*big.Int, error) {
+	balance, err := e.client.PendingBalanceAt(ctx, e.from)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	balance.Sub(balance, e.reservedGas())
+	if balance.Sign() < 0 {
+		balance.SetInt64(0)
+	}
+	return balance, nil
+}
+
+// reservedGas returns the most a value transfer can cost in gas.
+func (e *Payer) reservedGas() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(NativeTransferGasLimit), e.maxGas)
+}
+
+// createNativeTransaction creates a value transfer paying out the payout.
+// There is no contract to pay out multiple payouts in one transaction.
+func (e *Payer) createNativeTransaction(ctx context.Context, log *zap.Logger, opts *bind.TransactOpts,
+	payouts []*pipelinedb.Payout, price decimal.Decimal) (_ payer.Transaction, _ common.Address, err error) {
+
+	if len(payouts) > 1 {
+		return payer.Transaction{}, common.Address{}, errs.Errorf("multitransfer is not supported for native payouts")
+	}
+	payout := payouts[0]
+
+	// The gas limit only covers a transfer to an account. Contracts could
+	// run out of gas or reject the value altogether.
+	code, err := e.client.PendingCodeAt(ctx, payout.Payee)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+	if len(code) > 0 {
+		return payer.Transaction{}, common.Address{}, errs.Errorf("payee %s is a contract; native payouts can only be sent to accounts", payout.Payee)
+	}
+
+	value := storjtoken.FromUSD(payout.USD, price, NativeDecimals)
+	rawTx, err := opts.Signer(e.from, types.NewTx(&types.DynamicFeeTx{
+		Nonce:     opts.Nonce.Uint64(),
+		GasTipCap: opts.GasTipCap,
+		GasFeeCap: opts.GasFeeCap,
+		Gas:       NativeTransferGasLimit,
+		To:        &payout.Payee,
+		Value:     value,
+	}))
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+
+	// Grab the pending ETH balance for logging
+	ethBalance, err := e.client.PendingBalanceAt(ctx, opts.From)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+
+	log.Info("Transaction is created",
+		zap.String("payee", payout.Payee.String()),
+		zap.String("usd", payout.USD.String()),
+		zap.String("value", value.String()),
+		zap.String("pending-eth-balance", ethBalance.String()),
+		zap.String("hash", rawTx.Hash().String()),
+	)
+
+	return payer.Transaction{
+		Hash:  rawTx.Hash().Hex(),
+		Nonce: opts.Nonce.Uint64(),
+		Raw:   rawTx,
+	}, e.from, nil
+}
+
+// diagnoseNativeFailure checks the balance of the spender against the value
+// and gas of the failed transaction.
+func (e *Payer) diagnoseNativeFailure(ctx context.Context, tx pipelinedb.Transaction) (reason string, balanceRelated bool, err error) {
+	balance, err := e.client.BalanceAt(ctx, e.from, nil)
+	if err != nil {
+		return "", false, errs.Wrap(err)
+	}
+	cost := new(big.Int).Add(tx.Tokens, e.reservedGas())
+	if balance.Cmp(cost) < 0 {
+		return fmt.Sprintf("spender balance (%s) does not cover the transfer plus gas (%s)", balance, cost), true, nil
+	}
+	return "transaction failed", false, nil
+}
+
+// simulateNativePayoutGroup checks the balance of the spender against the
+// value transfers of the payout group. A value transfer to an account cannot
+// fail otherwise.
+func (e *Payer) simulateNativePayoutGroup(ctx context.Context, payouts []*pipelinedb.Payout, price decimal.Decimal, transferred *big.Int) ([]*pipelinedb.SimulationResult, error) {
+	if len(payouts) > 1 {
+		return nil, errs.Errorf("multitransfer is not supported for native payouts")
+	}
+
+	balance, err := e.nativeBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payout := payouts[0]
+	value := storjtoken.FromUSD(payout.USD, price, NativeDecimals)
+	total := new(big.Int).Add(transferred, value)
+
+	result := &pipelinedb.SimulationResult{
+		PayoutGroupID: payout.PayoutGroupID,
+		Payee:         payout.Payee,
+		USD:           payout.USD,
+		Tokens:        value,
+		Outcome:       pipelinedb.SimulationOK,
+	}
+	code, err := e.client.PendingCodeAt(ctx, payout.Payee)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	switch {
+	case total.Cmp(balance) > 0:
+		result.Outcome = pipelinedb.SimulationBalance
+		result.Reason = fmt.Sprintf("spender balance less gas (%s) does not cover the transfers (%s)", balance, total)
+	case len(code) > 0:
+		result.Outcome = pipelinedb.SimulationRevert
+		result.Reason = "payee is a contract; native payouts can only be sent to accounts"
+	}
+	return []*pipelinedb.SimulationResult{result}, nil
+}
@@ -0,0 +1,101 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/zeebo/errs/v2"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// From returns the address of the spender.
+func (e *Payer) From() common.Address {
+	return e.from
+}
+
+// Nonces returns the next nonce of the spender according to the latest block
+// and according to the node, counting the transactions in its pool that are
+// ready to be mined. Transactions in the pool behind a nonce gap are not
+// counted.
+func (e *Payer) Nonces(ctx context.Context) (latest, pending uint64, err error) {
+	latest, err = e.client.NonceAt(ctx, e.from, nil)
+	if err != nil {
+		return 0, 0, errs.Wrap(err)
+	}
+	pending, err = e.client.PendingNonceAt(ctx, e.from)
+	if err != nil {
+		return 0, 0, errs.Wrap(err)
+	}
+	return latest, pending, nil
+}
+
+// TransactionStatus returns the status of the transaction with the given
+// hash according to the node.
+func (e *Payer) TransactionStatus(ctx context.Context, hash string) (*pipelinedb.TxStatus, error) {
+	return e.getTransactionStatus(ctx, hash)
+}
+
+// ResendTransaction sends a previously signed transaction to the node again.
+func (e *Payer) ResendTransaction(ctx context.Context, tx pipelinedb.Transaction) error {
+	var rawTx types.Transaction
+	if err := rawTx.UnmarshalJSON(tx.Raw); err != nil {
+		return errs.Errorf("unable to decode transaction %s: %w", tx.Hash, err)
+	}
+	return errs.Wrap(e.client.SendTransaction(ctx, &rawTx))
+}
+
+// CreateCancelTransaction signs a zero-value transfer from the spender to
+// itself with the given nonce. Once mined, it uses up the nonce without
+// moving any tokens. If previous is not nil, the transfer replaces that
+// pending transaction and the fees are bumped above it.
+func (e *Payer) CreateCancelTransaction(ctx context.Context, log *zap.Logger, nonce uint64, previous *pipelinedb.Transaction) (*types.Transaction, error) {
+	var gasTipCap, gasFeeCap *big.Int
+	if previous != nil {
+		var previousTx types.Transaction
+		if err := previousTx.UnmarshalJSON(previous.Raw); err != nil {
+			return nil, errs.Errorf("unable to decode transaction %s: %w", previous.Hash, err)
+		}
+		var err error
+		gasTipCap, gasFeeCap, err = e.replacementFees(ctx, &previousTx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		gasTipCap, gasFeeCap, err = e.suggestFees(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if gasFeeCap.Cmp(e.maxGas) > 0 {
+			gasFeeCap.Set(e.maxGas)
+		}
+		if gasTipCap.Cmp(gasFeeCap) > 0 {
+			gasTipCap.Set(gasFeeCap)
+		}
+	}
+
+	tx, err := e.signer.SignTransaction(ctx, types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       params.TxGas,
+		To:        &e.from,
+		Value:     zero,
+	}))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	log.Info("Created cancel transaction",
+		zap.Uint64("nonce", nonce),
+		zap.String("hash", tx.Hash().String()),
+		zap.String("gas-tip-cap", gasTipCap.String()),
+		zap.String("gas-fee-cap", gasFeeCap.String()),
+	)
+	return tx, nil
+}
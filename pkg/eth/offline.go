@@ -0,0 +1,159 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs/v2"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// offlineSigner knows the address of a spender whose key is kept offline. It
+// refuses to sign.
+type offlineSigner struct {
+	address common.Address
+}
+
+// NewOfflineSigner returns a signer for a spender whose transactions are
+// planned and broadcast by this host but signed on another one. It fails to
+// sign anything, so nothing is ever sent unless it was signed offline.
+func NewOfflineSigner(address common.Address) Signer {
+	return offlineSigner{address: address}
+}
+
+func (s offlineSigner) Address() common.Address {
+	return s.address
+}
+
+func (s offlineSigner) SignTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	return nil, errs.Errorf("the key of spender %s is offline; transactions must be planned, signed and broadcast again", s.address)
+}
+
+// unsignedSigner returns transactions as they are, unsigned.
+type unsignedSigner struct {
+	address common.Address
+}
+
+func (s unsignedSigner) Address() common.Address {
+	return s.address
+}
+
+func (s unsignedSigner) SignTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	return tx, nil
+}
+
+// Owner returns the address of the account the tokens are paid from.
+func (e *Payer) Owner() common.Address {
+	return e.owner
+}
+
+// TokenAddress returns the address of the token contract.
+func (e *Payer) TokenAddress() common.Address {
+	return e.tokenAddress
+}
+
+// DisperseAddress returns the address of the disperse contract, or nil if
+// multitransfers are not enabled.
+func (e *Payer) DisperseAddress() *common.Address {
+	if e.disperse == nil {
+		return nil
+	}
+	disperseAddr := e.disperseAddr
+	return &disperseAddr
+}
+
+// CreateUnsignedTransaction creates the same transaction as
+// CreateRawTransaction for the given chain but leaves it unsigned, to be
+// signed offline. Since it cannot be replaced without signing again, the fee
+// cap is the max gas price. Only the base fee and the tip are paid.
+func (e *Payer) CreateUnsignedTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout,
+	nonce uint64, price decimal.Decimal, chainID *big.Int) (*types.Transaction, error) {
+
+	gasTipCap, _, err := e.suggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeCap := new(big.Int).Set(e.maxGas)
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasTipCap.Set(gasFeeCap)
+	}
+
+	unsigned := *e
+	unsigned.signer = unsignedSigner{address: e.from}
+	rawTx, _, err := unsigned.createTransaction(ctx, log, payouts, nonce, price, gasTipCap, gasFeeCap)
+	if err != nil {
+		return nil, err
+	}
+
+	// The contract bindings leave the chain ID to the signer.
+	tx := rawTx.Raw.(*types.Transaction)
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     tx.Nonce(),
+		GasTipCap: tx.GasTipCap(),
+		GasFeeCap: tx.GasFeeCap(),
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	}), nil
+}
+
+// Transfer is a transfer of tokens to a payee.
+type Transfer struct {
+	Payee  common.Address
+	Tokens *big.Int
+}
+
+// TransferCall returns the destination, data and gas limit of the
+// transaction that pays out the transfers: a transfer when the owner is the
+// spender, a transferFrom otherwise, or a disperseTokenSimple through the
+// disperse contract when there is more than one transfer.
+func TransferCall(owner, spender, tokenAddress common.Address, disperseAddress *common.Address, transfers []Transfer) (to common.Address, data []byte, gas uint64, err error) {
+	switch len(transfers) {
+	case 0:
+		return common.Address{}, nil, 0, errs.Errorf("no transfers")
+	case 1:
+	default:
+		if disperseAddress == nil {
+			return common.Address{}, nil, 0, errs.Errorf("multitransfer requires a disperse contract address")
+		}
+		disperseABI, err := contract.DisperseMetaData.GetAbi()
+		if err != nil {
+			return common.Address{}, nil, 0, errs.Wrap(err)
+		}
+		recipients := make([]common.Address, 0, len(transfers))
+		values := make([]*big.Int, 0, len(transfers))
+		for _, transfer := range transfers {
+			recipients = append(recipients, transfer.Payee)
+			values = append(values, transfer.Tokens)
+		}
+		data, err := disperseABI.Pack("disperseTokenSimple", tokenAddress, recipients, values)
+		if err != nil {
+			return common.Address{}, nil, 0, errs.Wrap(err)
+		}
+		return *disperseAddress, data, contract.DisperseGasLimit(len(transfers)), nil
+	}
+
+	tokenABI, err := contract.TokenMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, 0, errs.Wrap(err)
+	}
+	if owner == spender {
+		data, err = tokenABI.Pack("transfer", transfers[0].Payee, transfers[0].Tokens)
+		gas = contract.TokenTransferGasLimit
+	} else {
+		data, err = tokenABI.Pack("transferFrom", owner, transfers[0].Payee, transfers[0].Tokens)
+		gas = contract.TokenTransferFromGasLimit
+	}
+	if err != nil {
+		return common.Address{}, nil, 0, errs.Wrap(err)
+	}
+	return tokenAddress, data, gas, nil
+}
@@ -2,7 +2,6 @@ package eth
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 
@@ -26,16 +25,32 @@ import (
 type Payer struct {
 	client        Client
 	contract      *contract.Token
+	tokenAddress  common.Address
+	disperse      *contract.Disperse
+	disperseAddr  common.Address
 	owner         common.Address
-	gasTipCap     *big.Int
+	fees          FeeStrategy
 	maxGas        *big.Int
-	signer        bind.SignerFn
+	signer        Signer
 	from          common.Address
 	tokenDecimals int32
+	confirmations uint64
+	extraGas      ExtraGasFunc
+	native        bool
+	replace       bool
 }
 
+// ExtraGasFunc returns the gas a transaction to the given address with the
+// given data needs on top of its execution gas, like the L1 data fee that
+// Arbitrum charges as L2 gas.
+type ExtraGasFunc func(ctx context.Context, to common.Address, data []byte) (uint64, error)
+
 var (
-	_ payer.Payer = &Payer{}
+	_ payer.Payer           = &Payer{}
+	_ payer.Replacer        = &Payer{}
+	_ payer.FinalityChecker = &Payer{}
+	_ payer.FinalityHorizon = &Payer{}
+	_ payer.Reporter        = &Payer{}
 
 	// zero is a big int set to 0 for convenience.
 	zero = big.NewInt(0)
@@ -46,6 +61,7 @@ type Client interface {
 	ethereum.ChainReader
 	ethereum.ChainStateReader
 	ethereum.PendingStateReader
+	ethereum.PendingContractCaller
 	ethereum.TransactionReader
 }
 
@@ -53,49 +69,75 @@ func NewPayer(ctx context.Context,
 	client Client,
 	contractAddress common.Address,
 	owner common.Address,
-	key *ecdsa.PrivateKey,
-	chainID *big.Int,
-	gasTipCap *big.Int,
-	maxGas *big.Int) (*Payer, error) {
+	signer Signer,
+	fees FeeStrategy,
+	maxGas *big.Int,
+	disperseAddress *common.Address,
+	confirmations uint64) (*Payer, error) {
+
+	if confirmations == 0 {
+		confirmations = DefaultConfirmations
+	}
 
-	contract, err := contract.NewToken(contractAddress, &ignoreSend{
+	token, err := contract.NewToken(contractAddress, &ignoreSend{
 		ContractBackend: client,
 	})
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
 
-	if gasTipCap == nil {
+	// Without a fee strategy, tip whatever the node suggests right now for
+	// every transaction.
+	if fees == nil {
 		suggestedGasTip, err := client.SuggestGasTipCap(ctx)
 		if err != nil {
 			return nil, errs.Wrap(err)
 		}
-		gasTipCap = suggestedGasTip
+		fees = NewFixedFees(suggestedGasTip)
 	}
 
-	opts, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	decimals, err := token.Decimals(nil)
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
 
-	decimals, err := contract.Decimals(nil)
-	if err != nil {
-		return nil, errs.Wrap(err)
+	// The disperse contract transfers from the transaction sender, so it
+	// can only be used when the spender owns the tokens.
+	var disperse *contract.Disperse
+	var disperseAddr common.Address
+	if disperseAddress != nil {
+		disperseAddr = *disperseAddress
+		if owner != signer.Address() {
+			return nil, errs.Errorf("multitransfer with the disperse contract requires the owner (%s) to be the spender (%s)", owner, signer.Address())
+		}
+		disperse, err = contract.NewDisperse(disperseAddr, &ignoreSend{
+			ContractBackend: client,
+		})
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
 	}
 
 	return &Payer{
 		owner:         owner,
-		gasTipCap:     gasTipCap,
+		fees:          fees,
 		maxGas:        maxGas,
 		client:        client,
-		contract:      contract,
-		signer:        opts.Signer,
-		from:          opts.From,
+		contract:      token,
+		tokenAddress:  contractAddress,
+		disperse:      disperse,
+		disperseAddr:  disperseAddr,
+		signer:        signer,
+		from:          signer.Address(),
 		tokenDecimals: int32(decimals.Int64()),
+		confirmations: confirmations,
 	}, nil
 }
 
 func (e *Payer) String() string {
+	if e.native {
+		return payer.Native.String()
+	}
 	return payer.Eth.String()
 }
 
@@ -104,23 +146,34 @@ func (e *Payer) NextNonce(ctx context.Context) (uint64, error) {
 }
 
 func (e *Payer) CheckPreconditions(ctx context.Context) (unmet []string, err error) {
-	lastBlock, err := e.client.BlockByNumber(ctx, nil)
+	head, err := e.client.HeaderByNumber(ctx, nil)
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
 
+	gasTipCap, _, err := e.fees.SuggestFees(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+
 	// max gas should be higher than the base fee + tip
-	if e.maxGas.Cmp(new(big.Int).Add(lastBlock.BaseFee(), e.gasTipCap)) < 0 {
+	if e.maxGas.Cmp(new(big.Int).Add(head.BaseFee, gasTipCap)) < 0 {
 		unmet = append(unmet, fmt.Sprintf(
 			"the base fee of the last block (%s) plus the tip (%s) is larger than the max allowed gas price (%s)",
-			lastBlock.BaseFee(), e.gasTipCap, e.maxGas))
+			head.BaseFee, gasTipCap, e.maxGas))
 	}
 
 	return unmet, nil
 
 }
 
+// GetTokenBalance returns the token balance of the owner. For native
+// payouts it is the pending balance of the spender less the gas reserved for
+// a transaction.
 func (e *Payer) GetTokenBalance(ctx context.Context) (*big.Int, error) {
+	if e.native {
+		return e.nativeBalance(ctx)
+	}
 	storjBalance, err := e.contract.BalanceOf(&bind.CallOpts{
 		Pending: false,
 		Context: ctx,
@@ -129,46 +182,167 @@ func (e *Payer) GetTokenBalance(ctx context.Context) (*big.Int, error) {
 }
 
 func (e *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout,
-	nonce uint64, storjPrice decimal.Decimal) (_ payer.Transaction, _ common.Address, err error) {
+	nonce uint64, price decimal.Decimal) (_ payer.Transaction, _ common.Address, err error) {
 
-	var rawTx *types.Transaction
-	if len(payouts) > 1 {
-		return payer.Transaction{}, common.Address{}, errs.Errorf("multitransfer is not supported yet")
+	gasTipCap, gasFeeCap, err := e.suggestFees(ctx)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, err
+	}
+	// Without replacement, a transaction is never bumped, so the fee cap
+	// is the max gas price to keep it from being stranded by a base fee
+	// spike. Only the base fee and the tip are paid.
+	if !e.replace || gasFeeCap.Cmp(e.maxGas) > 0 {
+		gasFeeCap = new(big.Int).Set(e.maxGas)
+	}
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasTipCap.Set(gasFeeCap)
+	}
+	return e.createTransaction(ctx, log, payouts, nonce, price, gasTipCap, gasFeeCap)
+}
+
+// EnableReplacement makes CreateRawTransaction leave room under the max gas
+// price to bump the fee of the transaction with a replacement.
+func (e *Payer) EnableReplacement() {
+	e.replace = true
+}
+
+// CreateReplacementTransaction re-signs the payouts of a pending transaction
+// with the tip and fee cap bumped by at least the minimum amount nodes
+// require to accept a replacement. The fee cap never exceeds the max gas
+// price.
+func (e *Payer) CreateReplacementTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout,
+	previous pipelinedb.Transaction) (_ payer.Transaction, _ common.Address, err error) {
+
+	var previousTx types.Transaction
+	if err := previousTx.UnmarshalJSON(previous.Raw); err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Errorf("unable to decode transaction %s: %w", previous.Hash, err)
+	}
+
+	gasTipCap, gasFeeCap, err := e.replacementFees(ctx, &previousTx)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, err
+	}
+
+	log.Info("Replacing pending transaction",
+		zap.String("replaced", previous.Hash),
+		zap.String("gas-tip-cap", gasTipCap.String()),
+		zap.String("gas-fee-cap", gasFeeCap.String()),
+	)
+	return e.createTransaction(ctx, log, payouts, previous.Nonce, previous.Price, gasTipCap, gasFeeCap)
+}
+
+// replacementFees returns the tip and fee cap for a transaction replacing
+// the previous one, which are bumped by at least the minimum amount nodes
+// require to accept a replacement. It fails with ErrReplacementCapped if the
+// fee cap would exceed the max gas price.
+func (e *Payer) replacementFees(ctx context.Context, previousTx *types.Transaction) (gasTipCap, gasFeeCap *big.Int, err error) {
+	suggestedTipCap, suggestedFeeCap, err := e.suggestFees(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gasTipCap = bumpFee(previousTx.GasTipCap())
+	if gasTipCap.Cmp(suggestedTipCap) < 0 {
+		gasTipCap = suggestedTipCap
 	}
-	payout := payouts[0]
+
+	// Raise the suggested fee cap by however much the tip was raised above
+	// the suggestion to keep the same room for the base fee.
+	gasFeeCap = new(big.Int).Sub(gasTipCap, suggestedTipCap)
+	gasFeeCap.Add(gasFeeCap, suggestedFeeCap)
+	if gasFeeCap.Cmp(e.maxGas) > 0 {
+		gasFeeCap.Set(e.maxGas)
+	}
+	minGasFeeCap := bumpFee(previousTx.GasFeeCap())
+	if gasFeeCap.Cmp(minGasFeeCap) < 0 {
+		gasFeeCap = minGasFeeCap
+	}
+
+	if gasFeeCap.Cmp(e.maxGas) > 0 || gasTipCap.Cmp(gasFeeCap) > 0 {
+		return nil, nil, errs.Errorf("%w: replacing %s needs a tip of %s and a fee cap of %s (max %s)",
+			payer.ErrReplacementCapped, previousTx.Hash(), gasTipCap, gasFeeCap, e.maxGas)
+	}
+	return gasTipCap, gasFeeCap, nil
+}
+
+// suggestFees returns the fees the fee strategy suggests for a transaction
+// included after the latest block. If the strategy leaves the fee cap to the
+// payer, it leaves room for the base fee to double, which is also room for
+// bumping the fee later.
+func (e *Payer) suggestFees(ctx context.Context) (gasTipCap, gasFeeCap *big.Int, err error) {
+	head, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+	gasTipCap, gasFeeCap, err = e.fees.SuggestFees(ctx, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gasFeeCap == nil {
+		gasFeeCap = doubleBaseFeePlusTip(head.BaseFee, gasTipCap)
+	}
+	return gasTipCap, gasFeeCap, nil
+}
+
+// bumpFee returns the fee increased by 10%, rounded up, which is the
+// minimum price bump geth and most other nodes require for a replacement.
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(110))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func (e *Payer) createTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout,
+	nonce uint64, price decimal.Decimal, gasTipCap, gasFeeCap *big.Int) (_ payer.Transaction, _ common.Address, err error) {
 
 	opts := &bind.TransactOpts{
 		From:      e.from,
-		Signer:    e.signer,
+		Signer:    e.signerFn(ctx),
 		Value:     zero,
 		Nonce:     new(big.Int).SetUint64(nonce),
-		GasTipCap: e.gasTipCap,
-		GasFeeCap: e.maxGas,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
 		Context:   ctx,
 	}
 
-	storjTokens := storjtoken.FromUSD(payout.USD, storjPrice, e.tokenDecimals)
-	var storjAllowance *big.Int
+	if e.native {
+		return e.createNativeTransaction(ctx, log, opts, payouts, price)
+	}
+	if len(payouts) > 1 {
+		return e.createDisperseTransaction(ctx, log, opts, payouts, price)
+	}
+
+	var rawTx *types.Transaction
+	payout := payouts[0]
+
+	tokens := storjtoken.FromUSD(payout.USD, price, e.tokenDecimals)
+	var allowance *big.Int
 	if e.owner == opts.From {
 		opts.GasLimit = contract.TokenTransferGasLimit
-		rawTx, err = e.contract.Transfer(opts, payout.Payee, storjTokens)
+		if err := e.addExtraGas(ctx, opts, []Transfer{{Payee: payout.Payee, Tokens: tokens}}); err != nil {
+			return payer.Transaction{}, common.Address{}, err
+		}
+		rawTx, err = e.contract.Transfer(opts, payout.Payee, tokens)
 	} else {
-		// Check the STORJ allowance to make sure there is enough. Since the
+		// Check the token allowance to make sure there is enough. Since the
 		// contract does not support pending operations, the best we can do
 		// is check the live balance.
-		storjAllowance, err = e.contract.Allowance(&bind.CallOpts{
+		allowance, err = e.contract.Allowance(&bind.CallOpts{
 			Pending: false,
 			Context: ctx,
 		}, e.owner, opts.From)
 		if err != nil {
 			return payer.Transaction{}, common.Address{}, errs.Wrap(err)
 		}
-		if storjAllowance.Cmp(storjTokens) < 0 {
-			return payer.Transaction{}, common.Address{}, errs.Errorf("not enough STORJ allowance to cover transfer")
+		if allowance.Cmp(tokens) < 0 {
+			return payer.Transaction{}, common.Address{}, errs.Errorf("not enough token allowance to cover transfer")
 		}
 
 		opts.GasLimit = contract.TokenTransferFromGasLimit
-		rawTx, err = e.contract.TransferFrom(opts, e.owner, payout.Payee, storjTokens)
+		if err := e.addExtraGas(ctx, opts, []Transfer{{Payee: payout.Payee, Tokens: tokens}}); err != nil {
+			return payer.Transaction{}, common.Address{}, err
+		}
+		rawTx, err = e.contract.TransferFrom(opts, e.owner, payout.Payee, tokens)
 	}
 	if err != nil {
 		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
@@ -190,7 +364,7 @@ func (e *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payou
 	if e.owner != e.from {
 		fields = append(fields,
 			zap.String("spender", opts.From.String()),
-			zap.String("spender-storj-allowance", storjAllowance.String()),
+			zap.String("spender-token-allowance", allowance.String()),
 		)
 	}
 	log.With(fields...).Info("Transaction is created")
@@ -202,6 +376,107 @@ func (e *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payou
 	}, e.from, nil
 }
 
+// createDisperseTransaction creates a single transaction paying out all of
+// the payouts through the disperse contract. The gas limit scales with the
+// number of payouts.
+func (e *Payer) createDisperseTransaction(ctx context.Context, log *zap.Logger, opts *bind.TransactOpts,
+	payouts []*pipelinedb.Payout, price decimal.Decimal) (_ payer.Transaction, _ common.Address, err error) {
+
+	if e.disperse == nil {
+		return payer.Transaction{}, common.Address{}, errs.Errorf("multitransfer requires a disperse contract address")
+	}
+
+	opts.GasLimit = contract.DisperseGasLimit(len(payouts))
+
+	recipients := make([]common.Address, 0, len(payouts))
+	values := make([]*big.Int, 0, len(payouts))
+	tokens := new(big.Int)
+	sumUSD := decimal.Zero
+	for _, payout := range payouts {
+		value := storjtoken.FromUSD(payout.USD, price, e.tokenDecimals)
+		recipients = append(recipients, payout.Payee)
+		values = append(values, value)
+		tokens.Add(tokens, value)
+		sumUSD = sumUSD.Add(payout.USD)
+	}
+
+	// The disperse contract pulls the tokens with transferFrom, so it needs
+	// an allowance from the owner covering the whole batch. Since the
+	// contract does not support pending operations, the best we can do is
+	// check the live allowance.
+	allowance, err := e.contract.Allowance(&bind.CallOpts{
+		Pending: false,
+		Context: ctx,
+	}, e.owner, e.disperseAddr)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+	if allowance.Cmp(tokens) < 0 {
+		return payer.Transaction{}, common.Address{}, errs.Errorf("not enough token allowance for disperse contract %s to cover transfer (%s < %s)", e.disperseAddr, allowance, tokens)
+	}
+
+	transfers := make([]Transfer, 0, len(payouts))
+	for i := range recipients {
+		transfers = append(transfers, Transfer{Payee: recipients[i], Tokens: values[i]})
+	}
+	if err := e.addExtraGas(ctx, opts, transfers); err != nil {
+		return payer.Transaction{}, common.Address{}, err
+	}
+
+	rawTx, err := e.disperse.DisperseTokenSimple(opts, e.tokenAddress, recipients, values)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+
+	// Grab the pending ETH balance for logging
+	ethBalance, err := e.client.PendingBalanceAt(ctx, opts.From)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+
+	log.Info("Transaction is created",
+		zap.Int("payees", len(payouts)),
+		zap.String("usd", sumUSD.String()),
+		zap.String("disperse", e.disperseAddr.String()),
+		zap.String("disperse-token-allowance", allowance.String()),
+		zap.Uint64("gas-limit", opts.GasLimit),
+		zap.String("pending-eth-balance", ethBalance.String()),
+		zap.String("hash", rawTx.Hash().String()),
+	)
+
+	return payer.Transaction{
+		Hash:  rawTx.Hash().Hex(),
+		Nonce: opts.Nonce.Uint64(),
+		Raw:   rawTx,
+	}, e.from, nil
+}
+
+// WithExtraGas returns a copy of the payer that adds the extra gas to the
+// gas limit of every transaction paying out payouts.
+func (e *Payer) WithExtraGas(extraGas ExtraGasFunc) *Payer {
+	withExtraGas := *e
+	withExtraGas.extraGas = extraGas
+	return &withExtraGas
+}
+
+// addExtraGas adds the extra gas of the transaction paying out the transfers
+// to its gas limit.
+func (e *Payer) addExtraGas(ctx context.Context, opts *bind.TransactOpts, transfers []Transfer) error {
+	if e.extraGas == nil {
+		return nil
+	}
+	to, data, _, err := TransferCall(e.owner, e.from, e.tokenAddress, e.DisperseAddress(), transfers)
+	if err != nil {
+		return err
+	}
+	extraGas, err := e.extraGas(ctx, to, data)
+	if err != nil {
+		return err
+	}
+	opts.GasLimit += extraGas
+	return nil
+}
+
 func (e *Payer) SendTransaction(ctx context.Context, log *zap.Logger, t payer.Transaction) error {
 	switch tx := t.Raw.(type) {
 	case *types.Transaction:
@@ -249,6 +524,23 @@ func (e *Payer) CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceGroup
 	}
 
 	status := transactions.other[0]
+	if status.Receipt != nil {
+		// The transaction has been mined. Wait until enough blocks have
+		// been built on top of it before finalizing the nonce group, so
+		// that a reorg is unlikely to undo it.
+		depth, err := e.receiptDepth(ctx, status.Receipt)
+		if err != nil {
+			return "", transactions.all, err
+		}
+		if depth < e.confirmations {
+			log.Debug("Waiting for confirmations",
+				zap.String("hash", status.Hash),
+				zap.Uint64("depth", depth),
+				zap.Uint64("confirmations", e.confirmations))
+			return pipelinedb.TxPending, transactions.all, nil
+		}
+	}
+
 	switch status.State {
 	case pipelinedb.TxPending:
 		// The transaction is still pending. Nothing to do but wait.
@@ -273,6 +565,55 @@ func (e *Payer) CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceGroup
 	}
 }
 
+func (e *Payer) GasFees(ctx context.Context) (baseFee, maxGas *big.Int, err error) {
+	header, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+	return header.BaseFee, e.maxGas, nil
+}
+
+func (e *Payer) NativeBalance(ctx context.Context) (*big.Int, error) {
+	balance, err := e.client.BalanceAt(ctx, e.from, nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return balance, nil
+}
+
+// receiptDepth returns how many blocks deep the receipt is, counting the
+// block it was included in.
+func (e *Payer) receiptDepth(ctx context.Context, receipt *types.Receipt) (uint64, error) {
+	head, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, errs.Wrap(err)
+	}
+	if head.Number.Cmp(receipt.BlockNumber) < 0 {
+		return 0, nil
+	}
+	return new(big.Int).Sub(head.Number, receipt.BlockNumber).Uint64() + 1, nil
+}
+
+func (e *Payer) IsCanonical(ctx context.Context, hash string, blockHash common.Hash) (bool, error) {
+	txHash, err := batchpayment.HashFromString(hash)
+	if err != nil {
+		return false, err
+	}
+	return IsCanonical(ctx, e.client, txHash, blockHash)
+}
+
+func (e *Payer) FinalizedBlock(ctx context.Context) (uint64, error) {
+	head, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, errs.Wrap(err)
+	}
+	depth := e.confirmations * finalityFactor
+	if head.Number.Uint64() < depth {
+		return 0, nil
+	}
+	return head.Number.Uint64() - depth, nil
+}
+
 type nonceGroupTransactions struct {
 	all     []*pipelinedb.TxStatus
 	dropped []*pipelinedb.TxStatus
@@ -376,22 +717,35 @@ func (e *Payer) getTransactionStatus(ctx context.Context, hashString string) (*p
 	}, nil
 }
 
-func (e *Payer) PrintEstimate(ctx context.Context, remaining int64) error {
-
-	// TODO: revisit with multitransfer by estimating payout group size, etc.
+func (e *Payer) PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error {
 	var gasPerTx *big.Int
-	if e.owner == e.from {
+	var estimatedGasLeft *big.Int
+	switch {
+	case e.native:
+		gasPerTx = new(big.Int).SetUint64(NativeTransferGasLimit)
+	case remainingPayouts > remainingGroups && remainingGroups > 0:
+		// disperse rough cost estimate, assuming evenly sized payout groups
+		estimatedGasLeft = new(big.Int).SetUint64(contract.DisperseBaseGasLimit)
+		estimatedGasLeft.Mul(estimatedGasLeft, big.NewInt(remainingGroups))
+		estimatedGasLeft.Add(estimatedGasLeft, new(big.Int).Mul(
+			big.NewInt(remainingPayouts),
+			big.NewInt(contract.DisperseTransferGasLimit)))
+		gasPerTx = new(big.Int).Div(estimatedGasLeft, big.NewInt(remainingGroups))
+	case e.owner == e.from:
 		// transfer rough cost estimate
 		gasPerTx = big.NewInt(contract.TokenTransferGasLimit)
-	} else {
+	default:
 		// transfer-from rough cost estimate
 		gasPerTx = big.NewInt(contract.TokenTransferFromGasLimit)
 	}
+	if estimatedGasLeft == nil {
+		estimatedGasLeft = new(big.Int).Mul(big.NewInt(remainingGroups), gasPerTx)
+	}
+
 	gasFee, err := e.EstimatedGasFee(ctx)
 	if err != nil {
 		return err
 	}
-	estimatedGasLeft := new(big.Int).Mul(big.NewInt(remaining), gasPerTx)
 	estimatedGasCost := new(big.Int).Mul(estimatedGasLeft, gasFee)
 
 	fmt.Printf("Estimated Gas Per Tx........: %s\n", gasPerTx)
@@ -405,6 +759,16 @@ func (e *Payer) GetTokenDecimals(ctx context.Context) (int32, error) {
 	return e.tokenDecimals, nil
 }
 
+// signerFn adapts the signer of the spender for the contract bindings.
+func (e *Payer) signerFn(ctx context.Context) bind.SignerFn {
+	return func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if from != e.from {
+			return nil, bind.ErrNotAuthorized
+		}
+		return e.signer.SignTransaction(ctx, tx)
+	}
+}
+
 // ignoreSend wraps a contract backend to not actually send the transaction.
 // It is used with the token contract to prepare and sign transactions but not
 // actually send them.
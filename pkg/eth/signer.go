@@ -0,0 +1,164 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/zeebo/errs/v2"
+)
+
+// Signer signs the transactions of the spender.
+type Signer interface {
+	// Address returns the address of the spender.
+	Address() common.Address
+
+	// SignTransaction returns the transaction signed by the spender.
+	SignTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+}
+
+// keySigner signs with a private key held in memory.
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+	signer  types.Signer
+}
+
+// NewKeySigner returns a signer that signs with the given private key for
+// the given chain.
+func NewKeySigner(key *ecdsa.PrivateKey, chainID *big.Int) Signer {
+	return &keySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+		signer:  types.LatestSignerForChainID(chainID),
+	}
+}
+
+func (s *keySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *keySigner) SignTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	signed, err := types.SignTx(tx, s.signer, s.key)
+	return signed, errs.Wrap(err)
+}
+
+// ExternalSigner delegates signing to an external signer over JSON-RPC using
+// the account_signTransaction method of Clef's external API. The private key
+// never leaves the external signer.
+type ExternalSigner struct {
+	client  *rpc.Client
+	address common.Address
+	chainID *big.Int
+	signer  types.Signer
+}
+
+// NewExternalSigner dials the external signer at the given endpoint and makes
+// sure it manages the spender account. Unless empty, the auth token is sent
+// as a bearer token with every request over HTTP.
+func NewExternalSigner(ctx context.Context, endpoint, authToken string, address common.Address, chainID *big.Int) (_ *ExternalSigner, err error) {
+	var options []rpc.ClientOption
+	if authToken != "" {
+		options = append(options, rpc.WithHeader("Authorization", "Bearer "+authToken))
+	}
+	client, err := rpc.DialOptions(ctx, endpoint, options...)
+	if err != nil {
+		return nil, errs.Errorf("unable to dial external signer %q: %w", endpoint, err)
+	}
+	defer func() {
+		if err != nil {
+			client.Close()
+		}
+	}()
+
+	var accounts []common.Address
+	if err := client.CallContext(ctx, &accounts, "account_list"); err != nil {
+		return nil, errs.Errorf("unable to list accounts of external signer: %w", err)
+	}
+	found := false
+	for _, account := range accounts {
+		if account == address {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errs.Errorf("external signer does not manage spender %s", address)
+	}
+
+	return &ExternalSigner{
+		client:  client,
+		address: address,
+		chainID: chainID,
+		signer:  types.LatestSignerForChainID(chainID),
+	}, nil
+}
+
+// Close closes the connection to the external signer.
+func (s *ExternalSigner) Close() {
+	s.client.Close()
+}
+
+func (s *ExternalSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTransaction asks the external signer to sign the transaction. Since
+// some signers, like Clef, let the operator edit the transaction before
+// signing it, the signed transaction is checked to be the one requested and
+// to be signed by the spender.
+func (s *ExternalSigner) SignTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return nil, errs.Errorf("unsupported transaction type %d", tx.Type())
+	}
+
+	data := hexutil.Bytes(tx.Data())
+	var to *common.MixedcaseAddress
+	if tx.To() != nil {
+		t := common.NewMixedcaseAddress(*tx.To())
+		to = &t
+	}
+	args := apitypes.SendTxArgs{
+		From:                 common.NewMixedcaseAddress(s.address),
+		To:                   to,
+		Gas:                  hexutil.Uint64(tx.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+		Value:                hexutil.Big(*tx.Value()),
+		Nonce:                hexutil.Uint64(tx.Nonce()),
+		Input:                &data,
+		ChainID:              (*hexutil.Big)(s.chainID),
+	}
+
+	var result SignTransactionResult
+	if err := s.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+		return nil, errs.Errorf("external signer refused to sign: %w", err)
+	}
+	if result.Tx == nil {
+		return nil, errs.Errorf("external signer returned no transaction")
+	}
+
+	if s.signer.Hash(result.Tx) != s.signer.Hash(tx) {
+		return nil, errs.Errorf("external signer signed a different transaction %s", result.Tx.Hash())
+	}
+	from, err := types.Sender(s.signer, result.Tx)
+	if err != nil {
+		return nil, errs.Errorf("external signer returned an invalid signature: %w", err)
+	}
+	if from != s.address {
+		return nil, errs.Errorf("external signer signed with %s instead of spender %s", from, s.address)
+	}
+	return result.Tx, nil
+}
+
+// SignTransactionResult is the result of account_signTransaction.
+type SignTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
@@ -0,0 +1,261 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/zeebo/errs/v2"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+)
+
+// signerServiceVersion is the version of Clef's external API implemented by
+// the signer service.
+const signerServiceVersion = "6.1.0"
+
+// SignerService serves the part of Clef's external API used by
+// ExternalSigner, signing with a local signer. It stands in for Clef on a
+// host that holds the spender key when running Clef is not practical.
+//
+// It only signs the transactions the payers create: zero-value transfer()
+// and transferFrom() calls of the token contract, disperse calls of the
+// disperse contract for the token, and the zero-value self-transfers that
+// cancel nonces. Anything else, like an approve() call or a transfer of the
+// native coin, is refused.
+type SignerService struct {
+	log      *zap.Logger
+	signer   Signer
+	chainID  *big.Int
+	token    common.Address
+	disperse *common.Address
+}
+
+// NewSignerService returns a signer service that signs transactions for the
+// given chain with the given signer. It only signs calls of the given token
+// contract and, if not nil, of the given disperse contract.
+func NewSignerService(log *zap.Logger, signer Signer, chainID *big.Int, token common.Address, disperse *common.Address) *SignerService {
+	return &SignerService{
+		log:      log,
+		signer:   signer,
+		chainID:  chainID,
+		token:    token,
+		disperse: disperse,
+	}
+}
+
+// NewRPCServer returns a JSON-RPC server serving the signer service under
+// the account namespace, like Clef.
+func (s *SignerService) NewRPCServer() (*rpc.Server, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", s); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return server, nil
+}
+
+// Serve serves the signer service on the listener until the context is
+// canceled. On a unix socket, which only the users allowed to open the
+// socket file can connect to, it serves JSON-RPC like the IPC endpoint of a
+// node. Otherwise it serves JSON-RPC over HTTP, and every request must carry
+// the auth token as a bearer token.
+func (s *SignerService) Serve(ctx context.Context, listener net.Listener, authToken string) error {
+	rpcServer, err := s.NewRPCServer()
+	if err != nil {
+		return err
+	}
+	defer rpcServer.Stop()
+
+	log := s.log.With(zap.String("addr", listener.Addr().String()), zap.String("spender", s.signer.Address().String()))
+
+	if _, ok := listener.(*net.UnixListener); ok {
+		go func() {
+			<-ctx.Done()
+			_ = listener.Close()
+		}()
+		log.Info("Serving signer on unix socket")
+		err := rpcServer.ServeListener(listener)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return errs.Wrap(err)
+	}
+
+	if authToken == "" {
+		return errs.Errorf("an auth token is required to serve the signer on %s", listener.Addr())
+	}
+	server := &http.Server{
+		Handler:           RequireAuthToken(rpcServer, authToken),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(listener)
+	}()
+	log.Info("Serving signer")
+
+	select {
+	case err := <-errCh:
+		return errs.Wrap(err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return errs.Wrap(server.Shutdown(shutdownCtx))
+	}
+}
+
+// RequireAuthToken returns a handler that passes requests carrying the auth
+// token as a bearer token on to the given handler, and refuses the others.
+func RequireAuthToken(handler http.Handler, authToken string) http.Handler {
+	expected := []byte("Bearer " + authToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "invalid auth token", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// LoadAuthToken reads the auth token shared by the signer service and the
+// external signer from the file at the given path, after making sure only
+// the owner can read it.
+func LoadAuthToken(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", errs.Errorf("unable to stat auth token file: %w", err)
+	}
+	if (fi.Mode() & 0177) != 0 {
+		return "", errs.Errorf("%s mode %#o is too permissive (set to 0600)", path, fi.Mode())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errs.Errorf("unable to read auth token file: %w", err)
+	}
+	authToken := strings.TrimSpace(string(data))
+	if authToken == "" {
+		return "", errs.Errorf("auth token file %s is empty", path)
+	}
+	return authToken, nil
+}
+
+// Version implements account_version.
+func (s *SignerService) Version(ctx context.Context) (string, error) {
+	return signerServiceVersion, nil
+}
+
+// List implements account_list.
+func (s *SignerService) List(ctx context.Context) ([]common.Address, error) {
+	return []common.Address{s.signer.Address()}, nil
+}
+
+// SignTransaction implements account_signTransaction.
+func (s *SignerService) SignTransaction(ctx context.Context, args apitypes.SendTxArgs, methodSelector *string) (*SignTransactionResult, error) {
+	if args.From.Address() != s.signer.Address() {
+		return nil, errs.Errorf("unknown account %s", args.From.Address())
+	}
+	if args.ChainID == nil || args.ChainID.ToInt().Cmp(s.chainID) != 0 {
+		return nil, errs.Errorf("chain ID must be %s", s.chainID)
+	}
+	if args.MaxFeePerGas == nil || args.MaxPriorityFeePerGas == nil {
+		return nil, errs.Errorf("only EIP-1559 transactions are supported")
+	}
+
+	tx, err := args.ToTransaction()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if err := s.checkTransaction(tx); err != nil {
+		s.log.Warn("Refused to sign transaction", zap.Uint64("nonce", tx.Nonce()), zap.Error(err))
+		return nil, err
+	}
+	signed, err := s.signer.SignTransaction(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	to := "contract creation"
+	if signed.To() != nil {
+		to = signed.To().String()
+	}
+	s.log.Info("Signed transaction",
+		zap.String("hash", signed.Hash().String()),
+		zap.Uint64("nonce", signed.Nonce()),
+		zap.String("to", to),
+	)
+	return &SignTransactionResult{Raw: raw, Tx: signed}, nil
+}
+
+// checkTransaction returns an error unless the transaction is one a payer
+// creates.
+func (s *SignerService) checkTransaction(tx *types.Transaction) error {
+	if tx.Value().Sign() != 0 {
+		return errs.Errorf("refusing to sign transaction with value %s", tx.Value())
+	}
+	switch {
+	case tx.To() == nil:
+		return errs.Errorf("refusing to sign contract creation")
+	case *tx.To() == s.signer.Address() && len(tx.Data()) == 0:
+		// Cancels a nonce.
+		return nil
+	case *tx.To() == s.token:
+		return checkCall(contract.TokenMetaData, tx.Data(), "transfer", "transferFrom")
+	case s.disperse != nil && *tx.To() == *s.disperse:
+		if err := checkCall(contract.DisperseMetaData, tx.Data(), "disperseTokenSimple"); err != nil {
+			return err
+		}
+		// The token is the first argument, right after the method ID.
+		if token := common.BytesToAddress(tx.Data()[4 : 4+32]); token != s.token {
+			return errs.Errorf("refusing to sign disperse call for token %s", token)
+		}
+		return nil
+	default:
+		return errs.Errorf("refusing to sign transaction to %s", tx.To())
+	}
+}
+
+// checkCall returns an error unless the data is a well-formed call of one of
+// the given methods of the contract.
+func checkCall(metaData *bind.MetaData, data []byte, methods ...string) error {
+	contractABI, err := metaData.GetAbi()
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	if len(data) < 4 {
+		return errs.Errorf("refusing to sign call without a method ID")
+	}
+	method, err := contractABI.MethodById(data[:4])
+	if err != nil {
+		return errs.Errorf("refusing to sign call of unknown method %#x", data[:4])
+	}
+	if !slices.Contains(methods, method.Name) {
+		return errs.Errorf("refusing to sign call of %s", method.Name)
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return errs.Errorf("refusing to sign malformed call of %s: %w", method.Name, err)
+	}
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil || !bytes.Equal(packed, data[4:]) {
+		return errs.Errorf("refusing to sign malformed call of %s", method.Name)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs/v2"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+var _ payer.Simulator = &Payer{}
+
+// SimulatePayoutGroup checks the balance and allowance of the owner against
+// the transfers in the payout group, then calls the contract with the same
+// transfer, transferFrom or disperse call the payout group would be sent
+// with, against the pending state. Nothing is signed or sent.
+func (e *Payer) SimulatePayoutGroup(ctx context.Context, payouts []*pipelinedb.Payout, price decimal.Decimal, transferred *big.Int) ([]*pipelinedb.SimulationResult, error) {
+	if len(payouts) == 0 {
+		return nil, nil
+	}
+	if e.native {
+		return e.simulateNativePayoutGroup(ctx, payouts, price, transferred)
+	}
+	if len(payouts) > 1 && e.disperse == nil {
+		return nil, errs.Errorf("multitransfer requires a disperse contract address")
+	}
+
+	// The token contract of the payer is bound to a backend that hides the
+	// pending state, so bind a caller directly to the client.
+	token, err := contract.NewTokenCaller(e.tokenAddress, e.client)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	callOpts := &bind.CallOpts{Pending: true, Context: ctx}
+	balance, err := token.BalanceOf(callOpts, e.owner)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	// The tokens are pulled with transferFrom by the spender, or by the
+	// disperse contract for multitransfers, which needs an allowance.
+	var allowance *big.Int
+	var allowed common.Address
+	switch {
+	case len(payouts) > 1:
+		allowed = e.disperseAddr
+	case e.owner != e.from:
+		allowed = e.from
+	}
+	if allowed != (common.Address{}) {
+		allowance, err = token.Allowance(callOpts, e.owner, allowed)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+
+	results := make([]*pipelinedb.SimulationResult, 0, len(payouts))
+	total := new(big.Int).Set(transferred)
+	for _, payout := range payouts {
+		tokens := storjtoken.FromUSD(payout.USD, price, e.tokenDecimals)
+		total.Add(total, tokens)
+
+		result := &pipelinedb.SimulationResult{
+			PayoutGroupID: payout.PayoutGroupID,
+			Payee:         payout.Payee,
+			USD:           payout.USD,
+			Tokens:        tokens,
+			Outcome:       pipelinedb.SimulationOK,
+		}
+		switch {
+		case total.Cmp(balance) > 0:
+			result.Outcome = pipelinedb.SimulationBalance
+			result.Reason = fmt.Sprintf("owner balance (%s) does not cover the transfers (%s)", balance, total)
+		case allowance != nil && total.Cmp(allowance) > 0:
+			result.Outcome = pipelinedb.SimulationAllowance
+			result.Reason = fmt.Sprintf("allowance of %s (%s) does not cover the transfers (%s)", allowed, allowance, total)
+		}
+		results = append(results, result)
+	}
+
+	msg, err := e.transferCall(results)
+	if err != nil {
+		return nil, err
+	}
+	reason, err := e.simulateCall(ctx, msg)
+	if err != nil || reason == "" {
+		return results, err
+	}
+
+	if len(results) == 1 {
+		if results[0].Outcome == pipelinedb.SimulationOK {
+			results[0].Outcome = pipelinedb.SimulationRevert
+			results[0].Reason = reason
+		}
+		return results, nil
+	}
+
+	// The whole multitransfer reverts if any of its transfers does. Find
+	// the transfers that revert on their own by making the same call the
+	// disperse contract would make for each of them, as a transaction of its
+	// own.
+	tokenABI, err := contract.TokenMetaData.GetAbi()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	for _, result := range results {
+		if result.Outcome != pipelinedb.SimulationOK {
+			continue
+		}
+		data, err := tokenABI.Pack("transferFrom", e.from, result.Payee, result.Tokens)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		payeeReason, err := e.simulateCall(ctx, ethereum.CallMsg{
+			From: e.disperseAddr,
+			To:   &e.tokenAddress,
+			Gas:  contract.TokenTransferFromGasLimit,
+			Data: data,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Outcome = pipelinedb.SimulationRevert
+		if payeeReason != "" {
+			result.Reason = payeeReason
+		} else {
+			result.Reason = "payout group reverted: " + reason
+		}
+	}
+	return results, nil
+}
+
+// transferCall returns the call the payout group would be sent with.
+func (e *Payer) transferCall(results []*pipelinedb.SimulationResult) (ethereum.CallMsg, error) {
+	transfers := make([]Transfer, 0, len(results))
+	for _, result := range results {
+		transfers = append(transfers, Transfer{Payee: result.Payee, Tokens: result.Tokens})
+	}
+	to, data, gas, err := TransferCall(e.owner, e.from, e.tokenAddress, e.DisperseAddress(), transfers)
+	if err != nil {
+		return ethereum.CallMsg{}, err
+	}
+	return ethereum.CallMsg{
+		From: e.from,
+		To:   &to,
+		Gas:  gas,
+		Data: data,
+	}, nil
+}
+
+// simulateCall executes the call against the pending state. It returns why
+// the call reverted, or an empty reason if it succeeded. Errors reaching the
+// node are returned as errors.
+func (e *Payer) simulateCall(ctx context.Context, msg ethereum.CallMsg) (reason string, err error) {
+	_, err = e.client.PendingCallContract(ctx, msg)
+	if err == nil {
+		return "", nil
+	}
+
+	// The node reports execution errors, like reverts and running out of
+	// gas, as JSON-RPC errors.
+	var rpcErr rpc.Error
+	var dataErr rpc.DataError
+	if errors.As(err, &rpcErr) || errors.As(err, &dataErr) || strings.Contains(err.Error(), "execution reverted") {
+		return err.Error(), nil
+	}
+	return "", errs.Wrap(err)
+}
@@ -12,6 +12,15 @@ import (
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
 )
 
+// DefaultConfirmations is the default number of blocks a receipt must be
+// buried under (including its own block) before a transaction is final.
+const DefaultConfirmations = 1
+
+// finalityFactor is how many times the confirmations a block must be buried
+// under before it is considered final and its transactions are no longer
+// checked for reorgs.
+const finalityFactor = 64
+
 func GetTransactionInfo(ctx context.Context, client ethereum.TransactionReader, hash common.Hash) (pipelinedb.TxState, *types.Transaction, *types.Receipt, error) {
 	tx, pending, err := client.TransactionByHash(ctx, hash)
 	switch {
@@ -41,3 +50,16 @@ func TxStateFromReceipt(receipt *types.Receipt) pipelinedb.TxState {
 	}
 	return pipelinedb.TxFailed
 }
+
+// IsCanonical returns whether the transaction is still included in the block
+// with the given hash on the canonical chain.
+func IsCanonical(ctx context.Context, client ethereum.TransactionReader, hash common.Hash, blockHash common.Hash) (bool, error) {
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	switch {
+	case errors.Is(err, ethereum.NotFound):
+		return false, nil
+	case err != nil:
+		return false, errs.Wrap(err)
+	}
+	return receipt.BlockHash == blockHash, nil
+}
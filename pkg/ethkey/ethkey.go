@@ -0,0 +1,200 @@
+// Package ethkey loads the private keys of spenders, either stored as plain
+// hex or as encrypted Web3 Secret Storage JSON keystores, like the ones
+// written by geth.
+package ethkey
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/manifoldco/promptui"
+	"github.com/zeebo/errs"
+)
+
+const (
+	// PassphrasePrompt asks for the passphrase on the terminal.
+	PassphrasePrompt Passphrase = "prompt"
+
+	passphraseFDPrefix  = "fd:"
+	passphraseEnvPrefix = "env:"
+)
+
+var (
+	// fdPassphrases holds the passphrases read from file descriptors, which
+	// can only be read once, so they can be used for more than one key.
+	fdPassphrasesMu sync.Mutex
+	fdPassphrases   = make(map[uintptr]string)
+)
+
+// Passphrase is where the passphrase of an encrypted keystore is read from.
+// It is either "prompt", "fd:<N>" to read the first line of file descriptor
+// N, or "env:<NAME>" to read the environment variable NAME. Empty means
+// "prompt".
+type Passphrase string
+
+// Validate returns an error if the passphrase source is malformed.
+func (p Passphrase) Validate() error {
+	switch {
+	case p == "" || p == PassphrasePrompt:
+		return nil
+	case strings.HasPrefix(string(p), passphraseFDPrefix):
+		if _, err := strconv.ParseUint(strings.TrimPrefix(string(p), passphraseFDPrefix), 10, 32); err != nil {
+			return errs.New("invalid passphrase file descriptor %q", p)
+		}
+		return nil
+	case strings.HasPrefix(string(p), passphraseEnvPrefix):
+		if strings.TrimPrefix(string(p), passphraseEnvPrefix) == "" {
+			return errs.New("invalid passphrase environment variable %q", p)
+		}
+		return nil
+	default:
+		return errs.New("unsupported passphrase source %q (expected prompt, fd:<N> or env:<NAME>)", p)
+	}
+}
+
+// read returns the passphrase for the keystore at the given path.
+func (p Passphrase) read(path string) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(string(p), passphraseFDPrefix):
+		fd, _ := strconv.ParseUint(strings.TrimPrefix(string(p), passphraseFDPrefix), 10, 32)
+		return readFD(uintptr(fd))
+	case strings.HasPrefix(string(p), passphraseEnvPrefix):
+		name := strings.TrimPrefix(string(p), passphraseEnvPrefix)
+		passphrase, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errs.New("passphrase environment variable %s is not set", name)
+		}
+		return passphrase, nil
+	default:
+		passphrase, err := (&promptui.Prompt{
+			Label: fmt.Sprintf("Passphrase for %s", path),
+			Mask:  '*',
+		}).Run()
+		if err != nil {
+			return "", errors.New("aborted")
+		}
+		return passphrase, nil
+	}
+}
+
+func readFD(fd uintptr) (string, error) {
+	fdPassphrasesMu.Lock()
+	defer fdPassphrasesMu.Unlock()
+
+	if passphrase, ok := fdPassphrases[fd]; ok {
+		return passphrase, nil
+	}
+
+	f := os.NewFile(fd, fmt.Sprintf("fd%d", fd))
+	if f == nil {
+		return "", errs.New("invalid passphrase file descriptor %d", fd)
+	}
+	defer func() { _ = f.Close() }()
+
+	passphrase, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && passphrase == "" {
+		return "", errs.New("unable to read passphrase from file descriptor %d: %v", fd, err)
+	}
+	passphrase = strings.TrimRight(passphrase, "\r\n")
+	fdPassphrases[fd] = passphrase
+	return passphrase, nil
+}
+
+// Load loads the private key at the given path. Encrypted keystores are
+// decrypted with the passphrase read from the given source. Which describes
+// the key in errors.
+func Load(path, which string, passphrase Passphrase) (*ecdsa.PrivateKey, common.Address, error) {
+	data, err := readKeyFile(path, which)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+
+	if !isKeystore(data) {
+		key, err := crypto.LoadECDSA(path)
+		if err != nil {
+			return nil, common.Address{}, errs.New("unable to load %s key: %v", which, err)
+		}
+		return key, crypto.PubkeyToAddress(key.PublicKey), nil
+	}
+
+	auth, err := passphrase.read(path)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	key, err := keystore.DecryptKey(data, auth)
+	if err != nil {
+		return nil, common.Address{}, errs.New("unable to decrypt %s keystore: %v", which, err)
+	}
+	return key.PrivateKey, key.Address, nil
+}
+
+// Address returns the address of the private key at the given path without
+// decrypting it.
+func Address(path, which string) (common.Address, error) {
+	data, err := readKeyFile(path, which)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if !isKeystore(data) {
+		key, err := crypto.LoadECDSA(path)
+		if err != nil {
+			return common.Address{}, errs.New("unable to load %s key: %v", which, err)
+		}
+		return crypto.PubkeyToAddress(key.PublicKey), nil
+	}
+
+	var keyJSON struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(data, &keyJSON); err != nil {
+		return common.Address{}, errs.New("unable to parse %s keystore: %v", which, err)
+	}
+	if !common.IsHexAddress(keyJSON.Address) {
+		return common.Address{}, errs.New("%s keystore has an invalid address %q", which, keyJSON.Address)
+	}
+	return common.HexToAddress(keyJSON.Address), nil
+}
+
+// readKeyFile reads the key file after making sure only the owner can read
+// it.
+func readKeyFile(path, which string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errs.New("%s: %s not found", which, path)
+		}
+		return nil, errs.New("unable to stat %s key: %v", which, err)
+	}
+
+	if (fi.Mode() & 0177) != 0 {
+		return nil, errs.New("%s mode %#o is too permissive (set to 0600)", path, fi.Mode())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.New("unable to read %s key: %v", which, err)
+	}
+	return data, nil
+}
+
+// isKeystore returns true if the key file is a JSON keystore rather than a
+// plain hex key.
+func isKeystore(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
@@ -0,0 +1,108 @@
+package ethkey
+
+import (
+	"crypto/ecdsa"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadHex(t *testing.T) {
+	key, path := writeHexKey(t)
+
+	loaded, address, err := Load(path, "spender", "env:UNUSED")
+	require.NoError(t, err)
+	assert.Equal(t, key.D, loaded.D)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), address)
+
+	address, err = Address(path, "spender")
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), address)
+}
+
+func TestLoadKeystoreFromEnv(t *testing.T) {
+	key, path := writeKeystore(t, "hunter2")
+	t.Setenv("TEST_PASSPHRASE", "hunter2")
+
+	loaded, address, err := Load(path, "spender", "env:TEST_PASSPHRASE")
+	require.NoError(t, err)
+	assert.Equal(t, key.D, loaded.D)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), address)
+
+	address, err = Address(path, "spender")
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), address)
+
+	t.Setenv("TEST_PASSPHRASE", "wrong")
+	_, _, err = Load(path, "spender", "env:TEST_PASSPHRASE")
+	require.EqualError(t, err, "unable to decrypt spender keystore: could not decrypt key with given password")
+
+	_, _, err = Load(path, "spender", "env:TEST_PASSPHRASE_UNSET")
+	require.EqualError(t, err, "passphrase environment variable TEST_PASSPHRASE_UNSET is not set")
+}
+
+func TestLoadKeystoreFromFD(t *testing.T) {
+	key, path := writeKeystore(t, "hunter2")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString("hunter2\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	passphrase := Passphrase("fd:" + strconv.Itoa(int(r.Fd())))
+
+	// The passphrase is reused for the next key once the file descriptor
+	// has been read.
+	for i := 0; i < 2; i++ {
+		loaded, _, err := Load(path, "spender", passphrase)
+		require.NoError(t, err)
+		assert.Equal(t, key.D, loaded.D)
+	}
+}
+
+func TestLoadTooPermissive(t *testing.T) {
+	_, path := writeHexKey(t)
+	require.NoError(t, os.Chmod(path, 0644))
+
+	_, _, err := Load(path, "spender", PassphrasePrompt)
+	require.EqualError(t, err, path+" mode 0644 is too permissive (set to 0600)")
+}
+
+func TestPassphraseValidate(t *testing.T) {
+	for _, p := range []Passphrase{"", "prompt", "fd:3", "env:PASSPHRASE"} {
+		assert.NoError(t, p.Validate(), p)
+	}
+	for _, p := range []Passphrase{"file:/tmp/pass", "fd:", "fd:three", "env:"} {
+		assert.Error(t, p.Validate(), p)
+	}
+}
+
+func writeHexKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, crypto.SaveECDSA(path, key))
+	return key, path
+}
+
+func writeKeystore(t *testing.T, passphrase string) (*ecdsa.PrivateKey, string) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, passphrase, 2, 1)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0600))
+	return key, path
+}
@@ -0,0 +1,187 @@
+// Package metrics serves the progress of a payout in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipeline"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// Handler serves metrics for a payout. Every scrape reads the current state
+// from the payout database and the payer, so the metrics stay accurate across
+// restarts of the payout.
+type Handler struct {
+	log   *zap.Logger
+	db    *pipelinedb.DB
+	lanes []pipeline.Lane
+
+	now func() time.Time
+}
+
+// NewHandler returns a handler serving the metrics of the payout. The
+// balances are reported for each of the lanes; if there are none, the payer
+// is the only lane.
+func NewHandler(log *zap.Logger, db *pipelinedb.DB, payer payer.Payer, lanes []pipeline.Lane) *Handler {
+	if len(lanes) == 0 {
+		lanes = []pipeline.Lane{{Spender: payer.From(), Payer: payer}}
+	}
+	return &Handler{
+		log:   log,
+		db:    db,
+		lanes: lanes,
+		now:   time.Now,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := h.write(r.Context(), &buf); err != nil {
+		h.log.Warn("Failed to collect metrics", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// write writes the metrics. Metrics that come from the database are
+// required. Metrics that come from the chain are skipped with a warning if
+// they cannot be obtained, since a flaky node should not hide the progress.
+func (h *Handler) write(ctx context.Context, w io.Writer) error {
+	stats, err := h.db.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	spent, err := h.db.FetchSpendTotals(ctx)
+	if err != nil {
+		return err
+	}
+	nonceGroups, err := h.db.FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx)
+	if err != nil {
+		return err
+	}
+	priceLock, err := h.db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+
+	gauge(w, "crybapy_payouts", "Number of payouts.", stats.TotalPayouts)
+	gauge(w, "crybapy_payouts_pending", "Number of payouts that have not been paid yet.", stats.PendingPayouts)
+	gauge(w, "crybapy_payout_groups", "Number of payout groups.", stats.TotalPayoutGroups)
+	gauge(w, "crybapy_payout_groups_pending", "Number of payout groups that have not been paid yet.", stats.PendingPayoutGroups)
+
+	header(w, "crybapy_transactions", "gauge", "Number of transactions by state.")
+	sample(w, `crybapy_transactions{state="pending"}`, stats.PendingTransactions)
+	sample(w, `crybapy_transactions{state="confirmed"}`, stats.ConfirmedTransactions)
+	sample(w, `crybapy_transactions{state="failed"}`, stats.FailedTransactions)
+	sample(w, `crybapy_transactions{state="dropped"}`, stats.DroppedTransactions)
+
+	header(w, "crybapy_resends_total", "counter", "Number of transactions sent to resend or replace an earlier transaction for the same payout group.")
+	sample(w, "crybapy_resends_total", stats.TotalTransactions-int64(len(spent.PayoutGroups)))
+
+	gauge(w, "crybapy_nonce_groups_in_flight", "Number of nonce groups waiting on a pending transaction.", len(nonceGroups))
+
+	if priceLock != nil {
+		gauge(w, "crybapy_quote_age_seconds", "Age of the price locked for the payout.", h.now().Sub(priceLock.Timestamp).Seconds())
+	}
+
+	header(w, "crybapy_token_balance", "gauge", "Token balance the spender pays from, in the smallest token unit.")
+	for _, lane := range h.lanes {
+		if balance, err := lane.Payer.GetTokenBalance(ctx); err != nil {
+			h.log.Warn("Failed to get token balance for metrics", zap.Stringer("spender", lane.Spender), zap.Error(err))
+		} else {
+			sample(w, spenderSample("crybapy_token_balance", lane.Spender), balance)
+		}
+	}
+
+	// The lanes all run on the same chain with the same payer type, so the
+	// first one stands in for the others for the gas fees.
+	if reporter, ok := h.lanes[0].Payer.(payer.Reporter); ok {
+		header(w, "crybapy_native_balance_wei", "gauge", "Balance of the spender paying for gas.")
+		for _, lane := range h.lanes {
+			if balance, err := lane.Payer.(payer.Reporter).NativeBalance(ctx); err != nil {
+				h.log.Warn("Failed to get native balance for metrics", zap.Stringer("spender", lane.Spender), zap.Error(err))
+			} else {
+				sample(w, spenderSample("crybapy_native_balance_wei", lane.Spender), balance)
+			}
+		}
+		if baseFee, maxGas, err := reporter.GasFees(ctx); err != nil {
+			h.log.Warn("Failed to get gas fees for metrics", zap.Error(err))
+		} else {
+			gauge(w, "crybapy_base_fee_wei", "Base fee of the latest block.", baseFee)
+			gauge(w, "crybapy_max_gas_wei", "Maximum fee per gas the payer will pay.", maxGas)
+		}
+	}
+
+	return nil
+}
+
+// Serve serves the metrics at /metrics on the listener until the context is
+// canceled.
+func Serve(ctx context.Context, log *zap.Logger, listener net.Listener, handler http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(listener)
+	}()
+	log.Info("Serving metrics", zap.String("addr", listener.Addr().String()))
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+func gauge(w io.Writer, name, help string, value any) {
+	header(w, name, "gauge", help)
+	sample(w, name, value)
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+// spenderSample returns the name of the sample of the metric for the
+// spender.
+func spenderSample(name string, spender common.Address) string {
+	return fmt.Sprintf("%s{spender=%q}", name, spender.String())
+}
+
+func sample(w io.Writer, name string, value any) {
+	switch value := value.(type) {
+	case *big.Int:
+		if value == nil {
+			return
+		}
+		fmt.Fprintf(w, "%s %s\n", name, value)
+	case float64:
+		fmt.Fprintf(w, "%s %g\n", name, value)
+	default:
+		fmt.Fprintf(w, "%s %d\n", name, value)
+	}
+}
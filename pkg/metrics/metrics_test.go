@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipeline"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := pipelinedb.NewDB(ctx, filepath.Join(t.TempDir(), "payouts.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	payee := common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA")
+	require.NoError(t, db.CreatePayoutGroup(ctx, 1, []*pipelinedb.Payout{{Payee: payee, USD: decimal.New(1, 0)}}))
+	require.NoError(t, db.CreatePayoutGroup(ctx, 2, []*pipelinedb.Payout{{Payee: payee, USD: decimal.New(2, 0)}}))
+
+	pricedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, db.LockPrice(ctx, pipelinedb.PriceLock{
+		Symbol:    "STORJ",
+		Price:     decimal.New(1, 0),
+		Source:    pipelinedb.PriceSourceOperator,
+		Timestamp: pricedAt,
+	}))
+
+	for i, hash := range []string{
+		"0x1111111111111111111111111111111111111111111111111111111111111111",
+		"0x2222222222222222222222222222222222222222222222222222222222222222",
+	} {
+		_, err := db.CreateTransaction(ctx, pipelinedb.Transaction{
+			PayoutGroupID: 1,
+			Hash:          hash,
+			Nonce:         0,
+			Price:         decimal.New(1, 0),
+			Tokens:        decimal.New(int64(i+1), 8).BigInt(),
+			Raw:           []byte("{}"),
+		})
+		require.NoError(t, err)
+	}
+
+	simPayer, err := payer.NewSimPayer()
+	require.NoError(t, err)
+
+	handler := NewHandler(zaptest.NewLogger(t), db, simPayer, nil)
+	handler.now = func() time.Time { return pricedAt.Add(time.Minute) }
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"crybapy_payouts 2\n",
+		"crybapy_payouts_pending 2\n",
+		"crybapy_payout_groups 2\n",
+		"crybapy_payout_groups_pending 2\n",
+		`crybapy_transactions{state="pending"} 2` + "\n",
+		`crybapy_transactions{state="confirmed"} 0` + "\n",
+		"# TYPE crybapy_resends_total counter\n",
+		"crybapy_resends_total 1\n",
+		"crybapy_nonce_groups_in_flight 1\n",
+		"crybapy_quote_age_seconds 60\n",
+		`crybapy_token_balance{spender="` + simPayer.From().String() + `"} 1000000000000` + "\n",
+	} {
+		require.Contains(t, body, line)
+	}
+
+	// The sim payer does not report gas fees or a native balance.
+	require.NotContains(t, body, "crybapy_base_fee_wei")
+
+	// The balances are reported for each lane.
+	lanes := make([]pipeline.Lane, 2)
+	for i := range lanes {
+		simPayer, err := payer.NewSimPayer()
+		require.NoError(t, err)
+		lanes[i] = pipeline.Lane{
+			Spender: simPayer.From(),
+			Payer:   &reportingPayer{SimPayer: simPayer, balance: big.NewInt(int64(i + 1))},
+		}
+	}
+	handler = NewHandler(zaptest.NewLogger(t), db, lanes[0].Payer, lanes)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body = rec.Body.String()
+	require.Equal(t, 1, strings.Count(body, "# TYPE crybapy_native_balance_wei gauge\n"))
+	for i, lane := range lanes {
+		require.Contains(t, body, fmt.Sprintf("crybapy_token_balance{spender=%q} 1000000000000\n", lane.Spender.String()))
+		require.Contains(t, body, fmt.Sprintf("crybapy_native_balance_wei{spender=%q} %d\n", lane.Spender.String(), i+1))
+	}
+	require.Contains(t, body, "crybapy_base_fee_wei 7\n")
+}
+
+// reportingPayer is a sim payer that reports a native balance and gas fees.
+type reportingPayer struct {
+	*payer.SimPayer
+	balance *big.Int
+}
+
+func (p *reportingPayer) GasFees(ctx context.Context) (baseFee, maxGas *big.Int, err error) {
+	return big.NewInt(7), big.NewInt(9), nil
+}
+
+func (p *reportingPayer) NativeBalance(ctx context.Context) (*big.Int, error) {
+	return p.balance, nil
+}
@@ -0,0 +1,12 @@
+package payer
+
+import (
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// DBBinder is implemented by payers that cannot recover their state from
+// the chain alone and keep it in the payout database instead.
+type DBBinder interface {
+	// BindDB binds the payer to the payout database of the run.
+	BindDB(db *pipelinedb.DB)
+}
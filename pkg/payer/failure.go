@@ -0,0 +1,19 @@
+package payer
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// FailureDiagnoser is implemented by payers that can tell why a transaction
+// failed.
+type FailureDiagnoser interface {
+	// DiagnoseFailure returns why the transaction failed. The cause is
+	// balance related if the owner did not have enough tokens, or the
+	// spender or disperse contract did not have enough allowance, to cover
+	// the transfer. The receipt is nil if it is not known.
+	DiagnoseFailure(ctx context.Context, tx pipelinedb.Transaction, receipt *types.Receipt) (reason string, balanceRelated bool, err error)
+}
@@ -0,0 +1,25 @@
+package payer
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FinalityChecker is implemented by payers and auditors that can detect
+// when a confirmed transaction has been reorganized out of the canonical
+// chain.
+type FinalityChecker interface {
+	// IsCanonical returns whether the transaction is still included in the
+	// block with the given hash on the canonical chain.
+	IsCanonical(ctx context.Context, hash string, blockHash common.Hash) (bool, error)
+}
+
+// FinalityHorizon is implemented by payers that know how deep a reorg can
+// reasonably reach, so that transactions confirmed in older blocks need not
+// be checked again.
+type FinalityHorizon interface {
+	// FinalizedBlock returns the number of the newest block that is deep
+	// enough in the chain to no longer be reorganized.
+	FinalizedBlock(ctx context.Context) (uint64, error)
+}
@@ -0,0 +1,18 @@
+package payer
+
+import (
+	"context"
+	"math/big"
+)
+
+// Reporter is implemented by payers that can report network state for
+// metrics.
+type Reporter interface {
+	// GasFees returns the base fee of the latest block and the maximum fee
+	// per gas the payer is willing to pay.
+	GasFees(ctx context.Context) (baseFee, maxGas *big.Int, err error)
+
+	// NativeBalance returns the balance of the native coin (e.g. ETH) used
+	// to pay for gas.
+	NativeBalance(ctx context.Context) (*big.Int, error)
+}
@@ -6,11 +6,18 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
 	"go.uber.org/zap"
 
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
 )
 
+// ErrPreconditionNotMet is returned by CreateRawTransaction when a
+// precondition that CheckPreconditions reported as met no longer holds, like
+// the fee having gone up past the max fee since. The pipeline waits for the
+// preconditions and tries again instead of failing.
+var ErrPreconditionNotMet = errs.Class("precondition not met")
+
 // Transaction is a generic representation of a payment.
 type Transaction struct {
 	// Hash is the generic (unique) identifier of the transaction.
@@ -28,6 +35,10 @@ type Payer interface {
 	// Strings returns a string describing the payer type.
 	String() string
 
+	// From returns the address the transactions are sent from, which is
+	// recorded as their spender.
+	From() common.Address
+
 	// NextNonce queries chain for the next available nonce value.
 	NextNonce(ctx context.Context) (uint64, error)
 
@@ -41,7 +52,7 @@ type Payer interface {
 	GetTokenDecimals(ctx context.Context) (int32, error)
 
 	// CreateRawTransaction creates the chain transaction which will be persisted to the db.
-	CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, storjPrice decimal.Decimal) (tx Transaction, from common.Address, err error)
+	CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, price decimal.Decimal) (tx Transaction, from common.Address, err error)
 
 	// SendTransaction submits the transaction created earlier.
 	SendTransaction(ctx context.Context, log *zap.Logger, tx Transaction) error
@@ -49,6 +60,7 @@ type Payer interface {
 	// CheckNonceGroup returns with the status of the submitted transactions.
 	CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error)
 
-	// PrintEstimate prints out additional information about the planned actions.
-	PrintEstimate(ctx context.Context, remaining int64) error
+	// PrintEstimate prints out additional information about the planned
+	// actions, given the remaining payout groups and the payouts within them.
+	PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error
 }
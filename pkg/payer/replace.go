@@ -0,0 +1,30 @@
+package payer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// ErrReplacementCapped is returned by a Replacer when the fee of a pending
+// transaction cannot be bumped enough for a replacement to be accepted.
+var ErrReplacementCapped = errors.New("replacement fee would exceed the max gas price")
+
+// Replacer is implemented by payers that can replace a pending transaction
+// with one paying a higher fee.
+type Replacer interface {
+	// CreateReplacementTransaction re-signs the payouts of the previous
+	// transaction for the same nonce and token amounts with a bumped fee.
+	// The previous transaction must have been created by the same payer.
+	CreateReplacementTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, previous pipelinedb.Transaction) (tx Transaction, from common.Address, err error)
+
+	// EnableReplacement makes the payer leave room in the fee cap of new
+	// transactions to bump their fee later. Without it, new transactions
+	// are created with the highest fee cap allowed, since nothing bumps
+	// them if the base fee rises.
+	EnableReplacement()
+}
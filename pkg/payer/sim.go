@@ -23,7 +23,7 @@ type SimPayer struct {
 	from common.Address
 }
 
-func (s *SimPayer) PrintEstimate(ctx context.Context, remaining int64) error {
+func (s *SimPayer) PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error {
 	fmt.Println("SIMULATING MODE, NO REAL PAYMENT WILL HAPPEN")
 	return nil
 }
@@ -55,11 +55,15 @@ func (s *SimPayer) GetTokenBalance(ctx context.Context) (*big.Int, error) {
 	return big.NewInt(1000000000000), nil
 }
 
+func (s *SimPayer) From() common.Address {
+	return s.from
+}
+
 func (s *SimPayer) GetTokenDecimals(ctx context.Context) (int32, error) {
 	return 8, nil
 }
 
-func (s *SimPayer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, storjPrice decimal.Decimal) (tx Transaction, from common.Address, err error) {
+func (s *SimPayer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, price decimal.Decimal) (tx Transaction, from common.Address, err error) {
 	hash := make([]byte, 32)
 	_, err = rand.Read(hash)
 	if err != nil {
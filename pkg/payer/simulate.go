@@ -0,0 +1,20 @@
+package payer
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// Simulator is implemented by payers that can execute the transfers of a
+// payout group against the chain without signing or sending a transaction.
+type Simulator interface {
+	// SimulatePayoutGroup returns the outcome of the transfer to each payee
+	// in the payout group. The tokens already transferred by the payout
+	// groups simulated before are taken out of the balance and allowance of
+	// the owner.
+	SimulatePayoutGroup(ctx context.Context, payouts []*pipelinedb.Payout, price decimal.Decimal, transferred *big.Int) ([]*pipelinedb.SimulationResult, error)
+}
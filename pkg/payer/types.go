@@ -14,6 +14,9 @@ const (
 	Sim       Type = "sim"
 	ZkSyncEra Type = "zksync-era"
 	Polygon   Type = "polygon"
+	Safe      Type = "safe"
+	Rollup    Type = "rollup"
+	Native    Type = "native"
 )
 
 func (pt Type) String() string {
@@ -29,6 +32,12 @@ func TypeFromString(t string) (Type, error) {
 		return Polygon, nil
 	case "zksync-era", "zksync2": // zksync2 for backcompat
 		return ZkSyncEra, nil
+	case "rollup":
+		return Rollup, nil
+	case "safe":
+		return Safe, nil
+	case "native":
+		return Native, nil
 	case "sim":
 		return Sim, nil
 	default:
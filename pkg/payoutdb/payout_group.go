@@ -12,6 +12,8 @@ const (
 	unfinishedUnattachedConditional = `
 		WHERE
 			final_tx_hash IS NULL
+		AND
+			quarantine_reason IS NULL
 		AND
 			id NOT IN (SELECT payout_group_id FROM tx WHERE state == 'pending')
 `
@@ -53,3 +55,36 @@ func (db *DB) CountUnfinishedUnattachedPayoutGroup(ctx context.Context) (int64,
 
 	return count, nil
 }
+
+func (db *DB) AllUnfinishedUnattachedPayoutGroups(ctx context.Context) ([]*PayoutGroup, error) {
+	// See FirstUnfinishedUnattachedPayoutGroup for why only the primary keys
+	// are selected.
+	stmt := `SELECT pk FROM payout_group` + unfinishedUnattachedConditional + `ORDER BY id`
+	rows, err := db.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var pks []int64
+	for rows.Next() {
+		var pk int64
+		if err := rows.Scan(&pk); err != nil {
+			return nil, errs.Wrap(err)
+		}
+		pks = append(pks, pk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	payoutGroups := make([]*PayoutGroup, 0, len(pks))
+	for _, pk := range pks {
+		payoutGroup, err := db.Get_PayoutGroup_By_Pk(ctx, PayoutGroup_Pk(pk))
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		payoutGroups = append(payoutGroups, payoutGroup)
+	}
+	return payoutGroups, nil
+}
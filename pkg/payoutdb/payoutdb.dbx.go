@@ -265,7 +265,16 @@ func newsqlite3(db *DB) *sqlite3DB {
 }
 
 func (obj *sqlite3DB) Schema() string {
-	return `CREATE TABLE metadata (
+	return `CREATE TABLE log_claim (
+	pk INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	tx_hash TEXT NOT NULL,
+	log_index INTEGER NOT NULL,
+	claimed_by TEXT NOT NULL,
+	PRIMARY KEY ( pk ),
+	UNIQUE ( tx_hash, log_index )
+);
+CREATE TABLE metadata (
 	pk INTEGER NOT NULL,
 	created_at TIMESTAMP NOT NULL,
 	updated_at TIMESTAMP NOT NULL,
@@ -273,6 +282,10 @@ func (obj *sqlite3DB) Schema() string {
 	attempts INTEGER NOT NULL,
 	spender TEXT,
 	owner TEXT,
+	price TEXT,
+	price_source TEXT,
+	priced_at TIMESTAMP,
+	price_symbol TEXT,
 	PRIMARY KEY ( pk )
 );
 CREATE TABLE payout_group (
@@ -281,6 +294,7 @@ CREATE TABLE payout_group (
 	updated_at TIMESTAMP NOT NULL,
 	id INTEGER NOT NULL,
 	final_tx_hash TEXT,
+	quarantine_reason TEXT,
 	PRIMARY KEY ( pk ),
 	UNIQUE ( id )
 );
@@ -293,6 +307,17 @@ CREATE TABLE payout (
 	payout_group_id INTEGER NOT NULL REFERENCES payout_group( id ),
 	PRIMARY KEY ( pk )
 );
+CREATE TABLE simulation_result (
+	pk INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	payout_group_id INTEGER NOT NULL,
+	payee TEXT NOT NULL,
+	usd TEXT NOT NULL,
+	tokens TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	reason TEXT,
+	PRIMARY KEY ( pk )
+);
 CREATE TABLE tx (
 	pk INTEGER NOT NULL,
 	created_at TIMESTAMP NOT NULL,
@@ -302,12 +327,13 @@ CREATE TABLE tx (
 	spender TEXT NOT NULL,
 	nonce INTEGER NOT NULL,
 	estimated_gas_price TEXT NOT NULL,
-	storj_price TEXT NOT NULL,
-	storj_tokens TEXT NOT NULL,
+	price TEXT NOT NULL,
+	tokens TEXT NOT NULL,
 	payout_group_id INTEGER NOT NULL REFERENCES payout_group( id ),
 	raw TEXT NOT NULL,
 	state TEXT NOT NULL,
 	receipt TEXT,
+	block_hash TEXT,
 	PRIMARY KEY ( pk ),
 	UNIQUE ( hash )
 );
@@ -374,27 +400,148 @@ nextval:
 	fmt.Fprint(f, "]")
 }
 
-type Metadata struct {
+type LogClaim struct {
 	Pk        int64
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Version   int
-	Attempts  int
-	Spender   *string
-	Owner     *string
+	TxHash    string
+	LogIndex  uint64
+	ClaimedBy string
+}
+
+func (LogClaim) _Table() string { return "log_claim" }
+
+type LogClaim_Update_Fields struct {
+}
+
+type LogClaim_Pk_Field struct {
+	_set   bool
+	_null  bool
+	_value int64
+}
+
+func LogClaim_Pk(v int64) LogClaim_Pk_Field {
+	return LogClaim_Pk_Field{_set: true, _value: v}
+}
+
+func (f LogClaim_Pk_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (LogClaim_Pk_Field) _Column() string { return "pk" }
+
+type LogClaim_CreatedAt_Field struct {
+	_set   bool
+	_null  bool
+	_value time.Time
+}
+
+func LogClaim_CreatedAt(v time.Time) LogClaim_CreatedAt_Field {
+	v = toUTC(v)
+	return LogClaim_CreatedAt_Field{_set: true, _value: v}
+}
+
+func (f LogClaim_CreatedAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (LogClaim_CreatedAt_Field) _Column() string { return "created_at" }
+
+type LogClaim_TxHash_Field struct {
+	_set   bool
+	_null  bool
+	_value string
+}
+
+func LogClaim_TxHash(v string) LogClaim_TxHash_Field {
+	return LogClaim_TxHash_Field{_set: true, _value: v}
+}
+
+func (f LogClaim_TxHash_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (LogClaim_TxHash_Field) _Column() string { return "tx_hash" }
+
+type LogClaim_LogIndex_Field struct {
+	_set   bool
+	_null  bool
+	_value uint64
+}
+
+func LogClaim_LogIndex(v uint64) LogClaim_LogIndex_Field {
+	return LogClaim_LogIndex_Field{_set: true, _value: v}
+}
+
+func (f LogClaim_LogIndex_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (LogClaim_LogIndex_Field) _Column() string { return "log_index" }
+
+type LogClaim_ClaimedBy_Field struct {
+	_set   bool
+	_null  bool
+	_value string
+}
+
+func LogClaim_ClaimedBy(v string) LogClaim_ClaimedBy_Field {
+	return LogClaim_ClaimedBy_Field{_set: true, _value: v}
+}
+
+func (f LogClaim_ClaimedBy_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (LogClaim_ClaimedBy_Field) _Column() string { return "claimed_by" }
+
+type Metadata struct {
+	Pk          int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Version     int
+	Attempts    int
+	Spender     *string
+	Owner       *string
+	Price       *string
+	PriceSource *string
+	PricedAt    *time.Time
+	PriceSymbol *string
 }
 
 func (Metadata) _Table() string { return "metadata" }
 
 type Metadata_Create_Fields struct {
-	Spender Metadata_Spender_Field
-	Owner   Metadata_Owner_Field
+	Spender     Metadata_Spender_Field
+	Owner       Metadata_Owner_Field
+	Price       Metadata_Price_Field
+	PriceSource Metadata_PriceSource_Field
+	PricedAt    Metadata_PricedAt_Field
+	PriceSymbol Metadata_PriceSymbol_Field
 }
 
 type Metadata_Update_Fields struct {
-	Attempts Metadata_Attempts_Field
-	Spender  Metadata_Spender_Field
-	Owner    Metadata_Owner_Field
+	Attempts    Metadata_Attempts_Field
+	Spender     Metadata_Spender_Field
+	Owner       Metadata_Owner_Field
+	Price       Metadata_Price_Field
+	PriceSource Metadata_PriceSource_Field
+	PricedAt    Metadata_PricedAt_Field
+	PriceSymbol Metadata_PriceSymbol_Field
 }
 
 type Metadata_Pk_Field struct {
@@ -558,22 +705,154 @@ func (f Metadata_Owner_Field) value() interface{} {
 
 func (Metadata_Owner_Field) _Column() string { return "owner" }
 
+type Metadata_Price_Field struct {
+	_set   bool
+	_null  bool
+	_value *string
+}
+
+func Metadata_Price(v string) Metadata_Price_Field {
+	return Metadata_Price_Field{_set: true, _value: &v}
+}
+
+func Metadata_Price_Raw(v *string) Metadata_Price_Field {
+	if v == nil {
+		return Metadata_Price_Null()
+	}
+	return Metadata_Price(*v)
+}
+
+func Metadata_Price_Null() Metadata_Price_Field {
+	return Metadata_Price_Field{_set: true, _null: true}
+}
+
+func (f Metadata_Price_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f Metadata_Price_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Metadata_Price_Field) _Column() string { return "price" }
+
+type Metadata_PriceSource_Field struct {
+	_set   bool
+	_null  bool
+	_value *string
+}
+
+func Metadata_PriceSource(v string) Metadata_PriceSource_Field {
+	return Metadata_PriceSource_Field{_set: true, _value: &v}
+}
+
+func Metadata_PriceSource_Raw(v *string) Metadata_PriceSource_Field {
+	if v == nil {
+		return Metadata_PriceSource_Null()
+	}
+	return Metadata_PriceSource(*v)
+}
+
+func Metadata_PriceSource_Null() Metadata_PriceSource_Field {
+	return Metadata_PriceSource_Field{_set: true, _null: true}
+}
+
+func (f Metadata_PriceSource_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f Metadata_PriceSource_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Metadata_PriceSource_Field) _Column() string { return "price_source" }
+
+type Metadata_PricedAt_Field struct {
+	_set   bool
+	_null  bool
+	_value *time.Time
+}
+
+func Metadata_PricedAt(v time.Time) Metadata_PricedAt_Field {
+	v = toUTC(v)
+	return Metadata_PricedAt_Field{_set: true, _value: &v}
+}
+
+func Metadata_PricedAt_Raw(v *time.Time) Metadata_PricedAt_Field {
+	if v == nil {
+		return Metadata_PricedAt_Null()
+	}
+	return Metadata_PricedAt(*v)
+}
+
+func Metadata_PricedAt_Null() Metadata_PricedAt_Field {
+	return Metadata_PricedAt_Field{_set: true, _null: true}
+}
+
+func (f Metadata_PricedAt_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f Metadata_PricedAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Metadata_PricedAt_Field) _Column() string { return "priced_at" }
+
+type Metadata_PriceSymbol_Field struct {
+	_set   bool
+	_null  bool
+	_value *string
+}
+
+func Metadata_PriceSymbol(v string) Metadata_PriceSymbol_Field {
+	return Metadata_PriceSymbol_Field{_set: true, _value: &v}
+}
+
+func Metadata_PriceSymbol_Raw(v *string) Metadata_PriceSymbol_Field {
+	if v == nil {
+		return Metadata_PriceSymbol_Null()
+	}
+	return Metadata_PriceSymbol(*v)
+}
+
+func Metadata_PriceSymbol_Null() Metadata_PriceSymbol_Field {
+	return Metadata_PriceSymbol_Field{_set: true, _null: true}
+}
+
+func (f Metadata_PriceSymbol_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f Metadata_PriceSymbol_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Metadata_PriceSymbol_Field) _Column() string { return "price_symbol" }
+
 type PayoutGroup struct {
-	Pk          int64
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Id          int64
-	FinalTxHash *string
+	Pk               int64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Id               int64
+	FinalTxHash      *string
+	QuarantineReason *string
 }
 
 func (PayoutGroup) _Table() string { return "payout_group" }
 
 type PayoutGroup_Create_Fields struct {
-	FinalTxHash PayoutGroup_FinalTxHash_Field
+	FinalTxHash      PayoutGroup_FinalTxHash_Field
+	QuarantineReason PayoutGroup_QuarantineReason_Field
 }
 
 type PayoutGroup_Update_Fields struct {
-	FinalTxHash PayoutGroup_FinalTxHash_Field
+	FinalTxHash      PayoutGroup_FinalTxHash_Field
+	QuarantineReason PayoutGroup_QuarantineReason_Field
 }
 
 type PayoutGroup_Pk_Field struct {
@@ -686,6 +965,40 @@ func (f PayoutGroup_FinalTxHash_Field) value() interface{} {
 
 func (PayoutGroup_FinalTxHash_Field) _Column() string { return "final_tx_hash" }
 
+type PayoutGroup_QuarantineReason_Field struct {
+	_set   bool
+	_null  bool
+	_value *string
+}
+
+func PayoutGroup_QuarantineReason(v string) PayoutGroup_QuarantineReason_Field {
+	return PayoutGroup_QuarantineReason_Field{_set: true, _value: &v}
+}
+
+func PayoutGroup_QuarantineReason_Raw(v *string) PayoutGroup_QuarantineReason_Field {
+	if v == nil {
+		return PayoutGroup_QuarantineReason_Null()
+	}
+	return PayoutGroup_QuarantineReason(*v)
+}
+
+func PayoutGroup_QuarantineReason_Null() PayoutGroup_QuarantineReason_Field {
+	return PayoutGroup_QuarantineReason_Field{_set: true, _null: true}
+}
+
+func (f PayoutGroup_QuarantineReason_Field) isnull() bool {
+	return !f._set || f._null || f._value == nil
+}
+
+func (f PayoutGroup_QuarantineReason_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (PayoutGroup_QuarantineReason_Field) _Column() string { return "quarantine_reason" }
+
 type Payout struct {
 	Pk            int64
 	CreatedAt     time.Time
@@ -697,123 +1010,309 @@ type Payout struct {
 
 func (Payout) _Table() string { return "payout" }
 
-type Payout_Update_Fields struct {
+type Payout_Update_Fields struct {
+}
+
+type Payout_Pk_Field struct {
+	_set   bool
+	_null  bool
+	_value int64
+}
+
+func Payout_Pk(v int64) Payout_Pk_Field {
+	return Payout_Pk_Field{_set: true, _value: v}
+}
+
+func (f Payout_Pk_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Payout_Pk_Field) _Column() string { return "pk" }
+
+type Payout_CreatedAt_Field struct {
+	_set   bool
+	_null  bool
+	_value time.Time
+}
+
+func Payout_CreatedAt(v time.Time) Payout_CreatedAt_Field {
+	v = toUTC(v)
+	return Payout_CreatedAt_Field{_set: true, _value: v}
+}
+
+func (f Payout_CreatedAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Payout_CreatedAt_Field) _Column() string { return "created_at" }
+
+type Payout_CsvLine_Field struct {
+	_set   bool
+	_null  bool
+	_value int
+}
+
+func Payout_CsvLine(v int) Payout_CsvLine_Field {
+	return Payout_CsvLine_Field{_set: true, _value: v}
+}
+
+func (f Payout_CsvLine_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Payout_CsvLine_Field) _Column() string { return "csv_line" }
+
+type Payout_Payee_Field struct {
+	_set   bool
+	_null  bool
+	_value string
+}
+
+func Payout_Payee(v string) Payout_Payee_Field {
+	return Payout_Payee_Field{_set: true, _value: v}
+}
+
+func (f Payout_Payee_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Payout_Payee_Field) _Column() string { return "payee" }
+
+type Payout_Usd_Field struct {
+	_set   bool
+	_null  bool
+	_value string
+}
+
+func Payout_Usd(v string) Payout_Usd_Field {
+	return Payout_Usd_Field{_set: true, _value: v}
+}
+
+func (f Payout_Usd_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Payout_Usd_Field) _Column() string { return "usd" }
+
+type Payout_PayoutGroupId_Field struct {
+	_set   bool
+	_null  bool
+	_value int64
+}
+
+func Payout_PayoutGroupId(v int64) Payout_PayoutGroupId_Field {
+	return Payout_PayoutGroupId_Field{_set: true, _value: v}
+}
+
+func (f Payout_PayoutGroupId_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Payout_PayoutGroupId_Field) _Column() string { return "payout_group_id" }
+
+type SimulationResult struct {
+	Pk            int64
+	CreatedAt     time.Time
+	PayoutGroupId int64
+	Payee         string
+	Usd           string
+	Tokens        string
+	Outcome       string
+	Reason        *string
+}
+
+func (SimulationResult) _Table() string { return "simulation_result" }
+
+type SimulationResult_Create_Fields struct {
+	Reason SimulationResult_Reason_Field
+}
+
+type SimulationResult_Update_Fields struct {
+}
+
+type SimulationResult_Pk_Field struct {
+	_set   bool
+	_null  bool
+	_value int64
+}
+
+func SimulationResult_Pk(v int64) SimulationResult_Pk_Field {
+	return SimulationResult_Pk_Field{_set: true, _value: v}
+}
+
+func (f SimulationResult_Pk_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (SimulationResult_Pk_Field) _Column() string { return "pk" }
+
+type SimulationResult_CreatedAt_Field struct {
+	_set   bool
+	_null  bool
+	_value time.Time
+}
+
+func SimulationResult_CreatedAt(v time.Time) SimulationResult_CreatedAt_Field {
+	v = toUTC(v)
+	return SimulationResult_CreatedAt_Field{_set: true, _value: v}
+}
+
+func (f SimulationResult_CreatedAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
 }
 
-type Payout_Pk_Field struct {
+func (SimulationResult_CreatedAt_Field) _Column() string { return "created_at" }
+
+type SimulationResult_PayoutGroupId_Field struct {
 	_set   bool
 	_null  bool
 	_value int64
 }
 
-func Payout_Pk(v int64) Payout_Pk_Field {
-	return Payout_Pk_Field{_set: true, _value: v}
+func SimulationResult_PayoutGroupId(v int64) SimulationResult_PayoutGroupId_Field {
+	return SimulationResult_PayoutGroupId_Field{_set: true, _value: v}
 }
 
-func (f Payout_Pk_Field) value() interface{} {
+func (f SimulationResult_PayoutGroupId_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Payout_Pk_Field) _Column() string { return "pk" }
+func (SimulationResult_PayoutGroupId_Field) _Column() string { return "payout_group_id" }
 
-type Payout_CreatedAt_Field struct {
+type SimulationResult_Payee_Field struct {
 	_set   bool
 	_null  bool
-	_value time.Time
+	_value string
 }
 
-func Payout_CreatedAt(v time.Time) Payout_CreatedAt_Field {
-	v = toUTC(v)
-	return Payout_CreatedAt_Field{_set: true, _value: v}
+func SimulationResult_Payee(v string) SimulationResult_Payee_Field {
+	return SimulationResult_Payee_Field{_set: true, _value: v}
 }
 
-func (f Payout_CreatedAt_Field) value() interface{} {
+func (f SimulationResult_Payee_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Payout_CreatedAt_Field) _Column() string { return "created_at" }
+func (SimulationResult_Payee_Field) _Column() string { return "payee" }
 
-type Payout_CsvLine_Field struct {
+type SimulationResult_Usd_Field struct {
 	_set   bool
 	_null  bool
-	_value int
+	_value string
 }
 
-func Payout_CsvLine(v int) Payout_CsvLine_Field {
-	return Payout_CsvLine_Field{_set: true, _value: v}
+func SimulationResult_Usd(v string) SimulationResult_Usd_Field {
+	return SimulationResult_Usd_Field{_set: true, _value: v}
 }
 
-func (f Payout_CsvLine_Field) value() interface{} {
+func (f SimulationResult_Usd_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Payout_CsvLine_Field) _Column() string { return "csv_line" }
+func (SimulationResult_Usd_Field) _Column() string { return "usd" }
 
-type Payout_Payee_Field struct {
+type SimulationResult_Tokens_Field struct {
 	_set   bool
 	_null  bool
 	_value string
 }
 
-func Payout_Payee(v string) Payout_Payee_Field {
-	return Payout_Payee_Field{_set: true, _value: v}
+func SimulationResult_Tokens(v string) SimulationResult_Tokens_Field {
+	return SimulationResult_Tokens_Field{_set: true, _value: v}
 }
 
-func (f Payout_Payee_Field) value() interface{} {
+func (f SimulationResult_Tokens_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Payout_Payee_Field) _Column() string { return "payee" }
+func (SimulationResult_Tokens_Field) _Column() string { return "tokens" }
 
-type Payout_Usd_Field struct {
+type SimulationResult_Outcome_Field struct {
 	_set   bool
 	_null  bool
 	_value string
 }
 
-func Payout_Usd(v string) Payout_Usd_Field {
-	return Payout_Usd_Field{_set: true, _value: v}
+func SimulationResult_Outcome(v string) SimulationResult_Outcome_Field {
+	return SimulationResult_Outcome_Field{_set: true, _value: v}
 }
 
-func (f Payout_Usd_Field) value() interface{} {
+func (f SimulationResult_Outcome_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Payout_Usd_Field) _Column() string { return "usd" }
+func (SimulationResult_Outcome_Field) _Column() string { return "outcome" }
 
-type Payout_PayoutGroupId_Field struct {
+type SimulationResult_Reason_Field struct {
 	_set   bool
 	_null  bool
-	_value int64
+	_value *string
 }
 
-func Payout_PayoutGroupId(v int64) Payout_PayoutGroupId_Field {
-	return Payout_PayoutGroupId_Field{_set: true, _value: v}
+func SimulationResult_Reason(v string) SimulationResult_Reason_Field {
+	return SimulationResult_Reason_Field{_set: true, _value: &v}
 }
 
-func (f Payout_PayoutGroupId_Field) value() interface{} {
+func SimulationResult_Reason_Raw(v *string) SimulationResult_Reason_Field {
+	if v == nil {
+		return SimulationResult_Reason_Null()
+	}
+	return SimulationResult_Reason(*v)
+}
+
+func SimulationResult_Reason_Null() SimulationResult_Reason_Field {
+	return SimulationResult_Reason_Field{_set: true, _null: true}
+}
+
+func (f SimulationResult_Reason_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f SimulationResult_Reason_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Payout_PayoutGroupId_Field) _Column() string { return "payout_group_id" }
+func (SimulationResult_Reason_Field) _Column() string { return "reason" }
 
 type Transaction struct {
 	Pk                int64
@@ -824,23 +1323,26 @@ type Transaction struct {
 	Spender           string
 	Nonce             uint64
 	EstimatedGasPrice string
-	StorjPrice        string
-	StorjTokens       string
+	Price             string
+	Tokens            string
 	PayoutGroupId     int64
 	Raw               string
 	State             string
 	Receipt           *string
+	BlockHash         *string
 }
 
 func (Transaction) _Table() string { return "tx" }
 
 type Transaction_Create_Fields struct {
-	Receipt Transaction_Receipt_Field
+	Receipt   Transaction_Receipt_Field
+	BlockHash Transaction_BlockHash_Field
 }
 
 type Transaction_Update_Fields struct {
-	State   Transaction_State_Field
-	Receipt Transaction_Receipt_Field
+	State     Transaction_State_Field
+	Receipt   Transaction_Receipt_Field
+	BlockHash Transaction_BlockHash_Field
 }
 
 type Transaction_Pk_Field struct {
@@ -997,43 +1499,43 @@ func (f Transaction_EstimatedGasPrice_Field) value() interface{} {
 
 func (Transaction_EstimatedGasPrice_Field) _Column() string { return "estimated_gas_price" }
 
-type Transaction_StorjPrice_Field struct {
+type Transaction_Price_Field struct {
 	_set   bool
 	_null  bool
 	_value string
 }
 
-func Transaction_StorjPrice(v string) Transaction_StorjPrice_Field {
-	return Transaction_StorjPrice_Field{_set: true, _value: v}
+func Transaction_Price(v string) Transaction_Price_Field {
+	return Transaction_Price_Field{_set: true, _value: v}
 }
 
-func (f Transaction_StorjPrice_Field) value() interface{} {
+func (f Transaction_Price_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Transaction_StorjPrice_Field) _Column() string { return "storj_price" }
+func (Transaction_Price_Field) _Column() string { return "price" }
 
-type Transaction_StorjTokens_Field struct {
+type Transaction_Tokens_Field struct {
 	_set   bool
 	_null  bool
 	_value string
 }
 
-func Transaction_StorjTokens(v string) Transaction_StorjTokens_Field {
-	return Transaction_StorjTokens_Field{_set: true, _value: v}
+func Transaction_Tokens(v string) Transaction_Tokens_Field {
+	return Transaction_Tokens_Field{_set: true, _value: v}
 }
 
-func (f Transaction_StorjTokens_Field) value() interface{} {
+func (f Transaction_Tokens_Field) value() interface{} {
 	if !f._set || f._null {
 		return nil
 	}
 	return f._value
 }
 
-func (Transaction_StorjTokens_Field) _Column() string { return "storj_tokens" }
+func (Transaction_Tokens_Field) _Column() string { return "tokens" }
 
 type Transaction_PayoutGroupId_Field struct {
 	_set   bool
@@ -1124,6 +1626,38 @@ func (f Transaction_Receipt_Field) value() interface{} {
 
 func (Transaction_Receipt_Field) _Column() string { return "receipt" }
 
+type Transaction_BlockHash_Field struct {
+	_set   bool
+	_null  bool
+	_value *string
+}
+
+func Transaction_BlockHash(v string) Transaction_BlockHash_Field {
+	return Transaction_BlockHash_Field{_set: true, _value: &v}
+}
+
+func Transaction_BlockHash_Raw(v *string) Transaction_BlockHash_Field {
+	if v == nil {
+		return Transaction_BlockHash_Null()
+	}
+	return Transaction_BlockHash(*v)
+}
+
+func Transaction_BlockHash_Null() Transaction_BlockHash_Field {
+	return Transaction_BlockHash_Field{_set: true, _null: true}
+}
+
+func (f Transaction_BlockHash_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f Transaction_BlockHash_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (Transaction_BlockHash_Field) _Column() string { return "block_hash" }
+
 func toUTC(t time.Time) time.Time {
 	return t.UTC()
 }
@@ -1588,11 +2122,12 @@ func (obj *sqlite3Impl) CreateNoReturn_PayoutGroup(ctx context.Context,
 	__updated_at_val := __now.UTC()
 	__id_val := payout_group_id.value()
 	__final_tx_hash_val := optional.FinalTxHash.value()
+	__quarantine_reason_val := optional.QuarantineReason.value()
 
-	var __embed_stmt = __sqlbundle_Literal("INSERT INTO payout_group ( created_at, updated_at, id, final_tx_hash ) VALUES ( ?, ?, ?, ? )")
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO payout_group ( created_at, updated_at, id, final_tx_hash, quarantine_reason ) VALUES ( ?, ?, ?, ?, ? )")
 
 	var __values []interface{}
-	__values = append(__values, __created_at_val, __updated_at_val, __id_val, __final_tx_hash_val)
+	__values = append(__values, __created_at_val, __updated_at_val, __id_val, __final_tx_hash_val, __quarantine_reason_val)
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, __values...)
@@ -1611,8 +2146,8 @@ func (obj *sqlite3Impl) Create_Transaction(ctx context.Context,
 	transaction_spender Transaction_Spender_Field,
 	transaction_nonce Transaction_Nonce_Field,
 	transaction_estimated_gas_price Transaction_EstimatedGasPrice_Field,
-	transaction_storj_price Transaction_StorjPrice_Field,
-	transaction_storj_tokens Transaction_StorjTokens_Field,
+	transaction_price Transaction_Price_Field,
+	transaction_tokens Transaction_Tokens_Field,
 	transaction_payout_group_id Transaction_PayoutGroupId_Field,
 	transaction_raw Transaction_Raw_Field,
 	transaction_state Transaction_State_Field,
@@ -1627,17 +2162,18 @@ func (obj *sqlite3Impl) Create_Transaction(ctx context.Context,
 	__spender_val := transaction_spender.value()
 	__nonce_val := transaction_nonce.value()
 	__estimated_gas_price_val := transaction_estimated_gas_price.value()
-	__storj_price_val := transaction_storj_price.value()
-	__storj_tokens_val := transaction_storj_tokens.value()
+	__price_val := transaction_price.value()
+	__tokens_val := transaction_tokens.value()
 	__payout_group_id_val := transaction_payout_group_id.value()
 	__raw_val := transaction_raw.value()
 	__state_val := transaction_state.value()
 	__receipt_val := optional.Receipt.value()
+	__block_hash_val := optional.BlockHash.value()
 
-	var __embed_stmt = __sqlbundle_Literal("INSERT INTO tx ( created_at, updated_at, hash, owner, spender, nonce, estimated_gas_price, storj_price, storj_tokens, payout_group_id, raw, state, receipt ) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )")
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO tx ( created_at, updated_at, hash, owner, spender, nonce, estimated_gas_price, price, tokens, payout_group_id, raw, state, receipt, block_hash ) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )")
 
 	var __values []interface{}
-	__values = append(__values, __created_at_val, __updated_at_val, __hash_val, __owner_val, __spender_val, __nonce_val, __estimated_gas_price_val, __storj_price_val, __storj_tokens_val, __payout_group_id_val, __raw_val, __state_val, __receipt_val)
+	__values = append(__values, __created_at_val, __updated_at_val, __hash_val, __owner_val, __spender_val, __nonce_val, __estimated_gas_price_val, __price_val, __tokens_val, __payout_group_id_val, __raw_val, __state_val, __receipt_val, __block_hash_val)
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, __values...)
@@ -1654,6 +2190,34 @@ func (obj *sqlite3Impl) Create_Transaction(ctx context.Context,
 
 }
 
+func (obj *sqlite3Impl) CreateNoReturn_LogClaim(ctx context.Context,
+	log_claim_tx_hash LogClaim_TxHash_Field,
+	log_claim_log_index LogClaim_LogIndex_Field,
+	log_claim_claimed_by LogClaim_ClaimedBy_Field) (
+	err error) {
+
+	__now := obj.db.Hooks.Now().UTC()
+	__created_at_val := __now.UTC()
+	__tx_hash_val := log_claim_tx_hash.value()
+	__log_index_val := log_claim_log_index.value()
+	__claimed_by_val := log_claim_claimed_by.value()
+
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO log_claim ( created_at, tx_hash, log_index, claimed_by ) VALUES ( ?, ?, ?, ? )")
+
+	var __values []interface{}
+	__values = append(__values, __created_at_val, __tx_hash_val, __log_index_val, __claimed_by_val)
+
+	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
+	obj.logStmt(__stmt, __values...)
+
+	_, err = obj.driver.ExecContext(ctx, __stmt, __values...)
+	if err != nil {
+		return obj.makeErr(err)
+	}
+	return nil
+
+}
+
 func (obj *sqlite3Impl) CreateNoReturn_Metadata(ctx context.Context,
 	metadata_version Metadata_Version_Field,
 	metadata_attempts Metadata_Attempts_Field,
@@ -1667,11 +2231,49 @@ func (obj *sqlite3Impl) CreateNoReturn_Metadata(ctx context.Context,
 	__attempts_val := metadata_attempts.value()
 	__spender_val := optional.Spender.value()
 	__owner_val := optional.Owner.value()
+	__price_val := optional.Price.value()
+	__price_source_val := optional.PriceSource.value()
+	__priced_at_val := optional.PricedAt.value()
+	__price_symbol_val := optional.PriceSymbol.value()
+
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO metadata ( created_at, updated_at, version, attempts, spender, owner, price, price_source, priced_at, price_symbol ) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )")
+
+	var __values []interface{}
+	__values = append(__values, __created_at_val, __updated_at_val, __version_val, __attempts_val, __spender_val, __owner_val, __price_val, __price_source_val, __priced_at_val, __price_symbol_val)
+
+	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
+	obj.logStmt(__stmt, __values...)
+
+	_, err = obj.driver.ExecContext(ctx, __stmt, __values...)
+	if err != nil {
+		return obj.makeErr(err)
+	}
+	return nil
+
+}
+
+func (obj *sqlite3Impl) CreateNoReturn_SimulationResult(ctx context.Context,
+	simulation_result_payout_group_id SimulationResult_PayoutGroupId_Field,
+	simulation_result_payee SimulationResult_Payee_Field,
+	simulation_result_usd SimulationResult_Usd_Field,
+	simulation_result_tokens SimulationResult_Tokens_Field,
+	simulation_result_outcome SimulationResult_Outcome_Field,
+	optional SimulationResult_Create_Fields) (
+	err error) {
+
+	__now := obj.db.Hooks.Now().UTC()
+	__created_at_val := __now.UTC()
+	__payout_group_id_val := simulation_result_payout_group_id.value()
+	__payee_val := simulation_result_payee.value()
+	__usd_val := simulation_result_usd.value()
+	__tokens_val := simulation_result_tokens.value()
+	__outcome_val := simulation_result_outcome.value()
+	__reason_val := optional.Reason.value()
 
-	var __embed_stmt = __sqlbundle_Literal("INSERT INTO metadata ( created_at, updated_at, version, attempts, spender, owner ) VALUES ( ?, ?, ?, ?, ?, ? )")
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO simulation_result ( created_at, payout_group_id, payee, usd, tokens, outcome, reason ) VALUES ( ?, ?, ?, ?, ?, ?, ? )")
 
 	var __values []interface{}
-	__values = append(__values, __created_at_val, __updated_at_val, __version_val, __attempts_val, __spender_val, __owner_val)
+	__values = append(__values, __created_at_val, __payout_group_id_val, __payee_val, __usd_val, __tokens_val, __outcome_val, __reason_val)
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, __values...)
@@ -1742,7 +2344,7 @@ func (obj *sqlite3Impl) All_Transaction_By_PayoutGroupId(ctx context.Context,
 	transaction_payout_group_id Transaction_PayoutGroupId_Field) (
 	rows []*Transaction, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.storj_price, tx.storj_tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt FROM tx WHERE tx.payout_group_id = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.price, tx.tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt, tx.block_hash FROM tx WHERE tx.payout_group_id = ?")
 
 	var __values []interface{}
 	__values = append(__values, transaction_payout_group_id.value())
@@ -1758,7 +2360,7 @@ func (obj *sqlite3Impl) All_Transaction_By_PayoutGroupId(ctx context.Context,
 
 	for __rows.Next() {
 		transaction := &Transaction{}
-		err = __rows.Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.StorjPrice, &transaction.StorjTokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt)
+		err = __rows.Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.Price, &transaction.Tokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt, &transaction.BlockHash)
 		if err != nil {
 			return nil, obj.makeErr(err)
 		}
@@ -1775,7 +2377,7 @@ func (obj *sqlite3Impl) Get_PayoutGroup_By_Pk(ctx context.Context,
 	payout_group_pk PayoutGroup_Pk_Field) (
 	payout_group *PayoutGroup, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash FROM payout_group WHERE payout_group.pk = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash, payout_group.quarantine_reason FROM payout_group WHERE payout_group.pk = ?")
 
 	var __values []interface{}
 	__values = append(__values, payout_group_pk.value())
@@ -1784,7 +2386,7 @@ func (obj *sqlite3Impl) Get_PayoutGroup_By_Pk(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	payout_group = &PayoutGroup{}
-	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash)
+	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash, &payout_group.QuarantineReason)
 	if err != nil {
 		return (*PayoutGroup)(nil), obj.makeErr(err)
 	}
@@ -1895,7 +2497,7 @@ func (obj *sqlite3Impl) Count_PayoutGroup_By_FinalTxHash_Is_Null(ctx context.Con
 func (obj *sqlite3Impl) All_Transaction(ctx context.Context) (
 	rows []*Transaction, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.storj_price, tx.storj_tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt FROM tx")
+	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.price, tx.tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt, tx.block_hash FROM tx")
 
 	var __values []interface{}
 
@@ -1910,7 +2512,7 @@ func (obj *sqlite3Impl) All_Transaction(ctx context.Context) (
 
 	for __rows.Next() {
 		transaction := &Transaction{}
-		err = __rows.Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.StorjPrice, &transaction.StorjTokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt)
+		err = __rows.Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.Price, &transaction.Tokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt, &transaction.BlockHash)
 		if err != nil {
 			return nil, obj.makeErr(err)
 		}
@@ -1967,7 +2569,7 @@ func (obj *sqlite3Impl) All_Transaction_By_State_OrderBy_Asc_Nonce(ctx context.C
 	transaction_state Transaction_State_Field) (
 	rows []*Transaction, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.storj_price, tx.storj_tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt FROM tx WHERE tx.state = ? ORDER BY tx.nonce")
+	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.price, tx.tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt, tx.block_hash FROM tx WHERE tx.state = ? ORDER BY tx.nonce")
 
 	var __values []interface{}
 	__values = append(__values, transaction_state.value())
@@ -1983,7 +2585,7 @@ func (obj *sqlite3Impl) All_Transaction_By_State_OrderBy_Asc_Nonce(ctx context.C
 
 	for __rows.Next() {
 		transaction := &Transaction{}
-		err = __rows.Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.StorjPrice, &transaction.StorjTokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt)
+		err = __rows.Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.Price, &transaction.Tokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt, &transaction.BlockHash)
 		if err != nil {
 			return nil, obj.makeErr(err)
 		}
@@ -1996,11 +2598,67 @@ func (obj *sqlite3Impl) All_Transaction_By_State_OrderBy_Asc_Nonce(ctx context.C
 
 }
 
+func (obj *sqlite3Impl) All_PayoutGroup_By_QuarantineReason_IsNot_Null_OrderBy_Asc_Id(ctx context.Context) (
+	rows []*PayoutGroup, err error) {
+
+	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash, payout_group.quarantine_reason FROM payout_group WHERE payout_group.quarantine_reason is not NULL ORDER BY payout_group.id")
+
+	var __values []interface{}
+
+	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
+	obj.logStmt(__stmt, __values...)
+
+	__rows, err := obj.driver.QueryContext(ctx, __stmt, __values...)
+	if err != nil {
+		return nil, obj.makeErr(err)
+	}
+	defer __rows.Close()
+
+	for __rows.Next() {
+		payout_group := &PayoutGroup{}
+		err = __rows.Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash, &payout_group.QuarantineReason)
+		if err != nil {
+			return nil, obj.makeErr(err)
+		}
+		rows = append(rows, payout_group)
+	}
+	if err := __rows.Err(); err != nil {
+		return nil, obj.makeErr(err)
+	}
+	return rows, nil
+
+}
+
+func (obj *sqlite3Impl) Find_LogClaim_By_TxHash_And_LogIndex(ctx context.Context,
+	log_claim_tx_hash LogClaim_TxHash_Field,
+	log_claim_log_index LogClaim_LogIndex_Field) (
+	log_claim *LogClaim, err error) {
+
+	var __embed_stmt = __sqlbundle_Literal("SELECT log_claim.pk, log_claim.created_at, log_claim.tx_hash, log_claim.log_index, log_claim.claimed_by FROM log_claim WHERE log_claim.tx_hash = ? AND log_claim.log_index = ?")
+
+	var __values []interface{}
+	__values = append(__values, log_claim_tx_hash.value(), log_claim_log_index.value())
+
+	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
+	obj.logStmt(__stmt, __values...)
+
+	log_claim = &LogClaim{}
+	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&log_claim.Pk, &log_claim.CreatedAt, &log_claim.TxHash, &log_claim.LogIndex, &log_claim.ClaimedBy)
+	if err == sql.ErrNoRows {
+		return (*LogClaim)(nil), nil
+	}
+	if err != nil {
+		return (*LogClaim)(nil), obj.makeErr(err)
+	}
+	return log_claim, nil
+
+}
+
 func (obj *sqlite3Impl) Find_PayoutGroup_By_Id(ctx context.Context,
 	payout_group_id PayoutGroup_Id_Field) (
 	payout_group *PayoutGroup, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash FROM payout_group WHERE payout_group.id = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash, payout_group.quarantine_reason FROM payout_group WHERE payout_group.id = ?")
 
 	var __values []interface{}
 	__values = append(__values, payout_group_id.value())
@@ -2009,7 +2667,7 @@ func (obj *sqlite3Impl) Find_PayoutGroup_By_Id(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	payout_group = &PayoutGroup{}
-	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash)
+	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash, &payout_group.QuarantineReason)
 	if err == sql.ErrNoRows {
 		return (*PayoutGroup)(nil), nil
 	}
@@ -2024,7 +2682,7 @@ func (obj *sqlite3Impl) Find_Transaction_By_Hash(ctx context.Context,
 	transaction_hash Transaction_Hash_Field) (
 	transaction *Transaction, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.storj_price, tx.storj_tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt FROM tx WHERE tx.hash = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.price, tx.tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt, tx.block_hash FROM tx WHERE tx.hash = ?")
 
 	var __values []interface{}
 	__values = append(__values, transaction_hash.value())
@@ -2033,7 +2691,7 @@ func (obj *sqlite3Impl) Find_Transaction_By_Hash(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	transaction = &Transaction{}
-	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.StorjPrice, &transaction.StorjTokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt)
+	err = obj.driver.QueryRowContext(ctx, __stmt, __values...).Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.Price, &transaction.Tokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt, &transaction.BlockHash)
 	if err == sql.ErrNoRows {
 		return (*Transaction)(nil), nil
 	}
@@ -2047,7 +2705,7 @@ func (obj *sqlite3Impl) Find_Transaction_By_Hash(ctx context.Context,
 func (obj *sqlite3Impl) First_Metadata(ctx context.Context) (
 	metadata *Metadata, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT metadata.pk, metadata.created_at, metadata.updated_at, metadata.version, metadata.attempts, metadata.spender, metadata.owner FROM metadata LIMIT 1 OFFSET 0")
+	var __embed_stmt = __sqlbundle_Literal("SELECT metadata.pk, metadata.created_at, metadata.updated_at, metadata.version, metadata.attempts, metadata.spender, metadata.owner, metadata.price, metadata.price_source, metadata.priced_at, metadata.price_symbol FROM metadata LIMIT 1 OFFSET 0")
 
 	var __values []interface{}
 
@@ -2068,7 +2726,7 @@ func (obj *sqlite3Impl) First_Metadata(ctx context.Context) (
 	}
 
 	metadata = &Metadata{}
-	err = __rows.Scan(&metadata.Pk, &metadata.CreatedAt, &metadata.UpdatedAt, &metadata.Version, &metadata.Attempts, &metadata.Spender, &metadata.Owner)
+	err = __rows.Scan(&metadata.Pk, &metadata.CreatedAt, &metadata.UpdatedAt, &metadata.Version, &metadata.Attempts, &metadata.Spender, &metadata.Owner, &metadata.Price, &metadata.PriceSource, &metadata.PricedAt, &metadata.PriceSymbol)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -2110,6 +2768,37 @@ func (obj *sqlite3Impl) First_Metadata_Version(ctx context.Context) (
 
 }
 
+func (obj *sqlite3Impl) All_SimulationResult_OrderBy_Asc_Pk(ctx context.Context) (
+	rows []*SimulationResult, err error) {
+
+	var __embed_stmt = __sqlbundle_Literal("SELECT simulation_result.pk, simulation_result.created_at, simulation_result.payout_group_id, simulation_result.payee, simulation_result.usd, simulation_result.tokens, simulation_result.outcome, simulation_result.reason FROM simulation_result ORDER BY simulation_result.pk")
+
+	var __values []interface{}
+
+	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
+	obj.logStmt(__stmt, __values...)
+
+	__rows, err := obj.driver.QueryContext(ctx, __stmt, __values...)
+	if err != nil {
+		return nil, obj.makeErr(err)
+	}
+	defer __rows.Close()
+
+	for __rows.Next() {
+		simulation_result := &SimulationResult{}
+		err = __rows.Scan(&simulation_result.Pk, &simulation_result.CreatedAt, &simulation_result.PayoutGroupId, &simulation_result.Payee, &simulation_result.Usd, &simulation_result.Tokens, &simulation_result.Outcome, &simulation_result.Reason)
+		if err != nil {
+			return nil, obj.makeErr(err)
+		}
+		rows = append(rows, simulation_result)
+	}
+	if err := __rows.Err(); err != nil {
+		return nil, obj.makeErr(err)
+	}
+	return rows, nil
+
+}
+
 func (obj *sqlite3Impl) UpdateNoReturn_PayoutGroup_By_Id(ctx context.Context,
 	payout_group_id PayoutGroup_Id_Field,
 	update PayoutGroup_Update_Fields) (
@@ -2127,6 +2816,11 @@ func (obj *sqlite3Impl) UpdateNoReturn_PayoutGroup_By_Id(ctx context.Context,
 		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("final_tx_hash = ?"))
 	}
 
+	if update.QuarantineReason._set {
+		__values = append(__values, update.QuarantineReason.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("quarantine_reason = ?"))
+	}
+
 	__now := obj.db.Hooks.Now().UTC()
 
 	__values = append(__values, __now.UTC())
@@ -2169,6 +2863,11 @@ func (obj *sqlite3Impl) UpdateNoReturn_Transaction_By_Hash(ctx context.Context,
 		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("receipt = ?"))
 	}
 
+	if update.BlockHash._set {
+		__values = append(__values, update.BlockHash.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("block_hash = ?"))
+	}
+
 	__now := obj.db.Hooks.Now().UTC()
 
 	__values = append(__values, __now.UTC())
@@ -2216,6 +2915,26 @@ func (obj *sqlite3Impl) UpdateNoReturn_Metadata_By_Pk(ctx context.Context,
 		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("owner = ?"))
 	}
 
+	if update.Price._set {
+		__values = append(__values, update.Price.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("price = ?"))
+	}
+
+	if update.PriceSource._set {
+		__values = append(__values, update.PriceSource.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("price_source = ?"))
+	}
+
+	if update.PricedAt._set {
+		__values = append(__values, update.PricedAt.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("priced_at = ?"))
+	}
+
+	if update.PriceSymbol._set {
+		__values = append(__values, update.PriceSymbol.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("price_symbol = ?"))
+	}
+
 	__now := obj.db.Hooks.Now().UTC()
 
 	__values = append(__values, __now.UTC())
@@ -2258,13 +2977,13 @@ func (obj *sqlite3Impl) getLastPayoutGroup(ctx context.Context,
 	pk int64) (
 	payout_group *PayoutGroup, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash FROM payout_group WHERE _rowid_ = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT payout_group.pk, payout_group.created_at, payout_group.updated_at, payout_group.id, payout_group.final_tx_hash, payout_group.quarantine_reason FROM payout_group WHERE _rowid_ = ?")
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, pk)
 
 	payout_group = &PayoutGroup{}
-	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash)
+	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&payout_group.Pk, &payout_group.CreatedAt, &payout_group.UpdatedAt, &payout_group.Id, &payout_group.FinalTxHash, &payout_group.QuarantineReason)
 	if err != nil {
 		return (*PayoutGroup)(nil), obj.makeErr(err)
 	}
@@ -2276,13 +2995,13 @@ func (obj *sqlite3Impl) getLastTransaction(ctx context.Context,
 	pk int64) (
 	transaction *Transaction, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.storj_price, tx.storj_tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt FROM tx WHERE _rowid_ = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT tx.pk, tx.created_at, tx.updated_at, tx.hash, tx.owner, tx.spender, tx.nonce, tx.estimated_gas_price, tx.price, tx.tokens, tx.payout_group_id, tx.raw, tx.state, tx.receipt, tx.block_hash FROM tx WHERE _rowid_ = ?")
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, pk)
 
 	transaction = &Transaction{}
-	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.StorjPrice, &transaction.StorjTokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt)
+	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&transaction.Pk, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Hash, &transaction.Owner, &transaction.Spender, &transaction.Nonce, &transaction.EstimatedGasPrice, &transaction.Price, &transaction.Tokens, &transaction.PayoutGroupId, &transaction.Raw, &transaction.State, &transaction.Receipt, &transaction.BlockHash)
 	if err != nil {
 		return (*Transaction)(nil), obj.makeErr(err)
 	}
@@ -2294,13 +3013,13 @@ func (obj *sqlite3Impl) getLastMetadata(ctx context.Context,
 	pk int64) (
 	metadata *Metadata, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT metadata.pk, metadata.created_at, metadata.updated_at, metadata.version, metadata.attempts, metadata.spender, metadata.owner FROM metadata WHERE _rowid_ = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT metadata.pk, metadata.created_at, metadata.updated_at, metadata.version, metadata.attempts, metadata.spender, metadata.owner, metadata.price, metadata.price_source, metadata.priced_at, metadata.price_symbol FROM metadata WHERE _rowid_ = ?")
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, pk)
 
 	metadata = &Metadata{}
-	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&metadata.Pk, &metadata.CreatedAt, &metadata.UpdatedAt, &metadata.Version, &metadata.Attempts, &metadata.Spender, &metadata.Owner)
+	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&metadata.Pk, &metadata.CreatedAt, &metadata.UpdatedAt, &metadata.Version, &metadata.Attempts, &metadata.Spender, &metadata.Owner, &metadata.Price, &metadata.PriceSource, &metadata.PricedAt, &metadata.PriceSymbol)
 	if err != nil {
 		return (*Metadata)(nil), obj.makeErr(err)
 	}
@@ -2308,6 +3027,24 @@ func (obj *sqlite3Impl) getLastMetadata(ctx context.Context,
 
 }
 
+func (obj *sqlite3Impl) getLastSimulationResult(ctx context.Context,
+	pk int64) (
+	simulation_result *SimulationResult, err error) {
+
+	var __embed_stmt = __sqlbundle_Literal("SELECT simulation_result.pk, simulation_result.created_at, simulation_result.payout_group_id, simulation_result.payee, simulation_result.usd, simulation_result.tokens, simulation_result.outcome, simulation_result.reason FROM simulation_result WHERE _rowid_ = ?")
+
+	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
+	obj.logStmt(__stmt, pk)
+
+	simulation_result = &SimulationResult{}
+	err = obj.driver.QueryRowContext(ctx, __stmt, pk).Scan(&simulation_result.Pk, &simulation_result.CreatedAt, &simulation_result.PayoutGroupId, &simulation_result.Payee, &simulation_result.Usd, &simulation_result.Tokens, &simulation_result.Outcome, &simulation_result.Reason)
+	if err != nil {
+		return (*SimulationResult)(nil), obj.makeErr(err)
+	}
+	return simulation_result, nil
+
+}
+
 func (impl sqlite3Impl) isConstraintError(err error) (
 	constraint string, ok bool) {
 	if e, ok := err.(sqlite3.Error); ok {
@@ -2331,6 +3068,16 @@ func (obj *sqlite3Impl) deleteAll(ctx context.Context) (count int64, err error)
 		return 0, obj.makeErr(err)
 	}
 
+	__count, err = __res.RowsAffected()
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+	count += __count
+	__res, err = obj.driver.ExecContext(ctx, "DELETE FROM simulation_result;")
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+
 	__count, err = __res.RowsAffected()
 	if err != nil {
 		return 0, obj.makeErr(err)
@@ -2361,6 +3108,16 @@ func (obj *sqlite3Impl) deleteAll(ctx context.Context) (count int64, err error)
 		return 0, obj.makeErr(err)
 	}
 
+	__count, err = __res.RowsAffected()
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+	count += __count
+	__res, err = obj.driver.ExecContext(ctx, "DELETE FROM log_claim;")
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+
 	__count, err = __res.RowsAffected()
 	if err != nil {
 		return 0, obj.makeErr(err)
@@ -2460,6 +3217,24 @@ func (rx *Rx) All_Transaction_By_PayoutGroupId(ctx context.Context,
 	return tx.All_Transaction_By_PayoutGroupId(ctx, transaction_payout_group_id)
 }
 
+func (rx *Rx) All_PayoutGroup_By_QuarantineReason_IsNot_Null_OrderBy_Asc_Id(ctx context.Context) (
+	rows []*PayoutGroup, err error) {
+	var tx *Tx
+	if tx, err = rx.getTx(ctx); err != nil {
+		return
+	}
+	return tx.All_PayoutGroup_By_QuarantineReason_IsNot_Null_OrderBy_Asc_Id(ctx)
+}
+
+func (rx *Rx) All_SimulationResult_OrderBy_Asc_Pk(ctx context.Context) (
+	rows []*SimulationResult, err error) {
+	var tx *Tx
+	if tx, err = rx.getTx(ctx); err != nil {
+		return
+	}
+	return tx.All_SimulationResult_OrderBy_Asc_Pk(ctx)
+}
+
 func (rx *Rx) All_Transaction_By_State_OrderBy_Asc_Nonce(ctx context.Context,
 	transaction_state Transaction_State_Field) (
 	rows []*Transaction, err error) {
@@ -2517,6 +3292,19 @@ func (rx *Rx) Count_Transaction_By_State(ctx context.Context,
 	return tx.Count_Transaction_By_State(ctx, transaction_state)
 }
 
+func (rx *Rx) CreateNoReturn_LogClaim(ctx context.Context,
+	log_claim_tx_hash LogClaim_TxHash_Field,
+	log_claim_log_index LogClaim_LogIndex_Field,
+	log_claim_claimed_by LogClaim_ClaimedBy_Field) (
+	err error) {
+	var tx *Tx
+	if tx, err = rx.getTx(ctx); err != nil {
+		return
+	}
+	return tx.CreateNoReturn_LogClaim(ctx, log_claim_tx_hash, log_claim_log_index, log_claim_claimed_by)
+
+}
+
 func (rx *Rx) CreateNoReturn_Metadata(ctx context.Context,
 	metadata_version Metadata_Version_Field,
 	metadata_attempts Metadata_Attempts_Field,
@@ -2556,14 +3344,30 @@ func (rx *Rx) CreateNoReturn_PayoutGroup(ctx context.Context,
 
 }
 
+func (rx *Rx) CreateNoReturn_SimulationResult(ctx context.Context,
+	simulation_result_payout_group_id SimulationResult_PayoutGroupId_Field,
+	simulation_result_payee SimulationResult_Payee_Field,
+	simulation_result_usd SimulationResult_Usd_Field,
+	simulation_result_tokens SimulationResult_Tokens_Field,
+	simulation_result_outcome SimulationResult_Outcome_Field,
+	optional SimulationResult_Create_Fields) (
+	err error) {
+	var tx *Tx
+	if tx, err = rx.getTx(ctx); err != nil {
+		return
+	}
+	return tx.CreateNoReturn_SimulationResult(ctx, simulation_result_payout_group_id, simulation_result_payee, simulation_result_usd, simulation_result_tokens, simulation_result_outcome, optional)
+
+}
+
 func (rx *Rx) Create_Transaction(ctx context.Context,
 	transaction_hash Transaction_Hash_Field,
 	transaction_owner Transaction_Owner_Field,
 	transaction_spender Transaction_Spender_Field,
 	transaction_nonce Transaction_Nonce_Field,
 	transaction_estimated_gas_price Transaction_EstimatedGasPrice_Field,
-	transaction_storj_price Transaction_StorjPrice_Field,
-	transaction_storj_tokens Transaction_StorjTokens_Field,
+	transaction_price Transaction_Price_Field,
+	transaction_tokens Transaction_Tokens_Field,
 	transaction_payout_group_id Transaction_PayoutGroupId_Field,
 	transaction_raw Transaction_Raw_Field,
 	transaction_state Transaction_State_Field,
@@ -2573,8 +3377,19 @@ func (rx *Rx) Create_Transaction(ctx context.Context,
 	if tx, err = rx.getTx(ctx); err != nil {
 		return
 	}
-	return tx.Create_Transaction(ctx, transaction_hash, transaction_owner, transaction_spender, transaction_nonce, transaction_estimated_gas_price, transaction_storj_price, transaction_storj_tokens, transaction_payout_group_id, transaction_raw, transaction_state, optional)
+	return tx.Create_Transaction(ctx, transaction_hash, transaction_owner, transaction_spender, transaction_nonce, transaction_estimated_gas_price, transaction_price, transaction_tokens, transaction_payout_group_id, transaction_raw, transaction_state, optional)
+
+}
 
+func (rx *Rx) Find_LogClaim_By_TxHash_And_LogIndex(ctx context.Context,
+	log_claim_tx_hash LogClaim_TxHash_Field,
+	log_claim_log_index LogClaim_LogIndex_Field) (
+	log_claim *LogClaim, err error) {
+	var tx *Tx
+	if tx, err = rx.getTx(ctx); err != nil {
+		return
+	}
+	return tx.Find_LogClaim_By_TxHash_And_LogIndex(ctx, log_claim_tx_hash, log_claim_log_index)
 }
 
 func (rx *Rx) Find_PayoutGroup_By_Id(ctx context.Context,
@@ -2676,6 +3491,12 @@ type Methods interface {
 		transaction_payout_group_id Transaction_PayoutGroupId_Field) (
 		rows []*Transaction, err error)
 
+	All_PayoutGroup_By_QuarantineReason_IsNot_Null_OrderBy_Asc_Id(ctx context.Context) (
+		rows []*PayoutGroup, err error)
+
+	All_SimulationResult_OrderBy_Asc_Pk(ctx context.Context) (
+		rows []*SimulationResult, err error)
+
 	All_Transaction_By_State_OrderBy_Asc_Nonce(ctx context.Context,
 		transaction_state Transaction_State_Field) (
 		rows []*Transaction, err error)
@@ -2697,6 +3518,12 @@ type Methods interface {
 		transaction_state Transaction_State_Field) (
 		count int64, err error)
 
+	CreateNoReturn_LogClaim(ctx context.Context,
+		log_claim_tx_hash LogClaim_TxHash_Field,
+		log_claim_log_index LogClaim_LogIndex_Field,
+		log_claim_claimed_by LogClaim_ClaimedBy_Field) (
+		err error)
+
 	CreateNoReturn_Metadata(ctx context.Context,
 		metadata_version Metadata_Version_Field,
 		metadata_attempts Metadata_Attempts_Field,
@@ -2715,20 +3542,34 @@ type Methods interface {
 		optional PayoutGroup_Create_Fields) (
 		err error)
 
+	CreateNoReturn_SimulationResult(ctx context.Context,
+		simulation_result_payout_group_id SimulationResult_PayoutGroupId_Field,
+		simulation_result_payee SimulationResult_Payee_Field,
+		simulation_result_usd SimulationResult_Usd_Field,
+		simulation_result_tokens SimulationResult_Tokens_Field,
+		simulation_result_outcome SimulationResult_Outcome_Field,
+		optional SimulationResult_Create_Fields) (
+		err error)
+
 	Create_Transaction(ctx context.Context,
 		transaction_hash Transaction_Hash_Field,
 		transaction_owner Transaction_Owner_Field,
 		transaction_spender Transaction_Spender_Field,
 		transaction_nonce Transaction_Nonce_Field,
 		transaction_estimated_gas_price Transaction_EstimatedGasPrice_Field,
-		transaction_storj_price Transaction_StorjPrice_Field,
-		transaction_storj_tokens Transaction_StorjTokens_Field,
+		transaction_price Transaction_Price_Field,
+		transaction_tokens Transaction_Tokens_Field,
 		transaction_payout_group_id Transaction_PayoutGroupId_Field,
 		transaction_raw Transaction_Raw_Field,
 		transaction_state Transaction_State_Field,
 		optional Transaction_Create_Fields) (
 		transaction *Transaction, err error)
 
+	Find_LogClaim_By_TxHash_And_LogIndex(ctx context.Context,
+		log_claim_tx_hash LogClaim_TxHash_Field,
+		log_claim_log_index LogClaim_LogIndex_Field) (
+		log_claim *LogClaim, err error)
+
 	Find_PayoutGroup_By_Id(ctx context.Context,
 		payout_group_id PayoutGroup_Id_Field) (
 		payout_group *PayoutGroup, err error)
@@ -0,0 +1,16 @@
+package payoutdb
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+)
+
+// DeleteAllSimulationResults deletes the results of the last simulation.
+// DBX doesn't support deleting without a condition.
+func (tx *Tx) DeleteAllSimulationResults(ctx context.Context) error {
+	if _, err := tx.Tx.ExecContext(ctx, `DELETE FROM simulation_result`); err != nil {
+		return errs.Wrap(err)
+	}
+	return nil
+}
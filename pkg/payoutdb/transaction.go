@@ -0,0 +1,19 @@
+package payoutdb
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+)
+
+// NextNonce returns one more than the highest nonce of the transactions
+// signed by the spender, or zero if there are none. DBX doesn't support
+// selecting an aggregate.
+func (db *DB) NextNonce(ctx context.Context, spender string) (uint64, error) {
+	stmt := `SELECT COALESCE(MAX(nonce) + 1, 0) FROM tx WHERE spender = ?`
+	var nonce uint64
+	if err := db.DB.QueryRowContext(ctx, stmt, spender).Scan(&nonce); err != nil {
+		return 0, errs.Wrap(err)
+	}
+	return nonce, nil
+}
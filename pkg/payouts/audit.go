@@ -2,8 +2,10 @@ package payouts
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -12,6 +14,7 @@ import (
 	"storj.io/crypto-batch-payment/pkg/payer"
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
 	"storj.io/crypto-batch-payment/pkg/receipts"
+	"storj.io/crypto-batch-payment/pkg/safe"
 	"storj.io/crypto-batch-payment/pkg/zksyncera"
 
 	"storj.io/crypto-batch-payment/pkg/csv"
@@ -27,6 +30,7 @@ type AuditStats struct {
 	Total          int64
 	Confirmed      int64
 	FalseConfirmed int64
+	Reorged        int64
 	Overpaid       int64
 	Unstarted      int64
 	Pending        int64
@@ -39,9 +43,28 @@ type AuditStats struct {
 }
 
 func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type, nodeAddress string, chainID int, sink AuditSink, receiptsOut string, receiptsForce bool) (*AuditStats, error) {
+	// Load payouts from the CSV
+	rows, err := csv.Load(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	csvPayouts := FromCSV(rows)
+
+	// Load the database
+	sink.ReportStatusf("Loading database...")
+	dbDir, err := dbDirFromCSVPath(dir, csvPath)
+	if err != nil {
+		return nil, err
+	}
+	db, err := pipelinedb.OpenDB(ctx, DBPathFromDir(dbDir), true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
 	var auditor payer.Auditor
 	switch payerType {
-	case payer.Eth, payer.Polygon:
+	case payer.Eth, payer.Polygon, payer.Rollup, payer.Native:
 		client, err := ethclient.Dial(nodeAddress)
 		if err != nil {
 			return nil, errs.New("Failed to dial node %q: %v\n", nodeAddress, err)
@@ -51,6 +74,15 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 		if err != nil {
 			return nil, err
 		}
+	case payer.Safe:
+		// The safe auditor looks up the Safe transactions that executed
+		// the transfers in the database.
+		safeAuditor, err := safe.NewAuditor(nodeAddress, db)
+		if err != nil {
+			return nil, err
+		}
+		defer safeAuditor.Close()
+		auditor = safeAuditor
 	case payer.Sim:
 		auditor = payer.NewSimAuditor()
 	case payer.ZkSyncEra:
@@ -63,25 +95,6 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 		return nil, errs.New("unsupported auditor type: %v", payerType)
 	}
 
-	// Load payouts from the CSV
-	rows, err := csv.Load(csvPath)
-	if err != nil {
-		return nil, err
-	}
-	csvPayouts := FromCSV(rows)
-
-	// Load the database
-	sink.ReportStatusf("Loading database...")
-	dbDir, err := dbDirFromCSVPath(dir, csvPath)
-	if err != nil {
-		return nil, err
-	}
-	db, err := pipelinedb.OpenDB(ctx, DBPathFromDir(dbDir), true)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = db.Close() }()
-
 	// Load payout rows
 	sink.ReportStatusf("Fetching payouts...")
 	dbPayouts, err := db.FetchPayouts(ctx)
@@ -183,18 +196,23 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 		}
 
 		if tx.State == pipelinedb.TxDropped && state == pipelinedb.TxConfirmed {
-			sink.ReportErrorf("Double pay for payout group %d (tokens=%s)", tx.PayoutGroupID, tx.StorjTokens)
+			sink.ReportErrorf("Double pay for payout group %d (tokens=%s)", tx.PayoutGroupID, tx.Tokens)
 			stats.DoublePays++
-			stats.DoublePayStorj.Add(stats.DoublePayStorj, tx.StorjTokens)
+			stats.DoublePayStorj.Add(stats.DoublePayStorj, tx.Tokens)
 		} else {
 			sink.ReportWarnf("TX state mismatch on hash %q (db=%q, node=%q)", tx.Hash, tx.State, state)
 		}
 	}
 
+	finality, _ := auditor.(payer.FinalityChecker)
+	var reorged []*pipelinedb.Transaction
+
 	var receipts receipts.Buffer
 
 	// For each payout, ensure it belongs to a payout group with a confirmed
-	// transaction. Reconfirm the transaction against the blockchain.
+	// transaction. Reconfirm the transaction against the blockchain. All
+	// payouts in a payout group share the transaction, so the group is only
+	// checked once and every payout in it is receipted with the same hash.
 	sink.ReportStatusf("Checking payouts status...")
 	payoutGroupStatus := make(map[int64]string)
 	var payoutsConfirmed int64
@@ -218,9 +236,9 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 		if err != nil {
 			return nil, err
 		}
+		what := describePayoutGroup(dbPayout, numPayouts)
 		if len(txs) == 0 {
-			sink.ReportErrorf("Payout of %s to %s on line %d has no transactions",
-				dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine)
+			sink.ReportErrorf("%s has no transactions", what)
 			stats.Unstarted += numPayouts
 			continue
 		}
@@ -245,9 +263,8 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 		}
 
 		if len(confirmed) == 0 {
-			sink.ReportErrorf("Payout of %s to %s on line %d has no confirmed transactions (pending=%d dropped=%d failed=%d)",
-				dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine,
-				len(pending), len(dropped), len(failed))
+			sink.ReportErrorf("%s has no confirmed transactions (pending=%d dropped=%d failed=%d)",
+				what, len(pending), len(dropped), len(failed))
 			switch {
 			case len(pending) > 0:
 				stats.Pending += numPayouts
@@ -261,16 +278,30 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 			continue
 		}
 
-		var confirmedCount int
+		var confirmedCount, reorgedCount int
 		for _, tx := range confirmed {
+			if finality != nil && tx.BlockHash != nil {
+				canonical, err := finality.IsCanonical(ctx, tx.Hash, *tx.BlockHash)
+				if err != nil {
+					return nil, err
+				}
+				if !canonical {
+					sink.ReportErrorf("Transaction %s for %s is no longer in block %s on the canonical chain",
+						tx.Hash, lowerFirst(what), tx.BlockHash)
+					reorged = append(reorged, tx)
+					reorgedCount++
+					continue
+				}
+			}
+
 			state, err := auditor.CheckConfirmedTransactionState(ctx, tx.Hash)
 			switch {
 			case err != nil:
-				sink.ReportErrorf("Failed to get receipt for transaction %s for payout of %s to %s on line %d",
-					tx.Hash, dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine)
+				sink.ReportErrorf("Failed to get receipt for transaction %s for %s",
+					tx.Hash, lowerFirst(what))
 			case state != pipelinedb.TxConfirmed:
-				sink.ReportErrorf("Transaction %s was %s instead of confirmed for payout of %s to %s on line %d",
-					tx.Hash, state, dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine)
+				sink.ReportErrorf("Transaction %s was %s instead of confirmed for %s",
+					tx.Hash, state, lowerFirst(what))
 			default:
 				confirmedCount++
 			}
@@ -278,6 +309,11 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 
 		if confirmedCount > 0 {
 			txHash := confirmed[0].Hash
+			if payerType == payer.Safe && confirmed[0].Receipt != nil {
+				// Receipt the Safe transaction that executed the transfers
+				// rather than the hash of the transfers.
+				txHash = confirmed[0].Receipt.TxHash.String()
+			}
 			payoutGroupStatus[dbPayout.PayoutGroupID] = txHash
 			receipts.Emit(dbPayout.Payee, dbPayout.USD, txHash, payerType)
 			payoutsConfirmed += numPayouts
@@ -285,10 +321,11 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 
 		switch {
 		case confirmedCount > 1:
-			sink.ReportErrorf("Payout of %s to %s on line %d has more than one (%d) confirmed transactions recorded",
-				dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine,
-				len(confirmed))
+			sink.ReportErrorf("%s has more than one (%d) confirmed transactions recorded",
+				what, len(confirmed))
 			stats.Overpaid += numPayouts
+		case confirmedCount == 0 && reorgedCount > 0:
+			stats.Reorged += numPayouts
 		case confirmedCount == 0:
 			stats.FalseConfirmed += numPayouts
 		default:
@@ -296,6 +333,14 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 		}
 	}
 
+	// Reopen the payout groups of reorged transactions so that the next run
+	// verifies them again. The database is opened read-only for the audit.
+	if len(reorged) > 0 {
+		if err := reopenPayoutGroups(ctx, DBPathFromDir(dbDir), reorged, sink); err != nil {
+			return nil, err
+		}
+	}
+
 	// If all payout groups are confirmed and a receipts output has been
 	// configured then dump the receipts CSV.
 	switch {
@@ -313,3 +358,37 @@ func Audit(ctx context.Context, dir string, csvPath string, payerType payer.Type
 
 	return stats, nil
 }
+
+func reopenPayoutGroups(ctx context.Context, dbPath string, txs []*pipelinedb.Transaction, sink AuditSink) error {
+	db, err := pipelinedb.OpenDB(ctx, dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, tx := range txs {
+		if err := db.ReopenPayoutGroup(ctx, tx); err != nil {
+			return err
+		}
+		sink.ReportWarnf("Reopened payout group %d for re-verification; run the payout again to confirm it", tx.PayoutGroupID)
+	}
+	return nil
+}
+
+// describePayoutGroup describes the payout group the payout belongs to for
+// audit reporting. Single payout groups are described by the payout itself.
+func describePayoutGroup(dbPayout *pipelinedb.Payout, numPayouts int64) string {
+	if numPayouts <= 1 {
+		return fmt.Sprintf("Payout of %s to %s on line %d",
+			dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine)
+	}
+	return fmt.Sprintf("Payout group %d (%d payouts, including %s to %s on line %d)",
+		dbPayout.PayoutGroupID, numPayouts, dbPayout.USD, dbPayout.Payee.String(), dbPayout.CSVLine)
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -12,7 +12,14 @@ import (
 	"storj.io/crypto-batch-payment/pkg/csv"
 )
 
-func Import(ctx context.Context, dir string, csvPath string) error {
+// Import loads the payouts from the CSV into a new payout database. Payouts
+// are split into payout groups of up to groupSize payouts in CSV order. Each
+// payout group is paid out with a single transaction.
+func Import(ctx context.Context, dir string, csvPath string, groupSize int) error {
+	if groupSize < 1 {
+		return errs.New("group size must be at least 1; got %d", groupSize)
+	}
+
 	dbDir, err := dbDirFromCSVPath(dir, csvPath)
 	if err != nil {
 		return err
@@ -28,7 +35,7 @@ func Import(ctx context.Context, dir string, csvPath string) error {
 		return errs.Wrap(err)
 	}
 
-	if err := importPayouts(ctx, csvPath, dbDir); err != nil {
+	if err := importPayouts(ctx, csvPath, dbDir, groupSize); err != nil {
 		return err
 	}
 
@@ -45,7 +52,7 @@ func dbDirFromCSVPath(dir string, csvPath string) (string, error) {
 	return filepath.Join(dir, name), nil
 }
 
-func importPayouts(ctx context.Context, csvPath, dir string) error {
+func importPayouts(ctx context.Context, csvPath, dir string, groupSize int) error {
 	rows, err := csv.Load(csvPath)
 	if err != nil {
 		return err
@@ -68,7 +75,7 @@ func importPayouts(ctx context.Context, csvPath, dir string) error {
 		return errs.Wrap(err)
 	}
 
-	if err := createPayoutGroups(ctx, db, payouts); err != nil {
+	if err := createPayoutGroups(ctx, db, payouts, groupSize); err != nil {
 		return err
 	}
 
@@ -83,13 +90,17 @@ func importPayouts(ctx context.Context, csvPath, dir string) error {
 	return nil
 }
 
-func createPayoutGroups(ctx context.Context, db *pipelinedb.DB, payouts []*pipelinedb.Payout) error {
-	// Right now we only support a single transfer per transaction. Make a
-	// payout group for each row.
-	for i := range payouts {
-		if err := db.CreatePayoutGroup(ctx, int64(i+1), payouts[i:i+1]); err != nil {
+func createPayoutGroups(ctx context.Context, db *pipelinedb.DB, payouts []*pipelinedb.Payout, groupSize int) error {
+	// Make a payout group for every groupSize rows. The last group may be
+	// smaller.
+	var id int64
+	for len(payouts) > 0 {
+		n := min(groupSize, len(payouts))
+		id++
+		if err := db.CreatePayoutGroup(ctx, id, payouts[:n]); err != nil {
 			return err
 		}
+		payouts = payouts[n:]
 	}
 	return nil
 }
@@ -0,0 +1,330 @@
+package payouts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+var (
+	// cancelCheckInterval is how often CancelNonce checks whether the
+	// cancel transaction or the transaction it replaces has been mined.
+	cancelCheckInterval = 5 * time.Second
+)
+
+// NonceProblem describes why a nonce of the spender holds up the payout.
+type NonceProblem string
+
+const (
+	// NonceGap is a nonce below a nonce group of the spender that the node
+	// has no transaction for. The transactions with later nonces cannot be
+	// mined until it is filled.
+	NonceGap NonceProblem = "gap"
+
+	// NonceDropped is a nonce group whose transactions have all been dropped
+	// by the node while its nonce is still unused.
+	NonceDropped NonceProblem = "dropped"
+
+	// NonceReplaced is a nonce group whose transactions have all been
+	// dropped by the node because another transaction took its nonce,
+	// like one sent by another tool.
+	NonceReplaced NonceProblem = "replaced"
+)
+
+// NonceSlot is a nonce of the spender that is either used by a nonce group
+// in the database or is a gap.
+type NonceSlot struct {
+	Nonce uint64
+
+	// NonceGroup is the nonce group in the database with the nonce. It is
+	// nil for gaps.
+	NonceGroup *pipelinedb.NonceGroup
+
+	// Statuses are the statuses of the transactions of the nonce group
+	// according to the node.
+	Statuses []*pipelinedb.TxStatus
+
+	// Problem is empty unless the nonce holds up the payout.
+	Problem NonceProblem
+}
+
+// NonceStatus compares the nonces of the spender on chain to the nonce
+// groups in the database.
+type NonceStatus struct {
+	Spender common.Address
+
+	// Latest is the next nonce according to the latest block.
+	Latest uint64
+
+	// Pending is the next nonce according to the node, counting the
+	// transactions in its pool that are ready to be mined.
+	Pending uint64
+
+	// Slots are the unfinished nonce groups of the spender and the gaps
+	// between them, sorted by nonce.
+	Slots []NonceSlot
+}
+
+// FetchNonceStatus compares the nonces of the spender of the payer on chain
+// to the unfinished nonce groups in the database.
+func FetchNonceStatus(ctx context.Context, db *pipelinedb.DB, p *eth.Payer) (*NonceStatus, error) {
+	latest, pending, err := p.Nonces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceGroups, err := db.FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &NonceStatus{
+		Spender: p.From(),
+		Latest:  latest,
+		Pending: pending,
+	}
+
+	// Nonce groups are sorted by nonce. Every nonce from the pending nonce
+	// up to the last nonce group must be filled for the nonce group to be
+	// mined.
+	next := pending
+	for _, nonceGroup := range nonceGroups {
+		if nonceGroup.Spender != status.Spender {
+			continue
+		}
+		for ; next < nonceGroup.Nonce; next++ {
+			status.Slots = append(status.Slots, NonceSlot{Nonce: next, Problem: NonceGap})
+		}
+		if nonceGroup.Nonce >= next {
+			next = nonceGroup.Nonce + 1
+		}
+
+		slot := NonceSlot{
+			Nonce:      nonceGroup.Nonce,
+			NonceGroup: nonceGroup,
+		}
+		allDropped := true
+		for _, tx := range nonceGroup.Txs {
+			txStatus, err := p.TransactionStatus(ctx, tx.Hash)
+			if err != nil {
+				return nil, err
+			}
+			slot.Statuses = append(slot.Statuses, txStatus)
+			if txStatus.State != pipelinedb.TxDropped {
+				allDropped = false
+			}
+		}
+		switch {
+		case !allDropped:
+		case nonceGroup.Nonce < pending:
+			slot.Problem = NonceReplaced
+		default:
+			slot.Problem = NonceDropped
+		}
+		status.Slots = append(status.Slots, slot)
+	}
+	return status, nil
+}
+
+// PrintNonceStatus prints the nonce status in a human readable form.
+func PrintNonceStatus(w io.Writer, status *NonceStatus) error {
+	fmt.Fprintf(w, "Spender .......: %s\n", status.Spender)
+	fmt.Fprintf(w, "Chain nonce ...: %d\n", status.Latest)
+	fmt.Fprintf(w, "Pending nonce .: %d\n", status.Pending)
+	if len(status.Slots) == 0 {
+		fmt.Fprintln(w, "No unfinished nonce groups.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NONCE\tPAYOUT GROUP\tPROBLEM\tTRANSACTIONS")
+	for _, slot := range status.Slots {
+		payoutGroup := "-"
+		if slot.NonceGroup != nil {
+			payoutGroup = fmt.Sprint(slot.NonceGroup.PayoutGroupID)
+		}
+		problem := "-"
+		if slot.Problem != "" {
+			problem = string(slot.Problem)
+		}
+		var txs []string
+		for _, txStatus := range slot.Statuses {
+			txs = append(txs, fmt.Sprintf("%s (%s)", txStatus.Hash, txStatus.State))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", slot.Nonce, payoutGroup, problem, strings.Join(txs, ", "))
+	}
+	return errs.Wrap(tw.Flush())
+}
+
+// FillNonceGaps fixes the nonces that hold up the payout. Gaps are filled
+// with cancel transactions. Dropped nonce groups have their last transaction
+// sent again. Nonce groups replaced by a mined transaction are marked as
+// dropped so that their payout groups are sent again with a new nonce.
+// Nonce groups replaced by a transaction that is still pending are left
+// alone until it is mined.
+func FillNonceGaps(ctx context.Context, log *zap.Logger, db *pipelinedb.DB, p *eth.Payer, status *NonceStatus, promptConfirm func(label string) error) (filled int, err error) {
+	var slots []NonceSlot
+	for _, slot := range status.Slots {
+		if slot.Problem == NonceReplaced && slot.Nonce >= status.Latest {
+			log.Info("Nonce group replaced by a pending transaction; waiting for it to be mined",
+				zap.Uint64("nonce", slot.Nonce),
+				zap.Int64("payout-group-id", slot.NonceGroup.PayoutGroupID))
+			continue
+		}
+		if slot.Problem != "" {
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) == 0 {
+		return 0, nil
+	}
+
+	if err := promptConfirm(fmt.Sprintf("Fix %d nonces", len(slots))); err != nil {
+		return 0, err
+	}
+
+	for _, slot := range slots {
+		log := log.With(zap.Uint64("nonce", slot.Nonce))
+		switch slot.Problem {
+		case NonceGap:
+			tx, err := p.CreateCancelTransaction(ctx, log, slot.Nonce, nil)
+			if err != nil {
+				return filled, err
+			}
+			if err := p.SendTransaction(ctx, log, payer.Transaction{Hash: tx.Hash().String(), Nonce: slot.Nonce, Raw: tx}); err != nil {
+				return filled, errs.New("unable to fill nonce %d: %v", slot.Nonce, err)
+			}
+			log.Info("Filled nonce gap", zap.String("hash", tx.Hash().String()))
+		case NonceDropped:
+			last := slot.NonceGroup.Txs[len(slot.NonceGroup.Txs)-1]
+			if err := p.ResendTransaction(ctx, last); err != nil {
+				return filled, errs.New("unable to resend transaction %s with nonce %d: %v", last.Hash, slot.Nonce, err)
+			}
+			log.Info("Resent dropped transaction", zap.String("hash", last.Hash))
+		case NonceReplaced:
+			if err := requeueNonceGroup(ctx, db, slot.NonceGroup); err != nil {
+				return filled, err
+			}
+			log.Info("Nonce used by another transaction; payout group will be sent again",
+				zap.Int64("payout-group-id", slot.NonceGroup.PayoutGroupID))
+		}
+		filled++
+	}
+	return filled, nil
+}
+
+// CancelNonce replaces the transaction pending with the given nonce with a
+// cancel transaction. If the nonce belongs to a nonce group in the database,
+// it waits until either the cancel transaction or one of the transactions
+// of the nonce group is confirmed. If the cancel transaction wins, the
+// payout group is sent again with a new nonce on the next run.
+func CancelNonce(ctx context.Context, log *zap.Logger, db *pipelinedb.DB, p *eth.Payer, nonce uint64, promptConfirm func(label string) error) error {
+	log = log.With(zap.Uint64("nonce", nonce))
+
+	latest, _, err := p.Nonces(ctx)
+	if err != nil {
+		return err
+	}
+	if nonce < latest {
+		return errs.New("nonce %d has already been used; the next nonce is %d", nonce, latest)
+	}
+
+	nonceGroups, err := db.FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx)
+	if err != nil {
+		return err
+	}
+	var nonceGroup *pipelinedb.NonceGroup
+	for _, ng := range nonceGroups {
+		if ng.Spender == p.From() && ng.Nonce == nonce {
+			nonceGroup = ng
+			break
+		}
+	}
+
+	var previous *pipelinedb.Transaction
+	label := fmt.Sprintf("Cancel nonce %d", nonce)
+	if nonceGroup != nil {
+		previous = &nonceGroup.Txs[len(nonceGroup.Txs)-1]
+		label = fmt.Sprintf("Cancel nonce %d of payout group %d", nonce, nonceGroup.PayoutGroupID)
+	}
+
+	tx, err := p.CreateCancelTransaction(ctx, log, nonce, previous)
+	if err != nil {
+		return err
+	}
+	if err := promptConfirm(label); err != nil {
+		return err
+	}
+
+	if err := p.SendTransaction(ctx, log, payer.Transaction{Hash: tx.Hash().String(), Nonce: nonce, Raw: tx}); err != nil {
+		return errs.New("unable to send cancel transaction: %v", err)
+	}
+	log.Info("Sent cancel transaction", zap.String("hash", tx.Hash().String()))
+	if nonceGroup == nil {
+		return nil
+	}
+
+	// Check the cancel transaction along with the transactions it replaces,
+	// since any of them may end up being mined.
+	check := &pipelinedb.NonceGroup{
+		Nonce:         nonce,
+		Spender:       nonceGroup.Spender,
+		PayoutGroupID: nonceGroup.PayoutGroupID,
+		Txs:           append(append([]pipelinedb.Transaction(nil), nonceGroup.Txs...), pipelinedb.Transaction{Hash: tx.Hash().String()}),
+	}
+	for {
+		state, statuses, err := p.CheckNonceGroup(ctx, log, check, false)
+		if err != nil {
+			return err
+		}
+		if state == pipelinedb.TxDropped {
+			return errs.New("cancel transaction %s was dropped by the node", tx.Hash())
+		}
+		if state == pipelinedb.TxConfirmed || state == pipelinedb.TxFailed {
+			for _, status := range statuses {
+				if status.State == pipelinedb.TxDropped {
+					continue
+				}
+				if status.Hash != tx.Hash().String() {
+					return errs.New("transaction %s of payout group %d was mined before the cancel transaction; the payout group will be finalized on the next run",
+						status.Hash, nonceGroup.PayoutGroupID)
+				}
+			}
+			if err := requeueNonceGroup(ctx, db, nonceGroup); err != nil {
+				return err
+			}
+			log.Info("Nonce cancelled; payout group will be sent again",
+				zap.Int64("payout-group-id", nonceGroup.PayoutGroupID))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errs.New("cancel transaction %s not confirmed yet (%v); check again with the nonce fill-gaps command", tx.Hash(), ctx.Err())
+		case <-time.After(cancelCheckInterval):
+		}
+	}
+}
+
+// requeueNonceGroup marks the transactions of a nonce group whose nonce was
+// used by another transaction as dropped, so that the payout group is sent
+// again with a new nonce.
+func requeueNonceGroup(ctx context.Context, db *pipelinedb.DB, nonceGroup *pipelinedb.NonceGroup) error {
+	for _, tx := range nonceGroup.Txs {
+		if err := db.UpdateTransactionState(ctx, tx.Hash, pipelinedb.TxDropped); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package payouts
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/ethtest"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+var (
+	// deployer is the account that deploys the STORJ contract, so that the
+	// nonces of the spender start at 0.
+	deployer = ethtest.NewAccount()
+
+	// spender is the account paying out the STORJ it was granted when the
+	// contract was deployed.
+	spender = ethtest.NewAccount()
+
+	alice = ethtest.NewAccount()
+	bob   = ethtest.NewAccount()
+)
+
+func TestNonceStatusDetectsGaps(t *testing.T) {
+	test := newNonceTest(t, 2)
+
+	// Nonce 0 was never sent, nonce 1 is waiting for it in the pool and
+	// nonce 3 was dropped by the node.
+	test.createTransaction(1, 1, true)
+	test.createTransaction(2, 3, false)
+
+	status, err := FetchNonceStatus(test.ctx, test.db, test.payer)
+	require.NoError(t, err)
+	require.Equal(t, spender.Address, status.Spender)
+	require.Equal(t, uint64(0), status.Latest)
+	require.Equal(t, uint64(0), status.Pending)
+
+	require.Len(t, status.Slots, 4)
+	requireSlot(t, status.Slots[0], 0, 0, NonceGap)
+	requireSlot(t, status.Slots[1], 1, 1, "")
+	requireSlot(t, status.Slots[2], 2, 0, NonceGap)
+	requireSlot(t, status.Slots[3], 3, 2, NonceDropped)
+	require.Equal(t, pipelinedb.TxPending, status.Slots[1].Statuses[0].State)
+	require.Equal(t, pipelinedb.TxDropped, status.Slots[3].Statuses[0].State)
+}
+
+func TestFillNonceGaps(t *testing.T) {
+	test := newNonceTest(t, 2)
+
+	gapped := test.createTransaction(1, 1, true)
+	dropped := test.createTransaction(2, 3, false)
+
+	status, err := FetchNonceStatus(test.ctx, test.db, test.payer)
+	require.NoError(t, err)
+
+	var labels []string
+	filled, err := FillNonceGaps(test.ctx, test.log, test.db, test.payer, status, func(label string) error {
+		labels = append(labels, label)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, filled)
+	require.Equal(t, []string{"Fix 3 nonces"}, labels)
+
+	// The gaps are filled with cancel transactions and the dropped
+	// transaction is sent again, so all of them are mined.
+	test.backend.Commit()
+	latest, pending, err := test.payer.Nonces(test.ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), latest)
+	require.Equal(t, uint64(4), pending)
+	test.requireMined(gapped.Hash)
+	test.requireMined(dropped.Hash)
+
+	// Only the payouts are transferred.
+	test.requireTokens(alice.Address, 200_000_000)
+	test.requireTokens(bob.Address, 200_000_000)
+
+	status, err = FetchNonceStatus(test.ctx, test.db, test.payer)
+	require.NoError(t, err)
+	requireSlot(t, status.Slots[0], 1, 1, "")
+	requireSlot(t, status.Slots[1], 3, 2, "")
+}
+
+func TestFillNonceGapsRequeuesReplacedNonceGroups(t *testing.T) {
+	test := newNonceTest(t, 1)
+
+	// The nonce of the payout group is used up by a transaction of another
+	// tool, which is still pending at first.
+	replaced := test.createTransaction(1, 0, false)
+	other, err := test.payer.CreateCancelTransaction(test.ctx, test.log, 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, test.client.SendTransaction(test.ctx, other))
+
+	status, err := FetchNonceStatus(test.ctx, test.db, test.payer)
+	require.NoError(t, err)
+	require.Len(t, status.Slots, 1)
+	requireSlot(t, status.Slots[0], 0, 1, NonceReplaced)
+
+	// The payout group waits for the other transaction to be mined, in
+	// case the node drops it.
+	filled, err := FillNonceGaps(test.ctx, test.log, test.db, test.payer, status, failPrompt(t))
+	require.NoError(t, err)
+	require.Zero(t, filled)
+	test.requireState(replaced.Hash, pipelinedb.TxPending)
+
+	test.backend.Commit()
+	status, err = FetchNonceStatus(test.ctx, test.db, test.payer)
+	require.NoError(t, err)
+	requireSlot(t, status.Slots[0], 0, 1, NonceReplaced)
+
+	filled, err = FillNonceGaps(test.ctx, test.log, test.db, test.payer, status, func(string) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, 1, filled)
+
+	// The payout group is sent again with a new nonce on the next run.
+	test.requireState(replaced.Hash, pipelinedb.TxDropped)
+	nonceGroups, err := test.db.FetchUnfinishedTransactionsSortedIntoNonceGroups(test.ctx)
+	require.NoError(t, err)
+	require.Empty(t, nonceGroups)
+	payoutGroup, err := test.db.FetchFirstUnfinishedUnattachedPayoutGroup(test.ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), payoutGroup.ID)
+}
+
+func TestCreateCancelTransaction(t *testing.T) {
+	test := newNonceTest(t, 1)
+
+	// Without a transaction to replace, the fees are the suggested ones.
+	cancel, err := test.payer.CreateCancelTransaction(test.ctx, test.log, 5, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), cancel.Nonce())
+	require.Equal(t, spender.Address, *cancel.To())
+	require.Zero(t, cancel.Value().Sign())
+	require.Empty(t, cancel.Data())
+	require.Equal(t, params.TxGas, cancel.Gas())
+	require.LessOrEqual(t, cancel.GasFeeCap().Cmp(test.maxGas), 0)
+
+	// Replacing a transaction bumps its fees by at least 10%.
+	pending := test.createTransaction(1, 0, true)
+	var previous types.Transaction
+	require.NoError(t, previous.UnmarshalJSON(pending.Raw))
+	cancel, err = test.payer.CreateCancelTransaction(test.ctx, test.log, 0, pending)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cancel.Nonce())
+	require.True(t, isBumped(cancel.GasTipCap(), previous.GasTipCap()))
+	require.True(t, isBumped(cancel.GasFeeCap(), previous.GasFeeCap()))
+
+	// The node accepts the cancel transaction as a replacement.
+	require.NoError(t, test.client.SendTransaction(test.ctx, cancel))
+	test.backend.Commit()
+	test.requireMined(cancel.Hash().String())
+	test.requireTokens(alice.Address, 0)
+}
+
+func TestCancelNonce(t *testing.T) {
+	defer func(interval time.Duration) { cancelCheckInterval = interval }(cancelCheckInterval)
+	cancelCheckInterval = 10 * time.Millisecond
+
+	t.Run("replaces pending nonce group", func(t *testing.T) {
+		test := newNonceTest(t, 1)
+		pending := test.createTransaction(1, 0, true)
+
+		var labels []string
+		err := test.cancelNonce(0, pending.Hash, func(label string) error {
+			labels = append(labels, label)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Cancel nonce 0 of payout group 1"}, labels)
+
+		// The payout group is sent again with a new nonce on the next run.
+		test.requireState(pending.Hash, pipelinedb.TxDropped)
+		test.requireTokens(alice.Address, 0)
+		latest, _, err := test.payer.Nonces(test.ctx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), latest)
+	})
+
+	t.Run("cancels nonce without nonce group", func(t *testing.T) {
+		test := newNonceTest(t, 1)
+
+		var labels []string
+		err := CancelNonce(test.ctx, test.log, test.db, test.payer, 0, func(label string) error {
+			labels = append(labels, label)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Cancel nonce 0"}, labels)
+
+		test.backend.Commit()
+		latest, _, err := test.payer.Nonces(test.ctx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), latest)
+	})
+
+	t.Run("refuses used nonce", func(t *testing.T) {
+		test := newNonceTest(t, 1)
+		mined := test.createTransaction(1, 0, true)
+		test.backend.Commit()
+
+		err := CancelNonce(test.ctx, test.log, test.db, test.payer, 0, failPrompt(t))
+		require.EqualError(t, err, "nonce 0 has already been used; the next nonce is 1")
+		test.requireState(mined.Hash, pipelinedb.TxPending)
+	})
+
+	t.Run("aborted by prompt", func(t *testing.T) {
+		test := newNonceTest(t, 1)
+		pending := test.createTransaction(1, 0, true)
+
+		err := CancelNonce(test.ctx, test.log, test.db, test.payer, 0, func(string) error {
+			return errs.New("aborted")
+		})
+		require.EqualError(t, err, "aborted")
+
+		test.backend.Commit()
+		test.requireMined(pending.Hash)
+		test.requireState(pending.Hash, pipelinedb.TxPending)
+	})
+}
+
+type nonceTest struct {
+	t       *testing.T
+	ctx     context.Context
+	log     *zap.Logger
+	db      *pipelinedb.DB
+	backend *simulated.Backend
+	client  simulated.Client
+	token   *contract.Token
+	payer   *eth.Payer
+	maxGas  *big.Int
+}
+
+// newNonceTest returns a test with the given number of payout groups, each
+// paying out 2 USD to alice and bob in turn.
+func newNonceTest(t *testing.T, payoutGroups int) *nonceTest {
+	ctx := context.Background()
+
+	db, err := pipelinedb.NewDB(ctx, filepath.Join(t.TempDir(), "payouts.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	payees := []common.Address{alice.Address, bob.Address}
+	for id := int64(1); id <= int64(payoutGroups); id++ {
+		require.NoError(t, db.CreatePayoutGroup(ctx, id, []*pipelinedb.Payout{{
+			Payee: payees[(id-1)%2],
+			USD:   decimal.RequireFromString("2"),
+		}}))
+	}
+
+	initialBalance, _ := new(big.Int).SetString("900000000000000000", 10)
+	alloc := core.DefaultGenesisBlock().Alloc
+	alloc[deployer.Address] = types.Account{Balance: initialBalance}
+	alloc[spender.Address] = types.Account{Balance: initialBalance}
+	backend := simulated.NewBackend(alloc, simulated.WithMinerMinTip(big.NewInt(1)))
+	t.Cleanup(func() { _ = backend.Close() })
+	client := backend.Client()
+
+	auth, err := bind.NewKeyedTransactorWithChainID(deployer.Key, big.NewInt(1337))
+	require.NoError(t, err)
+	tokenAddress, _, token, err := contract.DeployToken(auth, client, spender.Address, "Storj", "STORJ", big.NewInt(1e11), big.NewInt(8))
+	require.NoError(t, err)
+	backend.Commit()
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+	maxGas := new(big.Int).Mul(head.BaseFee, big.NewInt(3))
+
+	p, err := eth.NewPayer(ctx, client, tokenAddress, spender.Address,
+		eth.NewKeySigner(spender.Key, big.NewInt(1337)), nil, maxGas, nil, 0)
+	require.NoError(t, err)
+	// Leave room under the max gas price to cancel the transactions, like
+	// a run with --replace-after does.
+	p.EnableReplacement()
+
+	return &nonceTest{
+		t:       t,
+		ctx:     ctx,
+		log:     zaptest.NewLogger(t),
+		db:      db,
+		backend: backend,
+		client:  client,
+		token:   token,
+		payer:   p,
+		maxGas:  maxGas,
+	}
+}
+
+// createTransaction records a transaction paying out the payout group with
+// the given nonce at a STORJ price of 1 USD, like the pipeline does, and
+// sends it to the node if send is true.
+func (test *nonceTest) createTransaction(payoutGroupID int64, nonce uint64, send bool) *pipelinedb.Transaction {
+	payouts, err := test.db.FetchPayoutGroupPayouts(test.ctx, payoutGroupID)
+	require.NoError(test.t, err)
+
+	rawTx, from, err := test.payer.CreateRawTransaction(test.ctx, test.log, payouts, nonce, decimal.NewFromInt(1))
+	require.NoError(test.t, err)
+	rawTxJSON, err := json.Marshal(rawTx.Raw)
+	require.NoError(test.t, err)
+
+	tx, err := test.db.CreateTransaction(test.ctx, pipelinedb.Transaction{
+		PayoutGroupID: payoutGroupID,
+		Hash:          rawTx.Hash,
+		Nonce:         rawTx.Nonce,
+		Owner:         spender.Address,
+		Spender:       from,
+		Price:         decimal.NewFromInt(1),
+		Tokens:        big.NewInt(200_000_000),
+		Raw:           rawTxJSON,
+	})
+	require.NoError(test.t, err)
+
+	if send {
+		require.NoError(test.t, test.payer.SendTransaction(test.ctx, test.log, rawTx))
+	}
+	return tx
+}
+
+// cancelNonce cancels the nonce of the pending transaction, mining a block
+// once the node replaced the transaction with the cancel transaction.
+func (test *nonceTest) cancelNonce(nonce uint64, replaced string, promptConfirm func(label string) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- CancelNonce(test.ctx, test.log, test.db, test.payer, nonce, promptConfirm)
+	}()
+
+	mined := false
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Millisecond):
+		}
+		if mined {
+			continue
+		}
+		status, err := test.payer.TransactionStatus(test.ctx, replaced)
+		require.NoError(test.t, err)
+		if status.State == pipelinedb.TxDropped {
+			test.backend.Commit()
+			mined = true
+		}
+	}
+}
+
+func (test *nonceTest) requireMined(hash string) {
+	status, err := test.payer.TransactionStatus(test.ctx, hash)
+	require.NoError(test.t, err)
+	require.Equal(test.t, pipelinedb.TxConfirmed, status.State, hash)
+}
+
+func (test *nonceTest) requireState(hash string, state pipelinedb.TxState) {
+	tx, err := test.db.FetchTransaction(test.ctx, common.HexToHash(hash))
+	require.NoError(test.t, err)
+	require.Equal(test.t, state, tx.State, hash)
+}
+
+func (test *nonceTest) requireTokens(address common.Address, expected int64) {
+	balance, err := test.token.BalanceOf(nil, address)
+	require.NoError(test.t, err)
+	require.Equal(test.t, big.NewInt(expected).String(), balance.String())
+}
+
+func requireSlot(t *testing.T, slot NonceSlot, nonce uint64, payoutGroupID int64, problem NonceProblem) {
+	require.Equal(t, nonce, slot.Nonce)
+	require.Equal(t, problem, slot.Problem)
+	if payoutGroupID == 0 {
+		require.Nil(t, slot.NonceGroup)
+		require.Empty(t, slot.Statuses)
+		return
+	}
+	require.Equal(t, payoutGroupID, slot.NonceGroup.PayoutGroupID)
+	require.Len(t, slot.Statuses, len(slot.NonceGroup.Txs))
+}
+
+// failPrompt returns a confirmation prompt that fails the test if called.
+func failPrompt(t *testing.T) func(label string) error {
+	return func(label string) error {
+		require.FailNow(t, "unexpected prompt", label)
+		return nil
+	}
+}
+
+// isBumped returns whether the fee is at least 10% above the previous one.
+func isBumped(fee, previous *big.Int) bool {
+	return new(big.Int).Mul(fee, big.NewInt(10)).Cmp(new(big.Int).Mul(previous, big.NewInt(11))) >= 0
+}
@@ -0,0 +1,559 @@
+package payouts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	batchpayment "storj.io/crypto-batch-payment/pkg"
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+// OfflinePlanVersion is the version of the offline plan file format.
+const OfflinePlanVersion = 2
+
+// OfflinePlan holds the transactions paying out a batch of payout groups,
+// planned on a host with access to the payout database and the node, to be
+// signed on an offline host holding the spender key and then broadcast
+// again from the first host.
+type OfflinePlan struct {
+	Version       int                   `json:"version"`
+	ChainID       *big.Int              `json:"chainId"`
+	Spender       common.Address        `json:"spender"`
+	Owner         common.Address        `json:"owner"`
+	Token         common.Address        `json:"token"`
+	TokenDecimals int32                 `json:"tokenDecimals"`
+	Disperse      *common.Address       `json:"disperse,omitempty"`
+	Symbol        string                `json:"symbol"`
+	Price         decimal.Decimal       `json:"price"`
+	Transactions  []*OfflineTransaction `json:"transactions"`
+}
+
+// OfflineTransaction is the transaction paying out a payout group. Signed
+// is empty until the plan is signed.
+type OfflineTransaction struct {
+	PayoutGroupID int64              `json:"payoutGroupId"`
+	Payouts       []OfflinePayout    `json:"payouts"`
+	Tokens        *big.Int           `json:"tokens"`
+	Unsigned      *types.Transaction `json:"unsigned"`
+	Signed        hexutil.Bytes      `json:"signed,omitempty"`
+}
+
+// OfflinePayout is a payout paid by an offline transaction.
+type OfflinePayout struct {
+	Payee  common.Address  `json:"payee"`
+	USD    decimal.Decimal `json:"usd"`
+	Tokens *big.Int        `json:"tokens"`
+}
+
+// ReadOfflinePlan reads an offline plan from a file.
+func ReadOfflinePlan(path string) (*OfflinePlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	plan := new(OfflinePlan)
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, errs.New("unable to decode offline plan %q: %v", path, err)
+	}
+	if plan.Version != OfflinePlanVersion {
+		return nil, errs.New("unsupported offline plan version %d; expected %d", plan.Version, OfflinePlanVersion)
+	}
+	if plan.ChainID == nil {
+		return nil, errs.New("offline plan %q has no chain ID", path)
+	}
+	if plan.Symbol == "" {
+		return nil, errs.New("offline plan %q has no symbol", path)
+	}
+	for _, tx := range plan.Transactions {
+		if tx.Unsigned == nil || tx.Tokens == nil {
+			return nil, errs.New("offline plan %q has an incomplete transaction for payout group %d", path, tx.PayoutGroupID)
+		}
+	}
+	return plan, nil
+}
+
+// WriteOfflinePlan writes the offline plan to a file. It does not overwrite
+// an existing file.
+func WriteOfflinePlan(path string, plan *OfflinePlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return errs.Wrap(err)
+	}
+	return errs.Wrap(f.Close())
+}
+
+// PlanOffline plans the unsigned transactions for the next payout groups to
+// send, up to count of them, starting at the pending nonce of the spender.
+// The spend limits, the balance of the owner and the allowance are checked
+// as if the transactions were sent. The price is locked after
+// confirmation if it has not been locked yet, since the planned amounts
+// depend on it.
+func PlanOffline(ctx context.Context, log *zap.Logger, config Config, db *pipelinedb.DB, p *eth.Payer, chainID *big.Int, count int) (*OfflinePlan, error) {
+	spender := p.From()
+	if err := checkSpenderIdle(ctx, db, spender, nil); err != nil {
+		return nil, err
+	}
+
+	priceLock, locked, err := lockPrice(ctx, config, db)
+	if err != nil {
+		return nil, err
+	}
+
+	decimals, err := p.GetTokenDecimals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, nonce, err := p.Nonces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spent, err := db.FetchSpendTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payoutGroups, err := db.FetchUnfinishedUnattachedPayoutGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(payoutGroups) > count {
+		payoutGroups = payoutGroups[:count]
+	}
+	if len(payoutGroups) == 0 {
+		return nil, errs.New("no payout groups left to send")
+	}
+
+	plan := &OfflinePlan{
+		Version:       OfflinePlanVersion,
+		ChainID:       chainID,
+		Spender:       spender,
+		Owner:         p.Owner(),
+		Token:         p.TokenAddress(),
+		TokenDecimals: decimals,
+		Disperse:      p.DisperseAddress(),
+		Symbol:        string(config.symbol()),
+		Price:         priceLock.Price,
+	}
+
+	transferred := new(big.Int)
+	for _, payoutGroup := range payoutGroups {
+		payouts, err := db.FetchPayoutGroupPayouts(ctx, payoutGroup.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &OfflineTransaction{
+			PayoutGroupID: payoutGroup.ID,
+			Tokens:        new(big.Int),
+		}
+		for _, payout := range payouts {
+			payoutTokens := storjtoken.FromUSD(payout.USD, priceLock.Price, decimals)
+			if payoutTokens.Sign() <= 0 {
+				return nil, errs.New("cannot transfer %s tokens for payout group %d: must be more than zero", payoutTokens, payoutGroup.ID)
+			}
+			tx.Payouts = append(tx.Payouts, OfflinePayout{
+				Payee:  payout.Payee,
+				USD:    payout.USD,
+				Tokens: payoutTokens,
+			})
+			tx.Tokens.Add(tx.Tokens, payoutTokens)
+		}
+
+		// Payout groups that have been sent before are already part of the
+		// totals.
+		if _, ok := spent.PayoutGroups[payoutGroup.ID]; !ok {
+			if err := config.SpendLimits.Check(spent, payoutGroup.ID, payouts, tx.Tokens, decimals, config.symbol()); err != nil {
+				return nil, err
+			}
+			spent.Add(payoutGroup.ID, payouts, tx.Tokens)
+		}
+
+		results, err := p.SimulatePayoutGroup(ctx, payouts, priceLock.Price, transferred)
+		if err != nil {
+			return nil, errs.New("unable to simulate payout group %d: %v", payoutGroup.ID, err)
+		}
+		for _, result := range results {
+			if result.Outcome != pipelinedb.SimulationOK {
+				return nil, errs.New("transfer to %s in payout group %d would fail: %s", result.Payee, payoutGroup.ID, result.Reason)
+			}
+		}
+		transferred.Add(transferred, tx.Tokens)
+
+		txLog := log.With(
+			zap.Uint64("nonce", nonce),
+			zap.Int64("payout-group-id", payoutGroup.ID),
+			zap.String("price", priceLock.Price.String()),
+			zap.String("tokens", tx.Tokens.String()))
+		tx.Unsigned, err = p.CreateUnsignedTransaction(ctx, txLog, payouts, nonce, priceLock.Price, chainID)
+		if err != nil {
+			return nil, err
+		}
+		plan.Transactions = append(plan.Transactions, tx)
+		nonce++
+	}
+
+	if err := printOfflinePlan(plan); err != nil {
+		return nil, err
+	}
+	if err := config.PromptConfirm(fmt.Sprintf("Plan %d transactions", len(plan.Transactions))); err != nil {
+		return nil, err
+	}
+
+	if !locked {
+		if priceLock.Source == pipelinedb.PriceSourceOperator {
+			if err := config.PromptConfirm(fmt.Sprintf("Lock operator provided %s price at $%s", config.symbol(), priceLock.Price)); err != nil {
+				return nil, err
+			}
+		}
+		if err := db.LockPrice(ctx, *priceLock); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// SignOfflinePlan checks that each transaction of the plan pays out its
+// payouts and nothing else, then signs them after confirmation. It needs
+// neither the payout database nor the node.
+func SignOfflinePlan(ctx context.Context, plan *OfflinePlan, signer eth.Signer, promptConfirm func(label string) error) error {
+	if signer.Address() != plan.Spender {
+		return errs.New("plan is for spender %s but the key is for %s", plan.Spender, signer.Address())
+	}
+	if len(plan.Transactions) == 0 {
+		return errs.New("plan has no transactions")
+	}
+
+	for i, tx := range plan.Transactions {
+		if len(tx.Signed) > 0 {
+			return errs.New("transaction for payout group %d is already signed", tx.PayoutGroupID)
+		}
+		if err := checkOfflineTransaction(plan, tx); err != nil {
+			return err
+		}
+		if i > 0 && tx.Unsigned.Nonce() != plan.Transactions[i-1].Unsigned.Nonce()+1 {
+			return errs.New("transaction for payout group %d has nonce %d; expected %d",
+				tx.PayoutGroupID, tx.Unsigned.Nonce(), plan.Transactions[i-1].Unsigned.Nonce()+1)
+		}
+	}
+
+	if err := printOfflinePlan(plan); err != nil {
+		return err
+	}
+	if err := promptConfirm(fmt.Sprintf("Sign %d transactions", len(plan.Transactions))); err != nil {
+		return err
+	}
+
+	for _, tx := range plan.Transactions {
+		signed, err := signer.SignTransaction(ctx, tx.Unsigned)
+		if err != nil {
+			return errs.New("unable to sign transaction for payout group %d: %v", tx.PayoutGroupID, err)
+		}
+		tx.Signed, err = signed.MarshalBinary()
+		if err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// BroadcastOfflinePlan records the signed transactions of the plan in the
+// payout database and sends them, after checking that they are signed by
+// the spender, that their payout groups have not been sent since the plan
+// was made and that the nonces still follow on from the pending nonce of
+// the spender. Transactions already recorded by an earlier broadcast of the
+// plan are skipped, or sent again if the node has dropped them. The
+// transactions are then tracked by running the pipeline in drain mode.
+func BroadcastOfflinePlan(ctx context.Context, log *zap.Logger, config Config, db *pipelinedb.DB, p *eth.Payer, chainID *big.Int, plan *OfflinePlan) error {
+	switch {
+	case plan.ChainID.Cmp(chainID) != 0:
+		return errs.New("plan is for chain %s; expected %s", plan.ChainID, chainID)
+	case plan.Spender != p.From():
+		return errs.New("plan is for spender %s; expected %s", plan.Spender, p.From())
+	case plan.Owner != p.Owner():
+		return errs.New("plan is for owner %s; expected %s", plan.Owner, p.Owner())
+	case plan.Token != p.TokenAddress():
+		return errs.New("plan is for token %s; expected %s", plan.Token, p.TokenAddress())
+	}
+
+	priceLock, err := db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+	if priceLock != nil {
+		if err := priceLock.CheckSymbol(plan.Symbol); err != nil {
+			return err
+		}
+	}
+	if priceLock == nil || !priceLock.Price.Equal(plan.Price) {
+		return errs.New("plan was made at a price of $%s which is no longer the locked price; plan again", plan.Price)
+	}
+
+	signer := types.LatestSignerForChainID(chainID)
+	signedTxs := make([]*types.Transaction, 0, len(plan.Transactions))
+	planned := make(map[string]bool)
+	for _, tx := range plan.Transactions {
+		if len(tx.Signed) == 0 {
+			return errs.New("transaction for payout group %d is not signed", tx.PayoutGroupID)
+		}
+		if err := checkOfflineTransaction(plan, tx); err != nil {
+			return err
+		}
+		signed := new(types.Transaction)
+		if err := signed.UnmarshalBinary(tx.Signed); err != nil {
+			return errs.New("unable to decode signed transaction for payout group %d: %v", tx.PayoutGroupID, err)
+		}
+		if signer.Hash(signed) != signer.Hash(tx.Unsigned) {
+			return errs.New("signed transaction for payout group %d does not match the planned one", tx.PayoutGroupID)
+		}
+		from, err := types.Sender(signer, signed)
+		if err != nil {
+			return errs.New("signed transaction for payout group %d has an invalid signature: %v", tx.PayoutGroupID, err)
+		}
+		if from != plan.Spender {
+			return errs.New("transaction for payout group %d is signed by %s instead of spender %s", tx.PayoutGroupID, from, plan.Spender)
+		}
+		signedTxs = append(signedTxs, signed)
+		planned[signed.Hash().String()] = true
+	}
+
+	if err := checkSpenderIdle(ctx, db, plan.Spender, planned); err != nil {
+		return err
+	}
+
+	recorded, err := db.FetchTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	recordedTxs := make(map[string]*pipelinedb.Transaction)
+	for _, tx := range recorded {
+		recordedTxs[tx.Hash] = tx
+	}
+
+	payoutGroups, err := db.FetchUnfinishedUnattachedPayoutGroups(ctx)
+	if err != nil {
+		return err
+	}
+	unattached := make(map[int64]bool)
+	for _, payoutGroup := range payoutGroups {
+		unattached[payoutGroup.ID] = true
+	}
+
+	// Send again the transactions recorded by an earlier broadcast that
+	// the node has dropped, then make sure the rest still follow on from
+	// the pending nonce.
+	var resend []*pipelinedb.Transaction
+	var send []int
+	for i, tx := range plan.Transactions {
+		if recordedTx, ok := recordedTxs[signedTxs[i].Hash().String()]; ok {
+			if recordedTx.State != pipelinedb.TxPending {
+				continue
+			}
+			status, err := p.TransactionStatus(ctx, recordedTx.Hash)
+			if err != nil {
+				return err
+			}
+			if status.State == pipelinedb.TxDropped {
+				resend = append(resend, recordedTx)
+			}
+			continue
+		}
+		if !unattached[tx.PayoutGroupID] {
+			return errs.New("payout group %d has been sent or finished since the plan was made; plan again", tx.PayoutGroupID)
+		}
+		payouts, err := db.FetchPayoutGroupPayouts(ctx, tx.PayoutGroupID)
+		if err != nil {
+			return err
+		}
+		if !offlinePayoutsMatch(tx.Payouts, payouts) {
+			return errs.New("payouts of payout group %d do not match the plan", tx.PayoutGroupID)
+		}
+		send = append(send, i)
+	}
+
+	for _, tx := range resend {
+		if err := p.ResendTransaction(ctx, *tx); err != nil {
+			return errs.New("unable to resend transaction %s with nonce %d: %v", tx.Hash, tx.Nonce, err)
+		}
+		log.Info("Resent dropped transaction", zap.String("hash", tx.Hash), zap.Uint64("nonce", tx.Nonce))
+	}
+
+	if len(send) > 0 {
+		_, pending, err := p.Nonces(ctx)
+		if err != nil {
+			return err
+		}
+		if first := plan.Transactions[send[0]].Unsigned.Nonce(); pending != first {
+			return errs.New("the pending nonce of spender %s is %d but the next planned nonce is %d; plan again", plan.Spender, pending, first)
+		}
+		if err := config.PromptConfirm(fmt.Sprintf("Broadcast %d transactions", len(send))); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range send {
+		tx, signed := plan.Transactions[i], signedTxs[i]
+		txLog := log.With(
+			zap.Uint64("nonce", signed.Nonce()),
+			zap.Int64("payout-group-id", tx.PayoutGroupID),
+			zap.String("owner", plan.Owner.String()),
+			zap.String("price", plan.Price.String()),
+			zap.String("tokens", tx.Tokens.String()),
+			zap.String("hash", signed.Hash().String()))
+
+		rawTxJSON, err := json.Marshal(signed)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		if _, err := db.CreateTransaction(ctx, pipelinedb.Transaction{
+			PayoutGroupID: tx.PayoutGroupID,
+			Hash:          signed.Hash().Hex(),
+			Nonce:         signed.Nonce(),
+			Owner:         plan.Owner,
+			Spender:       plan.Spender,
+			Price:         plan.Price,
+			Tokens:        tx.Tokens,
+			Raw:           rawTxJSON,
+		}); err != nil {
+			return err
+		}
+		if err := p.SendTransaction(ctx, txLog, payer.Transaction{Hash: signed.Hash().Hex(), Nonce: signed.Nonce(), Raw: signed}); err != nil {
+			return errs.New("unable to send transaction for payout group %d: %v; broadcast the plan again to retry", tx.PayoutGroupID, err)
+		}
+		txLog.Info("Broadcast transaction")
+	}
+	return nil
+}
+
+// checkOfflineTransaction checks that the unsigned transaction pays out the
+// payouts at the price of the plan and nothing else.
+func checkOfflineTransaction(plan *OfflinePlan, tx *OfflineTransaction) error {
+	unsigned := tx.Unsigned
+	if unsigned.Type() != types.DynamicFeeTxType {
+		return errs.New("transaction for payout group %d has unsupported type %d", tx.PayoutGroupID, unsigned.Type())
+	}
+	if unsigned.ChainId().Cmp(plan.ChainID) != 0 {
+		return errs.New("transaction for payout group %d is for chain %s; expected %s", tx.PayoutGroupID, unsigned.ChainId(), plan.ChainID)
+	}
+	if unsigned.Value().Sign() != 0 {
+		return errs.New("transaction for payout group %d transfers %s wei", tx.PayoutGroupID, unsigned.Value())
+	}
+
+	transfers := make([]eth.Transfer, 0, len(tx.Payouts))
+	total := new(big.Int)
+	for _, payout := range tx.Payouts {
+		tokens := storjtoken.FromUSD(payout.USD, plan.Price, plan.TokenDecimals)
+		if payout.Tokens == nil || tokens.Cmp(payout.Tokens) != 0 || tokens.Sign() <= 0 {
+			return errs.New("payout to %s in payout group %d does not pay $%s at $%s", payout.Payee, tx.PayoutGroupID, payout.USD, plan.Price)
+		}
+		transfers = append(transfers, eth.Transfer{Payee: payout.Payee, Tokens: tokens})
+		total.Add(total, tokens)
+	}
+	if total.Cmp(tx.Tokens) != 0 {
+		return errs.New("payout group %d pays %s tokens; expected %s", tx.PayoutGroupID, total, tx.Tokens)
+	}
+
+	to, data, gas, err := eth.TransferCall(plan.Owner, plan.Spender, plan.Token, plan.Disperse, transfers)
+	if err != nil {
+		return errs.New("payout group %d: %v", tx.PayoutGroupID, err)
+	}
+	if unsigned.To() == nil || *unsigned.To() != to || !bytes.Equal(unsigned.Data(), data) || unsigned.Gas() != gas {
+		return errs.New("transaction for payout group %d does not pay out its payouts", tx.PayoutGroupID)
+	}
+	return nil
+}
+
+// checkSpenderIdle returns an error if the spender has unfinished
+// transactions in the database other than the given ones, since planned
+// nonces must follow on from them.
+func checkSpenderIdle(ctx context.Context, db *pipelinedb.DB, spender common.Address, except map[string]bool) error {
+	nonceGroups, err := db.FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx)
+	if err != nil {
+		return err
+	}
+	for _, nonceGroup := range nonceGroups {
+		if nonceGroup.Spender != spender {
+			continue
+		}
+		for _, tx := range nonceGroup.Txs {
+			if !except[tx.Hash] {
+				return errs.New("spender %s has unfinished transactions; drain them first", spender)
+			}
+		}
+	}
+	return nil
+}
+
+// offlinePayoutsMatch returns true if the planned payouts are the payouts
+// in the database.
+func offlinePayoutsMatch(planned []OfflinePayout, payouts []*pipelinedb.Payout) bool {
+	if len(planned) != len(payouts) {
+		return false
+	}
+	for i, payout := range payouts {
+		if planned[i].Payee != payout.Payee || !planned[i].USD.Equal(payout.USD) {
+			return false
+		}
+	}
+	return true
+}
+
+func printOfflinePlan(plan *OfflinePlan) error {
+	totalUSD := decimal.Zero
+	totalTokens := new(big.Int)
+	maxFee := new(big.Int)
+	var payouts int
+	for _, tx := range plan.Transactions {
+		for _, payout := range tx.Payouts {
+			totalUSD = totalUSD.Add(payout.USD)
+		}
+		payouts += len(tx.Payouts)
+		totalTokens.Add(totalTokens, tx.Tokens)
+		maxFee.Add(maxFee, new(big.Int).Mul(tx.Unsigned.GasFeeCap(), new(big.Int).SetUint64(tx.Unsigned.Gas())))
+	}
+	if len(plan.Transactions) == 0 {
+		return errs.New("plan has no transactions")
+	}
+	first := plan.Transactions[0].Unsigned.Nonce()
+	symbol := coinmarketcap.Symbol(plan.Symbol)
+
+	fmt.Printf("Chain ID....................: %s\n", plan.ChainID)
+	fmt.Printf("Spender.....................: %s\n", plan.Spender)
+	fmt.Printf("Owner.......................: %s\n", plan.Owner)
+	fmt.Printf("Token.......................: %s\n", plan.Token)
+	if plan.Disperse != nil {
+		fmt.Printf("Disperse....................: %s\n", plan.Disperse)
+	}
+	fmt.Printf("%s: $%s\n", label("%s Price", symbol), plan.Price)
+	fmt.Printf("Transactions................: %d (nonces %d to %d)\n", len(plan.Transactions), first, first+uint64(len(plan.Transactions))-1)
+	fmt.Printf("Payouts.....................: %d\n", payouts)
+	fmt.Printf("Total USD...................: $%s\n", totalUSD)
+	fmt.Printf("%s: %s\n", label("Total %s", symbol), storjtoken.PrettySymbol(totalTokens, plan.TokenDecimals, plan.Symbol))
+	fmt.Printf("Max Gas Cost................: %s\n", batchpayment.PrettyETH(maxFee))
+	fmt.Println()
+	return nil
+}
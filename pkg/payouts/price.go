@@ -0,0 +1,151 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// NewPriceLock returns a price lock for the operator provided price. If
+// price is nil, the price of the symbol is quoted using the quoter instead.
+func NewPriceLock(ctx context.Context, quoter coinmarketcap.Quoter, symbol coinmarketcap.Symbol, price *decimal.Decimal) (*pipelinedb.PriceLock, error) {
+	if price != nil {
+		if !price.IsPositive() {
+			return nil, errs.New("%s price must be more than zero; got %s", symbol, price)
+		}
+		return &pipelinedb.PriceLock{
+			Symbol:    string(symbol),
+			Price:     *price,
+			Source:    pipelinedb.PriceSourceOperator,
+			Timestamp: time.Now(),
+		}, nil
+	}
+
+	quote, err := quoter.GetQuote(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &pipelinedb.PriceLock{
+		Symbol:    string(symbol),
+		Price:     quote.Price,
+		Source:    pipelinedb.PriceSourceCoinMarketCap,
+		Timestamp: quote.LastUpdated,
+	}, nil
+}
+
+// Reprice replaces the price locked for the payout after confirmation
+// by the operator. Transactions already sent keep the price they were sent
+// with.
+func Reprice(ctx context.Context, config Config, db *pipelinedb.DB) error {
+	current, err := db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+
+	symbol := config.symbol()
+	if current != nil {
+		if err := current.CheckSymbol(string(symbol)); err != nil {
+			return err
+		}
+	}
+	lock, err := NewPriceLock(ctx, config.Quoter, symbol, config.Price)
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		fmt.Printf("%s: %s\n", label("Locked %s Price", symbol), formatPriceLock(current))
+	} else {
+		fmt.Printf("%s: none\n", label("Locked %s Price", symbol))
+	}
+	fmt.Printf("%s: %s\n", label("New %s Price", symbol), formatPriceLock(lock))
+	fmt.Println()
+
+	if err := config.PromptConfirm(fmt.Sprintf("Lock %s price at $%s", symbol, lock.Price)); err != nil {
+		return err
+	}
+	return db.Reprice(ctx, *lock)
+}
+
+// lockPrice returns the price for the payout. If a price is already
+// locked, it is returned and locked is true. Otherwise a new price is
+// returned that still needs to be locked once the operator confirms.
+func lockPrice(ctx context.Context, config Config, db *pipelinedb.DB) (_ *pipelinedb.PriceLock, locked bool, err error) {
+	lock, err := db.FetchPriceLock(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if lock != nil {
+		if err := lock.CheckSymbol(string(config.symbol())); err != nil {
+			return nil, false, err
+		}
+		if config.Price != nil && !config.Price.Equal(lock.Price) {
+			return nil, false, errs.New("%s price is already locked at $%s; use the reprice command to change it", config.symbol(), lock.Price)
+		}
+		return lock, true, nil
+	}
+
+	lock, err = NewPriceLock(ctx, config.Quoter, config.symbol(), config.Price)
+	if err != nil {
+		return nil, false, err
+	}
+	return lock, false, nil
+}
+
+// PreviousPriceLock returns the latest price locked for the symbol by the
+// payouts in the data directory other than the named one. It returns nil if
+// none of them has locked a price for the symbol.
+func PreviousPriceLock(ctx context.Context, dataDir, name string, symbol coinmarketcap.Symbol) (*pipelinedb.PriceLock, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errs.Wrap(err)
+	}
+
+	var previous *pipelinedb.PriceLock
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == name {
+			continue
+		}
+		dbPath := DBPathFromDir(filepath.Join(dataDir, entry.Name()))
+		if _, err := os.Stat(dbPath); err != nil {
+			continue
+		}
+		lock, err := fetchPriceLock(ctx, dbPath)
+		if err != nil {
+			return nil, err
+		}
+		if lock == nil || lock.CheckSymbol(string(symbol)) != nil {
+			continue
+		}
+		if previous == nil || lock.Timestamp.After(previous.Timestamp) {
+			previous = lock
+		}
+	}
+	return previous, nil
+}
+
+func fetchPriceLock(ctx context.Context, dbPath string) (*pipelinedb.PriceLock, error) {
+	db, err := pipelinedb.OpenDB(ctx, dbPath, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	return db.FetchPriceLock(ctx)
+}
+
+func formatPriceLock(lock *pipelinedb.PriceLock) string {
+	return fmt.Sprintf("$%s (source=%s, at=%s)", lock.Price, lock.Source, lock.Timestamp.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,60 @@
+package payouts
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// QuarantineReport writes a CSV row for each payout in a quarantined payout
+// group, with the hash of the last transaction sent for the group and the
+// reason it was quarantined. It returns the number of quarantined payout
+// groups.
+func QuarantineReport(ctx context.Context, db *pipelinedb.DB, w io.Writer) (int, error) {
+	payoutGroups, err := db.FetchQuarantinedPayoutGroups(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"payout-group-id", "payee", "usd", "tx-hash", "reason"}); err != nil {
+		return 0, errs.Wrap(err)
+	}
+
+	for _, payoutGroup := range payoutGroups {
+		payouts, err := db.FetchPayoutGroupPayouts(ctx, payoutGroup.ID)
+		if err != nil {
+			return 0, err
+		}
+		txs, err := db.FetchPayoutGroupTransactions(ctx, payoutGroup.ID)
+		if err != nil {
+			return 0, err
+		}
+		var txHash string
+		if len(txs) > 0 {
+			txHash = txs[len(txs)-1].Hash
+		}
+		for _, payout := range payouts {
+			if err := out.Write([]string{
+				strconv.FormatInt(payoutGroup.ID, 10),
+				payout.Payee.String(),
+				payout.USD.String(),
+				txHash,
+				payoutGroup.QuarantineReason,
+			}); err != nil {
+				return 0, errs.Wrap(err)
+			}
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return 0, errs.Wrap(err)
+	}
+	return len(payoutGroups), nil
+}
@@ -3,8 +3,10 @@ package payouts
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 
 	"storj.io/crypto-batch-payment/pkg/payer"
@@ -18,22 +20,72 @@ import (
 type Config struct {
 	Quoter coinmarketcap.Quoter
 
+	// Symbol is the symbol of the coin or token the payouts are paid in,
+	// e.g. ETH for native payouts. Defaults to coinmarketcap.STORJ if unset.
+	Symbol coinmarketcap.Symbol
+
+	// Price, if set, is the operator provided price to lock for the payout
+	// instead of a quote.
+	Price *decimal.Decimal
+
 	PipelineLimit int
 
 	TxDelay time.Duration
 
 	Drain bool
 
+	ReplaceAfter time.Duration
+
+	RetryBudget time.Duration
+
+	// Quarantine, if true, quarantines payout groups whose transaction
+	// failed for a reason other than an insufficient balance instead of
+	// halting, up to MaxQuarantined of them.
+	Quarantine bool
+
+	MaxQuarantined int
+
+	SpendLimits pipeline.SpendLimits
+
+	// Observer, if set, is notified of pipeline events.
+	Observer pipeline.Observer
+
+	// Pause, if set, pauses (true) or resumes (false) the pipeline while it
+	// is running.
+	Pause <-chan bool
+
+	// Lanes, if set, are the spenders that send payout groups in parallel,
+	// each with its own nonces.
+	Lanes []pipeline.Lane
+
 	PromptConfirm func(label string) error
 }
 
+// symbol returns the symbol of the coin or token the payouts are paid in.
+func (config Config) symbol() coinmarketcap.Symbol {
+	if config.Symbol == "" {
+		return coinmarketcap.STORJ
+	}
+	return config.Symbol
+}
+
+// label formats the label of a line of the preview, padding it with dots
+// so that the values line up.
+func label(format string, symbol coinmarketcap.Symbol) string {
+	l := fmt.Sprintf(format, symbol)
+	if len(l) < 28 {
+		l += strings.Repeat(".", 28-len(l))
+	}
+	return l
+}
+
 func Preview(ctx context.Context, config Config, db *pipelinedb.DB, paymentPayer payer.Payer) error {
 	stats, err := db.Stats(ctx)
 	if err != nil {
 		return err
 	}
 
-	storjQuote, err := config.Quoter.GetQuote(ctx, coinmarketcap.STORJ)
+	priceLock, locked, err := lockPrice(ctx, config, db)
 	if err != nil {
 		return err
 	}
@@ -48,10 +100,20 @@ func Preview(ctx context.Context, config Config, db *pipelinedb.DB, paymentPayer
 		return err
 	}
 
-	estimatedSTORJ := storjtoken.FromUSD(stats.PendingUSD, storjQuote.Price, decimals)
+	symbol := config.symbol()
+	// Round up so that the estimate never falls short of what the payouts
+	// need.
+	estimatedTokens, err := storjtoken.Convert(stats.PendingUSD, priceLock.Price, decimals, storjtoken.RoundUp)
+	if err != nil {
+		return err
+	}
 
 	fmt.Printf("**PAYMENT TYPE**............: %s\n", paymentPayer)
-	fmt.Printf("Current STORJ Price.........: $%s\n", storjQuote.Price.String())
+	if locked {
+		fmt.Printf("%s: %s\n", label("Locked %s Price", symbol), formatPriceLock(priceLock))
+	} else {
+		fmt.Printf("%s: %s\n", label("%s Price to Lock", symbol), formatPriceLock(priceLock))
+	}
 	fmt.Println()
 	fmt.Printf("Total Payees................: %d\n", stats.Payees)
 	fmt.Printf("Total Payouts...............: %d\n", stats.TotalPayouts)
@@ -61,16 +123,19 @@ func Preview(ctx context.Context, config Config, db *pipelinedb.DB, paymentPayer
 	fmt.Printf("Pending Payouts.............: %d\n", stats.PendingPayouts)
 	fmt.Printf("Pending Payout Groups.......: %d\n", stats.PendingPayoutGroups)
 	fmt.Printf("Pending USD.................: $%s\n", stats.PendingUSD.String())
-	fmt.Printf("Pending in STORJ ~ .........: %s\n", storjtoken.Pretty(estimatedSTORJ, decimals))
-	fmt.Printf("Current STORJ balance ......: %s\n", storjtoken.Pretty(balance, decimals))
+	fmt.Printf("%s: %s\n", label("Pending in %s ~ ", symbol), storjtoken.PrettySymbol(estimatedTokens, decimals, string(symbol)))
+	fmt.Printf("%s: %s\n", label("Current %s balance ", symbol), storjtoken.PrettySymbol(balance, decimals, string(symbol)))
 	fmt.Println()
 	fmt.Printf("Total Transactions..........: %d\n", stats.TotalTransactions)
 	fmt.Printf("Pending Transactions........: %d\n", stats.PendingTransactions)
 	fmt.Printf("Failed Transactions.........: %d\n", stats.FailedTransactions)
 	fmt.Printf("Confirmed Transactions......: %d\n", stats.ConfirmedTransactions)
 	fmt.Printf("Dropped Transactions........: %d\n", stats.DroppedTransactions)
+	if stats.QuarantinedGroups > 0 {
+		fmt.Printf("Quarantined Payout Groups...: %d\n", stats.QuarantinedGroups)
+	}
 
-	err = paymentPayer.PrintEstimate(ctx, stats.PendingPayoutGroups)
+	err = paymentPayer.PrintEstimate(ctx, stats.PendingPayoutGroups, stats.PendingPayouts)
 	if err != nil {
 		return err
 	}
@@ -86,17 +151,37 @@ func Preview(ctx context.Context, config Config, db *pipelinedb.DB, paymentPayer
 		}
 	}
 
+	if !locked {
+		if priceLock.Source == pipelinedb.PriceSourceOperator {
+			if err := config.PromptConfirm(fmt.Sprintf("Lock operator provided %s price at $%s", symbol, priceLock.Price)); err != nil {
+				return err
+			}
+		}
+		if err := db.LockPrice(ctx, *priceLock); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func Run(ctx context.Context, log *zap.Logger, config Config, db *pipelinedb.DB, paymentPayer payer.Payer) error {
 	p, err := pipeline.New(paymentPayer, pipeline.Config{
-		Log:     log,
-		Quoter:  config.Quoter,
-		DB:      db,
-		Limit:   config.PipelineLimit,
-		Drain:   config.Drain,
-		TxDelay: config.TxDelay,
+		Log:            log,
+		Quoter:         config.Quoter,
+		Symbol:         config.symbol(),
+		DB:             db,
+		Limit:          config.PipelineLimit,
+		Drain:          config.Drain,
+		TxDelay:        config.TxDelay,
+		ReplaceAfter:   config.ReplaceAfter,
+		RetryBudget:    config.RetryBudget,
+		Quarantine:     config.Quarantine,
+		MaxQuarantined: config.MaxQuarantined,
+		SpendLimits:    config.SpendLimits,
+		Observer:       config.Observer,
+		Pause:          config.Pause,
+		Lanes:          config.Lanes,
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,102 @@
+package payouts
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// Simulate executes the transfers of the payout groups left to send against
+// the chain, in the order they would be sent, without signing or sending
+// any transaction. The price is the locked price, or the one that would be
+// locked, but it is not locked. The results are recorded in the database as
+// the simulation report, replacing the last one, which is all that is
+// written.
+func Simulate(ctx context.Context, log *zap.Logger, config Config, db *pipelinedb.DB, paymentPayer payer.Payer) (*pipelinedb.PriceLock, error) {
+	simulator, ok := paymentPayer.(payer.Simulator)
+	if !ok {
+		return nil, errs.New("the %s payer does not support simulation", paymentPayer)
+	}
+
+	priceLock, _, err := lockPrice(ctx, config, db)
+	if err != nil {
+		return nil, err
+	}
+
+	payoutGroups, err := db.FetchUnfinishedUnattachedPayoutGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*pipelinedb.SimulationResult
+	transferred := new(big.Int)
+	for i, payoutGroup := range payoutGroups {
+		payouts, err := db.FetchPayoutGroupPayouts(ctx, payoutGroup.ID)
+		if err != nil {
+			return nil, err
+		}
+		groupResults, err := simulator.SimulatePayoutGroup(ctx, payouts, priceLock.Price, transferred)
+		if err != nil {
+			return nil, errs.New("unable to simulate payout group %d: %v", payoutGroup.ID, err)
+		}
+		for _, result := range groupResults {
+			transferred.Add(transferred, result.Tokens)
+		}
+		results = append(results, groupResults...)
+
+		if (i+1)%100 == 0 {
+			log.Info("Simulated payout groups", zap.Int("simulated", i+1), zap.Int("total", len(payoutGroups)))
+		}
+	}
+
+	if err := db.RecordSimulation(ctx, results); err != nil {
+		return nil, err
+	}
+	return priceLock, nil
+}
+
+// SimulationReport writes a CSV row for each payee whose transfer would fail
+// according to the last simulation, with the reason. It returns the number
+// of payees simulated and the number whose transfer would fail.
+func SimulationReport(ctx context.Context, db *pipelinedb.DB, w io.Writer) (total, failed int, err error) {
+	results, err := db.FetchSimulationResults(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"payout-group-id", "payee", "usd", "tokens", "outcome", "reason"}); err != nil {
+		return 0, 0, errs.Wrap(err)
+	}
+
+	for _, result := range results {
+		if result.Outcome == pipelinedb.SimulationOK {
+			continue
+		}
+		failed++
+		if err := out.Write([]string{
+			strconv.FormatInt(result.PayoutGroupID, 10),
+			result.Payee.String(),
+			result.USD.String(),
+			result.Tokens.String(),
+			string(result.Outcome),
+			result.Reason,
+		}); err != nil {
+			return 0, 0, errs.Wrap(err)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return 0, 0, errs.Wrap(err)
+	}
+	return len(results), failed, nil
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
+
+	"storj.io/crypto-batch-payment/pkg/coinmarketcap"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+// ErrSpendLimit is returned when sending a payout group would breach one of
+// the spend limits.
+var ErrSpendLimit = errs.Class("spend limit")
+
+// SpendLimits are hard limits on what a payout can spend. They are checked
+// against the running totals in the payout database before each payout group
+// is signed. Zero values disable the corresponding limit.
+type SpendLimits struct {
+	// MaxTokens is the maximum amount of the token (in whole tokens) that
+	// can be paid out in total.
+	MaxTokens decimal.Decimal
+
+	// MaxUSD is the maximum USD that can be paid out in total.
+	MaxUSD decimal.Decimal
+
+	// MaxPayoutUSD is the maximum USD of a single payout.
+	MaxPayoutUSD decimal.Decimal
+
+	// MaxPayeeUSD is the maximum USD that can be paid out to a single payee
+	// address in total.
+	MaxPayeeUSD decimal.Decimal
+}
+
+// Check returns an error if sending the payouts for the given amount of
+// tokens would breach one of the limits. The symbol of the token is only used
+// in the error.
+func (limits SpendLimits) Check(spent *pipelinedb.SpendTotals, payoutGroupID int64, payouts []*pipelinedb.Payout, tokens *big.Int, decimals int32, symbol coinmarketcap.Symbol) error {
+	if !limits.MaxTokens.IsZero() {
+		maxTokens := limits.MaxTokens.Shift(decimals).BigInt()
+		total := new(big.Int).Add(spent.Tokens, tokens)
+		if total.Cmp(maxTokens) > 0 {
+			return ErrSpendLimit.New("payout group %d would bring the total to %s; max is %s %s",
+				payoutGroupID, storjtoken.PrettySymbol(total, decimals, string(symbol)), limits.MaxTokens, symbol)
+		}
+	}
+
+	payeeUSD := make(map[common.Address]decimal.Decimal)
+	totalUSD := spent.USD
+	for _, payout := range payouts {
+		if !limits.MaxPayoutUSD.IsZero() && payout.USD.GreaterThan(limits.MaxPayoutUSD) {
+			return ErrSpendLimit.New("payout on CSV line %d to %s is $%s; max per payout is $%s",
+				payout.CSVLine, payout.Payee, payout.USD, limits.MaxPayoutUSD)
+		}
+
+		totalUSD = totalUSD.Add(payout.USD)
+
+		payee := payout.Payee
+		if _, ok := payeeUSD[payee]; !ok {
+			payeeUSD[payee] = spent.PayeeUSD[payee]
+		}
+		payeeUSD[payee] = payeeUSD[payee].Add(payout.USD)
+		if !limits.MaxPayeeUSD.IsZero() && payeeUSD[payee].GreaterThan(limits.MaxPayeeUSD) {
+			return ErrSpendLimit.New("payout group %d would bring the total paid to %s to $%s; max per payee is $%s",
+				payoutGroupID, payee, payeeUSD[payee], limits.MaxPayeeUSD)
+		}
+	}
+
+	if !limits.MaxUSD.IsZero() && totalUSD.GreaterThan(limits.MaxUSD) {
+		return ErrSpendLimit.New("payout group %d would bring the total to $%s; max is $%s",
+			payoutGroupID, totalUSD, limits.MaxUSD)
+	}
+	return nil
+}
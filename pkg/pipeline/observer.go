@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"context"
+
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+// Observer is notified of pipeline events. It can be used to feed metrics,
+// notifications or custom reporting. Callbacks are invoked synchronously from
+// the pipeline and should return quickly.
+type Observer interface {
+	// TxCreated is called when a transaction has been signed and recorded in
+	// the payout database, before it is sent.
+	TxCreated(ctx context.Context, tx *pipelinedb.Transaction)
+
+	// TxSent is called after a transaction has been sent. The error is
+	// non-nil if sending failed.
+	TxSent(ctx context.Context, tx *pipelinedb.Transaction, err error)
+
+	// NonceGroupStateChanged is called when the state of a nonce group
+	// differs from the state last observed for it.
+	NonceGroupStateChanged(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, from, to pipelinedb.TxState)
+
+	// NonceGroupDropped is called when all of the transactions in a nonce
+	// group were dropped and the payout group has been resent.
+	NonceGroupDropped(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, resent *pipelinedb.Transaction)
+
+	// NonceGroupFailed is called when a nonce group has failed.
+	NonceGroupFailed(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, statuses []*pipelinedb.TxStatus)
+
+	// RunCompleted is called when the pipeline stops processing payouts. The
+	// error is nil if processing finished successfully.
+	RunCompleted(ctx context.Context, err error)
+}
+
+// NopObserver is an Observer that ignores all events. It can be embedded to
+// implement only some of the callbacks.
+type NopObserver struct{}
+
+var _ Observer = NopObserver{}
+
+func (NopObserver) TxCreated(ctx context.Context, tx *pipelinedb.Transaction) {}
+
+func (NopObserver) TxSent(ctx context.Context, tx *pipelinedb.Transaction, err error) {}
+
+func (NopObserver) NonceGroupStateChanged(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, from, to pipelinedb.TxState) {
+}
+
+func (NopObserver) NonceGroupDropped(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, resent *pipelinedb.Transaction) {
+}
+
+func (NopObserver) NonceGroupFailed(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, statuses []*pipelinedb.TxStatus) {
+}
+
+func (NopObserver) RunCompleted(ctx context.Context, err error) {}
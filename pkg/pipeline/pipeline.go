@@ -4,6 +4,8 @@ package pipeline
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/shopspring/decimal"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
@@ -23,6 +26,10 @@ const (
 	// txStatusPollInterval is often to poll for transaction status.
 	txStatusPollInterval = time.Second
 
+	// preconditionsPollInterval is how often to check the preconditions of
+	// the payer while they are not met.
+	preconditionsPollInterval = 5 * time.Second
+
 	// notDroppedUntil is how long to wait after submitting a transaction
 	// before it should be considered dropped.
 	notDroppedUntil = time.Second * 30
@@ -35,6 +42,14 @@ const (
 
 	// DefaultTxDelay is the default tx delay (see TxDelay in Config).
 	DefaultTxDelay = time.Duration(0)
+
+	// DefaultReplaceAfter is the default replacement age (see ReplaceAfter
+	// in Config). Replacement is disabled by default.
+	DefaultReplaceAfter = time.Duration(0)
+
+	// DefaultMaxQuarantined is the default number of payout groups that can
+	// be quarantined (see MaxQuarantined in Config).
+	DefaultMaxQuarantined = 10
 )
 
 var (
@@ -46,15 +61,22 @@ type Config struct {
 	// Log is the logger for logging pipeline progress
 	Log *zap.Logger
 
-	// Owner is the address of the account that the STORJ token will be paid
+	// Owner is the address of the account that the token will be paid
 	// from. In a Transfer-based flow, the Owner will match the address derived
 	// from the Spender key. In a TransferFrom-based flow, it will be a
 	// different account.
 	Owner common.Address
 
-	// Quoter is used to get price quotes for STORJ token
+	// Quoter is used to get a price quote for the token if no price has
+	// been locked in the payout database yet. The quoted price is locked for
+	// the rest of the payout.
 	Quoter coinmarketcap.Quoter
 
+	// Symbol is the symbol of the coin or token the payouts are paid in,
+	// which the Quoter is asked to quote. Defaults to coinmarketcap.STORJ if
+	// unset.
+	Symbol coinmarketcap.Symbol
+
 	// DB is the the payout database
 	DB *pipelinedb.DB
 
@@ -74,11 +96,71 @@ type Config struct {
 	// transactions and then halt.
 	Drain bool
 
+	// ReplaceAfter is how long the youngest transaction of a nonce group can
+	// stay pending before the payouts are re-signed with a higher fee and
+	// sent as a replacement. The replacement is recorded as another
+	// transaction in the nonce group. Only applies if the payer supports
+	// replacement. Zero disables replacement.
+	ReplaceAfter time.Duration
+
+	// SpendLimits are hard limits on what the payout can spend. Breaching
+	// one halts the pipeline before the offending payout group is signed.
+	SpendLimits SpendLimits
+
+	// Observer, if set, is notified of pipeline events.
+	Observer Observer
+
+	// Pause, if set, pauses (true) or resumes (false) the pipeline while
+	// it is running. A paused pipeline stops sending new payout groups but
+	// keeps checking the ones in flight, like Drain, and waits to be resumed
+	// instead of halting once they are done.
+	Pause <-chan bool
+
+	// Lanes, if set, are the spenders that send payout groups, each with
+	// its own nonce lane. All of them pay from the Owner using TransferFrom,
+	// so each spender needs an allowance. If unset, the payer passed to New
+	// is the only lane.
+	Lanes []Lane
+
+	// RetryBudget is how long a call to the payer or quoter that fails with
+	// a transient error (e.g. a timeout, a rate limit or a server error) is
+	// retried, with exponential backoff, before the pipeline gives up and
+	// halts. Errors that are not transient halt the pipeline right away.
+	// Defaults to DefaultRetryBudget if unset. Negative disables retries.
+	RetryBudget time.Duration
+
+	// Quarantine, if true, quarantines a payout group whose transaction
+	// failed instead of halting, unless the failure is due to an
+	// insufficient token balance or allowance. Quarantined payout groups are
+	// recorded with the reason in the payout database and are not sent
+	// again.
+	Quarantine bool
+
+	// MaxQuarantined is how many payout groups can be quarantined before
+	// the pipeline halts. Defaults to DefaultMaxQuarantined if unset.
+	MaxQuarantined int
+
+	// test hook used to manipulate the initial retry backoff
+	retryBackoff time.Duration
+
 	// test hook used to step the polling loop
 	stepInCh chan chan []*pipelinedb.NonceGroup
 
 	// test hook used to manipulate the polling interval
 	pollInterval time.Duration
+
+	// test hook used to manipulate the preconditions polling interval
+	preconditionsPollInterval time.Duration
+}
+
+// Lane is a spender that sends payout groups with its own nonces, in
+// parallel with the other lanes.
+type Lane struct {
+	// Spender is the address of the spender key used by the payer.
+	Spender common.Address
+
+	// Payer signs and sends the transactions of the lane.
+	Payer payer.Payer
 }
 
 type Pipeline struct {
@@ -86,17 +168,54 @@ type Pipeline struct {
 
 	owner   common.Address
 	quoter  coinmarketcap.Quoter
+	symbol  coinmarketcap.Symbol
 	db      *pipelinedb.DB
 	limit   int
 	txDelay time.Duration
 	drain   bool
 	payer   payer.Payer
 
-	pollInterval  time.Duration
+	pauseCh <-chan bool
+	paused  bool
+
+	replaceAfter time.Duration
+	spendLimits  SpendLimits
+	spent        *pipelinedb.SpendTotals
+	observer     Observer
+	states       map[stateKey]pipelinedb.TxState
+
+	retryBudget  time.Duration
+	retryBackoff time.Duration
+
+	quarantine     bool
+	maxQuarantined int
+	quarantined    int
+
+	price                     decimal.Decimal
+	pollInterval              time.Duration
+	preconditionsPollInterval time.Duration
+	lanes                     []*lane
+}
+
+// lane tracks the nonce groups in flight for a spender.
+type lane struct {
+	spender       common.Address
+	payer         payer.Payer
+	log           *zap.Logger
 	expectedNonce uint64
 	nonceGroups   []*pipelinedb.NonceGroup
 }
 
+// stateKey identifies a nonce group across lanes.
+type stateKey struct {
+	spender common.Address
+	nonce   uint64
+}
+
+func stateKeyOf(nonceGroup *pipelinedb.NonceGroup) stateKey {
+	return stateKey{spender: nonceGroup.Spender, nonce: nonceGroup.Nonce}
+}
+
 func New(payer payer.Payer, config Config) (*Pipeline, error) {
 	if config.Limit == 0 {
 		config.Limit = DefaultLimit
@@ -104,28 +223,128 @@ func New(payer payer.Payer, config Config) (*Pipeline, error) {
 	if config.pollInterval == 0 {
 		config.pollInterval = txStatusPollInterval
 	}
+	if config.preconditionsPollInterval == 0 {
+		config.preconditionsPollInterval = preconditionsPollInterval
+	}
+	if config.Observer == nil {
+		config.Observer = NopObserver{}
+	}
+	if config.RetryBudget == 0 {
+		config.RetryBudget = DefaultRetryBudget
+	}
+	if config.retryBackoff == 0 {
+		config.retryBackoff = defaultRetryBackoff
+	}
+	if config.MaxQuarantined == 0 {
+		config.MaxQuarantined = DefaultMaxQuarantined
+	}
+	if config.Symbol == "" {
+		config.Symbol = coinmarketcap.STORJ
+	}
+
+	var lanes []*lane
+	for _, l := range config.Lanes {
+		if l.Payer == nil {
+			return nil, errs.New("lane for spender %s has no payer", l.Spender)
+		}
+		lanes = append(lanes, &lane{
+			spender: l.Spender,
+			payer:   l.Payer,
+			log:     config.Log.With(zap.String("spender", l.Spender.String())),
+		})
+	}
+	if len(lanes) == 0 {
+		lanes = append(lanes, &lane{
+			spender: payer.From(),
+			payer:   payer,
+			log:     config.Log,
+		})
+	}
+	bindDB(lanes, config.DB)
+	if config.ReplaceAfter > 0 {
+		enableReplacement(lanes)
+	}
 
 	return &Pipeline{
-		log:          config.Log,
-		owner:        config.Owner,
-		quoter:       config.Quoter,
-		db:           config.DB,
-		limit:        config.Limit,
-		txDelay:      config.TxDelay,
-		drain:        config.Drain,
-		pollInterval: config.pollInterval,
-		payer:        payer,
+		log:            config.Log,
+		owner:          config.Owner,
+		quoter:         config.Quoter,
+		symbol:         config.Symbol,
+		db:             config.DB,
+		limit:          config.Limit,
+		txDelay:        config.TxDelay,
+		drain:          config.Drain,
+		replaceAfter:   config.ReplaceAfter,
+		spendLimits:    config.SpendLimits,
+		observer:       config.Observer,
+		pauseCh:        config.Pause,
+		retryBudget:    config.RetryBudget,
+		retryBackoff:   config.retryBackoff,
+		quarantine:     config.Quarantine,
+		maxQuarantined: config.MaxQuarantined,
+		states:         make(map[stateKey]pipelinedb.TxState),
+		pollInterval:   config.pollInterval,
+		payer:          payer,
+		lanes:          lanes,
+
+		preconditionsPollInterval: config.preconditionsPollInterval,
 	}, nil
 }
 
-func (p *Pipeline) ProcessPayouts(ctx context.Context) error {
+// waitForPreconditions waits until the preconditions of the payer of the
+// lane are met.
+func (p *Pipeline) waitForPreconditions(ctx context.Context, lane *lane) error {
+	for {
+		unmet, err := lane.payer.CheckPreconditions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(unmet) == 0 {
+			return nil
+		}
+		lane.log.Info("One or more preconditions are not met, waiting",
+			zap.Strings("unmet", unmet),
+			zap.String("wait", p.preconditionsPollInterval.String()))
+		if err := sleepFor(ctx, p.preconditionsPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// bindDB binds the payers of the lanes that keep their state in the payout
+// database to it.
+func bindDB(lanes []*lane, db *pipelinedb.DB) {
+	for _, lane := range lanes {
+		if binder, ok := lane.payer.(payer.DBBinder); ok {
+			binder.BindDB(db)
+		}
+	}
+}
+
+// enableReplacement makes the payers of the lanes that can replace pending
+// transactions leave room to bump their fees.
+func enableReplacement(lanes []*lane) {
+	for _, lane := range lanes {
+		if replacer, ok := lane.payer.(payer.Replacer); ok {
+			replacer.EnableReplacement()
+		}
+	}
+}
+
+func (p *Pipeline) ProcessPayouts(ctx context.Context) (err error) {
+	defer func() { p.observer.RunCompleted(ctx, err) }()
+
 	p.log.Info("Processing payouts",
 		zap.Int("limit", p.limit),
 		zap.String("tx-delay", p.txDelay.String()),
 		zap.Bool("drain", p.drain),
+		zap.String("replace-after", p.replaceAfter.String()),
+		zap.Int("lanes", len(p.lanes)),
+		zap.String("retry-budget", p.retryBudget.String()),
+		zap.Bool("quarantine", p.quarantine),
 	)
 
-	err := p.initPayout(ctx)
+	err = p.initPayout(ctx)
 	if err != nil {
 		return err
 	}
@@ -151,10 +370,39 @@ func (p *Pipeline) ProcessPayouts(ctx context.Context) error {
 }
 
 func (p *Pipeline) initPayout(ctx context.Context) error {
-	nonceGroups, err := p.db.FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx)
+	if err := p.lockPrice(ctx); err != nil {
+		return err
+	}
+
+	spent, err := p.db.FetchSpendTotals(ctx)
 	if err != nil {
 		return err
 	}
+	p.spent = spent
+	p.log.Info("Spend totals loaded",
+		zap.Int("payout-groups", len(spent.PayoutGroups)),
+		zap.String("usd", spent.USD.String()),
+		zap.String("tokens", spent.Tokens.String()),
+	)
+
+	if _, err := p.reopenReorgedPayoutGroups(ctx); err != nil {
+		return err
+	}
+
+	if p.quarantine {
+		quarantined, err := p.db.FetchQuarantinedPayoutGroups(ctx)
+		if err != nil {
+			return err
+		}
+		p.quarantined = len(quarantined)
+		if p.quarantined > 0 {
+			p.log.Warn("Payout groups quarantined by earlier runs", zap.Int("quarantined", p.quarantined))
+		}
+	}
+
+	if err := p.loadNonceGroups(ctx); err != nil {
+		return err
+	}
 
 	unstarted, err := p.db.CountUnfinishedUnattachedPayoutGroup(ctx)
 	if err != nil {
@@ -163,10 +411,9 @@ func (p *Pipeline) initPayout(ctx context.Context) error {
 
 	p.log.Info("Payout groups loaded",
 		zap.Int64("unstarted", unstarted),
-		zap.Int("pending", len(nonceGroups)),
+		zap.Int("pending", p.inFlight()),
 	)
 
-	p.nonceGroups = nonceGroups
 	done, err := p.checkNonceGroups(ctx)
 	if done {
 		return err
@@ -174,26 +421,140 @@ func (p *Pipeline) initPayout(ctx context.Context) error {
 	return nil
 }
 
+// loadNonceGroups loads the unfinished nonce groups from the database into
+// the lanes of the spenders that sent them.
+func (p *Pipeline) loadNonceGroups(ctx context.Context) error {
+	nonceGroups, err := p.db.FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, lane := range p.lanes {
+		lane.nonceGroups = nil
+	}
+	for _, nonceGroup := range nonceGroups {
+		lane, err := p.laneFor(nonceGroup.Spender)
+		if err != nil {
+			return err
+		}
+		lane.nonceGroups = append(lane.nonceGroups, nonceGroup)
+	}
+	return nil
+}
+
+// laneFor returns the lane of the spender. Nonce groups of other spenders,
+// like those left over from a run with more spenders, are not sent with the
+// nonces of a lane they do not belong to.
+func (p *Pipeline) laneFor(spender common.Address) (*lane, error) {
+	for _, lane := range p.lanes {
+		if lane.spender == spender {
+			return lane, nil
+		}
+	}
+	return nil, errs.New("spender %s has unfinished transactions but is not one of the configured spenders", spender)
+}
+
+// inFlight returns the number of nonce groups in flight across all lanes.
+func (p *Pipeline) inFlight() int {
+	var n int
+	for _, lane := range p.lanes {
+		n += len(lane.nonceGroups)
+	}
+	return n
+}
+
+// allNonceGroups returns the nonce groups in flight across all lanes.
+func (p *Pipeline) allNonceGroups() []*pipelinedb.NonceGroup {
+	var nonceGroups []*pipelinedb.NonceGroup
+	for _, lane := range p.lanes {
+		nonceGroups = append(nonceGroups, lane.nonceGroups...)
+	}
+	return nonceGroups
+}
+
 func (p *Pipeline) payoutStep(ctx context.Context) (bool, error) {
+	p.checkPause()
+
 	// Trim off nonce groups that have no more transactions. This only
 	// happens when a nonce group has been confirmed or failed.
-	var finished int
-	for len(p.nonceGroups) > 0 && len(p.nonceGroups[0].Txs) == 0 {
-		p.log.Info("Nonce group finished", zap.Uint64("nonce", p.nonceGroups[0].Nonce))
-		p.nonceGroups = p.nonceGroups[1:]
-		finished++
-	}
-	if finished > 0 {
-		p.log.Info("Pipeline status", zap.Int("len", len(p.nonceGroups)), zap.Int("limit", p.limit))
+	for _, lane := range p.lanes {
+		var finished int
+		for len(lane.nonceGroups) > 0 && len(lane.nonceGroups[0].Txs) == 0 {
+			lane.log.Info("Nonce group finished", zap.Uint64("nonce", lane.nonceGroups[0].Nonce))
+			lane.nonceGroups = lane.nonceGroups[1:]
+			finished++
+		}
+		if finished > 0 {
+			lane.log.Info("Pipeline status", zap.Int("len", len(lane.nonceGroups)), zap.Int("limit", p.limit))
+		}
 	}
 
 	// Fill up the pipeline
 	var added bool
-	for i := 0; len(p.nonceGroups) < p.limit && !p.drain; i++ {
-		payoutGroup, err := p.db.FetchFirstUnfinishedUnattachedPayoutGroup(ctx)
+	for _, lane := range p.lanes {
+		laneAdded, err := p.fillLane(ctx, lane)
+		if err != nil {
+			return true, err
+		}
+		added = added || laneAdded
+	}
+
+	// Pipeline is empty. Before declaring victory, make sure none of the
+	// confirmed transactions have been reorganized out of the chain.
+	if p.inFlight() == 0 && !p.drain && !p.paused {
+		reopened, err := p.reopenReorgedPayoutGroups(ctx)
+		if err != nil {
+			return true, err
+		}
+		if reopened {
+			if err := p.loadNonceGroups(ctx); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	// Pipeline is empty
+	if p.inFlight() == 0 {
+		if p.paused && !p.drain {
+			// Keep polling until resumed.
+			return false, nil
+		}
+		if p.drain {
+			p.log.Info("Drained existing transactions.")
+		} else {
+			p.log.Info("Processed all payout groups")
+		}
+		return true, nil
+	}
+
+	if added {
+		unfinishedPayouts, totalPayouts, err := p.db.FetchPayoutProgress(ctx)
 		if err != nil {
 			return true, err
 		}
+		p.log.Info("Waiting on nonce groups...",
+			zap.Int("pending", p.inFlight()),
+			zap.Int64("unfinished payouts", unfinishedPayouts),
+			zap.Int64("total payouts", totalPayouts),
+		)
+	}
+
+	done, err := p.checkNonceGroups(ctx)
+	if done {
+		return true, err
+	}
+	return false, nil
+}
+
+// fillLane sends payout groups on the lane until it is full or there are
+// no more payout groups to send. It returns true if any were sent.
+func (p *Pipeline) fillLane(ctx context.Context, lane *lane) (bool, error) {
+	var added bool
+	for i := 0; len(lane.nonceGroups) < p.limit && !p.drain && !p.paused; i++ {
+		payoutGroup, err := p.db.FetchFirstUnfinishedUnattachedPayoutGroup(ctx)
+		if err != nil {
+			return added, err
+		}
 		if payoutGroup == nil {
 			// no payout groups to add
 			break
@@ -201,7 +562,7 @@ func (p *Pipeline) payoutStep(ctx context.Context) (bool, error) {
 
 		if i > 0 && p.txDelay > 0 {
 			if err := sleepFor(ctx, p.txDelay); err != nil {
-				return true, err
+				return added, err
 			}
 		}
 
@@ -209,15 +570,27 @@ func (p *Pipeline) payoutStep(ctx context.Context) (bool, error) {
 		// to increment from) or grab the account nonce according to the
 		// blockchain.
 		var nextNonce uint64
-		if len(p.nonceGroups) > 0 {
-			nextNonce = p.nonceGroups[len(p.nonceGroups)-1].Nonce + 1
-			p.log.Info("Nonce from nonce group", zap.Uint64("nextNonce", nextNonce))
+		if len(lane.nonceGroups) > 0 {
+			nextNonce = lane.nonceGroups[len(lane.nonceGroups)-1].Nonce + 1
+			// A payout group reopened after a reorg can be older than
+			// transactions recorded since, so never go below those.
+			recorded, err := p.db.FetchNextNonce(ctx, lane.spender)
+			if err != nil {
+				return added, err
+			}
+			if recorded > nextNonce {
+				nextNonce = recorded
+			}
+			lane.log.Info("Nonce from nonce group", zap.Uint64("nextNonce", nextNonce))
 		} else {
-			nextNonce, err = p.payer.NextNonce(ctx)
+			err = p.retry(ctx, "next nonce", func() (err error) {
+				nextNonce, err = lane.payer.NextNonce(ctx)
+				return err
+			})
 			if err != nil {
-				return true, errs.New("unable to obtain next nonce from blockchain: %v", err)
+				return added, errs.New("unable to obtain next nonce from blockchain: %v", err)
 			}
-			p.log.Info("Nonce from chain", zap.Uint64("nextNonce", nextNonce))
+			lane.log.Info("Nonce from chain", zap.Uint64("nextNonce", nextNonce))
 			// NonceAt can return an earlier nonce than expected if the
 			// just-mined block cleared out the pipeline but the timing is
 			// weird enough for the node to not return right nonce based on
@@ -226,73 +599,68 @@ func (p *Pipeline) payoutStep(ctx context.Context) (bool, error) {
 			// a known bad nonce.
 			// TODO: we could spin here for a time until the node returns
 			// the expected nonce...
-			if p.expectedNonce > 0 && nextNonce < p.expectedNonce {
-				return true, errs.New("node returned used nonce %d; expected >= %d", nextNonce, p.expectedNonce)
+			if lane.expectedNonce > 0 && nextNonce < lane.expectedNonce {
+				return added, errs.New("node returned used nonce %d; expected >= %d; check the nonce status command", nextNonce, lane.expectedNonce)
 			}
-			p.expectedNonce = nextNonce + 1
+			lane.expectedNonce = nextNonce + 1
 		}
 
-		tx, err := p.sendTransaction(ctx, payoutGroup.ID, nextNonce)
+		tx, err := p.sendTransaction(ctx, lane, payoutGroup.ID, nextNonce)
 		if err != nil {
-			return true, err
+			return added, err
 		}
 
-		p.nonceGroups = append(p.nonceGroups, &pipelinedb.NonceGroup{
+		lane.nonceGroups = append(lane.nonceGroups, &pipelinedb.NonceGroup{
 			Nonce:         tx.Nonce,
+			Spender:       tx.Spender,
 			PayoutGroupID: payoutGroup.ID,
 			Txs:           []pipelinedb.Transaction{*tx},
 		})
-		p.log.Debug("Pipeline status", zap.Int("len", len(p.nonceGroups)), zap.Int("limit", p.limit))
+		lane.log.Debug("Pipeline status", zap.Int("len", len(lane.nonceGroups)), zap.Int("limit", p.limit))
 		added = true
 	}
+	return added, nil
+}
 
-	// Pipeline is empty
-	if len(p.nonceGroups) == 0 {
-		if p.drain {
-			p.log.Info("Drained existing transactions.")
-		} else {
-			p.log.Info("Processed all payout groups")
-		}
-		return true, nil
-	}
-
-	if added {
-		unfinishedPayouts, totalPayouts, err := p.db.FetchPayoutProgress(ctx)
+func (p *Pipeline) checkNonceGroups(ctx context.Context) (bool, error) {
+	var failed bool
+	for _, lane := range p.lanes {
+		laneFailed, err := p.checkLane(ctx, lane)
 		if err != nil {
 			return true, err
 		}
-		p.log.Info("Waiting on nonce groups...",
-			zap.Int("pending", len(p.nonceGroups)),
-			zap.Int64("unfinished payouts", unfinishedPayouts),
-			zap.Int64("total payouts", totalPayouts),
-		)
+		failed = failed || laneFailed
 	}
 
-	done, err := p.checkNonceGroups(ctx)
-	if done {
-		return true, err
+	if failed {
+		return true, errs.New("One or more transactions failed, possibly due to insufficient balances")
 	}
 	return false, nil
 }
 
-func (p *Pipeline) checkNonceGroups(ctx context.Context) (bool, error) {
-	// Check the status of each nonce group in the pipeline.
+// checkLane checks the status of each nonce group in the lane. It returns
+// true if any of them failed.
+func (p *Pipeline) checkLane(ctx context.Context, lane *lane) (bool, error) {
 	failedCount := 0
 checkLoop:
-	for i := range p.nonceGroups {
-		log := p.log.With(zap.Uint64("nonce", p.nonceGroups[i].Nonce), zap.Int64("payout-group-id", p.nonceGroups[i].PayoutGroupID))
+	for i := range lane.nonceGroups {
+		log := lane.log.With(zap.Uint64("nonce", lane.nonceGroups[i].Nonce), zap.Int64("payout-group-id", lane.nonceGroups[i].PayoutGroupID))
 
 		log.Debug("Checking nonce group",
-			zap.Int("txs", len(p.nonceGroups[i].Txs)),
+			zap.Int("txs", len(lane.nonceGroups[i].Txs)),
 		)
 
 		var err error
 		var state pipelinedb.TxState
 		var all []*pipelinedb.TxStatus
-		state, all, err = p.payer.CheckNonceGroup(ctx, log, p.nonceGroups[i], failedCount > 0)
+		err = p.retry(ctx, "check nonce group", func() (err error) {
+			state, all, err = lane.payer.CheckNonceGroup(ctx, log, lane.nonceGroups[i], failedCount > 0)
+			return err
+		})
 		if err != nil {
-			return true, err
+			return false, err
 		}
+		p.observeState(ctx, lane.nonceGroups[i], state)
 
 		switch state {
 		case pipelinedb.TxDropped:
@@ -300,46 +668,69 @@ checkLoop:
 			// has elapsed since their creation. This avoids false-positives
 			// when using networks like Infura, whose nodes are eventually
 			// consistent.
-			if time.Since(youngestTransactionTime(p.nonceGroups[i].Txs)) < notDroppedUntil {
+			if time.Since(youngestTransactionTime(lane.nonceGroups[i].Txs)) < notDroppedUntil {
 				break checkLoop
 			}
 			// All the transactions have been dropped. Send another transaction for
 			// this nonce group. Indicate to the caller that there was a drop.
-			tx, err := p.sendTransaction(ctx, p.nonceGroups[i].PayoutGroupID, p.nonceGroups[i].Nonce)
+			tx, err := p.sendTransaction(ctx, lane, lane.nonceGroups[i].PayoutGroupID, lane.nonceGroups[i].Nonce)
 			if err != nil {
-				return true, err
+				return false, err
 			}
-			p.nonceGroups[i].Txs = append(p.nonceGroups[i].Txs, *tx)
+			lane.nonceGroups[i].Txs = append(lane.nonceGroups[i].Txs, *tx)
+			p.states[stateKeyOf(lane.nonceGroups[i])] = pipelinedb.TxPending
+			p.observer.NonceGroupDropped(ctx, lane.nonceGroups[i], tx)
 			break checkLoop
 		case pipelinedb.TxPending:
 			// This group has not confirmed/failed. Don't look at the rest
 			// until we know it's fate. It is dangerous to look further
 			// since the node has shown to be unreliable in reporting
-			// transaction state for transactions of a later nonce.
+			// transaction state for transactions of a later nonce. If it
+			// has been pending for too long, it is likely underpriced and
+			// blocking the later nonces, so try to replace it, unless it
+			// has already been mined and is only waiting on confirmations.
+			if p.replaceAfter > 0 && !anyMined(all) && time.Since(youngestTransactionTime(lane.nonceGroups[i].Txs)) >= p.replaceAfter {
+				tx, err := p.replaceTransaction(ctx, lane, log, lane.nonceGroups[i])
+				if err != nil {
+					return false, err
+				}
+				if tx != nil {
+					lane.nonceGroups[i].Txs = append(lane.nonceGroups[i].Txs, *tx)
+				}
+			}
 			break checkLoop
 		case pipelinedb.TxFailed:
 			// The transaction has failed. Record the failure and
-			// return.
-			if err := p.db.FinalizeNonceGroup(ctx, p.nonceGroups[i], all); err != nil {
-				return true, err
+			// return, unless the payout group can be quarantined.
+			if err := p.db.FinalizeNonceGroup(ctx, lane.nonceGroups[i], all); err != nil {
+				return false, err
+			}
+			p.observer.NonceGroupFailed(ctx, lane.nonceGroups[i], all)
+			delete(p.states, stateKeyOf(lane.nonceGroups[i]))
+			var quarantined bool
+			if p.quarantine {
+				quarantined, err = p.quarantinePayoutGroup(ctx, lane, log, lane.nonceGroups[i], all)
+				if err != nil {
+					return false, err
+				}
+			}
+			lane.nonceGroups[i].Txs = nil
+			if !quarantined {
+				failedCount++
 			}
-			p.nonceGroups[i].Txs = nil
-			failedCount++
 		case pipelinedb.TxConfirmed:
-			if err := p.db.FinalizeNonceGroup(ctx, p.nonceGroups[i], all); err != nil {
-				return true, err
+			if err := p.db.FinalizeNonceGroup(ctx, lane.nonceGroups[i], all); err != nil {
+				return false, err
 			}
-			p.nonceGroups[i].Txs = nil
+			delete(p.states, stateKeyOf(lane.nonceGroups[i]))
+			lane.nonceGroups[i].Txs = nil
 		}
 	}
 
-	if failedCount > 0 {
-		return true, errs.New("One or more transactions failed, possibly due to insufficient balances")
-	}
-	return false, nil
+	return failedCount > 0, nil
 }
 
-func (p *Pipeline) sendTransaction(ctx context.Context, payoutGroupID int64, nonce uint64) (*pipelinedb.Transaction, error) {
+func (p *Pipeline) sendTransaction(ctx context.Context, lane *lane, payoutGroupID int64, nonce uint64) (*pipelinedb.Transaction, error) {
 	payouts, err := p.db.FetchPayoutGroupPayouts(ctx, payoutGroupID)
 	if err != nil {
 		return nil, err
@@ -348,25 +739,8 @@ func (p *Pipeline) sendTransaction(ctx context.Context, payoutGroupID int64, non
 		return nil, errs.New("no payouts associated with transfer %d", payoutGroupID)
 	}
 
-	sumUSD := decimal.Zero
-	for _, p := range payouts {
-		sumUSD = decimal.Sum(sumUSD, p.USD)
-	}
-
-	for {
-		unmet, err := p.payer.CheckPreconditions(ctx)
-		if err != nil {
-			return nil, err
-		}
-		if len(unmet) == 0 {
-			break
-		}
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(5 * time.Second):
-			p.log.Info("One or more preconditions are not met, waiting for 5 seconds", zap.Strings("unmet", unmet))
-		}
+	if err := p.waitForPreconditions(ctx, lane); err != nil {
+		return nil, err
 	}
 
 	decimals, err := p.payer.GetTokenDecimals(ctx)
@@ -374,40 +748,70 @@ func (p *Pipeline) sendTransaction(ctx context.Context, payoutGroupID int64, non
 		return nil, err
 	}
 
-	storjPrice, err := p.getStorjPrice(ctx)
-	if err != nil {
-		return nil, err
+	price := p.price
+
+	// Each payout is converted individually since that is how the payer
+	// builds the transfers. For multi-payout groups the sum of the converted
+	// amounts can differ slightly from converting the summed USD.
+	tokens := new(big.Int)
+	for _, payout := range payouts {
+		payoutTokens := storjtoken.FromUSD(payout.USD, price, decimals)
+		if payoutTokens.Cmp(zero) <= 0 {
+			p.log.Error("Token amount must be greater than zero",
+				zap.Int64("payout group", payoutGroupID),
+				zap.String("payee", payout.Payee.String()),
+				zap.String("usd", payout.USD.String()),
+				zap.String("price", price.String()),
+				zap.String("tokens", payoutTokens.String()),
+			)
+			return nil, errs.New("cannot transfer %s tokens for payout group %d: must be more than zero", payoutTokens, payoutGroupID)
+		}
+		tokens.Add(tokens, payoutTokens)
 	}
 
-	storjTokens := storjtoken.FromUSD(sumUSD, storjPrice, decimals)
-	if storjTokens.Cmp(zero) <= 0 {
-		p.log.Error("STORJ token amount must be greater than zero",
-			zap.Int64("payout group", payoutGroupID),
-			zap.String("usd", sumUSD.String()),
-			zap.String("price", storjPrice.String()),
-			zap.String("tokens", storjTokens.String()),
-		)
-		return nil, errs.New("cannot transfer %s tokens for payout group %d: must be more than zero", storjTokens, payoutGroupID)
+	// Payout groups that have been sent before (i.e. retries after a
+	// failure) are already part of the totals.
+	if _, ok := p.spent.PayoutGroups[payoutGroupID]; !ok {
+		if err := p.spendLimits.Check(p.spent, payoutGroupID, payouts, tokens, decimals, p.symbol); err != nil {
+			p.log.Error("Spend limit breached", zap.Int64("payout group", payoutGroupID), zap.Error(err))
+			return nil, err
+		}
 	}
 
-	// Check the STORJ balance to make sure there is enough.
-	storjBalance, err := p.payer.GetTokenBalance(ctx)
+	// Check the token balance to make sure there is enough.
+	var balance *big.Int
+	err = p.retry(ctx, "token balance", func() (err error) {
+		balance, err = p.payer.GetTokenBalance(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if storjBalance.Cmp(storjTokens) < 0 {
-		return nil, errs.New("not enough STORJ balance to cover transfer (%s < %s)", storjBalance, storjTokens)
+	if balance.Cmp(tokens) < 0 {
+		return nil, errs.New("not enough token balance to cover transfer (%s < %s)", balance, tokens)
 	}
 
-	txLog := p.log.With(
+	txLog := lane.log.With(
 		zap.Uint64("nonce", nonce),
 		zap.Int64("payout-group-id", payoutGroupID),
 		zap.String("owner", p.owner.String()),
-		zap.String("storj-price", storjPrice.String()),
-		zap.String("storj-tokens", storjTokens.String()),
-		zap.String("storj-balance", storjBalance.String()))
+		zap.String("price", price.String()),
+		zap.String("tokens", tokens.String()),
+		zap.String("balance", balance.String()))
 
-	rawTx, from, err := p.payer.CreateRawTransaction(ctx, txLog, payouts, nonce, storjPrice)
+	rawTx, from, err := lane.payer.CreateRawTransaction(ctx, txLog, payouts, nonce, price)
+	for payer.ErrPreconditionNotMet.Has(err) {
+		// A precondition stopped holding since it was checked, like the
+		// fee having gone up. Wait for it like for any unmet precondition.
+		txLog.Info("Precondition no longer met, waiting", zap.Error(err))
+		if err := sleepFor(ctx, p.preconditionsPollInterval); err != nil {
+			return nil, err
+		}
+		if err := p.waitForPreconditions(ctx, lane); err != nil {
+			return nil, err
+		}
+		rawTx, from, err = lane.payer.CreateRawTransaction(ctx, txLog, payouts, nonce, price)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -424,25 +828,265 @@ func (p *Pipeline) sendTransaction(ctx context.Context, payoutGroupID int64, non
 			Nonce:         rawTx.Nonce,
 			Owner:         p.owner,
 			Spender:       from,
-			StorjPrice:    storjPrice,
-			StorjTokens:   storjTokens,
+			Price:         price,
+			Tokens:        tokens,
 			Raw:           rawTxJSON,
 		})
 
 	if err != nil {
 		return nil, err
 	}
+	p.spent.Add(payoutGroupID, payouts, tokens)
+	p.observer.TxCreated(ctx, tx)
 
-	err = p.payer.SendTransaction(ctx, txLog, rawTx)
+	err = lane.payer.SendTransaction(ctx, txLog, rawTx)
+	p.observer.TxSent(ctx, tx, err)
 	return tx, err
 }
 
-func (p *Pipeline) getStorjPrice(ctx context.Context) (decimal.Decimal, error) {
-	storjQuote, err := p.quoter.GetQuote(ctx, coinmarketcap.STORJ)
+// replaceTransaction sends a replacement with a higher fee for the youngest
+// transaction in the nonce group. It returns nil if the payer does not
+// support replacement or the fee cannot be raised any further.
+func (p *Pipeline) replaceTransaction(ctx context.Context, lane *lane, log *zap.Logger, nonceGroup *pipelinedb.NonceGroup) (*pipelinedb.Transaction, error) {
+	replacer, ok := lane.payer.(payer.Replacer)
+	if !ok {
+		return nil, nil
+	}
+
+	payouts, err := p.db.FetchPayoutGroupPayouts(ctx, nonceGroup.PayoutGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := youngestTransaction(nonceGroup.Txs)
+	txLog := log.With(
+		zap.String("owner", p.owner.String()),
+		zap.String("price", previous.Price.String()),
+		zap.String("tokens", previous.Tokens.String()))
+
+	rawTx, from, err := replacer.CreateReplacementTransaction(ctx, txLog, payouts, previous)
+	switch {
+	case errors.Is(err, payer.ErrReplacementCapped):
+		txLog.Warn("Unable to replace pending transaction", zap.Error(err))
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	rawTxJSON, err := json.Marshal(rawTx.Raw)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	tx, err := p.db.CreateTransaction(ctx,
+		pipelinedb.Transaction{
+			PayoutGroupID: nonceGroup.PayoutGroupID,
+			Hash:          rawTx.Hash,
+			Nonce:         rawTx.Nonce,
+			Owner:         p.owner,
+			Spender:       from,
+			Price:         previous.Price,
+			Tokens:        previous.Tokens,
+			Raw:           rawTxJSON,
+		})
+	if err != nil {
+		return nil, err
+	}
+	p.observer.TxCreated(ctx, tx)
+
+	err = lane.payer.SendTransaction(ctx, txLog, rawTx)
+	p.observer.TxSent(ctx, tx, err)
+	return tx, err
+}
+
+// quarantinePayoutGroup quarantines the payout group of a failed nonce
+// group. It returns false if the failure is balance related and should halt
+// the pipeline instead. It returns an error once more payout groups have
+// been quarantined than allowed.
+func (p *Pipeline) quarantinePayoutGroup(ctx context.Context, lane *lane, log *zap.Logger, nonceGroup *pipelinedb.NonceGroup, statuses []*pipelinedb.TxStatus) (bool, error) {
+	tx, receipt := failedTransaction(nonceGroup, statuses)
+
+	reason := "transaction failed"
+	var balanceRelated bool
+	if diagnoser, ok := lane.payer.(payer.FailureDiagnoser); ok {
+		err := p.retry(ctx, "diagnose failure", func() (err error) {
+			reason, balanceRelated, err = diagnoser.DiagnoseFailure(ctx, tx, receipt)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+	} else {
+		var balance *big.Int
+		err := p.retry(ctx, "token balance", func() (err error) {
+			balance, err = lane.payer.GetTokenBalance(ctx)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		if tx.Tokens != nil && balance.Cmp(tx.Tokens) < 0 {
+			reason = fmt.Sprintf("token balance (%s) does not cover the transfer (%s)", balance, tx.Tokens)
+			balanceRelated = true
+		}
+	}
+
+	if balanceRelated {
+		log.Error("Transaction failed due to an insufficient balance; not quarantining",
+			zap.String("hash", tx.Hash),
+			zap.String("reason", reason))
+		return false, nil
+	}
+
+	if err := p.db.QuarantinePayoutGroup(ctx, nonceGroup.PayoutGroupID, reason); err != nil {
+		return false, err
+	}
+	p.quarantined++
+	log.Warn("Payout group quarantined",
+		zap.String("hash", tx.Hash),
+		zap.String("reason", reason),
+		zap.Int("quarantined", p.quarantined))
+
+	if p.quarantined > p.maxQuarantined {
+		return true, errs.New("%d payout groups have been quarantined, more than the limit of %d", p.quarantined, p.maxQuarantined)
+	}
+	return true, nil
+}
+
+// lockPrice loads the price locked for the payout. If no price
+// has been locked yet, a quote is obtained and locked so that every
+// transaction in the payout uses the same price.
+func (p *Pipeline) lockPrice(ctx context.Context) error {
+	lock, err := p.db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		if err := lock.CheckSymbol(string(p.symbol)); err != nil {
+			return err
+		}
+	} else {
+		var quote *coinmarketcap.Quote
+		err = p.retry(ctx, "price quote", func() (err error) {
+			quote, err = p.quoter.GetQuote(ctx, p.symbol)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		lock = &pipelinedb.PriceLock{
+			Symbol:    string(p.symbol),
+			Price:     quote.Price,
+			Source:    pipelinedb.PriceSourceCoinMarketCap,
+			Timestamp: quote.LastUpdated,
+		}
+		if err := p.db.LockPrice(ctx, *lock); err != nil {
+			return err
+		}
+	}
+
+	p.log.Info("Using locked price",
+		zap.String("symbol", string(p.symbol)),
+		zap.String("price", lock.Price.String()),
+		zap.String("source", lock.Source),
+		zap.Time("priced-at", lock.Timestamp),
+	)
+	p.price = lock.Price
+	return nil
+}
+
+// checkPause applies any pending pause or resume requests.
+func (p *Pipeline) checkPause() {
+	for {
+		select {
+		case paused := <-p.pauseCh:
+			if paused == p.paused {
+				continue
+			}
+			p.paused = paused
+			if paused {
+				p.log.Info("Pipeline paused; waiting on nonce groups in flight", zap.Int("pending", p.inFlight()))
+			} else {
+				p.log.Info("Pipeline resumed")
+			}
+		default:
+			return
+		}
+	}
+}
+
+// observeState notifies the observer if the state of the nonce group differs
+// from the state last observed for it. Nonce groups start out pending.
+func (p *Pipeline) observeState(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, state pipelinedb.TxState) {
+	from, ok := p.states[stateKeyOf(nonceGroup)]
+	if !ok {
+		from = pipelinedb.TxPending
+	}
+	if from == state {
+		return
+	}
+	p.states[stateKeyOf(nonceGroup)] = state
+	p.observer.NonceGroupStateChanged(ctx, nonceGroup, from, state)
+}
+
+// reopenReorgedPayoutGroups checks that every confirmed transaction is
+// still included in the block it was confirmed in. Payout groups whose
+// transaction has been reorganized out of the chain are reopened so that the
+// pipeline verifies them again. Transactions in blocks the payer considers
+// final are not checked. It returns true if any were reopened.
+func (p *Pipeline) reopenReorgedPayoutGroups(ctx context.Context) (bool, error) {
+	checker, ok := p.payer.(payer.FinalityChecker)
+	if !ok {
+		return false, nil
+	}
+
+	var finalized uint64
+	if horizon, ok := p.payer.(payer.FinalityHorizon); ok {
+		err := p.retry(ctx, "finalized block", func() (err error) {
+			finalized, err = horizon.FinalizedBlock(ctx)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	txs, err := p.db.FetchConfirmedTransactions(ctx)
 	if err != nil {
-		return decimal.Decimal{}, err
+		return false, err
 	}
-	return storjQuote.Price, nil
+
+	var reopened bool
+	for _, tx := range txs {
+		if tx.BlockHash == nil {
+			continue
+		}
+		if tx.Receipt != nil && tx.Receipt.BlockNumber != nil && tx.Receipt.BlockNumber.Uint64() <= finalized {
+			continue
+		}
+		var canonical bool
+		err := p.retry(ctx, "finality check", func() (err error) {
+			canonical, err = checker.IsCanonical(ctx, tx.Hash, *tx.BlockHash)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		if canonical {
+			continue
+		}
+		p.log.Warn("Confirmed transaction is no longer in the canonical chain; reopening payout group",
+			zap.String("hash", tx.Hash),
+			zap.String("block-hash", tx.BlockHash.String()),
+			zap.Uint64("nonce", tx.Nonce),
+			zap.Int64("payout-group-id", tx.PayoutGroupID),
+		)
+		if err := p.db.ReopenPayoutGroup(ctx, tx); err != nil {
+			return false, err
+		}
+		reopened = true
+	}
+	return reopened, nil
 }
 
 func sleepFor(ctx context.Context, d time.Duration) error {
@@ -455,13 +1099,44 @@ func sleepFor(ctx context.Context, d time.Duration) error {
 	}
 }
 
-func youngestTransactionTime(txs []pipelinedb.Transaction) time.Time {
+// anyMined returns true if any of the transactions has a receipt.
+func anyMined(statuses []*pipelinedb.TxStatus) bool {
+	for _, status := range statuses {
+		if status.Receipt != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// failedTransaction returns the transaction of the nonce group that failed
+// and its receipt. If none is reported as failed, the youngest transaction is
+// returned without a receipt.
+func failedTransaction(nonceGroup *pipelinedb.NonceGroup, statuses []*pipelinedb.TxStatus) (pipelinedb.Transaction, *types.Receipt) {
+	for _, status := range statuses {
+		if status.State != pipelinedb.TxFailed {
+			continue
+		}
+		for _, tx := range nonceGroup.Txs {
+			if tx.Hash == status.Hash {
+				return tx, status.Receipt
+			}
+		}
+	}
+	return youngestTransaction(nonceGroup.Txs), nil
+}
+
+func youngestTransaction(txs []pipelinedb.Transaction) pipelinedb.Transaction {
 	// This _should_ be the last transaction in the list, but just in case...
-	var youngest time.Time
+	var youngest pipelinedb.Transaction
 	for _, tx := range txs {
-		if youngest.Before(tx.CreatedAt) {
-			youngest = tx.CreatedAt
+		if youngest.CreatedAt.Before(tx.CreatedAt) {
+			youngest = tx
 		}
 	}
 	return youngest
 }
+
+func youngestTransactionTime(txs []pipelinedb.Transaction) time.Time {
+	return youngestTransaction(txs).CreatedAt
+}
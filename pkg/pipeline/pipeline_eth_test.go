@@ -2,9 +2,16 @@ package pipeline
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -12,11 +19,15 @@ import (
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient/simulated"
 
 	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payer"
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/safe"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/shopspring/decimal"
@@ -31,6 +42,9 @@ import (
 
 const (
 	initialStorj int64 = 1e11
+
+	// signerAuthToken is the auth token of the stand-in external signers.
+	signerAuthToken = "secret"
 )
 
 var (
@@ -46,6 +60,10 @@ var (
 	// using a transferFrom() flow.
 	spender = ethtest.NewAccount()
 
+	// extraSpender is another account that transactions are paid from (i.e.
+	// gas) when using a transferFrom() flow with multiple spender lanes.
+	extraSpender = ethtest.NewAccount()
+
 	alice = ethtest.NewAccount()
 	bob   = ethtest.NewAccount()
 	chuck = ethtest.NewAccount()
@@ -455,7 +473,7 @@ func TestPipelineTxFailure(t *testing.T) {
 	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(bob.Address))
 }
 
-func TestPipelineUsesLatestGasEstimateAndStorjPrice(t *testing.T) {
+func TestPipelineUsesLockedPrice(t *testing.T) {
 	test := NewPipelineTest(t)
 
 	test.InitializePayoutGroups([]*pipelinedb.Payout{
@@ -473,17 +491,23 @@ func TestPipelineUsesLatestGasEstimateAndStorjPrice(t *testing.T) {
 	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
 		switch step {
 		case 0:
-			// Pipeline just started with no existing nonce groups
+			// Pipeline just started with no existing nonce groups. The
+			// quoted price has been locked.
 			test.R.Empty(pipeline)
+			lock := test.FetchPriceLock()
+			test.R.Equal("STORJ", lock.Symbol)
+			test.R.Equal("1", lock.Price.String())
+			test.R.Equal(pipelinedb.PriceSourceCoinMarketCap, lock.Source)
 			return false, nil
 		case 1:
 			test.R.Len(pipeline, 1)
 
 			test.R.Len(pipeline[0].Txs, 1)
 			tx := test.FetchTransaction(pipeline[0].Txs[0].Hash)
-			test.R.Equal(decimal.RequireFromString("1.00").String(), tx.StorjPrice.String())
-			test.R.Equal(big.NewInt(100000000), tx.StorjTokens)
+			test.R.Equal(decimal.RequireFromString("1.00").String(), tx.Price.String())
+			test.R.Equal(big.NewInt(100000000), tx.Tokens)
 
+			// A new quote must not change the price mid-payout.
 			test.SetStorjPrice("10.00")
 
 			test.commit()
@@ -496,8 +520,8 @@ func TestPipelineUsesLatestGasEstimateAndStorjPrice(t *testing.T) {
 
 			test.R.Len(pipeline[0].Txs, 1)
 			tx := test.FetchTransaction(pipeline[0].Txs[0].Hash)
-			test.R.Equal(decimal.RequireFromString("10.00").String(), tx.StorjPrice.String())
-			test.R.Equal(big.NewInt(20000000), tx.StorjTokens)
+			test.R.Equal(decimal.RequireFromString("1.00").String(), tx.Price.String())
+			test.R.Equal(big.NewInt(200000000), tx.Tokens)
 
 			test.commit()
 			return false, nil
@@ -511,7 +535,74 @@ func TestPipelineUsesLatestGasEstimateAndStorjPrice(t *testing.T) {
 	})
 
 	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
-	test.RequireEqualBig(big.NewInt(2e7), test.STORJBalance(bob.Address))
+	test.RequireEqualBig(big.NewInt(2e8), test.STORJBalance(bob.Address))
+}
+
+func TestPipelineUsesPreviouslyLockedPrice(t *testing.T) {
+	test := NewPipelineTest(t)
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+	test.SetStorjPrice("1.00")
+	test.R.NoError(test.DB.LockPrice(context.Background(), pipelinedb.PriceLock{
+		Symbol:    "STORJ",
+		Price:     decimal.RequireFromString("2.00"),
+		Source:    pipelinedb.PriceSourceOperator,
+		Timestamp: time.Now(),
+	}))
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 1)
+
+			test.R.Len(pipeline[0].Txs, 1)
+			tx := test.FetchTransaction(pipeline[0].Txs[0].Hash)
+			test.R.Equal(decimal.RequireFromString("2.00").String(), tx.Price.String())
+			test.R.Equal(big.NewInt(50000000), tx.Tokens)
+
+			test.commit()
+			return false, nil
+		case 2:
+			return true, nil
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(5e7), test.STORJBalance(alice.Address))
+
+	lock := test.FetchPriceLock()
+	test.R.Equal("2", lock.Price.String())
+	test.R.Equal(pipelinedb.PriceSourceOperator, lock.Source)
+}
+
+func TestPipelineRejectsPriceLockedForOtherSymbol(t *testing.T) {
+	test := NewPipelineTest(t, WithNative())
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+	test.SetETHPrice("2000.00")
+	test.R.NoError(test.DB.LockPrice(context.Background(), pipelinedb.PriceLock{
+		Symbol:    "STORJ",
+		Price:     decimal.RequireFromString("0.50"),
+		Source:    pipelinedb.PriceSourceOperator,
+		Timestamp: time.Now(),
+	}))
+
+	test.AssertProcessPayoutsFails("price is locked for STORJ, not ETH")
 }
 
 func TestPipelineTransferFrom(t *testing.T) {
@@ -602,7 +693,195 @@ func TestPipelineTransferFrom(t *testing.T) {
 	test.RequireEqualBig(big.NewInt(0), test.Allowance(owner, spender))
 }
 
-func TestPipelineChecksSTORJBalanceBeforeTransfer(t *testing.T) {
+func TestPipelineSpenderLanes(t *testing.T) {
+	test := NewPipelineTest(t, WithSpender(spender), WithExtraSpenders(extraSpender))
+
+	test.Approve(owner, spender, big.NewInt(1e8))
+	test.Approve(owner, extraSpender, big.NewInt(1e8))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, _ func()) (bool, error) {
+		switch step {
+		case 0:
+			// Pipeline just started with no existing nonce groups
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			// Each lane sent a payout group with its own first nonce,
+			// even though the limit is one per lane.
+			test.R.Len(pipeline, 2)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			test.ValidatePipelineSlot(pipeline[1], 0, 2, pipelinedb.TxPending)
+			test.R.Equal(spender.Address, pipeline[0].Spender)
+			test.R.Equal(spender.Address, test.FetchTransaction(pipeline[0].Txs[0].Hash).Spender)
+			test.R.Equal(extraSpender.Address, pipeline[1].Spender)
+			test.R.Equal(extraSpender.Address, test.FetchTransaction(pipeline[1].Txs[0].Hash).Spender)
+			test.commit()
+			return false, nil
+		case 2:
+			test.R.Len(pipeline, 2)
+			test.R.Empty(pipeline[0].Txs)
+			test.R.Empty(pipeline[1].Txs)
+			return true, nil
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	// Both payees were paid from owner, via a spender each.
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(bob.Address))
+	test.RequireEqualBig(big.NewInt(initialStorj-2e8), test.STORJBalance(owner.Address))
+	test.RequireEqualBig(big.NewInt(0), test.Allowance(owner, spender))
+	test.RequireEqualBig(big.NewInt(0), test.Allowance(owner, extraSpender))
+}
+
+func TestPipelineSpenderLanesRestartWithoutExtraSpender(t *testing.T) {
+	test := NewPipelineTest(t, WithSpender(spender), WithExtraSpenders(extraSpender))
+
+	test.Approve(owner, spender, big.NewInt(1e8))
+	test.Approve(owner, extraSpender, big.NewInt(1e8))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 2)
+			cancel()
+			return true, context.Canceled
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	// Restarted without the extra spender, its unfinished nonce group must
+	// not be merged into the lane of the spender.
+	test.extraSpenders = nil
+	test.AssertProcessPayoutsFails(fmt.Sprintf("spender %s has unfinished transactions but is not one of the configured spenders", extraSpender.Address))
+}
+
+func TestPipelineQuarantineHaltsOnAllowanceFailure(t *testing.T) {
+	test := NewPipelineTest(t, WithSpender(spender), WithLimit(2), WithQuarantine())
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+	test.SetStorjPrice("1.00")
+
+	// Approve only one STORJ token worth. This will cause the second payout
+	// to fail due to insufficient allowance, which is not quarantined.
+	test.Approve(owner, spender, big.NewInt(1e8))
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 2)
+			test.commit()
+			return true, errors.New("One or more transactions failed, possibly due to insufficient balances")
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	quarantined, err := test.DB.FetchQuarantinedPayoutGroups(context.Background())
+	test.R.NoError(err)
+	test.R.Empty(quarantined)
+	test.R.Nil(test.FetchPayoutGroupFinalTxHash(2))
+}
+
+func TestPipelineQuarantineHaltsOnDisperseAllowanceFailure(t *testing.T) {
+	test := NewPipelineTest(t, WithLimit(2), WithDisperse(), WithQuarantine())
+
+	test.InitializePayoutGroupsOfSize(2, []*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: chuck.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: dave.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+	test.SetStorjPrice("1.00")
+
+	// Approve the disperse contract for one and a half payout groups. Each
+	// payout group is covered when it is sent, but the second one reverts
+	// once the first has used up most of the allowance. The disperse
+	// contract reverts without a reason, so the allowance is what tells
+	// that the failure is not to be quarantined.
+	test.ApproveDisperse(big.NewInt(3e8))
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 2)
+			test.commit()
+			return true, errors.New("One or more transactions failed, possibly due to insufficient balances")
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	quarantined, err := test.DB.FetchQuarantinedPayoutGroups(context.Background())
+	test.R.NoError(err)
+	test.R.Empty(quarantined)
+	test.R.NotNil(test.FetchPayoutGroupFinalTxHash(1))
+	test.R.Nil(test.FetchPayoutGroupFinalTxHash(2))
+}
+
+func TestPipelineChecksTokenBalanceBeforeTransfer(t *testing.T) {
 	test := NewPipelineTest(t)
 
 	test.InitializePayoutGroups([]*pipelinedb.Payout{
@@ -618,7 +897,7 @@ func TestPipelineChecksSTORJBalanceBeforeTransfer(t *testing.T) {
 
 	pipeline := test.NewPipeline()
 	err := pipeline.ProcessPayouts(context.Background())
-	test.R.EqualError(err, "not enough STORJ balance to cover transfer (100000000000 < 1000000000000)")
+	test.R.EqualError(err, "not enough token balance to cover transfer (100000000000 < 1000000000000)")
 
 	// No transactions should have been sent
 	pending, err := test.Client.PendingTransactionCount(context.Background())
@@ -632,7 +911,7 @@ func TestPipelineChecksSTORJBalanceBeforeTransfer(t *testing.T) {
 	test.RequireEqualBig(big.NewInt(initialStorj), test.STORJBalance(owner.Address))
 }
 
-func TestPipelineChecksSTORJAllowanceBeforeTransfer(t *testing.T) {
+func TestPipelineChecksTokenAllowanceBeforeTransfer(t *testing.T) {
 	test := NewPipelineTest(t, WithSpender(spender))
 
 	// Approve for 1/10 of a storj token. With the 1.00 price, spender will
@@ -650,7 +929,7 @@ func TestPipelineChecksSTORJAllowanceBeforeTransfer(t *testing.T) {
 
 	pipeline := test.NewPipeline()
 	err := pipeline.ProcessPayouts(context.Background())
-	test.R.EqualError(err, "not enough STORJ allowance to cover transfer")
+	test.R.EqualError(err, "not enough token allowance to cover transfer")
 
 	// No transactions should have been sent
 	pending, err := test.Client.PendingTransactionCount(context.Background())
@@ -717,64 +996,1221 @@ func TestPipelineTooSmallPayment(t *testing.T) {
 	test.AssertProcessPayoutsFails("cannot transfer 0 tokens for payout group 1: must be more than zero")
 }
 
-/////////////////////////////////////////////////////////////////////////////
-// Helpers
-/////////////////////////////////////////////////////////////////////////////
+func TestPipelineSpendLimits(t *testing.T) {
+	t.Run("max payout USD", func(t *testing.T) {
+		test := NewPipelineTest(t, WithSpendLimits(SpendLimits{
+			MaxPayoutUSD: decimal.RequireFromString("1.5"),
+		}))
+		test.InitializePayoutGroups([]*pipelinedb.Payout{
+			{CSVLine: 2, Payee: alice.Address, USD: decimal.RequireFromString("2.00")},
+		})
+		test.SetStorjPrice("1.00")
+
+		test.AssertProcessPayoutsFails(fmt.Sprintf("spend limit: payout on CSV line 2 to %s is $2; max per payout is $1.5", alice.Address))
+		test.RequireEqualBig(big.NewInt(0), test.STORJBalance(alice.Address))
+	})
 
-type PipelineTestOption func(*PipelineTest)
+	t.Run("max payee USD", func(t *testing.T) {
+		test := NewPipelineTest(t, WithSpendLimits(SpendLimits{
+			MaxPayeeUSD: decimal.RequireFromString("2.5"),
+		}))
+		test.InitializePayoutGroupsOfSize(2, []*pipelinedb.Payout{
+			{CSVLine: 2, Payee: alice.Address, USD: decimal.RequireFromString("1.00")},
+			{CSVLine: 3, Payee: alice.Address, USD: decimal.RequireFromString("2.00")},
+		})
+		test.SetStorjPrice("1.00")
+
+		test.AssertProcessPayoutsFails(fmt.Sprintf("spend limit: payout group 1 would bring the total paid to %s to $3; max per payee is $2.5", alice.Address))
+	})
 
-func WithLimit(limit int) PipelineTestOption {
-	return func(c *PipelineTest) {
-		c.limit = limit
-	}
-}
+	t.Run("max tokens", func(t *testing.T) {
+		test := NewPipelineTest(t, WithSpendLimits(SpendLimits{
+			MaxTokens: decimal.RequireFromString("1"),
+		}))
+		test.InitializePayoutGroups([]*pipelinedb.Payout{
+			{CSVLine: 2, Payee: alice.Address, USD: decimal.RequireFromString("2.00")},
+		})
+		test.SetStorjPrice("1.00")
 
-func WithSpender(spender *ethtest.Account) PipelineTestOption {
-	return func(c *PipelineTest) {
-		c.spender = spender
-	}
+		test.AssertProcessPayoutsFails("spend limit: payout group 1 would bring the total to 200000000 (2 STORJ); max is 1 STORJ")
+	})
+
+	t.Run("max tokens of another token", func(t *testing.T) {
+		test := NewPipelineTest(t, WithToken("USDC", 6), WithSpendLimits(SpendLimits{
+			MaxTokens: decimal.RequireFromString("1"),
+		}))
+		test.InitializePayoutGroups([]*pipelinedb.Payout{
+			{CSVLine: 2, Payee: alice.Address, USD: decimal.RequireFromString("2.00")},
+		})
+		test.SetPrice("USDC", "1.00")
+
+		test.AssertProcessPayoutsFails("spend limit: payout group 1 would bring the total to 2000000 (2 USDC); max is 1 USDC")
+	})
+
+	t.Run("max USD survives restarts", func(t *testing.T) {
+		test := NewPipelineTest(t, WithSpendLimits(SpendLimits{
+			MaxUSD: decimal.RequireFromString("3"),
+		}))
+		test.InitializePayoutGroups([]*pipelinedb.Payout{
+			{CSVLine: 2, Payee: alice.Address, USD: decimal.RequireFromString("1.00")},
+			{CSVLine: 3, Payee: bob.Address, USD: decimal.RequireFromString("2.00")},
+		})
+		test.SetStorjPrice("1.00")
+
+		test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+			switch step {
+			case 0:
+				return false, nil
+			case 1, 3:
+				test.R.Len(pipeline, 1)
+				test.commit()
+				return false, nil
+			case 2:
+				return false, nil
+			case 4:
+				return true, nil
+			default:
+				test.Fatalf("not expecting step %d", step)
+				return false, nil
+			}
+		})
+		test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+		test.RequireEqualBig(big.NewInt(2e8), test.STORJBalance(bob.Address))
+
+		// A fresh pipeline picks up the totals from the database.
+		test.R.NoError(test.DB.CreatePayoutGroup(context.Background(), 3, []*pipelinedb.Payout{
+			{CSVLine: 4, Payee: alice.Address, USD: decimal.RequireFromString("0.50")},
+		}))
+		test.AssertProcessPayoutsFails("spend limit: payout group 3 would bring the total to $3.5; max is $3")
+		test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+	})
 }
 
-func WithGasTipCap(gasTipCap *big.Int) PipelineTestOption {
-	return func(c *PipelineTest) {
-		c.gasTipCap = gasTipCap
+func TestPipelineDefaultFeeCap(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		opts       []PipelineTestOption
+		doubleBase bool
+	}{
+		{name: "without replacement"},
+		{name: "with replacement", opts: []PipelineTestOption{WithReplaceAfter(time.Hour)}, doubleBase: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			test := NewPipelineTest(t, tt.opts...)
+
+			test.InitializePayoutGroups([]*pipelinedb.Payout{
+				{
+					Payee: alice.Address,
+					USD:   decimal.RequireFromString("1.00"),
+				},
+			})
+
+			test.SetStorjPrice("1.00")
+
+			test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+				switch step {
+				case 0:
+					test.R.Empty(pipeline)
+					return false, nil
+				case 1:
+					tx := test.FetchRawTransaction(pipeline[0].Txs[0].Hash)
+					if tt.doubleBase {
+						// The fixed fees leave room for the base fee to
+						// double, which is room to bump the fee.
+						head, err := test.Client.HeaderByNumber(context.Background(), nil)
+						test.R.NoError(err)
+						expected := new(big.Int).Mul(head.BaseFee, big.NewInt(2))
+						test.RequireEqualBig(expected.Add(expected, tx.GasTipCap()), tx.GasFeeCap())
+					} else {
+						// Like before there were fee strategies, the fee
+						// cap is the max gas price.
+						test.RequireEqualBig(test.maxGas, tx.GasFeeCap())
+					}
+					test.commit()
+					return false, nil
+				case 2:
+					test.ValidatePipelineSlot(pipeline[0], 0, 1)
+					return true, nil
+				default:
+					return false, errors.New("should have finished")
+				}
+			})
+
+			test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+		})
 	}
 }
 
-type PipelineTest struct {
-	*testing.T
-	A *assert.Assertions
-	R *require.Assertions
+func TestPipelineReplacesPendingTransaction(t *testing.T) {
+	// Every pending transaction is immediately eligible for replacement.
+	test := NewPipelineTest(t, WithReplaceAfter(time.Nanosecond))
 
-	// config
-	limit     int
-	spender   *ethtest.Account
-	gasTipCap *big.Int
-	maxGas    *big.Int
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
 
-	DB *pipelinedb.DB
+	test.SetStorjPrice("1.00")
 
-	Quoter *ethtest.Quoter
+	var (
+		tx1Hash string
+		tx2Hash string
+	)
 
-	Backend         *simulated.Backend
-	Client          simulated.Client
-	Contract        *contract.Token
-	ContractAddress common.Address
-}
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			// The transaction was replaced right after it was sent since
+			// it was still pending. The replacement is part of the same
+			// nonce group and pays a higher fee for the same transfer.
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending, pipelinedb.TxPending)
+			tx1Hash = pipeline[0].Txs[0].Hash
+			tx2Hash = pipeline[0].Txs[1].Hash
 
-func NewPipelineTest(t *testing.T, opts ...PipelineTestOption) *PipelineTest {
-	dir := t.TempDir()
+			tx1 := test.FetchRawTransaction(tx1Hash)
+			tx2 := test.FetchRawTransaction(tx2Hash)
+			test.R.Equal(tx1.Nonce(), tx2.Nonce())
+			test.R.Equal(tx1.Data(), tx2.Data())
 
-	test := &PipelineTest{
-		T:     t,
-		A:     assert.New(t),
-		R:     require.New(t),
-		limit: 1,
-	}
+			// With replacement, the fee cap left room below the max gas
+			// price for the bump.
+			test.R.Negative(tx1.GasFeeCap().Cmp(test.maxGas))
+			test.R.GreaterOrEqual(new(big.Int).Mul(tx2.GasTipCap(), big.NewInt(100)).Cmp(new(big.Int).Mul(tx1.GasTipCap(), big.NewInt(110))), 0)
+			test.R.GreaterOrEqual(new(big.Int).Mul(tx2.GasFeeCap(), big.NewInt(100)).Cmp(new(big.Int).Mul(tx1.GasFeeCap(), big.NewInt(110))), 0)
+			test.R.LessOrEqual(tx2.GasFeeCap().Cmp(test.maxGas), 0)
 
-	for _, opt := range opts {
-		opt(test)
-	}
+			test.commit()
+			return false, nil
+		case 2:
+			// The replacement confirmed. The original was dropped.
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			test.R.Equal(pipelinedb.TxDropped, test.FetchTransactionState(tx1Hash))
+			test.R.Equal(pipelinedb.TxConfirmed, test.FetchTransactionState(tx2Hash))
+			test.R.Equal(common.HexToHash(tx2Hash), *test.FetchPayoutGroupFinalTxHash(1))
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+}
+
+func TestPipelineWaitsForConfirmations(t *testing.T) {
+	// Every pending transaction is immediately eligible for replacement,
+	// which must not happen once a transaction has been mined and is only
+	// waiting on confirmations.
+	test := NewPipelineTest(t, WithConfirmations(2), WithReplaceAfter(time.Nanosecond))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			// The transaction was replaced right after it was sent.
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending, pipelinedb.TxPending)
+			test.commit()
+			return false, nil
+		case 2:
+			// The replacement has been mined but is only one block deep.
+			// It stays pending and is not replaced again.
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending, pipelinedb.TxPending)
+			test.R.Nil(test.FetchPayoutGroupFinalTxHash(1))
+			test.commit()
+			return false, nil
+		case 3:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			test.R.NotNil(test.FetchPayoutGroupFinalTxHash(1))
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+}
+
+func TestPipelineReopensReorgedPayoutGroups(t *testing.T) {
+	test := NewPipelineTest(t)
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	var txHash string
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			txHash = pipeline[0].Txs[0].Hash
+			test.commit()
+			return false, nil
+		case 2:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	tx := test.FetchTransaction(txHash)
+	test.R.Equal(pipelinedb.TxConfirmed, tx.State)
+	test.R.NotNil(tx.BlockHash)
+	oldBlockHash := *tx.BlockHash
+
+	// Reorg the block containing the transaction out of the chain and
+	// include the transaction again in a different block on the new
+	// canonical chain.
+	oldBlock, err := test.Client.BlockByHash(context.Background(), oldBlockHash)
+	test.R.NoError(err)
+	test.R.NoError(test.Backend.Fork(oldBlock.ParentHash()))
+	test.commit()
+	test.commit()
+	test.R.NoError(test.Client.SendTransaction(context.Background(), test.FetchRawTransaction(txHash)))
+	test.commit()
+
+	// The next run notices the reorg, reopens the payout group and
+	// confirms it again against the new chain while initializing.
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Len(pipeline, 1)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	tx = test.FetchTransaction(txHash)
+	test.R.Equal(pipelinedb.TxConfirmed, tx.State)
+	test.R.NotNil(tx.BlockHash)
+	test.R.NotEqual(oldBlockHash, *tx.BlockHash)
+	test.R.Equal(common.HexToHash(txHash), *test.FetchPayoutGroupFinalTxHash(1))
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+}
+
+func TestPipelineContinuesAfterOlderReopenedPayoutGroup(t *testing.T) {
+	test := NewPipelineTest(t, WithLimit(2))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	var txHash string
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			test.ValidatePipelineSlot(pipeline[1], 1, 2, pipelinedb.TxPending)
+			txHash = pipeline[0].Txs[0].Hash
+			test.commit()
+			return false, nil
+		case 2:
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	// Reopen the payout group of the first nonce, like a reorg would, and
+	// add another payout group. Requiring more confirmations keeps the
+	// reopened group in the pipeline while the new one is sent, which must
+	// not reuse the nonce following the reopened group.
+	test.R.NoError(test.DB.ReopenPayoutGroup(context.Background(), test.FetchTransaction(txHash)))
+	test.R.NoError(test.DB.CreatePayoutGroup(context.Background(), 3, []*pipelinedb.Payout{
+		{
+			Payee: chuck.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	}))
+	test.confirmations = 3
+
+	var nextHash string
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Len(pipeline, 1)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 2)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			test.ValidatePipelineSlot(pipeline[1], 2, 3, pipelinedb.TxPending)
+			nextHash = pipeline[1].Txs[0].Hash
+			test.commit()
+			return false, nil
+		case 2, 3:
+			test.commit()
+			return false, nil
+		case 4:
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.R.Equal(common.HexToHash(txHash), *test.FetchPayoutGroupFinalTxHash(1))
+	test.R.Equal(common.HexToHash(nextHash), *test.FetchPayoutGroupFinalTxHash(3))
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(chuck.Address))
+}
+
+func TestPipelineFeeHistoryStrategy(t *testing.T) {
+	test := NewPipelineTest(t, WithFeeHistory())
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+
+			// The tip comes from the fee history, which only has the
+			// deployment transactions in it so far.
+			history, err := test.Client.FeeHistory(context.Background(), eth.DefaultFeeHistoryBlocks, nil, []float64{eth.DefaultFeeHistoryPercentile})
+			test.R.NoError(err)
+			var rewards []*big.Int
+			for _, reward := range history.Reward {
+				rewards = append(rewards, reward[0])
+			}
+			slices.SortFunc(rewards, func(a, b *big.Int) int { return a.Cmp(b) })
+
+			tx := test.FetchRawTransaction(pipeline[0].Txs[0].Hash)
+			test.RequireEqualBig(rewards[len(rewards)/2], tx.GasTipCap())
+
+			// Without replacement nothing bumps the fee, so the fee cap
+			// is the max gas price rather than the suggested one.
+			test.RequireEqualBig(test.maxGas, tx.GasFeeCap())
+
+			test.commit()
+			return false, nil
+		case 2:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+}
+
+func TestPipelineDisperse(t *testing.T) {
+	test := NewPipelineTest(t, WithLimit(2), WithDisperse())
+
+	test.InitializePayoutGroupsOfSize(3, []*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("2.00"),
+		},
+		{
+			Payee: chuck.Address,
+			USD:   decimal.RequireFromString("3.00"),
+		},
+		{
+			Payee: dave.Address,
+			USD:   decimal.RequireFromString("4.00"),
+		},
+		{
+			Payee: eve.Address,
+			USD:   decimal.RequireFromString("5.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+	test.ApproveDisperse(big.NewInt(15e8))
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			// Both payout groups are sent in one transaction each. The
+			// owner used nonce 0 for the approval.
+			test.R.Len(pipeline, 2)
+			test.ValidatePipelineSlot(pipeline[0], 1, 1, pipelinedb.TxPending)
+			test.ValidatePipelineSlot(pipeline[1], 2, 2, pipelinedb.TxPending)
+			test.RequireEqualBig(big.NewInt(6e8), test.FetchTransaction(pipeline[0].Txs[0].Hash).Tokens)
+			test.RequireEqualBig(big.NewInt(9e8), test.FetchTransaction(pipeline[1].Txs[0].Hash).Tokens)
+			test.commit()
+			return false, nil
+		case 2:
+			// Both nonce groups were confirmed in the previous step.
+			test.ValidatePipelineSlot(pipeline[0], 1, 1)
+			test.ValidatePipelineSlot(pipeline[1], 2, 2)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	// Every payout in a payout group maps to the shared transaction hash.
+	payouts, err := test.DB.FetchPayouts(context.Background())
+	test.R.NoError(err)
+	test.R.Len(payouts, 5)
+	finalTxHashes := make(map[int64]common.Hash)
+	for _, payout := range payouts {
+		hash := test.FetchPayoutGroupFinalTxHash(payout.PayoutGroupID)
+		test.R.NotNil(hash)
+		finalTxHashes[payout.PayoutGroupID] = *hash
+	}
+	test.R.Len(finalTxHashes, 2)
+
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+	test.RequireEqualBig(big.NewInt(2e8), test.STORJBalance(bob.Address))
+	test.RequireEqualBig(big.NewInt(3e8), test.STORJBalance(chuck.Address))
+	test.RequireEqualBig(big.NewInt(4e8), test.STORJBalance(dave.Address))
+	test.RequireEqualBig(big.NewInt(5e8), test.STORJBalance(eve.Address))
+	test.RequireEqualBig(big.NewInt(initialStorj-15e8), test.STORJBalance(owner.Address))
+}
+
+func TestPipelineDisperseNotEnoughAllowance(t *testing.T) {
+	test := NewPipelineTest(t, WithDisperse())
+
+	test.InitializePayoutGroupsOfSize(2, []*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("2.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+	test.ApproveDisperse(big.NewInt(2e8))
+
+	test.AssertProcessPayoutsFails(fmt.Sprintf("not enough token allowance for disperse contract %s to cover transfer (200000000 < 300000000)", test.DisperseAddress))
+}
+
+func TestPipelineSimulateBalance(t *testing.T) {
+	test := NewPipelineTest(t)
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("600.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("600.00"),
+		},
+	})
+
+	// Owner only has 1000 storj tokens, it can't cover both payouts.
+	results := test.Simulate(owner, "1.00")
+	test.R.Len(results, 2)
+	test.R.Equal(pipelinedb.SimulationOK, results[0].Outcome)
+	test.R.Equal(pipelinedb.SimulationBalance, results[1].Outcome)
+	test.R.Equal(bob.Address, results[1].Payee)
+	test.R.Equal("owner balance (100000000000) does not cover the transfers (120000000000)", results[1].Reason)
+
+	// No transactions should have been sent
+	test.R.Equal(uint(0), test.pendingTransactionCount())
+	test.RequireEqualBig(big.NewInt(initialStorj), test.STORJBalance(owner.Address))
+}
+
+func TestPipelineSimulateAllowance(t *testing.T) {
+	test := NewPipelineTest(t, WithSpender(spender))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	// Approve only one STORJ token worth. The second payout would fail due
+	// to insufficient allowance.
+	test.Approve(owner, spender, big.NewInt(1e8))
+
+	results := test.Simulate(spender, "1.00")
+	test.R.Len(results, 2)
+	test.R.Equal(pipelinedb.SimulationOK, results[0].Outcome)
+	test.R.Equal(pipelinedb.SimulationAllowance, results[1].Outcome)
+	test.R.Equal(fmt.Sprintf("allowance of %s (100000000) does not cover the transfers (200000000)", spender.Address), results[1].Reason)
+
+	test.R.Equal(uint(0), test.pendingTransactionCount())
+	test.RequireEqualBig(big.NewInt(1e8), test.Allowance(owner, spender))
+}
+
+func TestPipelineSimulateDisperse(t *testing.T) {
+	test := NewPipelineTest(t, WithDisperse())
+
+	test.InitializePayoutGroupsOfSize(2, []*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("2.00"),
+		},
+	})
+
+	test.ApproveDisperse(big.NewInt(2e8))
+
+	// Bob's transfer exceeds the allowance of the disperse contract, which
+	// reverts the whole payout group, including Alice's transfer.
+	results := test.Simulate(owner, "1.00")
+	test.R.Len(results, 2)
+	test.R.Equal(pipelinedb.SimulationRevert, results[0].Outcome)
+	test.R.Contains(results[0].Reason, "payout group reverted: ")
+	test.R.Equal(pipelinedb.SimulationAllowance, results[1].Outcome)
+
+	test.R.Equal(uint(0), test.pendingTransactionCount())
+}
+
+func TestPipelineExternalSigner(t *testing.T) {
+	test := NewPipelineTest(t, WithSpender(spender), WithExternalSigner())
+
+	test.Approve(owner, spender, big.NewInt(1e8))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 1)
+			test.R.Len(pipeline[0].Txs, 1)
+			tx := test.FetchTransaction(pipeline[0].Txs[0].Hash)
+			test.R.Equal(spender.Address, tx.Spender)
+			test.commit()
+			return false, nil
+		case 2:
+			return true, nil
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+	test.RequireEqualBig(big.NewInt(0), test.Allowance(owner, spender))
+}
+
+func TestPipelineExternalSignerUnknownSpender(t *testing.T) {
+	test := NewPipelineTest(t)
+
+	server := test.newSignerServer(eth.NewKeySigner(spender.Key, big.NewInt(1337)))
+	_, err := eth.NewExternalSigner(context.Background(), server.URL, signerAuthToken, alice.Address, big.NewInt(1337))
+	test.R.EqualError(err, fmt.Sprintf("external signer does not manage spender %s", alice.Address))
+}
+
+func TestPipelineExternalSignerAuthToken(t *testing.T) {
+	test := NewPipelineTest(t)
+
+	server := test.newSignerServer(eth.NewKeySigner(spender.Key, big.NewInt(1337)))
+	_, err := eth.NewExternalSigner(context.Background(), server.URL, "", spender.Address, big.NewInt(1337))
+	test.R.ErrorContains(err, "401 Unauthorized")
+	_, err = eth.NewExternalSigner(context.Background(), server.URL, "wrong", spender.Address, big.NewInt(1337))
+	test.R.ErrorContains(err, "401 Unauthorized")
+}
+
+func TestPipelineExternalSignerUnixSocket(t *testing.T) {
+	test := NewPipelineTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Unix socket paths are limited to about a hundred bytes, which the
+	// test temp dir may exceed.
+	dir, err := os.MkdirTemp("", "signer")
+	test.R.NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "signer.ipc")
+
+	listener, err := net.Listen("unix", socket)
+	test.R.NoError(err)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- test.newSignerService(eth.NewKeySigner(spender.Key, big.NewInt(1337))).Serve(ctx, listener, "")
+	}()
+	defer func() {
+		cancel()
+		test.R.NoError(<-errCh)
+	}()
+
+	signer, err := eth.NewExternalSigner(ctx, socket, "", spender.Address, big.NewInt(1337))
+	test.R.NoError(err)
+	defer signer.Close()
+
+	tx, err := signer.SignTransaction(ctx, types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       21000,
+		To:        &spender.Address,
+		Value:     big.NewInt(0),
+	}))
+	test.R.NoError(err)
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1337)), tx)
+	test.R.NoError(err)
+	test.R.Equal(spender.Address, sender)
+}
+
+func TestPipelineExternalSignerRefusesOtherTransactions(t *testing.T) {
+	test := NewPipelineTest(t, WithDisperse())
+	ctx := context.Background()
+
+	server := test.newSignerServer(eth.NewKeySigner(spender.Key, big.NewInt(1337)))
+	signer, err := eth.NewExternalSigner(ctx, server.URL, signerAuthToken, spender.Address, big.NewInt(1337))
+	test.R.NoError(err)
+	defer signer.Close()
+
+	tokenABI, err := contract.TokenMetaData.GetAbi()
+	test.R.NoError(err)
+	disperseABI, err := contract.DisperseMetaData.GetAbi()
+	test.R.NoError(err)
+	pack := func(contractABI *abi.ABI, method string, args ...any) []byte {
+		data, err := contractABI.Pack(method, args...)
+		test.R.NoError(err)
+		return data
+	}
+	other := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	for _, tc := range []struct {
+		name        string
+		to          *common.Address
+		value       int64
+		data        []byte
+		expectedErr string
+	}{
+		{
+			name: "transfer",
+			to:   &test.ContractAddress,
+			data: pack(tokenABI, "transfer", alice.Address, big.NewInt(1)),
+		},
+		{
+			name: "transferFrom",
+			to:   &test.ContractAddress,
+			data: pack(tokenABI, "transferFrom", owner.Address, alice.Address, big.NewInt(1)),
+		},
+		{
+			name: "disperse",
+			to:   test.DisperseAddress,
+			data: pack(disperseABI, "disperseTokenSimple", test.ContractAddress, []common.Address{alice.Address}, []*big.Int{big.NewInt(1)}),
+		},
+		{
+			name: "cancel",
+			to:   &spender.Address,
+		},
+		{
+			name:        "approve",
+			to:          &test.ContractAddress,
+			data:        pack(tokenABI, "approve", alice.Address, big.NewInt(1)),
+			expectedErr: "refusing to sign call of approve",
+		},
+		{
+			name:        "truncated transfer",
+			to:          &test.ContractAddress,
+			data:        pack(tokenABI, "transfer", alice.Address, big.NewInt(1))[:36],
+			expectedErr: "refusing to sign malformed call of transfer",
+		},
+		{
+			name:        "transfer with value",
+			to:          &test.ContractAddress,
+			value:       1,
+			data:        pack(tokenABI, "transfer", alice.Address, big.NewInt(1)),
+			expectedErr: "refusing to sign transaction with value 1",
+		},
+		{
+			name:        "native transfer",
+			to:          &alice.Address,
+			value:       1,
+			expectedErr: "refusing to sign transaction with value 1",
+		},
+		{
+			name:        "other contract",
+			to:          &other,
+			data:        pack(tokenABI, "transfer", alice.Address, big.NewInt(1)),
+			expectedErr: "refusing to sign transaction to " + other.String(),
+		},
+		{
+			name:        "disperse other token",
+			to:          test.DisperseAddress,
+			data:        pack(disperseABI, "disperseTokenSimple", other, []common.Address{alice.Address}, []*big.Int{big.NewInt(1)}),
+			expectedErr: "refusing to sign disperse call for token " + other.String(),
+		},
+		{
+			name:        "contract creation",
+			data:        []byte{0x60, 0x00},
+			expectedErr: "refusing to sign contract creation",
+		},
+	} {
+		_, err := signer.SignTransaction(ctx, types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(1337),
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(2),
+			Gas:       100000,
+			To:        tc.to,
+			Value:     big.NewInt(tc.value),
+			Data:      tc.data,
+		}))
+		if tc.expectedErr == "" {
+			test.R.NoError(err, tc.name)
+		} else {
+			test.R.ErrorContains(err, tc.expectedErr, tc.name)
+		}
+	}
+}
+
+func TestPipelineOfflineSigning(t *testing.T) {
+	test := NewPipelineTest(t, WithSpender(spender), WithOfflineSigner())
+	ctx := context.Background()
+
+	test.Approve(owner, spender, big.NewInt(1e8))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	offlinePayer := test.newPayer(spender.Key)
+	payouts, err := test.DB.FetchPayoutGroupPayouts(ctx, 1)
+	test.R.NoError(err)
+
+	// The spender key is offline so nothing can be signed.
+	_, _, err = offlinePayer.CreateRawTransaction(ctx, zaptest.NewLogger(test), payouts, 0, decimal.RequireFromString("1.00"))
+	test.R.ErrorContains(err, fmt.Sprintf("the key of spender %s is offline", spender.Address))
+
+	unsigned, err := offlinePayer.CreateUnsignedTransaction(ctx, zaptest.NewLogger(test), payouts, 0, decimal.RequireFromString("1.00"), big.NewInt(1337))
+	test.R.NoError(err)
+	test.RequireEqualBig(big.NewInt(1337), unsigned.ChainId())
+	test.RequireEqualBig(test.maxGas, unsigned.GasFeeCap())
+
+	to, data, gas, err := eth.TransferCall(owner.Address, spender.Address, test.ContractAddress, nil, []eth.Transfer{
+		{Payee: alice.Address, Tokens: big.NewInt(1e8)},
+	})
+	test.R.NoError(err)
+	test.R.Equal(to, *unsigned.To())
+	test.R.Equal(data, unsigned.Data())
+	test.R.Equal(gas, unsigned.Gas())
+
+	// Sign on the "offline" host, then record and send the signed
+	// transaction the way the broadcast command does.
+	signed, err := eth.NewKeySigner(spender.Key, big.NewInt(1337)).SignTransaction(ctx, unsigned)
+	test.R.NoError(err)
+	rawTxJSON, err := json.Marshal(signed)
+	test.R.NoError(err)
+	_, err = test.DB.CreateTransaction(ctx, pipelinedb.Transaction{
+		PayoutGroupID: 1,
+		Hash:          signed.Hash().Hex(),
+		Nonce:         signed.Nonce(),
+		Owner:         owner.Address,
+		Spender:       spender.Address,
+		Price:         decimal.RequireFromString("1.00"),
+		Tokens:        big.NewInt(1e8),
+		Raw:           rawTxJSON,
+	})
+	test.R.NoError(err)
+	test.R.NoError(offlinePayer.SendTransaction(ctx, zaptest.NewLogger(test), payer.Transaction{Hash: signed.Hash().Hex(), Nonce: signed.Nonce(), Raw: signed}))
+
+	// The pipeline tracks the transaction without the key.
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Len(pipeline, 1)
+			test.R.Len(pipeline[0].Txs, 1)
+			test.R.Equal(signed.Hash().Hex(), pipeline[0].Txs[0].Hash)
+			test.commit()
+			return false, nil
+		case 1:
+			return true, nil
+		default:
+			test.Fatalf("not expecting step %d", step)
+			return false, nil
+		}
+	})
+
+	test.R.Equal(pipelinedb.TxConfirmed, test.FetchTransactionState(signed.Hash().Hex()))
+	test.RequireEqualBig(big.NewInt(1e8), test.STORJBalance(alice.Address))
+}
+
+/////////////////////////////////////////////////////////////////////////////
+// Helpers
+/////////////////////////////////////////////////////////////////////////////
+
+type PipelineTestOption func(*PipelineTest)
+
+func TestPipelineNativePayouts(t *testing.T) {
+	test := NewPipelineTest(t, WithLimit(2), WithNative())
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("10.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("20.00"),
+		},
+	})
+
+	// The payouts are converted at the ETH price. The STORJ price is not
+	// quoted at all.
+	test.SetETHPrice("2000.00")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 2)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			test.ValidatePipelineSlot(pipeline[1], 1, 2, pipelinedb.TxPending)
+			test.RequireEqualBig(big.NewInt(5e15), test.FetchTransaction(pipeline[0].Txs[0].Hash).Tokens)
+			test.RequireEqualBig(big.NewInt(1e16), test.FetchTransaction(pipeline[1].Txs[0].Hash).Tokens)
+
+			// The transactions are plain value transfers.
+			rawTx := test.FetchRawTransaction(pipeline[0].Txs[0].Hash)
+			test.R.Equal(alice.Address, *rawTx.To())
+			test.R.Empty(rawTx.Data())
+			test.R.Equal(eth.NativeTransferGasLimit, rawTx.Gas())
+			test.commit()
+			return false, nil
+		case 2:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			test.ValidatePipelineSlot(pipeline[1], 1, 2)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(5e15), test.ETHBalance(alice.Address))
+	test.RequireEqualBig(big.NewInt(1e16), test.ETHBalance(bob.Address))
+	test.RequireEqualBig(big.NewInt(initialStorj), test.STORJBalance(owner.Address))
+}
+
+func TestPipelineNativeChecksBalanceBeforeTransfer(t *testing.T) {
+	test := NewPipelineTest(t, WithNative())
+
+	// The owner has 0.9 ETH, which covers 0.899995 ETH, but not once the
+	// gas of the transfer is reserved.
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1799.99"),
+		},
+	})
+	test.SetETHPrice("2000.00")
+
+	pipeline := test.NewPipeline()
+	err := pipeline.ProcessPayouts(context.Background())
+	test.R.ErrorContains(err, "not enough token balance to cover transfer")
+
+	// No transactions should have been sent
+	test.R.Zero(test.pendingTransactionCount())
+	test.RequireEqualBig(big.NewInt(0), test.ETHBalance(alice.Address))
+}
+
+func TestPipelineOtherToken(t *testing.T) {
+	test := NewPipelineTest(t, WithToken("USDC", 6))
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("10.00"),
+		},
+	})
+
+	// The payout is converted at the USDC price and rounded down to the
+	// smallest unit of the token.
+	test.SetPrice("USDC", "0.9999")
+
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 1)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			tx := test.FetchTransaction(pipeline[0].Txs[0].Hash)
+			test.R.Equal("0.9999", tx.Price.String())
+			test.RequireEqualBig(big.NewInt(10001000), tx.Tokens)
+			test.commit()
+			return false, nil
+		case 2:
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(10001000), test.STORJBalance(alice.Address))
+}
+
+func TestPipelineSafe(t *testing.T) {
+	test := NewPipelineTest(t, WithLimit(2), WithSafe())
+
+	test.InitializePayoutGroups([]*pipelinedb.Payout{
+		{
+			Payee: alice.Address,
+			USD:   decimal.RequireFromString("1.00"),
+		},
+		{
+			Payee: bob.Address,
+			USD:   decimal.RequireFromString("2.00"),
+		},
+		{
+			Payee: chuck.Address,
+			USD:   decimal.RequireFromString("3.00"),
+		},
+	})
+
+	test.SetStorjPrice("1.00")
+
+	// The Safe payer counts up the nonces of the payout groups past the
+	// limit of the pipeline.
+	test.ProcessPayouts(func(step int, pipeline []*pipelinedb.NonceGroup, cancel func()) (bool, error) {
+		switch step {
+		case 0:
+			test.R.Empty(pipeline)
+			return false, nil
+		case 1:
+			test.R.Len(pipeline, 2)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1, pipelinedb.TxPending)
+			test.ValidatePipelineSlot(pipeline[1], 1, 2, pipelinedb.TxPending)
+			test.ExecuteSafeTransfers(pipeline)
+			return false, nil
+		case 2:
+			test.R.Len(pipeline, 2)
+			test.ValidatePipelineSlot(pipeline[0], 0, 1)
+			test.ValidatePipelineSlot(pipeline[1], 1, 2)
+			return false, nil
+		case 3:
+			test.R.Len(pipeline, 1)
+			test.ValidatePipelineSlot(pipeline[0], 2, 3, pipelinedb.TxPending)
+			test.ExecuteSafeTransfers(pipeline)
+			return false, nil
+		case 4:
+			test.R.Len(pipeline, 1)
+			test.ValidatePipelineSlot(pipeline[0], 2, 3)
+			return true, nil
+		default:
+			return false, errors.New("should have finished")
+		}
+	})
+
+	test.RequireEqualBig(big.NewInt(100000000), test.STORJBalance(alice.Address))
+	test.RequireEqualBig(big.NewInt(200000000), test.STORJBalance(bob.Address))
+	test.RequireEqualBig(big.NewInt(300000000), test.STORJBalance(chuck.Address))
+}
+
+func WithLimit(limit int) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.limit = limit
+	}
+}
+
+func WithSpender(spender *ethtest.Account) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.spender = spender
+	}
+}
+
+func WithExtraSpenders(spenders ...*ethtest.Account) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.extraSpenders = spenders
+	}
+}
+
+func WithQuarantine() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.quarantine = true
+	}
+}
+
+func WithExternalSigner() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.externalSigner = true
+	}
+}
+
+func WithOfflineSigner() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.offlineSigner = true
+	}
+}
+
+func WithDisperse() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.disperse = true
+	}
+}
+
+func WithReplaceAfter(replaceAfter time.Duration) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.replaceAfter = replaceAfter
+	}
+}
+
+func WithSpendLimits(spendLimits SpendLimits) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.spendLimits = spendLimits
+	}
+}
+
+func WithConfirmations(confirmations uint64) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.confirmations = confirmations
+	}
+}
+
+func WithFeeHistory() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.feeHistory = true
+	}
+}
+
+func WithNative() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.native = true
+	}
+}
+
+func WithToken(symbol coinmarketcap.Symbol, decimals int64) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.tokenSymbol = symbol
+		c.tokenDecimals = decimals
+	}
+}
+
+func WithSafe() PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.safe = true
+	}
+}
+
+func WithGasTipCap(gasTipCap *big.Int) PipelineTestOption {
+	return func(c *PipelineTest) {
+		c.gasTipCap = gasTipCap
+	}
+}
+
+type PipelineTest struct {
+	*testing.T
+	A *assert.Assertions
+	R *require.Assertions
+
+	// config
+	limit         int
+	spender       *ethtest.Account
+	extraSpenders []*ethtest.Account
+	gasTipCap     *big.Int
+	maxGas        *big.Int
+	disperse      bool
+	native        bool
+	safe          bool
+	tokenSymbol   coinmarketcap.Symbol
+	tokenDecimals int64
+
+	externalSigner bool
+	offlineSigner  bool
+
+	feeHistory bool
+
+	replaceAfter  time.Duration
+	spendLimits   SpendLimits
+	confirmations uint64
+	quarantine    bool
+
+	DB *pipelinedb.DB
+
+	Quoter *ethtest.Quoter
+
+	Backend         *simulated.Backend
+	Client          simulated.Client
+	Contract        *contract.Token
+	ContractAddress common.Address
+	DisperseAddress *common.Address
+}
+
+func NewPipelineTest(t *testing.T, opts ...PipelineTestOption) *PipelineTest {
+	dir := t.TempDir()
+
+	test := &PipelineTest{
+		T:             t,
+		A:             assert.New(t),
+		R:             require.New(t),
+		limit:         1,
+		tokenDecimals: 8,
+	}
+
+	for _, opt := range opts {
+		opt(test)
+	}
 
 	db, err := pipelinedb.NewDB(context.Background(), filepath.Join(dir, "payouts.db"))
 	test.R.NoError(err)
@@ -797,11 +2233,17 @@ func NewPipelineTest(t *testing.T, opts ...PipelineTestOption) *PipelineTest {
 }
 
 func (test *PipelineTest) InitializePayoutGroups(payouts []*pipelinedb.Payout) {
-	// Payout groups consist of only single payouts until we support
-	// multitransfer.
-	for i := range payouts {
-		err := test.DB.CreatePayoutGroup(context.Background(), int64(i+1), payouts[i:i+1])
+	test.InitializePayoutGroupsOfSize(1, payouts)
+}
+
+func (test *PipelineTest) InitializePayoutGroupsOfSize(size int, payouts []*pipelinedb.Payout) {
+	var id int64
+	for len(payouts) > 0 {
+		n := min(size, len(payouts))
+		id++
+		err := test.DB.CreatePayoutGroup(context.Background(), id, payouts[:n])
 		test.R.NoError(err)
+		payouts = payouts[n:]
 	}
 }
 
@@ -812,6 +2254,17 @@ func (test *PipelineTest) SetStorjPrice(s string) {
 	})
 }
 
+func (test *PipelineTest) SetETHPrice(s string) {
+	test.SetPrice(coinmarketcap.ETH, s)
+}
+
+func (test *PipelineTest) SetPrice(symbol coinmarketcap.Symbol, s string) {
+	test.Quoter.SetQuote(symbol, &coinmarketcap.Quote{
+		LastUpdated: time.Now(),
+		Price:       decimal.RequireFromString(s),
+	})
+}
+
 func (test *PipelineTest) initNetwork() {
 	// Create a network, giving the owner and spender a little bit of cheese
 	// to get things going.
@@ -822,6 +2275,7 @@ func (test *PipelineTest) initNetwork() {
 	alloc[deployer.Address] = types.Account{Balance: initialBalance}
 	alloc[owner.Address] = types.Account{Balance: initialBalance}
 	alloc[spender.Address] = types.Account{Balance: initialBalance}
+	alloc[extraSpender.Address] = types.Account{Balance: initialBalance}
 
 	test.Backend = simulated.NewBackend(alloc, simulated.WithMinerMinTip(big.NewInt(1)))
 	test.Cleanup(func() {
@@ -832,13 +2286,22 @@ func (test *PipelineTest) initNetwork() {
 	// Deploy the ETH20 contract and commit
 	auth, err := bind.NewKeyedTransactorWithChainID(deployer.Key, big.NewInt(1337))
 	test.R.NoError(err)
+
+	// Deploy the disperse contract before the token contract shadows the
+	// package name.
+	if test.disperse {
+		disperseAddress, _, _, err := contract.DeployDisperse(auth, test.Client)
+		test.R.NoError(err, "unable to deploy disperse contract")
+		test.DisperseAddress = &disperseAddress
+	}
+
 	contractAddress, _, contract, err := contract.DeployToken(
 		auth,
 		test.Client,
 		owner.Address,
 		"Storj", "STORJ",
 		big.NewInt(initialStorj),
-		big.NewInt(8))
+		big.NewInt(test.tokenDecimals))
 	test.R.NoError(err, "unable to deploy contract")
 	test.Contract = contract
 	test.ContractAddress = contractAddress
@@ -855,21 +2318,35 @@ func (test *PipelineTest) newPipeline(stepInCh chan chan []*pipelinedb.NonceGrou
 	if test.spender != nil {
 		spenderKey = test.spender.Key
 	}
-	payer, err := eth.NewPayer(context.Background(),
-		test.Client,
-		test.ContractAddress,
-		owner.Address,
-		spenderKey,
-		big.NewInt(1337),
-		test.gasTipCap,
-		test.maxGas)
-	test.R.NoError(err)
-	pipeline, err := New(payer, Config{
+	var defaultPayer payer.Payer = test.newPayer(spenderKey)
+	if test.safe {
+		defaultPayer = test.newSafePayer()
+	}
+
+	var lanes []Lane
+	if len(test.extraSpenders) > 0 {
+		lanes = append(lanes, Lane{Spender: crypto.PubkeyToAddress(spenderKey.PublicKey), Payer: defaultPayer})
+		for _, extraSpender := range test.extraSpenders {
+			lanes = append(lanes, Lane{Spender: extraSpender.Address, Payer: test.newPayer(extraSpender.Key)})
+		}
+	}
+
+	symbol := test.tokenSymbol
+	if test.native {
+		symbol = coinmarketcap.ETH
+	}
+
+	pipeline, err := New(defaultPayer, Config{
 		Log:          zaptest.NewLogger(test),
 		Owner:        owner.Address,
 		Quoter:       test.Quoter,
+		Symbol:       symbol,
 		DB:           test.DB,
 		Limit:        test.limit,
+		ReplaceAfter: test.replaceAfter,
+		SpendLimits:  test.spendLimits,
+		Quarantine:   test.quarantine,
+		Lanes:        lanes,
 		stepInCh:     stepInCh,
 		pollInterval: pollInterval,
 	})
@@ -877,6 +2354,108 @@ func (test *PipelineTest) newPipeline(stepInCh chan chan []*pipelinedb.NonceGrou
 	return pipeline
 }
 
+func (test *PipelineTest) newPayer(spenderKey *ecdsa.PrivateKey) *eth.Payer {
+	var fees eth.FeeStrategy
+	switch {
+	case test.feeHistory:
+		var err error
+		fees, err = eth.NewFeeHistoryFees(test.Client, 0, 0)
+		test.R.NoError(err)
+	case test.gasTipCap != nil:
+		fees = eth.NewFixedFees(test.gasTipCap)
+	}
+	signer := eth.NewKeySigner(spenderKey, big.NewInt(1337))
+	if test.externalSigner {
+		server := test.newSignerServer(signer)
+		externalSigner, err := eth.NewExternalSigner(context.Background(), server.URL, signerAuthToken, signer.Address(), big.NewInt(1337))
+		test.R.NoError(err)
+		test.Cleanup(externalSigner.Close)
+		signer = externalSigner
+	}
+	if test.offlineSigner {
+		signer = eth.NewOfflineSigner(signer.Address())
+	}
+	if test.native {
+		payer, err := eth.NewNativePayer(context.Background(),
+			test.Client,
+			signer,
+			fees,
+			test.maxGas,
+			test.confirmations)
+		test.R.NoError(err)
+		return payer
+	}
+	payer, err := eth.NewPayer(context.Background(),
+		test.Client,
+		test.ContractAddress,
+		owner.Address,
+		signer,
+		fees,
+		test.maxGas,
+		test.DisperseAddress,
+		test.confirmations)
+	test.R.NoError(err)
+	return payer
+}
+
+// newSafePayer returns a payer exporting the payout groups to batches for a
+// Safe played by the owner.
+func (test *PipelineTest) newSafePayer() *safe.Payer {
+	payer, err := safe.NewPayer(context.Background(),
+		test.Client,
+		test.ContractAddress,
+		string(test.tokenSymbol),
+		owner.Address,
+		big.NewInt(1337),
+		filepath.Join(test.TempDir(), "batches"),
+		test.confirmations)
+	test.R.NoError(err)
+	return payer
+}
+
+// Simulate simulates the payout groups in order with a payer for the given
+// spender at the given price, the way payouts.Simulate does.
+func (test *PipelineTest) Simulate(spender *ethtest.Account, price string) []*pipelinedb.SimulationResult {
+	ctx := context.Background()
+	payer := test.newPayer(spender.Key)
+
+	payoutGroups, err := test.DB.FetchUnfinishedUnattachedPayoutGroups(ctx)
+	test.R.NoError(err)
+
+	var results []*pipelinedb.SimulationResult
+	transferred := new(big.Int)
+	for _, payoutGroup := range payoutGroups {
+		payouts, err := test.DB.FetchPayoutGroupPayouts(ctx, payoutGroup.ID)
+		test.R.NoError(err)
+		groupResults, err := payer.SimulatePayoutGroup(ctx, payouts, decimal.RequireFromString(price), transferred)
+		test.R.NoError(err)
+		for _, result := range groupResults {
+			transferred.Add(transferred, result.Tokens)
+		}
+		results = append(results, groupResults...)
+	}
+	return results
+}
+
+// newSignerServer serves a stand-in external signer for the signer, which
+// requires signerAuthToken.
+func (test *PipelineTest) newSignerServer(signer eth.Signer) *httptest.Server {
+	rpcServer, err := test.newSignerService(signer).NewRPCServer()
+	test.R.NoError(err)
+	server := httptest.NewServer(eth.RequireAuthToken(rpcServer, signerAuthToken))
+	test.Cleanup(func() {
+		server.Close()
+		rpcServer.Stop()
+	})
+	return server
+}
+
+// newSignerService returns a stand-in external signer for the signer, which
+// signs calls of the token and disperse contracts of the test.
+func (test *PipelineTest) newSignerService(signer eth.Signer) *eth.SignerService {
+	return eth.NewSignerService(zaptest.NewLogger(test), signer, big.NewInt(1337), test.ContractAddress, test.DisperseAddress)
+}
+
 func (test *PipelineTest) ProcessPayouts(step func(int, []*pipelinedb.NonceGroup, func()) (bool, error)) {
 	stepInCh := make(chan chan []*pipelinedb.NonceGroup)
 	pipeline := test.newPipeline(stepInCh, time.Minute)
@@ -888,7 +2467,7 @@ func (test *PipelineTest) ProcessPayouts(step func(int, []*pipelinedb.NonceGroup
 	test.R.NoError(err)
 	for i := 0; ; i++ {
 		test.Logf("============= STEP %d ============= ", i)
-		expectedDone, expectedErr := step(i, pipeline.nonceGroups, cancel)
+		expectedDone, expectedErr := step(i, pipeline.allNonceGroups(), cancel)
 		test.R.NoError(err)
 
 		if ctx.Err() != nil {
@@ -915,6 +2494,24 @@ func (test *PipelineTest) ProcessPayouts(step func(int, []*pipelinedb.NonceGroup
 	}
 }
 
+// ExecuteSafeTransfers executes the pending payout groups exported by the
+// Safe payer the way the owners of the Safe would, one Safe transaction per
+// payout group, and commits them.
+func (test *PipelineTest) ExecuteSafeTransfers(pipeline []*pipelinedb.NonceGroup) {
+	auth, err := bind.NewKeyedTransactorWithChainID(owner.Key, big.NewInt(1337))
+	test.R.NoError(err)
+	for _, nonceGroup := range pipeline {
+		for _, tx := range nonceGroup.Txs {
+			var transfers safe.Transfers
+			test.R.NoError(json.Unmarshal(test.FetchTransaction(tx.Hash).Raw, &transfers))
+			test.R.Len(transfers.Transfers, 1, "payout groups are executed with a single transfer")
+			_, err := test.Contract.Transfer(auth, transfers.Transfers[0].Payee, transfers.Transfers[0].Tokens)
+			test.R.NoError(err)
+		}
+	}
+	test.commit()
+}
+
 func (test *PipelineTest) AssertProcessPayoutsFails(expectedErr string) {
 	pipeline := test.newPipeline(nil, time.Minute)
 
@@ -925,6 +2522,13 @@ func (test *PipelineTest) AssertProcessPayoutsFails(expectedErr string) {
 	test.R.EqualError(err, expectedErr)
 }
 
+func (test *PipelineTest) FetchPriceLock() *pipelinedb.PriceLock {
+	lock, err := test.DB.FetchPriceLock(context.Background())
+	test.R.NoError(err)
+	test.R.NotNil(lock)
+	return lock
+}
+
 func (test *PipelineTest) FetchTransaction(hashString string) *pipelinedb.Transaction {
 	hash, err := batchpayment.HashFromString(hashString)
 	test.R.NoError(err)
@@ -934,6 +2538,12 @@ func (test *PipelineTest) FetchTransaction(hashString string) *pipelinedb.Transa
 	return tx
 }
 
+func (test *PipelineTest) FetchRawTransaction(hashString string) *types.Transaction {
+	var tx types.Transaction
+	test.R.NoError(tx.UnmarshalJSON(test.FetchTransaction(hashString).Raw))
+	return &tx
+}
+
 func (test *PipelineTest) FetchTransactionState(hashString string) pipelinedb.TxState {
 	return test.FetchTransaction(hashString).State
 }
@@ -988,6 +2598,17 @@ func (test *PipelineTest) ApproveNoCommit(owner, spender *ethtest.Account, amoun
 	return tx.Hash()
 }
 
+func (test *PipelineTest) ApproveDisperse(amount *big.Int) {
+	opts, err := bind.NewKeyedTransactorWithChainID(owner.Key, big.NewInt(1337))
+	test.R.NoError(err)
+	tx, err := test.Contract.Approve(opts, *test.DisperseAddress, amount)
+	test.R.NoError(err)
+	test.commit()
+	state, _, _, err := eth.GetTransactionInfo(context.Background(), test.Client, tx.Hash())
+	test.R.NoError(err)
+	test.R.Equal(pipelinedb.TxConfirmed, state)
+}
+
 func (test *PipelineTest) Allowance(owner, spender *ethtest.Account) *big.Int {
 	allowance, err := test.Contract.Allowance(nil, owner.Address, spender.Address)
 	test.R.NoError(err)
@@ -3,13 +3,17 @@ package pipeline
 import (
 	"context"
 	"crypto/rand"
+	"fmt"
 	"math/big"
+	"net/http"
 	"path/filepath"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -114,6 +118,395 @@ func Test_TestIntermittentTxError(t *testing.T) {
 
 }
 
+func Test_PauseResume(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+		{
+			Payee: common.HexToAddress("0xd32E554823E3b08F80A8173FAcc2B6AD3502376F"),
+			USD:   decimal.New(100, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pauseCh := make(chan bool, 1)
+	pipeline.pauseCh = pauseCh
+	pipeline.limit = 1
+
+	// Keep the first payout group in flight.
+	testPayer.checkNonceGroupHandler = func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
+		return pipelinedb.TxPending, nil, nil
+	}
+
+	err := pipeline.initPayout(ctx)
+	require.NoError(t, err)
+
+	done, err := pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Len(t, pipeline.allNonceGroups(), 1)
+
+	// Pause while the first payout group is in flight. It is still
+	// checked and finishes, but the next one is not sent.
+	pauseCh <- true
+	testPayer.checkNonceGroupHandler = statusFailsWith()
+
+	done, err = pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.False(t, done)
+	assertPaymetGroupStatus(ctx, t, db, 0, pipelinedb.TxConfirmed)
+
+	for range 3 {
+		done, err = pipeline.payoutStep(ctx)
+		require.NoError(t, err)
+		require.False(t, done, "a paused pipeline should wait to be resumed")
+		require.Empty(t, pipeline.allNonceGroups())
+	}
+	txs, err := db.FetchPayoutGroupTransactions(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, txs)
+
+	// Resume and finish the payout.
+	pauseCh <- false
+
+	done, err = pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.False(t, done)
+	assertPaymetGroupStatus(ctx, t, db, 1, pipelinedb.TxConfirmed)
+
+	done, err = pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.True(t, done)
+}
+
+func Test_Observer(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+		{
+			Payee: common.HexToAddress("0xd32E554823E3b08F80A8173FAcc2B6AD3502376F"),
+			USD:   decimal.New(100, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pipeline.pollInterval = time.Millisecond
+	testPayer.checkNonceGroupHandler = statusFailsWith(1)
+
+	observer := &recordingObserver{}
+	pipeline.observer = observer
+
+	err := pipeline.ProcessPayouts(ctx)
+	require.EqualError(t, err, "One or more transactions failed, possibly due to insufficient balances")
+
+	require.Equal(t, []string{
+		"created 0",
+		"sent 0 <nil>",
+		"created 1",
+		"sent 1 <nil>",
+		"state 0 pending->confirmed",
+		"state 1 pending->failed",
+		"failed 1",
+		"completed One or more transactions failed, possibly due to insufficient balances",
+	}, observer.events)
+}
+
+func Test_Quarantine(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+		{
+			Payee: common.HexToAddress("0xd32E554823E3b08F80A8173FAcc2B6AD3502376F"),
+			USD:   decimal.New(100, 0),
+		},
+		{
+			Payee: common.HexToAddress("0xef6458a66605d05C0DAE84EFF844b0d0a7AAb506"),
+			USD:   decimal.New(1, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pipeline.pollInterval = time.Millisecond
+	pipeline.quarantine = true
+	testPayer.checkNonceGroupHandler = statusFailsWith(1)
+
+	// The failed payout group is quarantined and the rest are paid.
+	err := pipeline.ProcessPayouts(ctx)
+	require.NoError(t, err)
+	assertPaymetGroupStatus(ctx, t, db, 0, pipelinedb.TxConfirmed)
+	assertPaymetGroupStatus(ctx, t, db, 1, pipelinedb.TxFailed)
+	assertPaymetGroupStatus(ctx, t, db, 2, pipelinedb.TxConfirmed)
+
+	quarantined, err := db.FetchQuarantinedPayoutGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1)
+	require.Equal(t, int64(1), quarantined[0].ID)
+	require.Equal(t, "transaction failed", quarantined[0].QuarantineReason)
+
+	// Quarantined payout groups are not sent again.
+	pipeline, _ = createTestPipeline(ctx, t, db)
+	pipeline.quarantine = true
+	err = pipeline.ProcessPayouts(ctx)
+	require.NoError(t, err)
+	assertPaymetGroupStatus(ctx, t, db, 1, pipelinedb.TxFailed)
+}
+
+func Test_QuarantineLimit(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+		{
+			Payee: common.HexToAddress("0xd32E554823E3b08F80A8173FAcc2B6AD3502376F"),
+			USD:   decimal.New(100, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pipeline.pollInterval = time.Millisecond
+	pipeline.quarantine = true
+	pipeline.maxQuarantined = 1
+	testPayer.checkNonceGroupHandler = statusFailsWith(0, 1)
+
+	err := pipeline.ProcessPayouts(ctx)
+	require.EqualError(t, err, "2 payout groups have been quarantined, more than the limit of 1")
+
+	quarantined, err := db.FetchQuarantinedPayoutGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, quarantined, 2)
+}
+
+func Test_RetryTransientErrors(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pipeline.retryBackoff = time.Millisecond
+
+	err := pipeline.initPayout(ctx)
+	require.NoError(t, err)
+
+	// Transient errors are retried until the check succeeds.
+	var calls int
+	testPayer.checkNonceGroupHandler = func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
+		calls++
+		if calls <= 2 {
+			return "", nil, rpc.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}
+		}
+		return statusResult(pipelinedb.TxConfirmed, nonceGroup.Txs[0].Hash)
+	}
+
+	done, err := pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, 3, calls)
+	assertPaymetGroupStatus(ctx, t, db, 0, pipelinedb.TxConfirmed)
+
+	done, err = pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.True(t, done)
+}
+
+func Test_RetryStopsOnFatalErrorsAndBudget(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pipeline.retryBackoff = time.Millisecond
+	pipeline.retryBudget = 20 * time.Millisecond
+
+	err := pipeline.initPayout(ctx)
+	require.NoError(t, err)
+
+	// Fatal errors are not retried.
+	var calls int
+	testPayer.checkNonceGroupHandler = func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
+		calls++
+		return "", nil, errs.New("invalid transaction")
+	}
+
+	done, err := pipeline.payoutStep(ctx)
+	require.EqualError(t, err, "invalid transaction")
+	require.True(t, done)
+	require.Equal(t, 1, calls)
+
+	// Transient errors are retried until the budget is spent.
+	calls = 0
+	testPayer.checkNonceGroupHandler = func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
+		calls++
+		return "", nil, errs.New("Post \"https://node\": read tcp: connection reset by peer")
+	}
+
+	done, err = pipeline.payoutStep(ctx)
+	require.ErrorContains(t, err, "connection reset by peer")
+	require.True(t, done)
+	require.Greater(t, calls, 1)
+}
+
+func Test_WaitsWhenPreconditionNoLongerMet(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+	pipeline.preconditionsPollInterval = time.Millisecond
+
+	err := pipeline.initPayout(ctx)
+	require.NoError(t, err)
+
+	// The transaction is created again once the precondition holds again.
+	var calls int
+	testPayer.createRawTxHandler = func(ctx context.Context, nonce uint64) error {
+		calls++
+		if calls <= 2 {
+			return payer.ErrPreconditionNotMet.New("estimated fee is larger than the max allowed fee")
+		}
+		return nil
+	}
+
+	done, err := pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, 3, calls)
+	assertPaymetGroupStatus(ctx, t, db, 0, pipelinedb.TxConfirmed)
+
+	done, err = pipeline.payoutStep(ctx)
+	require.NoError(t, err)
+	require.True(t, done)
+}
+
+func Test_FailsWhenCreatingTransactionFails(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	db := createTestDB(ctx, t, []*pipelinedb.Payout{
+		{
+			Payee: common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA"),
+			USD:   decimal.New(1, 0),
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	pipeline, testPayer := createTestPipeline(ctx, t, db)
+
+	err := pipeline.initPayout(ctx)
+	require.NoError(t, err)
+
+	// Other errors stop the pipeline.
+	testPayer.createRawTxHandler = func(ctx context.Context, nonce uint64) error {
+		return errs.New("invalid transaction")
+	}
+
+	_, err = pipeline.payoutStep(ctx)
+	require.EqualError(t, err, "invalid transaction")
+}
+
+func Test_IsTransient(t *testing.T) {
+	for _, tt := range []struct {
+		err       error
+		transient bool
+	}{
+		{err: nil, transient: false},
+		{err: context.Canceled, transient: false},
+		{err: errs.Wrap(context.DeadlineExceeded), transient: true},
+		{err: errs.Wrap(syscall.ECONNRESET), transient: true},
+		{err: rpc.HTTPError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}, transient: true},
+		{err: rpc.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}, transient: false},
+		{err: errs.New("unexpected status 429: slow down"), transient: true},
+		{err: errs.New("unexpected status 502: "), transient: true},
+		{err: errs.New("unexpected status 401: "), transient: false},
+		{err: errs.New("failed to check: i/o timeout"), transient: true},
+		{err: errs.New("daily request count exceeded, request rate limited"), transient: true},
+		{err: errs.New("project ID request rate exceeded"), transient: true},
+		{err: errs.New("request limit exceeded"), transient: true},
+		{err: errs.Wrap(rpcError{code: rpcLimitExceeded, message: "too busy"}), transient: true},
+		{err: errs.Wrap(rpcError{code: http.StatusTooManyRequests, message: "slow down"}), transient: true},
+		{err: errs.Wrap(rpcError{code: -32000, message: "gas limit exceeded"}), transient: false},
+		{err: errs.New("gas limit exceeded"), transient: false},
+		{err: errs.New("exceeds block gas limit"), transient: false},
+		{err: errs.New("tx fee (1.50 ether) exceeds the configured cap (1.00 ether)"), transient: false},
+		{err: errs.New("insufficient funds for gas * price + value"), transient: false},
+		{err: errs.New("nonce too low"), transient: false},
+	} {
+		assert.Equal(t, tt.transient, IsTransient(tt.err), "%v", tt.err)
+	}
+}
+
+// rpcError is a JSON-RPC error with a code, like the ones the node returns.
+type rpcError struct {
+	code    int
+	message string
+}
+
+func (e rpcError) Error() string  { return e.message }
+func (e rpcError) ErrorCode() int { return e.code }
+
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) TxCreated(ctx context.Context, tx *pipelinedb.Transaction) {
+	o.events = append(o.events, fmt.Sprintf("created %d", tx.Nonce))
+}
+
+func (o *recordingObserver) TxSent(ctx context.Context, tx *pipelinedb.Transaction, err error) {
+	o.events = append(o.events, fmt.Sprintf("sent %d %v", tx.Nonce, err))
+}
+
+func (o *recordingObserver) NonceGroupStateChanged(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, from, to pipelinedb.TxState) {
+	o.events = append(o.events, fmt.Sprintf("state %d %s->%s", nonceGroup.Nonce, from, to))
+}
+
+func (o *recordingObserver) NonceGroupDropped(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, resent *pipelinedb.Transaction) {
+	o.events = append(o.events, fmt.Sprintf("dropped %d", nonceGroup.Nonce))
+}
+
+func (o *recordingObserver) NonceGroupFailed(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, statuses []*pipelinedb.TxStatus) {
+	o.events = append(o.events, fmt.Sprintf("failed %d", nonceGroup.Nonce))
+}
+
+func (o *recordingObserver) RunCompleted(ctx context.Context, err error) {
+	o.events = append(o.events, fmt.Sprintf("completed %v", err))
+}
+
 func statusFailsWith(noncesToFail ...int) func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
 	return func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
 		for _, i := range noncesToFail {
@@ -176,8 +569,12 @@ func createTestPipeline(ctx context.Context, t *testing.T, db *pipelinedb.DB) (*
 
 var _ payer.Payer = &TestPayer{}
 
+// testPayerFrom is the address the TestPayer sends from.
+var testPayerFrom = common.HexToAddress("0x94F31A2f6522dbf0594bf9c37F124fB6EAC4d9cd")
+
 type TestPayer struct {
 	nextNonce              uint64
+	createRawTxHandler     func(ctx context.Context, nonce uint64) error
 	sendTransactionHandler func(ctx context.Context, tx payer.Transaction) error
 	checkNonceGroupHandler func(ctx context.Context, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error)
 }
@@ -211,18 +608,27 @@ func (t *TestPayer) GetTokenBalance(ctx context.Context) (*big.Int, error) {
 	return big.NewInt(10_000_00000000), nil
 }
 
+func (t *TestPayer) From() common.Address {
+	return testPayerFrom
+}
+
 func (t *TestPayer) GetTokenDecimals(ctx context.Context) (int32, error) {
 	return 8, nil
 }
 
-func (t *TestPayer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, storjPrice decimal.Decimal) (tx payer.Transaction, from common.Address, err error) {
+func (t *TestPayer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, price decimal.Decimal) (tx payer.Transaction, from common.Address, err error) {
+	if t.createRawTxHandler != nil {
+		if err := t.createRawTxHandler(ctx, nonce); err != nil {
+			return payer.Transaction{}, common.Address{}, err
+		}
+	}
 	hash := make([]byte, 32)
 	_, err = rand.Read(hash)
 	return payer.Transaction{
 		Hash:  common.BytesToHash(hash).String(),
 		Nonce: nonce,
 		Raw:   make(map[string]string),
-	}, common.HexToAddress("0x94F31A2f6522dbf0594bf9c37F124fB6EAC4d9cd"), err
+	}, testPayerFrom, err
 
 }
 
@@ -234,7 +640,7 @@ func (t *TestPayer) CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceG
 	return t.checkNonceGroupHandler(ctx, nonceGroup, checkOnly)
 }
 
-func (t *TestPayer) PrintEstimate(ctx context.Context, remaining int64) error {
+func (t *TestPayer) PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error {
 	panic("implement me")
 }
 
@@ -253,7 +659,7 @@ func statusResult(status pipelinedb.TxState, hash string) (pipelinedb.TxState, [
 	return status, []*pipelinedb.TxStatus{
 		{
 			Hash:  hash,
-			State: pipelinedb.TxConfirmed,
+			State: status,
 			Receipt: &types.Receipt{
 				Logs: []*types.Log{},
 			},
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultRetryBudget is the default retry budget (see RetryBudget in
+	// Config).
+	DefaultRetryBudget = 10 * time.Minute
+
+	// defaultRetryBackoff is how long to wait before the first retry of a
+	// transient error. The wait doubles with every retry up to
+	// maxRetryBackoff.
+	defaultRetryBackoff = time.Second
+
+	// maxRetryBackoff is the longest wait between retries.
+	maxRetryBackoff = time.Minute
+
+	// rpcLimitExceeded is the JSON-RPC error code used by node providers
+	// when a request is rate limited.
+	rpcLimitExceeded = -32005
+
+	// rpcTooManyRequests is the JSON-RPC error code some node providers
+	// use instead, borrowed from the HTTP status.
+	rpcTooManyRequests = http.StatusTooManyRequests
+)
+
+var (
+	// transientMessages are lower-cased fragments of error messages that
+	// indicate a transient error. They catch errors that have lost their
+	// type by being formatted into another error. Rate limits are only
+	// matched by phrases specific to them, since a bare "limit exceeded"
+	// also matches gas limit errors, which are fatal.
+	transientMessages = []string{
+		"timeout",
+		"deadline exceeded",
+		"timed out",
+		"connection reset",
+		"connection refused",
+		"broken pipe",
+		"unexpected eof",
+		"too many requests",
+		"rate limit",
+		"request limit exceeded",
+		"request rate exceeded",
+		"internal server error",
+		"bad gateway",
+		"service unavailable",
+	}
+
+	// transientStatusRx matches HTTP status codes that indicate a transient
+	// error in formatted error messages.
+	transientStatusRx = regexp.MustCompile(`\bstatus:? (429|5\d\d)\b`)
+)
+
+// IsTransient returns true if the error is likely to go away on its own,
+// like a timeout, a connection reset, a rate limit or a server error from
+// the node or price provider. Other errors are fatal.
+func IsTransient(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return isTransientStatus(httpErr.StatusCode)
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case rpcLimitExceeded, rpcTooManyRequests:
+			return true
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range transientMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return transientStatusRx.MatchString(msg)
+}
+
+func isTransientStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// retry calls fn until it succeeds, fails with an error that is not
+// transient, or the retry budget is spent. The wait between calls backs off
+// exponentially.
+func (p *Pipeline) retry(ctx context.Context, operation string, fn func() error) error {
+	deadline := time.Now().Add(p.retryBudget)
+	backoff := p.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsTransient(err) || ctx.Err() != nil {
+			return err
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			p.log.Error("Transient error persisted past the retry budget",
+				zap.String("operation", operation),
+				zap.Int("attempts", attempt),
+				zap.String("retry-budget", p.retryBudget.String()),
+				zap.Error(err),
+			)
+			return err
+		}
+		p.log.Warn("Transient error; retrying",
+			zap.String("operation", operation),
+			zap.Int("attempt", attempt),
+			zap.String("backoff", backoff.String()),
+			zap.Error(err),
+		)
+		if err := sleepFor(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
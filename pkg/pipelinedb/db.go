@@ -20,7 +20,16 @@ import (
 )
 
 const (
-	dbVersion = 2
+	dbVersion = 10
+)
+
+const (
+	// PriceSourceCoinMarketCap is the source of prices quoted from
+	// CoinMarketCap.
+	PriceSourceCoinMarketCap = "coinmarketcap"
+
+	// PriceSourceOperator is the source of prices provided by the operator.
+	PriceSourceOperator = "operator"
 )
 
 type DB struct {
@@ -175,6 +184,95 @@ func (db *DB) RecordStart(ctx context.Context, spender common.Address, owner *co
 	return nil
 }
 
+// PriceLock is the price locked in for a payout. Every transaction
+// sent for the payout is priced with it.
+type PriceLock struct {
+	// Symbol is the symbol of the coin or token the price is for. It is
+	// empty for prices locked before the symbol was recorded.
+	Symbol string
+
+	// Price is the price of the coin or token in USD.
+	Price decimal.Decimal
+
+	// Source describes where the price came from (e.g. "coinmarketcap").
+	Source string
+
+	// Timestamp is when the price was quoted.
+	Timestamp time.Time
+}
+
+// CheckSymbol returns an error if the price is locked for another symbol
+// than the given one. Prices locked without a symbol are not checked.
+func (lock *PriceLock) CheckSymbol(symbol string) error {
+	if lock.Symbol != "" && lock.Symbol != symbol {
+		return errs.New("price is locked for %s, not %s", lock.Symbol, symbol)
+	}
+	return nil
+}
+
+// FetchPriceLock returns the locked price. It returns nil if no price
+// has been locked yet.
+func (db *DB) FetchPriceLock(ctx context.Context) (*PriceLock, error) {
+	row, err := db.db.First_Metadata(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if row == nil {
+		return nil, errs.New("database metadata is missing")
+	}
+	return PriceLockFromRow(row)
+}
+
+// LockPrice locks the price for the payout. It fails if a price has
+// already been locked. Use Reprice to change a locked price.
+func (db *DB) LockPrice(ctx context.Context, lock PriceLock) error {
+	existing, err := db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errs.New("price is already locked at $%s (source=%s, at=%s)", existing.Price, existing.Source, existing.Timestamp.Format(time.RFC3339))
+	}
+	return db.updatePriceLock(ctx, lock)
+}
+
+// Reprice replaces the locked price. Transactions that have already
+// been sent keep the price they were sent with. It fails if the locked price
+// is for another symbol.
+func (db *DB) Reprice(ctx context.Context, lock PriceLock) error {
+	existing, err := db.FetchPriceLock(ctx)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if err := existing.CheckSymbol(lock.Symbol); err != nil {
+			return err
+		}
+	}
+	return db.updatePriceLock(ctx, lock)
+}
+
+func (db *DB) updatePriceLock(ctx context.Context, lock PriceLock) error {
+	if !lock.Price.IsPositive() {
+		return errs.New("price must be more than zero; got %s", lock.Price)
+	}
+	if lock.Source == "" {
+		return errs.New("price source is required")
+	}
+	if lock.Symbol == "" {
+		return errs.New("price symbol is required")
+	}
+	if err := db.db.UpdateNoReturn_Metadata_By_Pk(ctx, payoutdb.Metadata_Pk(db.metadata.Pk), payoutdb.Metadata_Update_Fields{
+		Price:       payoutdb.Metadata_Price(lock.Price.String()),
+		PriceSource: payoutdb.Metadata_PriceSource(lock.Source),
+		PricedAt:    payoutdb.Metadata_PricedAt(lock.Timestamp),
+		PriceSymbol: payoutdb.Metadata_PriceSymbol(lock.Symbol),
+	}); err != nil {
+		return errs.Wrap(err)
+	}
+	return nil
+}
+
 func (db *DB) CreatePayoutGroup(ctx context.Context, payoutGroupID int64, payouts []*Payout) error {
 	return db.db.WithTx(ctx, func(tx *payoutdb.Tx) error {
 		if err := tx.CreateNoReturn_PayoutGroup(ctx,
@@ -218,29 +316,37 @@ func (db *DB) FetchUnfinishedTransactionsSortedIntoNonceGroups(ctx context.Conte
 		return nil, err
 	}
 
+	// Each spender has its own nonces, so transactions are grouped by
+	// spender and nonce.
+	type nonceKey struct {
+		spender common.Address
+		nonce   uint64
+	}
+
 	groups := make([]*NonceGroup, 0, len(txs))
+	groupsByKey := make(map[nonceKey]*NonceGroup)
 	for _, tx := range txs {
-		// add the tx to the last group if the nonces match.
-		if len(groups) > 0 {
-			lastGroup := groups[len(groups)-1]
-			if lastGroup.Nonce == tx.Nonce {
-				if lastGroup.PayoutGroupID != tx.PayoutGroupID {
-					return nil, errs.New("expected payout group %d on nonce group %d transaction %s; got %d",
-						lastGroup.PayoutGroupID,
-						lastGroup.Nonce,
-						tx.Hash,
-						tx.PayoutGroupID)
-				}
-				lastGroup.Txs = append(lastGroup.Txs, *tx)
-				continue
+		key := nonceKey{spender: tx.Spender, nonce: tx.Nonce}
+		if group, ok := groupsByKey[key]; ok {
+			if group.PayoutGroupID != tx.PayoutGroupID {
+				return nil, errs.New("expected payout group %d on nonce group %d transaction %s; got %d",
+					group.PayoutGroupID,
+					group.Nonce,
+					tx.Hash,
+					tx.PayoutGroupID)
 			}
+			group.Txs = append(group.Txs, *tx)
+			continue
 		}
 
-		groups = append(groups, &NonceGroup{
+		group := &NonceGroup{
 			Nonce:         tx.Nonce,
+			Spender:       tx.Spender,
 			PayoutGroupID: tx.PayoutGroupID,
 			Txs:           []Transaction{*tx},
-		})
+		}
+		groupsByKey[key] = group
+		groups = append(groups, group)
 	}
 
 	return groups, nil
@@ -316,6 +422,118 @@ func (db *DB) FinalizeNonceGroup(ctx context.Context, nonceGroup *NonceGroup, st
 	})
 }
 
+// FetchConfirmedTransactions returns all of the confirmed transactions
+// sorted by nonce.
+func (db *DB) FetchConfirmedTransactions(ctx context.Context) ([]*Transaction, error) {
+	rows, err := db.db.All_Transaction_By_State_OrderBy_Asc_Nonce(ctx,
+		payoutdb.Transaction_State(string(TxConfirmed)))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return TransactionsFromRows(rows)
+}
+
+// ReopenPayoutGroup puts a confirmed transaction back into the pending state
+// and clears the final tx hash on its payout group. It is used when the block
+// the transaction was included in is no longer part of the canonical chain,
+// so that the payout group is verified again.
+func (db *DB) ReopenPayoutGroup(ctx context.Context, tx *Transaction) error {
+	return db.db.WithTx(ctx, func(dbtx *payoutdb.Tx) error {
+		if err := dbtx.UpdateNoReturn_Transaction_By_Hash(ctx,
+			payoutdb.Transaction_Hash(tx.Hash),
+			payoutdb.Transaction_Update_Fields{
+				State:     payoutdb.Transaction_State(string(TxPending)),
+				Receipt:   payoutdb.Transaction_Receipt_Null(),
+				BlockHash: payoutdb.Transaction_BlockHash_Null(),
+			},
+		); err != nil {
+			return errs.Wrap(err)
+		}
+		if err := dbtx.UpdateNoReturn_PayoutGroup_By_Id(ctx,
+			payoutdb.PayoutGroup_Id(tx.PayoutGroupID),
+			payoutdb.PayoutGroup_Update_Fields{
+				FinalTxHash: payoutdb.PayoutGroup_FinalTxHash_Null(),
+			},
+		); err != nil {
+			return errs.Wrap(err)
+		}
+		return nil
+	})
+}
+
+// QuarantinePayoutGroup records why the payout group failed and excludes it
+// from the payout groups left to send.
+func (db *DB) QuarantinePayoutGroup(ctx context.Context, payoutGroupID int64, reason string) error {
+	if reason == "" {
+		return errs.New("quarantine reason is required")
+	}
+	err := db.db.UpdateNoReturn_PayoutGroup_By_Id(ctx,
+		payoutdb.PayoutGroup_Id(payoutGroupID),
+		payoutdb.PayoutGroup_Update_Fields{
+			QuarantineReason: payoutdb.PayoutGroup_QuarantineReason(reason),
+		})
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	return nil
+}
+
+// FetchQuarantinedPayoutGroups returns the quarantined payout groups sorted
+// by ID.
+func (db *DB) FetchQuarantinedPayoutGroups(ctx context.Context) ([]*PayoutGroup, error) {
+	rows, err := db.db.All_PayoutGroup_By_QuarantineReason_IsNot_Null_OrderBy_Asc_Id(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return PayoutGroupsFromRows(rows)
+}
+
+// FetchUnfinishedUnattachedPayoutGroups returns the payout groups that are
+// left to send, sorted by ID.
+func (db *DB) FetchUnfinishedUnattachedPayoutGroups(ctx context.Context) ([]*PayoutGroup, error) {
+	rows, err := db.db.AllUnfinishedUnattachedPayoutGroups(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return PayoutGroupsFromRows(rows)
+}
+
+// RecordSimulation replaces the results of the last simulation with the
+// given results.
+func (db *DB) RecordSimulation(ctx context.Context, results []*SimulationResult) error {
+	return db.db.WithTx(ctx, func(tx *payoutdb.Tx) error {
+		if err := tx.DeleteAllSimulationResults(ctx); err != nil {
+			return err
+		}
+		for _, result := range results {
+			var optional payoutdb.SimulationResult_Create_Fields
+			if result.Reason != "" {
+				optional.Reason = payoutdb.SimulationResult_Reason(result.Reason)
+			}
+			if err := tx.CreateNoReturn_SimulationResult(ctx,
+				payoutdb.SimulationResult_PayoutGroupId(result.PayoutGroupID),
+				payoutdb.SimulationResult_Payee(result.Payee.String()),
+				payoutdb.SimulationResult_Usd(result.USD.String()),
+				payoutdb.SimulationResult_Tokens(result.Tokens.String()),
+				payoutdb.SimulationResult_Outcome(string(result.Outcome)),
+				optional,
+			); err != nil {
+				return errs.Wrap(err)
+			}
+		}
+		return nil
+	})
+}
+
+// FetchSimulationResults returns the results of the last simulation.
+func (db *DB) FetchSimulationResults(ctx context.Context) ([]*SimulationResult, error) {
+	rows, err := db.db.All_SimulationResult_OrderBy_Asc_Pk(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return SimulationResultsFromRows(rows)
+}
+
 func (db *DB) CreateTransaction(ctx context.Context, tx Transaction) (*Transaction, error) {
 	row, err := db.db.Create_Transaction(ctx,
 		payoutdb.Transaction_Hash(tx.Hash),
@@ -323,8 +541,8 @@ func (db *DB) CreateTransaction(ctx context.Context, tx Transaction) (*Transacti
 		payoutdb.Transaction_Spender(tx.Spender.String()),
 		payoutdb.Transaction_Nonce(tx.Nonce),
 		payoutdb.Transaction_EstimatedGasPrice("0"),
-		payoutdb.Transaction_StorjPrice(tx.StorjPrice.String()),
-		payoutdb.Transaction_StorjTokens(tx.StorjTokens.String()),
+		payoutdb.Transaction_Price(tx.Price.String()),
+		payoutdb.Transaction_Tokens(tx.Tokens.String()),
 		payoutdb.Transaction_PayoutGroupId(tx.PayoutGroupID),
 		payoutdb.Transaction_Raw(string(tx.Raw)),
 		payoutdb.Transaction_State(string(TxPending)),
@@ -363,6 +581,44 @@ func (db *DB) FetchTransaction(ctx context.Context, hash common.Hash) (*Transact
 	return TransactionFromRow(row)
 }
 
+// FetchNextNonce returns the nonce following the highest nonce of the
+// transactions recorded for the spender, or zero if there are none.
+func (db *DB) FetchNextNonce(ctx context.Context, spender common.Address) (uint64, error) {
+	return db.db.NextNonce(ctx, spender.String())
+}
+
+// ClaimLogs records that the event logs confirmed the transaction with the
+// given hash. It fails if any of the logs was already claimed.
+func (db *DB) ClaimLogs(ctx context.Context, hash string, logs []LogID) error {
+	return db.db.WithTx(ctx, func(tx *payoutdb.Tx) error {
+		for _, log := range logs {
+			if err := tx.CreateNoReturn_LogClaim(ctx,
+				payoutdb.LogClaim_TxHash(log.TxHash.String()),
+				payoutdb.LogClaim_LogIndex(uint64(log.Index)),
+				payoutdb.LogClaim_ClaimedBy(hash),
+			); err != nil {
+				return errs.Wrap(err)
+			}
+		}
+		return nil
+	})
+}
+
+// FetchLogClaim returns the hash of the transaction the event log confirmed,
+// or an empty string if it has not been claimed.
+func (db *DB) FetchLogClaim(ctx context.Context, log LogID) (string, error) {
+	row, err := db.db.Find_LogClaim_By_TxHash_And_LogIndex(ctx,
+		payoutdb.LogClaim_TxHash(log.TxHash.String()),
+		payoutdb.LogClaim_LogIndex(uint64(log.Index)))
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	if row == nil {
+		return "", nil
+	}
+	return row.ClaimedBy, nil
+}
+
 func (db *DB) FetchTransactions(ctx context.Context) ([]*Transaction, error) {
 	rows, err := db.db.All_Transaction(ctx)
 	if err != nil {
@@ -394,6 +650,7 @@ type DBStats struct {
 	PendingUSD            decimal.Decimal
 	TotalPayoutGroups     int64
 	PendingPayoutGroups   int64
+	QuarantinedGroups     int64
 	TotalTransactions     int64
 	PendingTransactions   int64
 	FailedTransactions    int64
@@ -445,6 +702,12 @@ func (db *DB) Stats(ctx context.Context) (_ *DBStats, err error) {
 		return nil, errs.Wrap(err)
 	}
 
+	quarantined, err := db.db.All_PayoutGroup_By_QuarantineReason_IsNot_Null_OrderBy_Asc_Id(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	stats.QuarantinedGroups = int64(len(quarantined))
+
 	stats.TotalTransactions, err = db.db.Count_Transaction(ctx)
 	if err != nil {
 		return nil, errs.Wrap(err)
@@ -469,6 +732,72 @@ func (db *DB) Stats(ctx context.Context) (_ *DBStats, err error) {
 	return stats, nil
 }
 
+// SpendTotals are the running totals of what has been committed to
+// transactions. A payout group counts once it has been sent, no matter how
+// many times it is retried or replaced afterwards.
+type SpendTotals struct {
+	Tokens       *big.Int
+	USD          decimal.Decimal
+	PayeeUSD     map[common.Address]decimal.Decimal
+	PayoutGroups map[int64]struct{}
+}
+
+func NewSpendTotals() *SpendTotals {
+	return &SpendTotals{
+		Tokens:       new(big.Int),
+		PayeeUSD:     make(map[common.Address]decimal.Decimal),
+		PayoutGroups: make(map[int64]struct{}),
+	}
+}
+
+// Add adds a sent payout group to the totals. It is a no-op if the payout
+// group has already been counted.
+func (totals *SpendTotals) Add(payoutGroupID int64, payouts []*Payout, tokens *big.Int) {
+	if _, ok := totals.PayoutGroups[payoutGroupID]; ok {
+		return
+	}
+	totals.PayoutGroups[payoutGroupID] = struct{}{}
+	totals.Tokens.Add(totals.Tokens, tokens)
+	for _, payout := range payouts {
+		totals.USD = totals.USD.Add(payout.USD)
+		totals.PayeeUSD[payout.Payee] = totals.PayeeUSD[payout.Payee].Add(payout.USD)
+	}
+}
+
+// FetchSpendTotals calculates the spend totals from the transactions
+// recorded so far. If a payout group has been sent more than once (e.g.
+// after a reprice), the largest token amount is counted.
+func (db *DB) FetchSpendTotals(ctx context.Context) (*SpendTotals, error) {
+	txs, err := db.FetchTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupTokens := make(map[int64]*big.Int)
+	for _, tx := range txs {
+		if tokens, ok := groupTokens[tx.PayoutGroupID]; !ok || tokens.Cmp(tx.Tokens) < 0 {
+			groupTokens[tx.PayoutGroupID] = tx.Tokens
+		}
+	}
+
+	payouts, err := db.FetchPayouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groupPayouts := make(map[int64][]*Payout)
+	for _, payout := range payouts {
+		if _, ok := groupTokens[payout.PayoutGroupID]; ok {
+			groupPayouts[payout.PayoutGroupID] = append(groupPayouts[payout.PayoutGroupID], payout)
+		}
+	}
+
+	totals := NewSpendTotals()
+	for payoutGroupID, tokens := range groupTokens {
+		totals.Add(payoutGroupID, groupPayouts[payoutGroupID], tokens)
+	}
+	return totals, nil
+}
+
 func setTransactionStatus(ctx context.Context, db payoutdb.Methods, status *TxStatus) error {
 	update := payoutdb.Transaction_Update_Fields{
 		State: payoutdb.Transaction_State(string(status.State)),
@@ -480,6 +809,9 @@ func setTransactionStatus(ctx context.Context, db payoutdb.Methods, status *TxSt
 			return errs.Wrap(err)
 		}
 		update.Receipt = payoutdb.Transaction_Receipt(string(receiptJSON))
+		if status.Receipt.BlockHash != (common.Hash{}) {
+			update.BlockHash = payoutdb.Transaction_BlockHash(status.Receipt.BlockHash.String())
+		}
 	}
 
 	return db.UpdateNoReturn_Transaction_By_Hash(ctx,
@@ -524,9 +856,36 @@ func PayoutFromRow(row *payoutdb.Payout) (*Payout, error) {
 	}, nil
 }
 
+func PriceLockFromRow(row *payoutdb.Metadata) (*PriceLock, error) {
+	if row.Price == nil {
+		return nil, nil
+	}
+	price, err := decimal.NewFromString(*row.Price)
+	if err != nil {
+		return nil, errs.New("unable to convert locked price: %v", err)
+	}
+	lock := &PriceLock{
+		Price: price,
+	}
+	if row.PriceSource != nil {
+		lock.Source = *row.PriceSource
+	}
+	if row.PricedAt != nil {
+		lock.Timestamp = *row.PricedAt
+	}
+	if row.PriceSymbol != nil {
+		lock.Symbol = *row.PriceSymbol
+	}
+	return lock, nil
+}
+
 type PayoutGroup struct {
 	ID          int64
 	FinalTxHash *common.Hash
+
+	// QuarantineReason is why the payout group was quarantined. It is empty
+	// if the payout group is not quarantined.
+	QuarantineReason string
 }
 
 func PayoutGroupsFromRows(rows []*payoutdb.PayoutGroup) ([]*PayoutGroup, error) {
@@ -551,12 +910,25 @@ func PayoutGroupFromRow(row *payoutdb.PayoutGroup) (*PayoutGroup, error) {
 		finalTxHash = &hash
 	}
 
+	var quarantineReason string
+	if row.QuarantineReason != nil {
+		quarantineReason = *row.QuarantineReason
+	}
+
 	return &PayoutGroup{
-		ID:          row.Id,
-		FinalTxHash: finalTxHash,
+		ID:               row.Id,
+		FinalTxHash:      finalTxHash,
+		QuarantineReason: quarantineReason,
 	}, nil
 }
 
+// LogID identifies an event log by the transaction that emitted it and its
+// index in the block.
+type LogID struct {
+	TxHash common.Hash
+	Index  uint
+}
+
 type Transaction struct {
 	CreatedAt         time.Time
 	Hash              string
@@ -564,12 +936,13 @@ type Transaction struct {
 	Spender           common.Address
 	Nonce             uint64
 	EstimatedGasPrice *big.Int
-	StorjPrice        decimal.Decimal
-	StorjTokens       *big.Int
+	Price             decimal.Decimal
+	Tokens            *big.Int
 	PayoutGroupID     int64
 	Raw               []byte
 	State             TxState
 	Receipt           *types.Receipt
+	BlockHash         *common.Hash
 }
 
 func TransactionsFromRows(rows []*payoutdb.Transaction) ([]*Transaction, error) {
@@ -597,13 +970,13 @@ func TransactionFromRow(row *payoutdb.Transaction) (*Transaction, error) {
 	if !ok {
 		return nil, errs.New("unable to convert estimated gas price for transaction pk %d", row.Pk)
 	}
-	storjPrice, err := decimal.NewFromString(row.StorjPrice)
+	price, err := decimal.NewFromString(row.Price)
 	if err != nil {
-		return nil, errs.New("unable to convert storj price for transaction pk %d: %v", row.Pk, err)
+		return nil, errs.New("unable to convert price for transaction pk %d: %v", row.Pk, err)
 	}
-	storjTokens, ok := new(big.Int).SetString(row.StorjTokens, 10)
+	tokens, ok := new(big.Int).SetString(row.Tokens, 10)
 	if !ok {
-		return nil, errs.New("unable to convert storj tokens for transaction pk %d", row.Pk)
+		return nil, errs.New("unable to convert tokens for transaction pk %d", row.Pk)
 	}
 
 	raw := []byte(row.Raw)
@@ -621,6 +994,15 @@ func TransactionFromRow(row *payoutdb.Transaction) (*Transaction, error) {
 		return nil, errs.New("unable to convert state for transaction pk %d: %v", row.Pk, err)
 	}
 
+	var blockHash *common.Hash
+	if row.BlockHash != nil {
+		hash, err := batchpayment.HashFromString(*row.BlockHash)
+		if err != nil {
+			return nil, errs.New("unable to convert block hash for transaction pk %d: %v", row.Pk, err)
+		}
+		blockHash = &hash
+	}
+
 	return &Transaction{
 		CreatedAt:         row.CreatedAt,
 		Hash:              row.Hash,
@@ -628,17 +1010,19 @@ func TransactionFromRow(row *payoutdb.Transaction) (*Transaction, error) {
 		Spender:           spender,
 		Nonce:             row.Nonce,
 		EstimatedGasPrice: estimatedGasPrice,
-		StorjPrice:        storjPrice,
-		StorjTokens:       storjTokens,
+		Price:             price,
+		Tokens:            tokens,
 		PayoutGroupID:     row.PayoutGroupId,
 		Raw:               raw,
 		State:             state,
 		Receipt:           receipt,
+		BlockHash:         blockHash,
 	}, nil
 }
 
 type NonceGroup struct {
 	Nonce         uint64
+	Spender       common.Address
 	PayoutGroupID int64
 	Txs           []Transaction
 }
@@ -720,6 +1104,38 @@ func migrateDB(ctx context.Context, db *payoutdb.DB, version int) (err error) {
 			if err := migrateV2(ctx, tx); err != nil {
 				return err
 			}
+		case 3:
+			if err := migrateV3(ctx, tx); err != nil {
+				return err
+			}
+		case 4:
+			if err := migrateV4(ctx, tx); err != nil {
+				return err
+			}
+		case 5:
+			if err := migrateV5(ctx, tx); err != nil {
+				return err
+			}
+		case 6:
+			if err := migrateV6(ctx, tx); err != nil {
+				return err
+			}
+		case 7:
+			if err := migrateV7(ctx, tx); err != nil {
+				return err
+			}
+		case 8:
+			if err := migrateV8(ctx, tx); err != nil {
+				return err
+			}
+		case 9:
+			if err := migrateV9(ctx, tx); err != nil {
+				return err
+			}
+		case 10:
+			if err := migrateV10(ctx, tx); err != nil {
+				return err
+			}
 		default:
 			return errs.New("no migration to version %d available", to)
 		}
@@ -789,3 +1205,142 @@ func migrateV2(ctx context.Context, tx *sql.Tx) error {
 	}
 	return nil
 }
+
+func migrateV3(ctx context.Context, tx *sql.Tx) error {
+	// version 3 added the locked STORJ price to the metadata table.
+	stmts := []string{
+		`ALTER TABLE metadata ADD COLUMN price TEXT;`,
+		`ALTER TABLE metadata ADD COLUMN price_source TEXT;`,
+		`ALTER TABLE metadata ADD COLUMN priced_at TIMESTAMP;`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV4(ctx context.Context, tx *sql.Tx) error {
+	// version 4 added the block hash to the tx table. It is backfilled from
+	// the receipts of finalized transactions.
+	stmts := []string{
+		`ALTER TABLE tx ADD COLUMN block_hash TEXT;`,
+		`UPDATE tx SET block_hash = json_extract(receipt, '$.blockHash') WHERE receipt IS NOT NULL;`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV5(ctx context.Context, tx *sql.Tx) error {
+	// version 5 added the quarantine reason to the payout_group table.
+	stmts := []string{
+		`ALTER TABLE payout_group ADD COLUMN quarantine_reason TEXT;`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV6(ctx context.Context, tx *sql.Tx) error {
+	// version 6 added the simulation_result table.
+	stmts := []string{
+		`CREATE TABLE simulation_result (
+			pk INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			payout_group_id INTEGER NOT NULL,
+			payee TEXT NOT NULL,
+			usd TEXT NOT NULL,
+			storj_tokens TEXT NOT NULL,
+			outcome TEXT NOT NULL,
+			reason TEXT,
+			PRIMARY KEY ( pk )
+		);`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV7(ctx context.Context, tx *sql.Tx) error {
+	// version 7 renamed the "storj_price" and "storj_tokens" columns of the
+	// tx table to "price" and "tokens" since payouts can be paid in any
+	// token.
+	stmts := []string{
+		`ALTER TABLE tx RENAME COLUMN storj_price TO price;`,
+		`ALTER TABLE tx RENAME COLUMN storj_tokens TO tokens;`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV8(ctx context.Context, tx *sql.Tx) error {
+	// version 8 added the log_claim table.
+	stmts := []string{
+		`CREATE TABLE log_claim (
+			pk INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			tx_hash TEXT NOT NULL,
+			log_index INTEGER NOT NULL,
+			claimed_by TEXT NOT NULL,
+			PRIMARY KEY ( pk ),
+			UNIQUE ( tx_hash, log_index )
+		);`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV9(ctx context.Context, tx *sql.Tx) error {
+	// version 9 added the symbol of the locked price. Prices locked before
+	// are left without one, since they may be for STORJ or a native coin.
+	stmts := []string{
+		`ALTER TABLE metadata ADD COLUMN price_symbol TEXT;`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func migrateV10(ctx context.Context, tx *sql.Tx) error {
+	// version 10 renamed the "storj_tokens" column of the simulation_result
+	// table to "tokens", like version 7 did for the tx table.
+	stmts := []string{
+		`ALTER TABLE simulation_result RENAME COLUMN storj_tokens TO tokens;`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -2,10 +2,14 @@ package pipelinedb
 
 import (
 	"context"
+	"math/big"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +69,184 @@ func readDir(t *testing.T, dir string) []string {
 	}
 	return names
 }
+
+func TestPriceLock(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewDB(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, db.Close()) }()
+
+	lock, err := db.FetchPriceLock(ctx)
+	require.NoError(t, err)
+	require.Nil(t, lock)
+
+	quoted := PriceLock{
+		Symbol:    "STORJ",
+		Price:     decimal.RequireFromString("0.5123"),
+		Source:    PriceSourceCoinMarketCap,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	require.NoError(t, db.LockPrice(ctx, quoted))
+
+	lock, err = db.FetchPriceLock(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+	assert.Equal(t, quoted.Price.String(), lock.Price.String())
+	assert.Equal(t, quoted.Source, lock.Source)
+	assert.Equal(t, "STORJ", lock.Symbol)
+	assert.True(t, quoted.Timestamp.Equal(lock.Timestamp))
+
+	// The price cannot be locked twice.
+	operator := PriceLock{
+		Symbol:    "STORJ",
+		Price:     decimal.RequireFromString("0.6"),
+		Source:    PriceSourceOperator,
+		Timestamp: quoted.Timestamp.Add(time.Hour),
+	}
+	require.EqualError(t, db.LockPrice(ctx, operator), "price is already locked at $0.5123 (source=coinmarketcap, at=2024-01-02T03:04:05Z)")
+
+	// It can only be changed explicitly.
+	require.NoError(t, db.Reprice(ctx, operator))
+	lock, err = db.FetchPriceLock(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0.6", lock.Price.String())
+	assert.Equal(t, "operator", lock.Source)
+
+	require.EqualError(t, db.Reprice(ctx, PriceLock{Symbol: "STORJ", Source: "operator"}), "price must be more than zero; got 0")
+	require.EqualError(t, db.Reprice(ctx, PriceLock{Symbol: "STORJ", Price: operator.Price}), "price source is required")
+
+	// The price cannot be changed to the price of another symbol.
+	require.EqualError(t, db.Reprice(ctx, PriceLock{Symbol: "ETH", Price: operator.Price, Source: "operator"}), "price is locked for STORJ, not ETH")
+}
+
+func TestQuarantinePayoutGroup(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewDB(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, db.Close()) }()
+
+	payee := common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA")
+	require.NoError(t, db.CreatePayoutGroup(ctx, 1, []*Payout{{Payee: payee, USD: decimal.New(1, 0)}}))
+	require.NoError(t, db.CreatePayoutGroup(ctx, 2, []*Payout{{Payee: payee, USD: decimal.New(2, 0)}}))
+
+	require.EqualError(t, db.QuarantinePayoutGroup(ctx, 1, ""), "quarantine reason is required")
+	require.NoError(t, db.QuarantinePayoutGroup(ctx, 1, "execution reverted"))
+
+	quarantined, err := db.FetchQuarantinedPayoutGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, int64(1), quarantined[0].ID)
+	assert.Equal(t, "execution reverted", quarantined[0].QuarantineReason)
+
+	// Quarantined payout groups are not sent again.
+	payoutGroup, err := db.FetchFirstUnfinishedUnattachedPayoutGroup(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, payoutGroup)
+	assert.Equal(t, int64(2), payoutGroup.ID)
+	assert.Empty(t, payoutGroup.QuarantineReason)
+
+	count, err := db.CountUnfinishedUnattachedPayoutGroup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	stats, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.QuarantinedGroups)
+}
+
+func TestRecordSimulation(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewDB(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, db.Close()) }()
+
+	alice := common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA")
+	bob := common.HexToAddress("0x8A6c3F5E2d6d5eE6a4F0e5D2f1f8bE9Cc0C4a2b1")
+	require.NoError(t, db.CreatePayoutGroup(ctx, 1, []*Payout{{Payee: alice, USD: decimal.New(1, 0)}}))
+	require.NoError(t, db.CreatePayoutGroup(ctx, 2, []*Payout{{Payee: bob, USD: decimal.New(2, 0)}}))
+	require.NoError(t, db.QuarantinePayoutGroup(ctx, 1, "execution reverted"))
+
+	// Only the payout groups left to send are simulated.
+	payoutGroups, err := db.FetchUnfinishedUnattachedPayoutGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, payoutGroups, 1)
+	assert.Equal(t, int64(2), payoutGroups[0].ID)
+
+	first := []*SimulationResult{
+		{PayoutGroupID: 1, Payee: alice, USD: decimal.New(1, 0), Tokens: big.NewInt(1e8), Outcome: SimulationOK},
+	}
+	require.NoError(t, db.RecordSimulation(ctx, first))
+
+	// Recording a simulation replaces the last one.
+	second := []*SimulationResult{
+		{PayoutGroupID: 2, Payee: bob, USD: decimal.New(2, 0), Tokens: big.NewInt(2e8), Outcome: SimulationBalance, Reason: "owner balance (1) does not cover the transfers (2)"},
+	}
+	require.NoError(t, db.RecordSimulation(ctx, second))
+
+	results, err := db.FetchSimulationResults(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(2), results[0].PayoutGroupID)
+	assert.Equal(t, bob, results[0].Payee)
+	assert.True(t, decimal.New(2, 0).Equal(results[0].USD))
+	assert.Equal(t, big.NewInt(2e8), results[0].Tokens)
+	assert.Equal(t, SimulationBalance, results[0].Outcome)
+	assert.Equal(t, second[0].Reason, results[0].Reason)
+}
+
+func TestNextNonceAndLogClaims(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewDB(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, db.Close()) }()
+
+	safe := common.HexToAddress("0x58408e92BD76B15b23531F5BA3a6253513748ecA")
+	other := common.HexToAddress("0x8A6c3F5E2d6d5eE6a4F0e5D2f1f8bE9Cc0C4a2b1")
+	require.NoError(t, db.CreatePayoutGroup(ctx, 1, []*Payout{{Payee: other, USD: decimal.New(1, 0)}}))
+
+	nonce, err := db.FetchNextNonce(ctx, safe)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), nonce)
+
+	for i, hash := range []string{"0x01", "0x02"} {
+		_, err := db.CreateTransaction(ctx, Transaction{
+			Hash:          hash,
+			Owner:         safe,
+			Spender:       safe,
+			Nonce:         uint64(i * 5),
+			Price:         decimal.New(1, 0),
+			Tokens:        big.NewInt(1),
+			PayoutGroupID: 1,
+			Raw:           []byte("{}"),
+		})
+		require.NoError(t, err)
+	}
+
+	// The nonce follows the highest one of the spender.
+	nonce, err = db.FetchNextNonce(ctx, safe)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), nonce)
+	nonce, err = db.FetchNextNonce(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), nonce)
+
+	// A log can only be claimed once.
+	log := LogID{TxHash: common.HexToHash("0x03"), Index: 2}
+	claimedBy, err := db.FetchLogClaim(ctx, log)
+	require.NoError(t, err)
+	assert.Empty(t, claimedBy)
+
+	require.NoError(t, db.ClaimLogs(ctx, "0x01", []LogID{log}))
+	claimedBy, err = db.FetchLogClaim(ctx, log)
+	require.NoError(t, err)
+	assert.Equal(t, "0x01", claimedBy)
+
+	require.Error(t, db.ClaimLogs(ctx, "0x02", []LogID{{TxHash: log.TxHash, Index: 3}, log}))
+	claimedBy, err = db.FetchLogClaim(ctx, LogID{TxHash: log.TxHash, Index: 3})
+	require.NoError(t, err)
+	assert.Empty(t, claimedBy, "claims are recorded all at once or not at all")
+}
@@ -57,6 +57,27 @@ func testMigration(t *testing.T, version int) {
 		_, err = db.Stats(ctx)
 		assert.NoError(t, err)
 
+		// Block hashes are backfilled from the receipts. The price and
+		// tokens survive the renaming of their columns.
+		txs, err := db.FetchTransactions(ctx)
+		require.NoError(t, err)
+		for _, tx := range txs {
+			assert.Equal(t, "0.5", tx.Price.String())
+			assert.Equal(t, "10000", tx.Tokens.String())
+			if tx.Receipt != nil {
+				if assert.NotNil(t, tx.BlockHash, "block hash not backfilled for %s", tx.Hash) {
+					assert.Equal(t, tx.Receipt.BlockHash, *tx.BlockHash)
+				}
+			}
+		}
+
+		// The simulated tokens survive the renaming of their column.
+		results, err := db.FetchSimulationResults(ctx)
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.Equal(t, "10000", result.Tokens.String())
+		}
+
 		// If not readOnly, try to attempt a write to make sure the database
 		// is still open in the correct mode.
 		if !readOnly {
@@ -69,7 +90,7 @@ func testMigration(t *testing.T, version int) {
 		doRaw(t, dbPath, func(t *testing.T, rawDB *sql.DB) {
 			var gotVersion int
 			require.NoError(t, rawDB.QueryRow("SELECT version FROM metadata").Scan(&gotVersion))
-			assert.Equal(t, version+1, gotVersion)
+			assert.Equal(t, dbVersion, gotVersion)
 		})
 	}
 
@@ -0,0 +1,103 @@
+package pipelinedb
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
+
+	batchpayment "storj.io/crypto-batch-payment/pkg"
+	"storj.io/crypto-batch-payment/pkg/payoutdb"
+)
+
+type SimulationOutcome string
+
+const (
+	// SimulationOK represents a transfer that would succeed.
+	SimulationOK SimulationOutcome = "ok"
+
+	// SimulationRevert represents a transfer that would be reverted by the
+	// contract for a reason other than the balance or allowance.
+	SimulationRevert SimulationOutcome = "revert"
+
+	// SimulationAllowance represents a transfer that the allowance of the
+	// owner would not cover, along with the transfers before it.
+	SimulationAllowance SimulationOutcome = "allowance"
+
+	// SimulationBalance represents a transfer that the balance of the owner
+	// would not cover, along with the transfers before it.
+	SimulationBalance SimulationOutcome = "balance"
+)
+
+func SimulationOutcomeFromString(s string) (SimulationOutcome, bool) {
+	switch SimulationOutcome(s) {
+	case SimulationOK:
+		return SimulationOK, true
+	case SimulationRevert:
+		return SimulationRevert, true
+	case SimulationAllowance:
+		return SimulationAllowance, true
+	case SimulationBalance:
+		return SimulationBalance, true
+	}
+	return "", false
+}
+
+// SimulationResult is the outcome of simulating the transfer to a payee.
+type SimulationResult struct {
+	PayoutGroupID int64
+	Payee         common.Address
+	USD           decimal.Decimal
+	Tokens        *big.Int
+	Outcome       SimulationOutcome
+
+	// Reason is why the transfer would fail. It is empty if it would
+	// succeed.
+	Reason string
+}
+
+func SimulationResultsFromRows(rows []*payoutdb.SimulationResult) ([]*SimulationResult, error) {
+	results := make([]*SimulationResult, 0, len(rows))
+	for _, row := range rows {
+		result, err := SimulationResultFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func SimulationResultFromRow(row *payoutdb.SimulationResult) (*SimulationResult, error) {
+	payee, err := batchpayment.AddressFromString(row.Payee)
+	if err != nil {
+		return nil, errs.New("unable to convert payee for simulation result pk %d: %v", row.Pk, err)
+	}
+	usd, err := decimal.NewFromString(row.Usd)
+	if err != nil {
+		return nil, errs.New("unable to convert USD for simulation result pk %d: %v", row.Pk, err)
+	}
+	tokens, ok := new(big.Int).SetString(row.Tokens, 10)
+	if !ok {
+		return nil, errs.New("unable to convert tokens for simulation result pk %d", row.Pk)
+	}
+	outcome, ok := SimulationOutcomeFromString(row.Outcome)
+	if !ok {
+		return nil, errs.New("unable to convert outcome for simulation result pk %d", row.Pk)
+	}
+
+	var reason string
+	if row.Reason != nil {
+		reason = *row.Reason
+	}
+
+	return &SimulationResult{
+		PayoutGroupID: row.PayoutGroupId,
+		Payee:         payee,
+		USD:           usd,
+		Tokens:        tokens,
+		Outcome:       outcome,
+		Reason:        reason,
+	}, nil
+}
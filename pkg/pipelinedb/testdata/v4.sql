@@ -0,0 +1,61 @@
+PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE metadata (
+	pk INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	version INTEGER NOT NULL,
+	attempts INTEGER NOT NULL,
+	spender TEXT,
+	owner TEXT,
+	price TEXT,
+	price_source TEXT,
+	priced_at TIMESTAMP,
+	PRIMARY KEY ( pk )
+);
+CREATE TABLE payout_group (
+	pk INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	id INTEGER NOT NULL,
+	final_tx_hash TEXT,
+	PRIMARY KEY ( pk ),
+	UNIQUE ( id )
+);
+CREATE TABLE payout (
+	pk INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	csv_line INTEGER NOT NULL,
+	payee TEXT NOT NULL,
+	usd TEXT NOT NULL,
+	payout_group_id INTEGER NOT NULL REFERENCES payout_group( id ),
+	PRIMARY KEY ( pk )
+);
+CREATE TABLE tx (
+	pk INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	hash TEXT NOT NULL,
+	owner TEXT NOT NULL,
+	spender TEXT NOT NULL,
+	nonce INTEGER NOT NULL,
+	estimated_gas_price TEXT NOT NULL,
+	storj_price TEXT NOT NULL,
+	storj_tokens TEXT NOT NULL,
+	payout_group_id INTEGER NOT NULL REFERENCES payout_group( id ),
+	raw TEXT NOT NULL,
+	state TEXT NOT NULL,
+	receipt TEXT,
+	block_hash TEXT,
+	PRIMARY KEY ( pk ),
+	UNIQUE ( hash )
+);
+CREATE INDEX payout_group_final_tx_hash_index ON payout_group ( final_tx_hash ) ;
+
+INSERT INTO metadata VALUES(1,'2019-09-14 15:03:11.593+00:00','2019-09-14 15:03:11.593+00:00',4,1,'0xC043c8e32697298CaE99AD69027aAbd84610D244',NULL,'0.5','coinmarketcap','2019-09-14 15:03:11+00:00');
+INSERT INTO payout_group VALUES(1,'2019-09-14 15:03:11.608+00:00','2019-09-14 15:03:11.608+00:00',1,NULL);
+INSERT INTO payout VALUES(1,'2019-09-14 15:03:11.608+00:00',2,'0xC043c8e32697298CaE99AD69027aAbd84610D244','0.00005',1);
+INSERT INTO tx VALUES(1,'2019-09-14 15:04:11.608+00:00','2019-09-14 15:05:11.608+00:00','0x1111111111111111111111111111111111111111111111111111111111111111','0xC043c8e32697298CaE99AD69027aAbd84610D244','0xC043c8e32697298CaE99AD69027aAbd84610D244',0,'0','0.5','10000',1,'{}','confirmed','{"type":"0x2","root":"0x","status":"0x1","cumulativeGasUsed":"0xc7a4","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","logs":[],"transactionHash":"0x1111111111111111111111111111111111111111111111111111111111111111","contractAddress":"0x0000000000000000000000000000000000000000","gasUsed":"0xc7a4","effectiveGasPrice":"0x3b9aca07","blockHash":"0x2222222222222222222222222222222222222222222222222222222222222222","blockNumber":"0x5","transactionIndex":"0x0"}','0x2222222222222222222222222222222222222222222222222222222222222222');
+UPDATE payout_group SET final_tx_hash = '0x1111111111111111111111111111111111111111111111111111111111111111' WHERE id = 1;
+
+COMMIT;
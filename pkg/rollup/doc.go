@@ -0,0 +1,4 @@
+// Package rollup implements payouts on EVM rollups, like Arbitrum, Optimism
+// and Base, whose transactions pay an L1 data fee on top of their L2
+// execution gas.
+package rollup
@@ -0,0 +1,163 @@
+package rollup
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/zeebo/errs"
+)
+
+// Stack is the software stack of a rollup, which decides how it charges the
+// L1 data fee.
+type Stack string
+
+const (
+	// OPStack rollups, like Optimism and Base, charge the L1 data fee on top
+	// of the gas, as reported by their gas price oracle.
+	OPStack Stack = "op-stack"
+
+	// Arbitrum rollups charge the L1 data fee as extra L2 gas, as estimated
+	// by their node interface.
+	Arbitrum Stack = "arbitrum"
+)
+
+func (s Stack) String() string {
+	return string(s)
+}
+
+// StackFromString parses string to a Stack const.
+func StackFromString(s string) (Stack, error) {
+	switch strings.ToLower(s) {
+	case "op-stack", "optimism", "base":
+		return OPStack, nil
+	case "arbitrum":
+		return Arbitrum, nil
+	default:
+		return "", errs.New("invalid rollup stack %q", s)
+	}
+}
+
+var (
+	// GasPriceOracleAddress is the address of the gas price oracle
+	// predeploy of OP Stack rollups.
+	GasPriceOracleAddress = common.HexToAddress("0x420000000000000000000000000000000000000F")
+
+	// NodeInterfaceAddress is the address of the virtual node interface
+	// contract of Arbitrum rollups. It can only be called with eth_call.
+	NodeInterfaceAddress = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+
+	gasPriceOracleABI = mustParseABI(`[{"inputs":[{"internalType":"bytes","name":"_data","type":"bytes"}],"name":"getL1Fee","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`)
+
+	nodeInterfaceABI = mustParseABI(`[{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"bool","name":"contractCreation","type":"bool"},{"internalType":"bytes","name":"data","type":"bytes"}],"name":"gasEstimateL1Component","outputs":[{"internalType":"uint64","name":"gasEstimateForL1","type":"uint64"},{"internalType":"uint256","name":"baseFee","type":"uint256"},{"internalType":"uint256","name":"l1BaseFeeEstimate","type":"uint256"}],"stateMutability":"payable","type":"function"}]`)
+)
+
+// L1FeeOracle estimates the L1 data fee of transactions on a rollup.
+type L1FeeOracle interface {
+	// L1Fee returns the L1 data fee of the unsigned transaction, in wei.
+	L1Fee(ctx context.Context, tx *types.Transaction) (*big.Int, error)
+
+	// ExtraGas returns the gas to add to the gas limit of a transaction to
+	// the given address with the given data to pay for its L1 data fee.
+	// It is zero on rollups that charge the fee on top of the gas.
+	ExtraGas(ctx context.Context, to common.Address, data []byte) (uint64, error)
+}
+
+// NewL1FeeOracle returns the L1 fee oracle of rollups of the given stack.
+func NewL1FeeOracle(stack Stack, caller ethereum.ContractCaller) (L1FeeOracle, error) {
+	switch stack {
+	case OPStack:
+		return &opStackOracle{caller: caller}, nil
+	case Arbitrum:
+		return &arbitrumOracle{caller: caller}, nil
+	default:
+		return nil, errs.New("unsupported rollup stack %q", stack)
+	}
+}
+
+type opStackOracle struct {
+	caller ethereum.ContractCaller
+}
+
+func (o *opStackOracle) L1Fee(ctx context.Context, tx *types.Transaction) (*big.Int, error) {
+	// The oracle expects the transaction without its signature and pads it
+	// for one itself.
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	out, err := call(ctx, o.caller, GasPriceOracleAddress, gasPriceOracleABI, "getL1Fee", unsigned)
+	if err != nil {
+		return nil, err
+	}
+	return abi.ConvertType(out[0], new(big.Int)).(*big.Int), nil
+}
+
+func (o *opStackOracle) ExtraGas(ctx context.Context, to common.Address, data []byte) (uint64, error) {
+	return 0, nil
+}
+
+type arbitrumOracle struct {
+	caller ethereum.ContractCaller
+}
+
+func (o *arbitrumOracle) L1Fee(ctx context.Context, tx *types.Transaction) (*big.Int, error) {
+	if tx.To() == nil {
+		return nil, errs.New("contract creation is not supported")
+	}
+	gasForL1, baseFee, err := o.estimateL1Component(ctx, *tx.To(), tx.Data())
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(gasForL1), baseFee), nil
+}
+
+func (o *arbitrumOracle) ExtraGas(ctx context.Context, to common.Address, data []byte) (uint64, error) {
+	gasForL1, _, err := o.estimateL1Component(ctx, to, data)
+	if err != nil {
+		return 0, err
+	}
+	// The L1 base fee may rise before the transaction is included. Any gas
+	// left over is refunded.
+	return gasForL1 + gasForL1/4, nil
+}
+
+func (o *arbitrumOracle) estimateL1Component(ctx context.Context, to common.Address, data []byte) (gasForL1 uint64, baseFee *big.Int, err error) {
+	out, err := call(ctx, o.caller, NodeInterfaceAddress, nodeInterfaceABI, "gasEstimateL1Component", to, false, data)
+	if err != nil {
+		return 0, nil, err
+	}
+	gasForL1 = *abi.ConvertType(out[0], new(uint64)).(*uint64)
+	baseFee = abi.ConvertType(out[1], new(big.Int)).(*big.Int)
+	return gasForL1, baseFee, nil
+}
+
+// call calls the method of the contract at the latest block and returns its
+// outputs.
+func call(ctx context.Context, caller ethereum.ContractCaller, address common.Address, contractABI abi.ABI, method string, args ...any) ([]any, error) {
+	input, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	output, err := caller.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, nil)
+	if err != nil {
+		return nil, errs.New("failed to call %s on %s: %v", method, address, err)
+	}
+	out, err := contractABI.Unpack(method, output)
+	if err != nil {
+		return nil, errs.New("failed to unpack %s from %s: %v", method, address, err)
+	}
+	return out, nil
+}
+
+func mustParseABI(s string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -0,0 +1,163 @@
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zeebo/errs"
+
+	batchpayment "storj.io/crypto-batch-payment/pkg"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payer"
+)
+
+var (
+	_ payer.Payer           = &Payer{}
+	_ payer.Replacer        = &Payer{}
+	_ payer.FinalityChecker = &Payer{}
+	_ payer.Reporter        = &Payer{}
+)
+
+// Payer pays out on a rollup. Transactions are created, sent and tracked
+// like on Ethereum, but the L1 data fee of each is accounted for when
+// checking the preconditions and estimating the cost of the payout.
+type Payer struct {
+	*eth.Payer
+	oracle  L1FeeOracle
+	chainID *big.Int
+	maxGas  *big.Int
+}
+
+// NewPayer returns a payer for the rollup with the given chain ID, whose L1
+// data fees are estimated by the oracle.
+func NewPayer(ctx context.Context,
+	client eth.Client,
+	oracle L1FeeOracle,
+	chainID *big.Int,
+	contractAddress common.Address,
+	owner common.Address,
+	signer eth.Signer,
+	fees eth.FeeStrategy,
+	maxGas *big.Int,
+	disperseAddress *common.Address,
+	confirmations uint64) (*Payer, error) {
+
+	ethPayer, err := eth.NewPayer(ctx,
+		client,
+		contractAddress,
+		owner,
+		signer,
+		fees,
+		maxGas,
+		disperseAddress,
+		confirmations,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payer{
+		Payer:   ethPayer.WithExtraGas(oracle.ExtraGas),
+		oracle:  oracle,
+		chainID: chainID,
+		maxGas:  maxGas,
+	}, nil
+}
+
+func (p *Payer) String() string {
+	return payer.Rollup.String()
+}
+
+func (p *Payer) CheckPreconditions(ctx context.Context) (unmet []string, err error) {
+	unmet, err = p.Payer.CheckPreconditions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The spender pays the L1 data fee on top of the gas, so it must at
+	// least afford a transaction paying a single payout.
+	tx, err := p.sampleTransaction(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	l1Fee, err := p.oracle.L1Fee(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	gasFee := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), p.maxGas)
+	balance, err := p.NativeBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(new(big.Int).Add(gasFee, l1Fee)) < 0 {
+		unmet = append(unmet, fmt.Sprintf(
+			"the spender balance (%s) does not cover the gas of a transaction at the max allowed gas price (%s) plus its L1 data fee (%s)",
+			batchpayment.PrettyETH(balance), batchpayment.PrettyETH(gasFee), batchpayment.PrettyETH(l1Fee)))
+	}
+
+	return unmet, nil
+}
+
+func (p *Payer) PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error {
+	if err := p.Payer.PrintEstimate(ctx, remainingGroups, remainingPayouts); err != nil {
+		return err
+	}
+	if remainingGroups == 0 {
+		return nil
+	}
+
+	// Rough estimate, assuming evenly sized payout groups.
+	tx, err := p.sampleTransaction(ctx, int(remainingPayouts/remainingGroups))
+	if err != nil {
+		return err
+	}
+	l1FeePerTx, err := p.oracle.L1Fee(ctx, tx)
+	if err != nil {
+		return err
+	}
+	l1Fee := new(big.Int).Mul(l1FeePerTx, big.NewInt(remainingGroups))
+
+	fmt.Printf("Estimated L1 Data Fee Per Tx: %s\n", batchpayment.PrettyETH(l1FeePerTx))
+	fmt.Printf("Remaining L1 Data Fee.......: %s\n", batchpayment.PrettyETH(l1Fee))
+	return nil
+}
+
+// sampleTransaction returns an unsigned transaction paying out a payout group
+// of the given size to made up payees, for estimating the L1 data fee. The
+// fee depends on how well the transaction compresses, so the payees and
+// amounts are made up of random looking bytes.
+func (p *Payer) sampleTransaction(ctx context.Context, size int) (*types.Transaction, error) {
+	if size < 1 || p.DisperseAddress() == nil {
+		size = 1
+	}
+	transfers := make([]eth.Transfer, 0, size)
+	for i := 0; i < size; i++ {
+		seed := crypto.Keccak256(big.NewInt(int64(i)).Bytes())
+		transfers = append(transfers, eth.Transfer{
+			Payee:  common.BytesToAddress(seed),
+			Tokens: new(big.Int).SetBytes(seed[:9]),
+		})
+	}
+
+	to, data, gas, err := eth.TransferCall(p.Owner(), p.From(), p.TokenAddress(), p.DisperseAddress(), transfers)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := p.NextNonce(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   p.chainID,
+		Nonce:     nonce,
+		GasTipCap: p.maxGas,
+		GasFeeCap: p.maxGas,
+		Gas:       gas,
+		To:        &to,
+		Data:      data,
+	}), nil
+}
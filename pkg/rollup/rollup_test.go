@@ -0,0 +1,187 @@
+package rollup_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/ethtest"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/rollup"
+)
+
+var (
+	ctx = context.Background()
+
+	owner = ethtest.NewAccount()
+	alice = ethtest.NewAccount()
+)
+
+func TestOPStackOracle(t *testing.T) {
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID: big.NewInt(10),
+		Nonce:   1,
+		Gas:     contract.TokenTransferGasLimit,
+		To:      &alice.Address,
+		Data:    []byte{1, 2, 3},
+	})
+	unsigned, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	caller := &fakeCaller{
+		t:      t,
+		to:     rollup.GasPriceOracleAddress,
+		abi:    `[{"inputs":[{"name":"_data","type":"bytes"}],"name":"getL1Fee","outputs":[{"name":"","type":"uint256"}],"type":"function"}]`,
+		method: "getL1Fee",
+		args:   []any{unsigned},
+		result: []any{big.NewInt(1234)},
+	}
+	oracle, err := rollup.NewL1FeeOracle(rollup.OPStack, caller)
+	require.NoError(t, err)
+
+	l1Fee, err := oracle.L1Fee(ctx, tx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1234), l1Fee)
+
+	// The fee is charged on top of the gas.
+	extraGas, err := oracle.ExtraGas(ctx, alice.Address, []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.Zero(t, extraGas)
+}
+
+func TestArbitrumOracle(t *testing.T) {
+	caller := &fakeCaller{
+		t:      t,
+		to:     rollup.NodeInterfaceAddress,
+		abi:    `[{"inputs":[{"name":"to","type":"address"},{"name":"contractCreation","type":"bool"},{"name":"data","type":"bytes"}],"name":"gasEstimateL1Component","outputs":[{"name":"gasEstimateForL1","type":"uint64"},{"name":"baseFee","type":"uint256"},{"name":"l1BaseFeeEstimate","type":"uint256"}],"type":"function"}]`,
+		method: "gasEstimateL1Component",
+		args:   []any{alice.Address, false, []byte{1, 2, 3}},
+		result: []any{uint64(1000), big.NewInt(100), big.NewInt(5)},
+	}
+	oracle, err := rollup.NewL1FeeOracle(rollup.Arbitrum, caller)
+	require.NoError(t, err)
+
+	l1Fee, err := oracle.L1Fee(ctx, types.NewTx(&types.DynamicFeeTx{
+		To:   &alice.Address,
+		Data: []byte{1, 2, 3},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100000), l1Fee)
+
+	// The fee is charged as gas, with some room for the L1 base fee to rise.
+	extraGas, err := oracle.ExtraGas(ctx, alice.Address, []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1250), extraGas)
+}
+
+func TestStackFromString(t *testing.T) {
+	for s, expected := range map[string]rollup.Stack{
+		"op-stack": rollup.OPStack,
+		"Optimism": rollup.OPStack,
+		"base":     rollup.OPStack,
+		"arbitrum": rollup.Arbitrum,
+	} {
+		stack, err := rollup.StackFromString(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, stack, s)
+	}
+	_, err := rollup.StackFromString("zksync")
+	require.Error(t, err)
+}
+
+func TestPayer(t *testing.T) {
+	balance := big.NewInt(params.Ether)
+	alloc := core.DefaultGenesisBlock().Alloc
+	alloc[owner.Address] = types.Account{Balance: balance}
+	backend := simulated.NewBackend(alloc, simulated.WithMinerMinTip(big.NewInt(1)))
+	t.Cleanup(func() { _ = backend.Close() })
+	client := backend.Client()
+
+	chainID := big.NewInt(1337)
+	auth, err := bind.NewKeyedTransactorWithChainID(owner.Key, chainID)
+	require.NoError(t, err)
+	tokenAddress, _, _, err := contract.DeployToken(auth, client, owner.Address, "Storj", "STORJ", big.NewInt(1000000000000), big.NewInt(8))
+	require.NoError(t, err)
+	backend.Commit()
+
+	oracle := &fakeOracle{l1Fee: big.NewInt(params.GWei), extraGas: 5000}
+	maxGas := big.NewInt(100 * params.GWei)
+	p, err := rollup.NewPayer(ctx, client, oracle, chainID, tokenAddress, owner.Address,
+		eth.NewKeySigner(owner.Key, chainID), eth.NewFixedFees(big.NewInt(params.GWei)), maxGas, nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, "rollup", p.String())
+
+	unmet, err := p.CheckPreconditions(ctx)
+	require.NoError(t, err)
+	require.Empty(t, unmet)
+
+	// Transactions carry the extra gas the oracle asks for.
+	tx, _, err := p.CreateRawTransaction(ctx, zaptest.NewLogger(t), []*pipelinedb.Payout{
+		{Payee: alice.Address, USD: decimal.RequireFromString("1"), PayoutGroupID: 1},
+	}, 1, decimal.RequireFromString("1"))
+	require.NoError(t, err)
+	rawTx := tx.Raw.(*types.Transaction)
+	require.Equal(t, uint64(contract.TokenTransferGasLimit+5000), rawTx.Gas())
+	require.Equal(t, tokenAddress, oracle.to)
+
+	// The spender cannot afford a transaction once the L1 data fee exceeds
+	// its balance.
+	oracle.l1Fee = balance
+	unmet, err = p.CheckPreconditions(ctx)
+	require.NoError(t, err)
+	require.Len(t, unmet, 1)
+	require.Contains(t, unmet[0], "L1 data fee")
+}
+
+// fakeCaller expects a single call of a contract method and returns the
+// result.
+type fakeCaller struct {
+	t      *testing.T
+	to     common.Address
+	abi    string
+	method string
+	args   []any
+	result []any
+}
+
+func (c *fakeCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(c.abi))
+	require.NoError(c.t, err)
+	require.Equal(c.t, c.to, *call.To)
+
+	input, err := parsed.Pack(c.method, c.args...)
+	require.NoError(c.t, err)
+	require.Equal(c.t, input, call.Data)
+
+	return parsed.Methods[c.method].Outputs.Pack(c.result...)
+}
+
+type fakeOracle struct {
+	l1Fee    *big.Int
+	extraGas uint64
+	to       common.Address
+}
+
+func (o *fakeOracle) L1Fee(ctx context.Context, tx *types.Transaction) (*big.Int, error) {
+	return o.l1Fee, nil
+}
+
+func (o *fakeOracle) ExtraGas(ctx context.Context, to common.Address, data []byte) (uint64, error) {
+	o.to = to
+	return o.extraGas, nil
+}
@@ -0,0 +1,99 @@
+package safe
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/zeebo/errs"
+
+	batchpayment "storj.io/crypto-batch-payment/pkg"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+var (
+	_ payer.Auditor         = &Auditor{}
+	_ payer.FinalityChecker = &Auditor{}
+)
+
+// Auditor audits payout groups paid out from a Safe. The transactions in
+// the database are identified by the hash of their transfers, so the
+// auditor checks the Safe transaction that executed them instead, as
+// recorded in their receipt.
+type Auditor struct {
+	client *ethclient.Client
+	db     *pipelinedb.DB
+}
+
+func NewAuditor(nodeAddress string, db *pipelinedb.DB) (*Auditor, error) {
+	client, err := ethclient.Dial(nodeAddress)
+	if err != nil {
+		return nil, errs.New("Failed to dial node %q: %v\n", nodeAddress, err)
+	}
+
+	return &Auditor{
+		client: client,
+		db:     db,
+	}, nil
+}
+
+func (a *Auditor) CheckTransactionState(ctx context.Context, hash string) (pipelinedb.TxState, error) {
+	execution, err := a.execution(ctx, hash)
+	if err != nil {
+		return pipelinedb.TxFailed, err
+	}
+	if execution == nil {
+		return pipelinedb.TxPending, nil
+	}
+	state, _, _, err := eth.GetTransactionInfo(ctx, a.client, *execution)
+	return state, err
+}
+
+func (a *Auditor) CheckConfirmedTransactionState(ctx context.Context, hash string) (pipelinedb.TxState, error) {
+	execution, err := a.execution(ctx, hash)
+	if err != nil {
+		return pipelinedb.TxFailed, err
+	}
+	if execution == nil {
+		return pipelinedb.TxPending, nil
+	}
+	receipt, err := a.client.TransactionReceipt(ctx, *execution)
+	if err != nil {
+		return pipelinedb.TxFailed, err
+	}
+	return eth.TxStateFromReceipt(receipt), nil
+}
+
+func (a *Auditor) IsCanonical(ctx context.Context, hash string, blockHash common.Hash) (bool, error) {
+	execution, err := a.execution(ctx, hash)
+	if err != nil {
+		return false, err
+	}
+	if execution == nil {
+		return false, errs.New("transfers %s have not been executed", hash)
+	}
+	return eth.IsCanonical(ctx, a.client, *execution, blockHash)
+}
+
+// execution returns the hash of the Safe transaction that executed the
+// transfers, or nil if they have not been executed yet.
+func (a *Auditor) execution(ctx context.Context, hash string) (*common.Hash, error) {
+	txHash, err := batchpayment.HashFromString(hash)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := a.db.FetchTransaction(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Receipt == nil {
+		return nil, nil
+	}
+	return &tx.Receipt.TxHash, nil
+}
+
+func (a *Auditor) Close() {
+	a.client.Close()
+}
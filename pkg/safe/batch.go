@@ -0,0 +1,103 @@
+package safe
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zeebo/errs"
+)
+
+const (
+	batchVersion     = "1.0"
+	txBuilderVersion = "1.16.5"
+)
+
+// Batch is a batch of transactions in the JSON format the Safe Transaction
+// Builder imports.
+type Batch struct {
+	Version      string             `json:"version"`
+	ChainID      string             `json:"chainId"`
+	CreatedAt    int64              `json:"createdAt"`
+	Meta         BatchMeta          `json:"meta"`
+	Transactions []BatchTransaction `json:"transactions"`
+}
+
+// BatchMeta describes a batch.
+type BatchMeta struct {
+	Name                   string `json:"name"`
+	Description            string `json:"description"`
+	TxBuilderVersion       string `json:"txBuilderVersion"`
+	CreatedFromSafeAddress string `json:"createdFromSafeAddress"`
+}
+
+// BatchTransaction is a contract call in a batch. The Transaction Builder
+// encodes the call from the method and the input values.
+type BatchTransaction struct {
+	To                   common.Address    `json:"to"`
+	Value                string            `json:"value"`
+	Data                 *string           `json:"data"`
+	ContractMethod       ContractMethod    `json:"contractMethod"`
+	ContractInputsValues map[string]string `json:"contractInputsValues"`
+}
+
+// ContractMethod is the ABI of the method called by a batch transaction.
+type ContractMethod struct {
+	Inputs  []ContractMethodInput `json:"inputs"`
+	Name    string                `json:"name"`
+	Payable bool                  `json:"payable"`
+}
+
+// ContractMethodInput is an input of a contract method.
+type ContractMethodInput struct {
+	InternalType string `json:"internalType"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+}
+
+// transferMethod is the ERC-20 transfer method.
+var transferMethod = ContractMethod{
+	Inputs: []ContractMethodInput{
+		{InternalType: "address", Name: "to", Type: "address"},
+		{InternalType: "uint256", Name: "value", Type: "uint256"},
+	},
+	Name: "transfer",
+}
+
+// transferTransaction returns a batch transaction transferring the tokens
+// to the payee.
+func transferTransaction(token, payee common.Address, tokens *big.Int) BatchTransaction {
+	return BatchTransaction{
+		To:             token,
+		Value:          "0",
+		ContractMethod: transferMethod,
+		ContractInputsValues: map[string]string{
+			"to":    payee.String(),
+			"value": tokens.String(),
+		},
+	}
+}
+
+// writeBatch writes the batch to the path, replacing the file atomically
+// so that a partially written batch is never imported.
+func writeBatch(path string, batch *Batch) error {
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".batch-*.json")
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return errs.Wrap(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Wrap(err)
+	}
+	return errs.Wrap(os.Rename(tmp.Name(), path))
+}
@@ -0,0 +1,3 @@
+// Package safe implements payouts from a Safe multisig by exporting the
+// transfers as Safe Transaction Builder batches instead of signing them.
+package safe
@@ -0,0 +1,513 @@
+package safe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/eth"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/storjtoken"
+)
+
+var (
+	_ payer.Payer    = &Payer{}
+	_ payer.DBBinder = &Payer{}
+)
+
+// logPageBlocks is how many blocks each request for Transfer events covers,
+// since nodes refuse or time out on requests for too many blocks at once.
+const logPageBlocks = 2000
+
+// Client is the node API used by the payer.
+type Client interface {
+	bind.ContractCaller
+	bind.ContractFilterer
+	ethereum.ChainReader
+	ethereum.TransactionReader
+}
+
+// Transfers are the transfers paying out a payout group from the Safe. They
+// are recorded as the raw transaction of the payout group.
+type Transfers struct {
+	Safe          common.Address `json:"safe"`
+	PayoutGroupID int64          `json:"payoutGroupId"`
+	Nonce         uint64         `json:"nonce"`
+	FromBlock     uint64         `json:"fromBlock"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	Transfers     []Transfer     `json:"transfers"`
+}
+
+// Transfer is a transfer of tokens from the Safe to a payee.
+type Transfer struct {
+	Payee  common.Address `json:"payee"`
+	Tokens *big.Int       `json:"tokens"`
+}
+
+// Payer pays out from a Safe multisig. Instead of signing transactions, it
+// writes the transfers of the payout groups to Safe Transaction Builder
+// batch files, to be imported, signed and executed by the owners of the
+// Safe. A payout group is confirmed once a single Safe transaction executed
+// after the payout group was exported emits a Transfer event from the Safe
+// to each of its payees for the exact amount. The events that confirmed a
+// payout group are claimed in the payout database, so that they cannot
+// confirm another one, even after a restart.
+type Payer struct {
+	client        Client
+	token         *contract.TokenCaller
+	filterer      *contract.TokenFilterer
+	tokenAddress  common.Address
+	tokenSymbol   string
+	safe          common.Address
+	chainID       *big.Int
+	batchDir      string
+	tokenDecimals int32
+	confirmations uint64
+
+	// batch is the batch the transfers are written to until the pipeline
+	// is done filling up.
+	batch       *Batch
+	batchPath   string
+	batchGroups int
+
+	db *pipelinedb.DB
+
+	// scans are the Transfer events found so far for the pending payout
+	// groups, by the hash of their transfers.
+	scans map[string]*transferScan
+}
+
+// transferScan is the progress of looking for the Transfer events of a
+// payout group.
+type transferScan struct {
+	// next is the first block not scanned yet.
+	next uint64
+
+	// logs are the Transfer events found so far that could have executed
+	// each of the transfers.
+	logs [][]types.Log
+}
+
+// NewPayer returns a payer that exports the transfers from the Safe to
+// batch files in batchDir.
+func NewPayer(ctx context.Context,
+	client Client,
+	tokenAddress common.Address,
+	tokenSymbol string,
+	safeAddress common.Address,
+	chainID *big.Int,
+	batchDir string,
+	confirmations uint64) (*Payer, error) {
+
+	if confirmations == 0 {
+		confirmations = eth.DefaultConfirmations
+	}
+
+	if err := os.MkdirAll(batchDir, 0755); err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	token, err := contract.NewTokenCaller(tokenAddress, client)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	filterer, err := contract.NewTokenFilterer(tokenAddress, client)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	decimals, err := token.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	return &Payer{
+		client:        client,
+		token:         token,
+		filterer:      filterer,
+		tokenAddress:  tokenAddress,
+		tokenSymbol:   tokenSymbol,
+		safe:          safeAddress,
+		chainID:       chainID,
+		batchDir:      batchDir,
+		tokenDecimals: int32(decimals.Int64()),
+		confirmations: confirmations,
+		scans:         make(map[string]*transferScan),
+	}, nil
+}
+
+func (p *Payer) String() string {
+	return payer.Safe.String()
+}
+
+// BindDB binds the payer to the payout database, which keeps the nonces of
+// the exported payout groups and the Transfer events that confirmed them.
+func (p *Payer) BindDB(db *pipelinedb.DB) {
+	p.db = db
+}
+
+// NextNonce returns the nonce following the last payout group exported from
+// the Safe. The nonces of payout groups are not Safe nonces; they only need
+// to be unique, so they are counted up from the payout database.
+func (p *Payer) NextNonce(ctx context.Context) (uint64, error) {
+	if p.db == nil {
+		return 0, errs.New("safe payer is not bound to a payout database")
+	}
+	return p.db.FetchNextNonce(ctx, p.safe)
+}
+
+func (p *Payer) CheckPreconditions(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (p *Payer) GetTokenBalance(ctx context.Context) (*big.Int, error) {
+	balance, err := p.token.BalanceOf(&bind.CallOpts{Context: ctx}, p.safe)
+	return balance, errs.Wrap(err)
+}
+
+// From returns the address of the Safe, which the transfers are sent from.
+func (p *Payer) From() common.Address {
+	return p.safe
+}
+
+func (p *Payer) GetTokenDecimals(ctx context.Context) (int32, error) {
+	return p.tokenDecimals, nil
+}
+
+func (p *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, price decimal.Decimal) (_ payer.Transaction, _ common.Address, err error) {
+	if len(payouts) == 0 {
+		return payer.Transaction{}, common.Address{}, errs.New("no payouts")
+	}
+
+	// The transfers are executed some time after they are exported, so
+	// the Transfer events are looked for from the current block on.
+	head, err := p.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+
+	transfers := &Transfers{
+		Safe:          p.safe,
+		PayoutGroupID: payouts[0].PayoutGroupID,
+		Nonce:         nonce,
+		FromBlock:     head.Number.Uint64(),
+		CreatedAt:     time.Now().UTC(),
+	}
+	for _, payout := range payouts {
+		transfers.Transfers = append(transfers.Transfers, Transfer{
+			Payee:  payout.Payee,
+			Tokens: storjtoken.FromUSD(payout.USD, price, p.tokenDecimals),
+		})
+	}
+
+	// There is no transaction hash until the Safe executes the transfers,
+	// so the payout group is identified by the hash of its transfers.
+	data, err := json.Marshal(transfers)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	}
+	hash := crypto.Keccak256Hash(data)
+
+	log.Info("Transfers are created",
+		zap.Int("payees", len(transfers.Transfers)),
+		zap.String("hash", hash.String()),
+	)
+	return payer.Transaction{
+		Hash:  hash.String(),
+		Nonce: nonce,
+		Raw:   transfers,
+	}, p.safe, nil
+}
+
+// SendTransaction adds the transfers to the current batch file. The batch
+// is rewritten with every payout group and closed once the pipeline is done
+// filling up, so the next payout groups go to a new batch file.
+func (p *Payer) SendTransaction(ctx context.Context, log *zap.Logger, tx payer.Transaction) error {
+	transfers, ok := tx.Raw.(*Transfers)
+	if !ok {
+		return errs.New("payer doesn't support transaction %v", tx.Raw)
+	}
+
+	if p.batch == nil {
+		now := time.Now().UTC()
+		p.batch = &Batch{
+			Version:   batchVersion,
+			ChainID:   p.chainID.String(),
+			CreatedAt: now.UnixMilli(),
+			Meta: BatchMeta{
+				Name:                   fmt.Sprintf("%s payouts from nonce %d", p.tokenSymbol, transfers.Nonce),
+				TxBuilderVersion:       txBuilderVersion,
+				CreatedFromSafeAddress: p.safe.String(),
+			},
+		}
+		p.batchPath = filepath.Join(p.batchDir, fmt.Sprintf("batch-%s-%d.json", now.Format("20060102T150405Z"), transfers.Nonce))
+		p.batchGroups = 0
+	}
+
+	for _, transfer := range transfers.Transfers {
+		p.batch.Transactions = append(p.batch.Transactions, transferTransaction(p.tokenAddress, transfer.Payee, transfer.Tokens))
+	}
+	p.batchGroups++
+	p.batch.Meta.Description = fmt.Sprintf("%d payout groups, %d transfers", p.batchGroups, len(p.batch.Transactions))
+
+	if err := writeBatch(p.batchPath, p.batch); err != nil {
+		return err
+	}
+	log.Info("Transfers added to batch", zap.String("batch", p.batchPath))
+	return nil
+}
+
+// CheckNonceGroup looks for the Transfer events of the payout group. The
+// payout group stays pending until the Safe has executed all of its
+// transfers, since the owners of the Safe may take a while to sign them.
+func (p *Payer) CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceGroup *pipelinedb.NonceGroup, checkOnly bool) (pipelinedb.TxState, []*pipelinedb.TxStatus, error) {
+	// The pipeline checks the nonce groups once it is done filling up.
+	if p.batch != nil {
+		log.Info("Batch ready to be imported into the Safe Transaction Builder",
+			zap.String("batch", p.batchPath),
+			zap.Int("transfers", len(p.batch.Transactions)))
+		p.batch = nil
+	}
+
+	if len(nonceGroup.Txs) != 1 {
+		return "", nil, errs.New("nonce group should have only 1 transaction, not %d", len(nonceGroup.Txs))
+	}
+	tx := nonceGroup.Txs[0]
+
+	var transfers Transfers
+	if err := json.Unmarshal(tx.Raw, &transfers); err != nil {
+		return "", nil, errs.New("unable to decode transfers %s: %v", tx.Hash, err)
+	}
+
+	status := &pipelinedb.TxStatus{
+		Hash:  tx.Hash,
+		State: pipelinedb.TxPending,
+	}
+
+	logs, err := p.findTransfers(ctx, tx.Hash, &transfers)
+	if err != nil {
+		return "", nil, err
+	}
+	if logs == nil {
+		log.Debug("Waiting for the Safe to execute the transfers", zap.String("hash", tx.Hash))
+		return pipelinedb.TxPending, []*pipelinedb.TxStatus{status}, nil
+	}
+
+	// Wait until the execution transaction is buried deep enough.
+	execution := logs[0]
+	head, err := p.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return "", nil, errs.Wrap(err)
+	}
+	if depth := head.Number.Uint64() - execution.BlockNumber + 1; depth < p.confirmations {
+		log.Debug("Waiting for confirmations",
+			zap.String("hash", tx.Hash),
+			zap.Uint64("depth", depth),
+			zap.Uint64("confirmations", p.confirmations))
+		return pipelinedb.TxPending, []*pipelinedb.TxStatus{status}, nil
+	}
+
+	receipt, err := p.client.TransactionReceipt(ctx, execution.TxHash)
+	if err != nil {
+		return "", nil, errs.Wrap(err)
+	}
+	if err := p.claimLogs(ctx, tx.Hash, logs); err != nil {
+		return "", nil, err
+	}
+	delete(p.scans, tx.Hash)
+
+	status.State = pipelinedb.TxConfirmed
+	status.Receipt = receipt
+	log.Info("Transfers executed by the Safe",
+		zap.String("hash", tx.Hash),
+		zap.String("execution", receipt.TxHash.String()))
+	return pipelinedb.TxConfirmed, []*pipelinedb.TxStatus{status}, nil
+}
+
+// findTransfers returns a Transfer event for each of the transfers, all
+// emitted by the same Safe transaction, or nil if the Safe has not executed
+// the transfers yet.
+func (p *Payer) findTransfers(ctx context.Context, hash string, transfers *Transfers) ([]types.Log, error) {
+	if len(transfers.Transfers) == 0 {
+		return nil, nil
+	}
+	if p.db == nil {
+		return nil, errs.New("safe payer is not bound to a payout database")
+	}
+
+	scanned, err := p.scanTransfers(ctx, hash, transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([][]types.Log, 0, len(transfers.Transfers))
+	for _, logs := range scanned {
+		logs, err := p.unclaimedLogs(ctx, hash, logs)
+		if err != nil {
+			return nil, err
+		}
+		if len(logs) == 0 {
+			return nil, nil
+		}
+		candidates = append(candidates, logs)
+	}
+
+	// Try the Safe transactions that could have executed the first
+	// transfer, in the order they were executed.
+	for _, first := range candidates[0] {
+		if logs := matchExecution(first.TxHash, candidates); logs != nil {
+			return logs, nil
+		}
+	}
+	return nil, nil
+}
+
+// scanTransfers returns the Transfer events from the Safe that could have
+// executed each of the transfers. The blocks are scanned in pages, picking up
+// after the last block scanned for the payout group. Blocks too shallow to be
+// final are scanned again the next time.
+func (p *Payer) scanTransfers(ctx context.Context, hash string, transfers *Transfers) ([][]types.Log, error) {
+	scan, ok := p.scans[hash]
+	if !ok {
+		scan = &transferScan{
+			next: transfers.FromBlock,
+			logs: make([][]types.Log, len(transfers.Transfers)),
+		}
+		p.scans[hash] = scan
+	}
+
+	head, err := p.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	last := head.Number.Uint64()
+	var final uint64
+	if last+1 >= p.confirmations {
+		final = last + 1 - p.confirmations
+	}
+
+	logs := make([][]types.Log, len(transfers.Transfers))
+	copy(logs, scan.logs)
+	for start := scan.next; start <= last; start += logPageBlocks {
+		end := min(start+logPageBlocks-1, last)
+		for i, transfer := range transfers.Transfers {
+			page, err := p.transferLogs(ctx, start, end, transfer)
+			if err != nil {
+				return nil, err
+			}
+			logs[i] = append(logs[i], page...)
+		}
+		if end <= final {
+			copy(scan.logs, logs)
+			scan.next = end + 1
+		}
+	}
+	return logs, nil
+}
+
+// matchExecution returns a distinct Transfer event emitted by the execution
+// transaction for each of the transfers, or nil if the execution transaction
+// did not execute all of them.
+func matchExecution(execution common.Hash, candidates [][]types.Log) []types.Log {
+	used := make(map[pipelinedb.LogID]bool)
+	logs := make([]types.Log, 0, len(candidates))
+	for _, transferLogs := range candidates {
+		var found *types.Log
+		for i := range transferLogs {
+			id := logID(&transferLogs[i])
+			if transferLogs[i].TxHash == execution && !used[id] {
+				found = &transferLogs[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		used[logID(found)] = true
+		logs = append(logs, *found)
+	}
+	return logs
+}
+
+// transferLogs returns the Transfer events from the Safe in the given
+// blocks that could have executed the transfer.
+func (p *Payer) transferLogs(ctx context.Context, start, end uint64, transfer Transfer) ([]types.Log, error) {
+	iter, err := p.filterer.FilterTransfer(&bind.FilterOpts{Start: start, End: &end, Context: ctx}, []common.Address{p.safe}, []common.Address{transfer.Payee})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer func() { _ = iter.Close() }()
+
+	var logs []types.Log
+	for iter.Next() {
+		event := iter.Event
+		if event.Raw.Removed || event.Value.Cmp(transfer.Tokens) != 0 {
+			continue
+		}
+		logs = append(logs, event.Raw)
+	}
+	return logs, errs.Wrap(iter.Error())
+}
+
+// unclaimedLogs returns the Transfer events not claimed by other payout
+// groups than the one with the given hash.
+func (p *Payer) unclaimedLogs(ctx context.Context, hash string, logs []types.Log) ([]types.Log, error) {
+	var unclaimed []types.Log
+	for i := range logs {
+		claimedBy, err := p.db.FetchLogClaim(ctx, logID(&logs[i]))
+		if err != nil {
+			return nil, err
+		}
+		if claimedBy != "" && claimedBy != hash {
+			continue
+		}
+		unclaimed = append(unclaimed, logs[i])
+	}
+	return unclaimed, nil
+}
+
+// claimLogs claims the Transfer events that confirmed the payout group with
+// the given hash, unless they were claimed by it before.
+func (p *Payer) claimLogs(ctx context.Context, hash string, logs []types.Log) error {
+	var unclaimed []pipelinedb.LogID
+	for i := range logs {
+		id := logID(&logs[i])
+		claimedBy, err := p.db.FetchLogClaim(ctx, id)
+		if err != nil {
+			return err
+		}
+		if claimedBy == "" {
+			unclaimed = append(unclaimed, id)
+		}
+	}
+	if len(unclaimed) == 0 {
+		return nil
+	}
+	return p.db.ClaimLogs(ctx, hash, unclaimed)
+}
+
+func logID(log *types.Log) pipelinedb.LogID {
+	return pipelinedb.LogID{TxHash: log.TxHash, Index: log.Index}
+}
+
+func (p *Payer) PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error {
+	fmt.Printf("Safe........................: %s\n", p.safe)
+	fmt.Printf("Batch Directory.............: %s\n", p.batchDir)
+	fmt.Println("Transfers are exported to Safe Transaction Builder batches. The Safe pays for the gas when they are executed.")
+	return nil
+}
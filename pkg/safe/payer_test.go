@@ -0,0 +1,294 @@
+package safe_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/ethtest"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+	"storj.io/crypto-batch-payment/pkg/safe"
+)
+
+var (
+	deployer = ethtest.NewAccount()
+	// The Safe is played by an externally owned account, since only its
+	// Transfer events matter to the payer.
+	treasury = ethtest.NewAccount()
+	alice    = ethtest.NewAccount()
+	bob      = ethtest.NewAccount()
+)
+
+func TestPayer(t *testing.T) {
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+
+	balance, _ := new(big.Int).SetString("900000000000000000", 10)
+	alloc := core.DefaultGenesisBlock().Alloc
+	alloc[deployer.Address] = types.Account{Balance: balance}
+	alloc[treasury.Address] = types.Account{Balance: balance}
+
+	backend := simulated.NewBackend(alloc, simulated.WithMinerMinTip(big.NewInt(1)))
+	t.Cleanup(func() { _ = backend.Close() })
+	client := backend.Client()
+
+	chainID := big.NewInt(1337)
+	auth, err := bind.NewKeyedTransactorWithChainID(deployer.Key, chainID)
+	require.NoError(t, err)
+	tokenAddress, _, token, err := contract.DeployToken(auth, client, treasury.Address, "Storj", "STORJ", big.NewInt(1000000000000), big.NewInt(8))
+	require.NoError(t, err)
+	backend.Commit()
+
+	batchDir := filepath.Join(t.TempDir(), "batches")
+	p, err := safe.NewPayer(ctx, client, tokenAddress, "STORJ", treasury.Address, chainID, batchDir, 1)
+	require.NoError(t, err)
+
+	// The payer needs the payout database to count up nonces.
+	_, err = p.NextNonce(ctx)
+	require.Error(t, err)
+	db, err := pipelinedb.OpenInMemoryDB(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	p.BindDB(db)
+
+	nonce, err := p.NextNonce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), nonce)
+
+	// Export a payout group paying alice and bob at $1 per STORJ.
+	payouts := []*pipelinedb.Payout{
+		{Payee: alice.Address, USD: decimal.RequireFromString("1"), PayoutGroupID: 1},
+		{Payee: bob.Address, USD: decimal.RequireFromString("2"), PayoutGroupID: 1},
+	}
+	tx, from, err := p.CreateRawTransaction(ctx, log, payouts, 0, decimal.RequireFromString("1"))
+	require.NoError(t, err)
+	require.Equal(t, treasury.Address, from)
+	require.NoError(t, p.SendTransaction(ctx, log, tx))
+
+	entries, err := os.ReadDir(batchDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	data, err := os.ReadFile(filepath.Join(batchDir, entries[0].Name()))
+	require.NoError(t, err)
+
+	var batch safe.Batch
+	require.NoError(t, json.Unmarshal(data, &batch))
+	require.Equal(t, "1337", batch.ChainID)
+	require.Equal(t, treasury.Address.String(), batch.Meta.CreatedFromSafeAddress)
+	require.Equal(t, "STORJ payouts from nonce 0", batch.Meta.Name)
+	require.Len(t, batch.Transactions, 2)
+	for i, payee := range []common.Address{alice.Address, bob.Address} {
+		require.Equal(t, tokenAddress, batch.Transactions[i].To)
+		require.Equal(t, "transfer", batch.Transactions[i].ContractMethod.Name)
+		require.Equal(t, payee.String(), batch.Transactions[i].ContractInputsValues["to"])
+	}
+	require.Equal(t, "100000000", batch.Transactions[0].ContractInputsValues["value"])
+	require.Equal(t, "200000000", batch.Transactions[1].ContractInputsValues["value"])
+
+	raw, err := json.Marshal(tx.Raw)
+	require.NoError(t, err)
+	nonceGroup := &pipelinedb.NonceGroup{
+		Nonce:         0,
+		Spender:       treasury.Address,
+		PayoutGroupID: 1,
+		Txs:           []pipelinedb.Transaction{{Hash: tx.Hash, Raw: raw, State: pipelinedb.TxPending}},
+	}
+
+	checkState := func(expected pipelinedb.TxState) []*pipelinedb.TxStatus {
+		state, statuses, err := p.CheckNonceGroup(ctx, log, nonceGroup, false)
+		require.NoError(t, err)
+		require.Equal(t, expected, state)
+		require.Len(t, statuses, 1)
+		require.Equal(t, tx.Hash, statuses[0].Hash)
+		return statuses
+	}
+
+	// Nothing has been executed yet.
+	checkState(pipelinedb.TxPending)
+
+	// Paying only alice is not enough.
+	treasuryAuth, err := bind.NewKeyedTransactorWithChainID(treasury.Key, chainID)
+	require.NoError(t, err)
+	_, err = token.Transfer(treasuryAuth, alice.Address, big.NewInt(100000000))
+	require.NoError(t, err)
+	backend.Commit()
+	checkState(pipelinedb.TxPending)
+
+	// Paying bob the wrong amount is not enough either.
+	_, err = token.Transfer(treasuryAuth, bob.Address, big.NewInt(100000000))
+	require.NoError(t, err)
+	backend.Commit()
+	checkState(pipelinedb.TxPending)
+
+	// Paying bob the right amount in another Safe transaction than alice
+	// is not enough either, since the batch is executed at once.
+	_, err = token.Transfer(treasuryAuth, bob.Address, big.NewInt(200000000))
+	require.NoError(t, err)
+	backend.Commit()
+	checkState(pipelinedb.TxPending)
+
+	// Execute the batch as a single transaction, which the disperse
+	// contract stands in for.
+	disperseAddress, _, disperse, err := contract.DeployDisperse(auth, client)
+	require.NoError(t, err)
+	backend.Commit()
+	_, err = token.Approve(treasuryAuth, disperseAddress, big.NewInt(300000000))
+	require.NoError(t, err)
+	backend.Commit()
+	executed, err := disperse.DisperseTokenSimple(treasuryAuth, tokenAddress,
+		[]common.Address{alice.Address, bob.Address},
+		[]*big.Int{big.NewInt(100000000), big.NewInt(200000000)})
+	require.NoError(t, err)
+	backend.Commit()
+	statuses := checkState(pipelinedb.TxConfirmed)
+	require.NotNil(t, statuses[0].Receipt)
+	require.Equal(t, executed.Hash(), statuses[0].Receipt.TxHash)
+
+	// Checking again finds the same, already claimed, transfers.
+	statuses = checkState(pipelinedb.TxConfirmed)
+	require.Equal(t, executed.Hash(), statuses[0].Receipt.TxHash)
+
+	// Another payout group paying alice the same amount is not confirmed by
+	// the transfer that confirmed the first one.
+	tx2, _, err := p.CreateRawTransaction(ctx, log, payouts[:1], 1, decimal.RequireFromString("1"))
+	require.NoError(t, err)
+	require.NoError(t, p.SendTransaction(ctx, log, tx2))
+	raw2, err := json.Marshal(tx2.Raw)
+	require.NoError(t, err)
+
+	// Export the payout group before the execution so that the transfer
+	// to alice is in range.
+	var transfers safe.Transfers
+	require.NoError(t, json.Unmarshal(raw2, &transfers))
+	transfers.FromBlock = statuses[0].Receipt.BlockNumber.Uint64()
+	raw2, err = json.Marshal(&transfers)
+	require.NoError(t, err)
+
+	// The claims survive a restart of the payer.
+	restarted, err := safe.NewPayer(ctx, client, tokenAddress, "STORJ", treasury.Address, chainID, batchDir, 1)
+	require.NoError(t, err)
+	restarted.BindDB(db)
+	state, _, err := restarted.CheckNonceGroup(ctx, log, &pipelinedb.NonceGroup{
+		Nonce:         1,
+		Spender:       treasury.Address,
+		PayoutGroupID: 2,
+		Txs:           []pipelinedb.Transaction{{Hash: tx2.Hash, Raw: raw2, State: pipelinedb.TxPending}},
+	}, false)
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxPending, state)
+
+	// The second payout group went into a new batch.
+	entries, err = os.ReadDir(batchDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
+// filterClient records the log queries made through it.
+type filterClient struct {
+	simulated.Client
+	queries []ethereum.FilterQuery
+}
+
+func (c *filterClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	c.queries = append(c.queries, query)
+	return c.Client.FilterLogs(ctx, query)
+}
+
+func TestPayerPagesTransferLogs(t *testing.T) {
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+
+	balance, _ := new(big.Int).SetString("900000000000000000", 10)
+	alloc := core.DefaultGenesisBlock().Alloc
+	alloc[deployer.Address] = types.Account{Balance: balance}
+	alloc[treasury.Address] = types.Account{Balance: balance}
+
+	backend := simulated.NewBackend(alloc, simulated.WithMinerMinTip(big.NewInt(1)))
+	t.Cleanup(func() { _ = backend.Close() })
+	client := &filterClient{Client: backend.Client()}
+
+	chainID := big.NewInt(1337)
+	auth, err := bind.NewKeyedTransactorWithChainID(deployer.Key, chainID)
+	require.NoError(t, err)
+	tokenAddress, _, token, err := contract.DeployToken(auth, client, treasury.Address, "Storj", "STORJ", big.NewInt(1000000000000), big.NewInt(8))
+	require.NoError(t, err)
+	backend.Commit()
+
+	p, err := safe.NewPayer(ctx, client, tokenAddress, "STORJ", treasury.Address, chainID, filepath.Join(t.TempDir(), "batches"), 1)
+	require.NoError(t, err)
+	db, err := pipelinedb.OpenInMemoryDB(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	p.BindDB(db)
+
+	payouts := []*pipelinedb.Payout{
+		{Payee: alice.Address, USD: decimal.RequireFromString("1"), PayoutGroupID: 1},
+	}
+	tx, _, err := p.CreateRawTransaction(ctx, log, payouts, 0, decimal.RequireFromString("1"))
+	require.NoError(t, err)
+	require.NoError(t, p.SendTransaction(ctx, log, tx))
+	raw, err := json.Marshal(tx.Raw)
+	require.NoError(t, err)
+	var transfers safe.Transfers
+	require.NoError(t, json.Unmarshal(raw, &transfers))
+
+	nonceGroup := &pipelinedb.NonceGroup{
+		Nonce:         0,
+		Spender:       treasury.Address,
+		PayoutGroupID: 1,
+		Txs:           []pipelinedb.Transaction{{Hash: tx.Hash, Raw: raw, State: pipelinedb.TxPending}},
+	}
+	checkState := func(expected pipelinedb.TxState) {
+		state, _, err := p.CheckNonceGroup(ctx, log, nonceGroup, false)
+		require.NoError(t, err)
+		require.Equal(t, expected, state)
+	}
+	// requireScanned requires the queries since the last call to cover the
+	// blocks from start to the head in pages of at most 2000 blocks.
+	requireScanned := func(start uint64) {
+		head, err := client.BlockNumber(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, client.queries)
+		next := start
+		for _, query := range client.queries {
+			require.NotNil(t, query.ToBlock)
+			require.Equal(t, next, query.FromBlock.Uint64())
+			require.LessOrEqual(t, query.ToBlock.Uint64()-query.FromBlock.Uint64(), uint64(1999))
+			next = query.ToBlock.Uint64() + 1
+		}
+		require.Equal(t, head+1, next)
+		client.queries = nil
+	}
+
+	// Many blocks go by before the Safe executes the transfers.
+	for range 4500 {
+		backend.Commit()
+	}
+	checkState(pipelinedb.TxPending)
+	requireScanned(transfers.FromBlock)
+
+	// Only the blocks since the last check are scanned for the execution.
+	head, err := client.BlockNumber(ctx)
+	require.NoError(t, err)
+	treasuryAuth, err := bind.NewKeyedTransactorWithChainID(treasury.Key, chainID)
+	require.NoError(t, err)
+	_, err = token.Transfer(treasuryAuth, alice.Address, big.NewInt(100000000))
+	require.NoError(t, err)
+	backend.Commit()
+	checkState(pipelinedb.TxConfirmed)
+	requireScanned(head + 1)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/shopspring/decimal"
+	"github.com/zeebo/errs"
 )
 
 var (
@@ -17,21 +18,76 @@ var (
 	// DefaultChainID is the chain id for Mainnet:
 	// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-155.md#list-of-chain-ids
 	DefaultChainID = big.NewInt(0x1)
+
+	// PolygonContractAddress is the address of the STORJ token bridged to
+	// Polygon PoS by the official Polygon bridge.
+	PolygonContractAddress = common.HexToAddress("0xd72357dAcA2cF11A5F155b9FF7880E595A3F5792")
+
+	// PolygonChainID is the chain id for Polygon PoS.
+	PolygonChainID = big.NewInt(137)
+)
+
+// PolygonDecimals is the number of decimals of the STORJ token bridged to
+// Polygon PoS, unlike the 8 decimals of the token on Ethereum.
+const PolygonDecimals = 18
+
+// Rounding is how an amount that falls between two of the smallest units of
+// a token is rounded.
+type Rounding int
+
+const (
+	// RoundDown rounds toward zero, so that a payee is never paid more than
+	// they are owed. Payouts are always rounded down.
+	RoundDown Rounding = iota
+
+	// RoundUp rounds away from zero, so that an estimate of what payouts
+	// need never falls short.
+	RoundUp
 )
 
-// FromUSD converts from USD to STORJ WEI, at the given price (rounded down).
+// Convert converts from USD to the smallest unit of a token with the given
+// decimals, at the given price of the token in USD, rounded as given. Tokens
+// with any number of decimals are supported, including none at all.
+func Convert(usd, price decimal.Decimal, decimals int32, rounding Rounding) (*big.Int, error) {
+	if !price.IsPositive() {
+		return nil, errs.New("price must be more than zero; got %s", price)
+	}
+	if decimals < 0 {
+		return nil, errs.New("decimals must not be negative; got %d", decimals)
+	}
+
+	// Divide exactly so that the rounding asked for is the only one.
+	quo := new(big.Rat).Quo(usd.Shift(decimals).Rat(), price.Rat())
+	tokens, rem := new(big.Int).QuoRem(quo.Num(), quo.Denom(), new(big.Int))
+	switch rounding {
+	case RoundDown:
+	case RoundUp:
+		if rem.Sign() != 0 {
+			tokens.Add(tokens, big.NewInt(int64(rem.Sign())))
+		}
+	default:
+		return nil, errs.New("unknown rounding %d", rounding)
+	}
+	return tokens, nil
+}
+
+// FromUSD converts from USD to the smallest unit of a token with the given
+// decimals, at the given price, rounded down. It panics if the price is not
+// positive or the decimals are negative.
 func FromUSD(usd, price decimal.Decimal, decimals int32) *big.Int {
-	if decimals < 8 {
-		// this should never happen based on our knowledge. ETH STORJ token uses 8, Polygon uses 18
-		panic("Decimals (digits of token) is less then 8. We don't support such an ERC-20 token for safety reasons. Potential overpayment!!!")
+	tokens, err := Convert(usd, price, decimals, RoundDown)
+	if err != nil {
+		panic(err)
 	}
-	tokens, _ := usd.Shift(decimals).QuoRem(price, 0)
-	// The quotient should have an exponent of zero, since it has no fractional
-	// part, so returning the coefficient should represent the number of
-	// tokens.
-	return tokens.Coefficient()
+	return tokens
 }
 
 func Pretty(token *big.Int, digits int32) string {
-	return fmt.Sprintf("%s (%s STORJ)", token, decimal.NewFromBigInt(token, -digits).String())
+	return PrettySymbol(token, digits, "STORJ")
+}
+
+// PrettySymbol is like Pretty for an amount of the coin or token with the
+// given symbol.
+func PrettySymbol(token *big.Int, digits int32, symbol string) string {
+	return fmt.Sprintf("%s (%s %s)", token, decimal.NewFromBigInt(token, -digits).String(), symbol)
 }
@@ -0,0 +1,68 @@
+package storjtoken
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		usd      string
+		price    string
+		decimals int32
+		down     string
+		up       string
+	}{
+		{name: "exact", usd: "10", price: "0.5", decimals: 8, down: "2000000000", up: "2000000000"},
+		{name: "inexact", usd: "1", price: "0.3", decimals: 8, down: "333333333", up: "333333334"},
+		{name: "18 decimals", usd: "1", price: "3", decimals: 18, down: "333333333333333333", up: "333333333333333334"},
+		{name: "6 decimals", usd: "1.234567891", price: "1", decimals: 6, down: "1234567", up: "1234568"},
+		{name: "0 decimals", usd: "10", price: "3", decimals: 0, down: "3", up: "4"},
+		{name: "0 decimals below one", usd: "1", price: "3", decimals: 0, down: "0", up: "1"},
+		{name: "zero usd", usd: "0", price: "0.3", decimals: 8, down: "0", up: "0"},
+		{name: "negative usd", usd: "-1", price: "0.3", decimals: 8, down: "-333333333", up: "-333333334"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			usd := decimal.RequireFromString(tt.usd)
+			price := decimal.RequireFromString(tt.price)
+
+			down, err := Convert(usd, price, tt.decimals, RoundDown)
+			require.NoError(t, err)
+			assert.Equal(t, tt.down, down.String(), "rounded down")
+
+			up, err := Convert(usd, price, tt.decimals, RoundUp)
+			require.NoError(t, err)
+			assert.Equal(t, tt.up, up.String(), "rounded up")
+
+			assert.Equal(t, down, FromUSD(usd, price, tt.decimals))
+		})
+	}
+}
+
+func TestConvertInvalid(t *testing.T) {
+	one := decimal.NewFromInt(1)
+	for _, tt := range []struct {
+		name     string
+		price    decimal.Decimal
+		decimals int32
+		rounding Rounding
+		err      string
+	}{
+		{name: "zero price", price: decimal.Zero, decimals: 8, rounding: RoundDown, err: "price must be more than zero; got 0"},
+		{name: "negative price", price: one.Neg(), decimals: 8, rounding: RoundDown, err: "price must be more than zero; got -1"},
+		{name: "negative decimals", price: one, decimals: -1, rounding: RoundDown, err: "decimals must not be negative; got -1"},
+		{name: "unknown rounding", price: one, decimals: 8, rounding: Rounding(2), err: "unknown rounding 2"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Convert(one, tt.price, tt.decimals, tt.rounding)
+			require.EqualError(t, err, tt.err)
+			assert.Nil(t, tokens)
+		})
+	}
+
+	assert.Panics(t, func() { FromUSD(one, decimal.Zero, 8) })
+}
@@ -3,12 +3,11 @@ package zksyncera
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
-	"strings"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -18,12 +17,13 @@ import (
 	"github.com/zeebo/errs"
 	"github.com/zksync-sdk/zksync2-go/accounts"
 	"github.com/zksync-sdk/zksync2-go/clients"
-	"github.com/zksync-sdk/zksync2-go/contracts/erc20"
 	zktypes "github.com/zksync-sdk/zksync2-go/types"
 	"github.com/zksync-sdk/zksync2-go/utils"
 	"go.uber.org/zap"
 
+	batchpayment "storj.io/crypto-batch-payment/pkg"
 	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/eth"
 	"storj.io/crypto-batch-payment/pkg/payer"
 	"storj.io/crypto-batch-payment/pkg/pipelinedb"
 	"storj.io/crypto-batch-payment/pkg/storjtoken"
@@ -34,12 +34,16 @@ type Payer struct {
 	zk               clients.Client
 	signer           *accounts.BaseSigner
 	contractAddress  common.Address
-	erc20abi         abi.ABI
 	decimals         int32
 	paymasterAddress *common.Address
 	paymasterPayload []byte
+	disperseAddress  *common.Address
+	maxFee           *big.Int
 }
 
+// NewPayer returns a payer paying out from the spender on zkSync Era. Payout
+// groups with more than one payout are paid out in a single transaction
+// through the disperse contract, if any, which the spender must have approved.
 func NewPayer(
 	contractAddress common.Address,
 	url string,
@@ -47,6 +51,7 @@ func NewPayer(
 	chainID int,
 	paymasterAddress *common.Address,
 	paymasterPayload []byte,
+	disperseAddress *common.Address,
 	maxFee *big.Int) (*Payer, error) {
 
 	ethSigner, err := accounts.NewBaseSignerFromRawPrivateKey(key.D.Bytes(), int64(chainID))
@@ -65,19 +70,15 @@ func NewPayer(
 		return nil, errs.Wrap(err)
 	}
 
-	erc20abi, err := abi.JSON(strings.NewReader(erc20.IERC20MetaData.ABI))
-	if err != nil {
-		return nil, fmt.Errorf("failed to load erc20abi: %w", err)
-	}
-
 	p := &Payer{
 		wallet:           wallet,
 		zk:               zkClients,
 		signer:           ethSigner,
 		contractAddress:  contractAddress,
-		erc20abi:         erc20abi,
 		paymasterAddress: paymasterAddress,
 		paymasterPayload: paymasterPayload,
+		disperseAddress:  disperseAddress,
+		maxFee:           maxFee,
 	}
 	p.decimals, err = p.GetTokenDecimals(context.Background())
 	return p, errs.Wrap(err)
@@ -96,14 +97,47 @@ func (p *Payer) NextNonce(ctx context.Context) (uint64, error) {
 	return nonce, nil
 }
 
-func (p *Payer) CheckPreconditions(ctx context.Context) ([]string, error) {
-	return nil, nil
+func (p *Payer) CheckPreconditions(ctx context.Context) (unmet []string, err error) {
+	callMsg, gas, err := p.sampleTransferCall(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	fee := new(big.Int).Mul(callMsg.GasFeeCap, new(big.Int).SetUint64(gas))
+
+	// The max fee is per payout. Payout groups paid out through the
+	// disperse contract need less gas per payout than a single transfer,
+	// so they fit in the max fee whenever a single transfer does.
+	if p.maxFee != nil && fee.Cmp(p.maxFee) > 0 {
+		unmet = append(unmet, fmt.Sprintf(
+			"the estimated fee of a transfer (%s) at the suggested gas price (%s) is larger than the max allowed fee per payout (%s)",
+			batchpayment.PrettyETH(fee), batchpayment.PrettyETH(callMsg.GasFeeCap), batchpayment.PrettyETH(p.maxFee)))
+	}
+
+	// Without a paymaster, the spender pays the fee itself.
+	if p.paymasterAddress == nil {
+		balance, err := p.zk.BalanceAt(ctx, callMsg.From, nil)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		if balance.Cmp(fee) < 0 {
+			unmet = append(unmet, fmt.Sprintf(
+				"the spender balance (%s) does not cover the estimated fee of a transfer (%s)",
+				batchpayment.PrettyETH(balance), batchpayment.PrettyETH(fee)))
+		}
+	}
+
+	return unmet, nil
 }
 
 func (p *Payer) GetTokenBalance(ctx context.Context) (*big.Int, error) {
 	return p.wallet.Balance(ctx, p.contractAddress, nil)
 }
 
+// From returns the address of the spender.
+func (p *Payer) From() common.Address {
+	return p.signer.Address()
+}
+
 func (p *Payer) GetTokenDecimals(ctx context.Context) (int32, error) {
 	tokenContract, err := contract.NewToken(p.contractAddress, p.zk)
 	if err != nil {
@@ -117,24 +151,37 @@ func (p *Payer) GetTokenDecimals(ctx context.Context) (int32, error) {
 
 }
 
-func (p *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, storjPrice decimal.Decimal) (tx payer.Transaction, from common.Address, err error) {
+func (p *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payouts []*pipelinedb.Payout, nonce uint64, price decimal.Decimal) (tx payer.Transaction, from common.Address, err error) {
 	from = p.signer.Address()
 
-	if len(payouts) > 1 {
-		return payer.Transaction{}, common.Address{}, errs.New("multitransfer is not supported yet")
+	transfers := make([]eth.Transfer, 0, len(payouts))
+	tokens := new(big.Int)
+	for _, payout := range payouts {
+		tokenAmount := storjtoken.FromUSD(payout.USD, price, p.decimals)
+		transfers = append(transfers, eth.Transfer{
+			Payee:  payout.Payee,
+			Tokens: tokenAmount,
+		})
+		tokens.Add(tokens, tokenAmount)
 	}
-	payout := payouts[0]
 
-	tokenAmount := storjtoken.FromUSD(payout.USD, storjPrice, p.decimals)
-
-	packedData, err := p.erc20abi.Pack("transfer", payout.Payee, tokenAmount)
-	if err != nil {
-		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+	// The disperse contract pulls the tokens with transferFrom, so it needs
+	// an allowance to cover all of the transfers.
+	if len(transfers) > 1 && p.disperseAddress != nil {
+		allowance, err := p.disperseAllowance(ctx)
+		if err != nil {
+			return payer.Transaction{}, common.Address{}, err
+		}
+		if allowance.Cmp(tokens) < 0 {
+			return payer.Transaction{}, common.Address{}, errs.New("not enough token allowance for disperse contract %s to cover transfer (%s < %s)", p.disperseAddress, allowance, tokens)
+		}
 	}
 
-	gasPrice, err := p.zk.SuggestGasPrice(ctx)
+	// The gas limit the call returns is tuned for Ethereum. It is estimated
+	// on L2 below instead, where the pubdata makes up most of the cost.
+	to, packedData, _, err := eth.TransferCall(from, from, p.contractAddress, p.disperseAddress, transfers)
 	if err != nil {
-		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
+		return payer.Transaction{}, common.Address{}, err
 	}
 
 	chainID, err := p.zk.ChainID(ctx)
@@ -142,33 +189,23 @@ func (p *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payou
 		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
 	}
 
-	callMsg := zktypes.CallMsg{
-		CallMsg: ethereum.CallMsg{
-			From:      from,
-			To:        &p.contractAddress,
-			Gas:       0,             // estimated below
-			GasTipCap: big.NewInt(0), // TODO: Estimate correct one
-			GasFeeCap: gasPrice,
-			Value:     nil,
-			Data:      packedData,
-		},
-		Meta: &zktypes.Eip712Meta{
-			GasPerPubdata: utils.NewBig(utils.DefaultGasPerPubdataLimit.Int64()),
-		},
+	callMsg, gas, err := p.transferCall(ctx, to, packedData)
+	if err != nil {
+		return payer.Transaction{}, common.Address{}, err
 	}
 
-	if p.paymasterAddress != nil {
-		callMsg.Meta.PaymasterParams = &zktypes.PaymasterParams{
-			Paymaster:      *p.paymasterAddress,
-			PaymasterInput: p.paymasterPayload,
+	// The preconditions are checked right before, but the gas price may
+	// have gone up since, in which case the pipeline waits for it to come
+	// down again.
+	fee := new(big.Int).Mul(callMsg.GasFeeCap, new(big.Int).SetUint64(gas))
+	if p.maxFee != nil {
+		maxFee := new(big.Int).Mul(p.maxFee, big.NewInt(int64(len(transfers))))
+		if fee.Cmp(maxFee) > 0 {
+			return payer.Transaction{}, common.Address{}, payer.ErrPreconditionNotMet.New("estimated fee (%s) is larger than the max allowed fee for %d payouts (%s)",
+				batchpayment.PrettyETH(fee), len(transfers), batchpayment.PrettyETH(maxFee))
 		}
 	}
 
-	gas, err := p.zk.EstimateGasL2(ctx, callMsg)
-	if err != nil {
-		return payer.Transaction{}, common.Address{}, errs.Wrap(err)
-	}
-
 	data := &zktypes.Transaction712{
 		Nonce:     big.NewInt(int64(nonce)),
 		GasTipCap: callMsg.GasTipCap,
@@ -219,6 +256,14 @@ func (p *Payer) CreateRawTransaction(ctx context.Context, log *zap.Logger, payou
 		append(hashTypedData, crypto.Keccak256(signature)...),
 	))
 
+	log.Info("Transaction is created",
+		zap.Int("payouts", len(transfers)),
+		zap.String("tokens", tokens.String()),
+		zap.Uint64("gas", gas),
+		zap.String("gas-price", callMsg.GasFeeCap.String()),
+		zap.String("hash", hash.String()),
+	)
+
 	return payer.Transaction{
 		Hash:  hash.String(),
 		Nonce: nonce,
@@ -244,7 +289,24 @@ func (p *Payer) CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceGroup
 
 	txHash := common.HexToHash(nonceGroup.Txs[0].Hash)
 	zkReceipt, err := p.zk.TransactionReceipt(ctx, txHash)
-	if err != nil {
+	switch {
+	case errors.Is(err, ethereum.NotFound):
+		// Receipts aren't available for pending transactions. The node
+		// does not know dropped transactions at all.
+		zkReceipt = nil
+		_, _, err = p.zk.TransactionByHash(ctx, txHash)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			return pipelinedb.TxDropped, []*pipelinedb.TxStatus{
+				{
+					Hash:  nonceGroup.Txs[0].Hash,
+					State: pipelinedb.TxDropped,
+				},
+			}, nil
+		case err != nil:
+			return pipelinedb.TxDropped, []*pipelinedb.TxStatus{}, errs.Wrap(err)
+		}
+	case err != nil:
 		return pipelinedb.TxDropped, []*pipelinedb.TxStatus{}, errs.Wrap(err)
 	}
 
@@ -267,18 +329,140 @@ func (p *Payer) CheckNonceGroup(ctx context.Context, log *zap.Logger, nonceGroup
 			State:   status,
 			Receipt: receipt,
 		},
-	}, err
+	}, nil
 
 }
 
-func (p *Payer) PrintEstimate(ctx context.Context, remaining int64) error {
+func (p *Payer) PrintEstimate(ctx context.Context, remainingGroups, remainingPayouts int64) error {
 	if p.paymasterAddress != nil {
 		fmt.Printf("Paymaster address...........: %s\n", p.paymasterAddress)
 		fmt.Printf("Paymaster payload...........: %s\n", common.Bytes2Hex(p.paymasterPayload))
 	}
+
+	// Rough estimate, assuming evenly sized payout groups that need as much
+	// gas as a sample one at the current gas price.
+	size := 1
+	if remainingGroups > 0 {
+		size = int(remainingPayouts / remainingGroups)
+	}
+	callMsg, gas, err := p.sampleTransferCall(ctx, size)
+	if err != nil {
+		return err
+	}
+	feePerTx := new(big.Int).Mul(callMsg.GasFeeCap, new(big.Int).SetUint64(gas))
+	remainingFee := new(big.Int).Mul(feePerTx, big.NewInt(remainingGroups))
+
+	fmt.Printf("Estimated Gas Per Tx........: %d\n", gas)
+	fmt.Printf("Current gas price...........: %s\n", batchpayment.PrettyETH(callMsg.GasFeeCap))
+	fmt.Printf("Estimated Fee Per Tx........: %s\n", batchpayment.PrettyETH(feePerTx))
+	if p.maxFee != nil {
+		fmt.Printf("Max Fee Per Payout..........: %s\n", batchpayment.PrettyETH(p.maxFee))
+	}
+	fmt.Printf("Remaining Fee...............: %s\n", batchpayment.PrettyETH(remainingFee))
 	return nil
 }
 
+// transferCall returns the call of the token or disperse contract with the
+// given data at the gas price suggested by the node, and the gas it is
+// estimated to need. With a paymaster, the estimate includes the gas of
+// validating the paymaster and of its post-transaction hook, which the fee
+// pays for.
+func (p *Payer) transferCall(ctx context.Context, to common.Address, data []byte) (zktypes.CallMsg, uint64, error) {
+	gasPrice, err := p.zk.SuggestGasPrice(ctx)
+	if err != nil {
+		return zktypes.CallMsg{}, 0, errs.Wrap(err)
+	}
+
+	callMsg := zktypes.CallMsg{
+		CallMsg: ethereum.CallMsg{
+			From:      p.signer.Address(),
+			To:        &to,
+			Gas:       0,             // estimated below
+			GasTipCap: big.NewInt(0), // TODO: Estimate correct one
+			GasFeeCap: gasPrice,
+			Value:     nil,
+			Data:      data,
+		},
+		Meta: &zktypes.Eip712Meta{
+			GasPerPubdata: utils.NewBig(utils.DefaultGasPerPubdataLimit.Int64()),
+		},
+	}
+
+	if p.paymasterAddress != nil {
+		callMsg.Meta.PaymasterParams = &zktypes.PaymasterParams{
+			Paymaster:      *p.paymasterAddress,
+			PaymasterInput: p.paymasterPayload,
+		}
+	}
+
+	gas, err := p.zk.EstimateGasL2(ctx, callMsg)
+	if err != nil {
+		return zktypes.CallMsg{}, 0, errs.Wrap(err)
+	}
+	return callMsg, gas, nil
+}
+
+// sampleTransferCall returns the call paying out a payout group of the given
+// size to made up payees, and the gas it is estimated to need. Most of the
+// gas pays for the pubdata of the balances written, so the payees are fresh
+// addresses and the amounts random looking, like those of real payouts. The
+// amounts are bounded by what the spender can transfer, for the estimate to
+// succeed. Without a disperse contract, groups hold a single payout.
+func (p *Payer) sampleTransferCall(ctx context.Context, size int) (zktypes.CallMsg, uint64, error) {
+	if size < 1 || p.disperseAddress == nil {
+		size = 1
+	}
+	from := p.signer.Address()
+
+	tokenContract, err := contract.NewToken(p.contractAddress, p.zk)
+	if err != nil {
+		return zktypes.CallMsg{}, 0, errs.Wrap(err)
+	}
+	available, err := tokenContract.BalanceOf(&bind.CallOpts{Context: ctx}, from)
+	if err != nil {
+		return zktypes.CallMsg{}, 0, errs.Wrap(err)
+	}
+	if size > 1 {
+		allowance, err := p.disperseAllowance(ctx)
+		if err != nil {
+			return zktypes.CallMsg{}, 0, err
+		}
+		if allowance.Cmp(available) < 0 {
+			available = allowance
+		}
+	}
+	perTransfer := new(big.Int).Div(available, big.NewInt(int64(size)))
+
+	transfers := make([]eth.Transfer, 0, size)
+	for i := 0; i < size; i++ {
+		seed := crypto.Keccak256(big.NewInt(int64(i)).Bytes())
+		tokens := new(big.Int).SetBytes(seed[:4])
+		if tokens.Cmp(perTransfer) > 0 {
+			tokens.Set(perTransfer)
+		}
+		transfers = append(transfers, eth.Transfer{
+			Payee:  common.BytesToAddress(seed),
+			Tokens: tokens,
+		})
+	}
+	to, data, _, err := eth.TransferCall(from, from, p.contractAddress, p.disperseAddress, transfers)
+	if err != nil {
+		return zktypes.CallMsg{}, 0, err
+	}
+	return p.transferCall(ctx, to, data)
+}
+
+// disperseAllowance returns the allowance of the disperse contract to
+// transfer the tokens of the spender.
+func (p *Payer) disperseAllowance(ctx context.Context) (*big.Int, error) {
+	tokenContract, err := contract.NewToken(p.contractAddress, p.zk)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	allowance, err := tokenContract.Allowance(&bind.CallOpts{Context: ctx}, p.signer.Address(), *p.disperseAddress)
+	return allowance, errs.Wrap(err)
+}
+
 var (
 	_ payer.Payer = &Payer{}
 )
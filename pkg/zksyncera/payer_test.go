@@ -0,0 +1,445 @@
+package zksyncera
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+	"github.com/zksync-sdk/zksync2-go/accounts"
+	"github.com/zksync-sdk/zksync2-go/clients"
+	zktypes "github.com/zksync-sdk/zksync2-go/types"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/crypto-batch-payment/pkg/contract"
+	"storj.io/crypto-batch-payment/pkg/payer"
+	"storj.io/crypto-batch-payment/pkg/pipelinedb"
+)
+
+var (
+	tokenAddress    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	disperseAddress = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	alice           = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	bob             = common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	chuck           = common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	ctx := context.Background()
+
+	client := newFakeClient()
+	client.balance = big.NewInt(50_000_000_000_000)
+
+	// A fee of 0.00005 ETH is covered by the balance and the max fee.
+	p := newTestPayer(t, client, big.NewInt(50_000_000_000_000))
+	unmet, err := p.CheckPreconditions(ctx)
+	require.NoError(t, err)
+	require.Empty(t, unmet)
+
+	// The fee is estimated for a transfer of tokens to a fresh payee, which
+	// writes more pubdata than a transfer of nothing to the spender.
+	method, args := client.lastCall(t)
+	require.Equal(t, "transfer", method.Name)
+	require.NotEqual(t, p.signer.Address(), args[0])
+	require.Positive(t, args[1].(*big.Int).Sign())
+
+	// Without a max fee, only the balance of the spender limits the fee.
+	p.maxFee = nil
+	client.gasPrice = big.NewInt(200_000_000)
+	unmet, err = p.CheckPreconditions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"the spender balance (50000 GWei) does not cover the estimated fee of a transfer (100000 GWei)",
+	}, unmet)
+
+	// Neither the max fee nor the balance cover a fee of 0.0001 ETH.
+	p.maxFee = big.NewInt(50_000_000_000_000)
+	unmet, err = p.CheckPreconditions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"the estimated fee of a transfer (100000 GWei) at the suggested gas price (0.2 GWei) is larger than the max allowed fee per payout (50000 GWei)",
+		"the spender balance (50000 GWei) does not cover the estimated fee of a transfer (100000 GWei)",
+	}, unmet)
+
+	// With a paymaster, the balance of the spender does not matter.
+	paymaster := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	p.paymasterAddress = &paymaster
+	unmet, err = p.CheckPreconditions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"the estimated fee of a transfer (100000 GWei) at the suggested gas price (0.2 GWei) is larger than the max allowed fee per payout (50000 GWei)",
+	}, unmet)
+	require.Equal(t, paymaster, client.estimated[len(client.estimated)-1].Meta.PaymasterParams.Paymaster)
+}
+
+func TestCreateRawTransactionMaxFee(t *testing.T) {
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+
+	client := newFakeClient()
+	p := newTestPayer(t, client, big.NewInt(50_000_000_000_000))
+
+	payouts := []*pipelinedb.Payout{{Payee: alice, USD: decimal.RequireFromString("1")}}
+	tx, from, err := p.CreateRawTransaction(ctx, log, payouts, 3, decimal.RequireFromString("0.5"))
+	require.NoError(t, err)
+	require.Equal(t, p.signer.Address(), from)
+	require.Equal(t, uint64(3), tx.Nonce)
+	require.NotEmpty(t, tx.Raw)
+
+	method, args := client.lastCall(t)
+	require.Equal(t, "transfer", method.Name)
+	require.Equal(t, alice, args[0])
+	require.Equal(t, big.NewInt(200_000_000), args[1])
+
+	// The gas price went up after the preconditions were checked.
+	client.gasPrice = big.NewInt(100_000_001)
+	_, _, err = p.CreateRawTransaction(ctx, log, payouts, 3, decimal.RequireFromString("0.5"))
+	require.Error(t, err)
+	require.True(t, payer.ErrPreconditionNotMet.Has(err), err)
+}
+
+func TestCreateRawTransactionDisperse(t *testing.T) {
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+
+	client := newFakeClient()
+	p := newTestPayer(t, client, big.NewInt(50_000_000_000_000))
+	p.disperseAddress = &disperseAddress
+
+	// Paying out three payouts in one transaction needs more gas than a
+	// single transfer, but less than three.
+	client.gas = 1_200_000
+	client.allowance = big.NewInt(600_000_000)
+
+	payouts := []*pipelinedb.Payout{
+		{Payee: alice, USD: decimal.RequireFromString("1")},
+		{Payee: bob, USD: decimal.RequireFromString("0.5")},
+		{Payee: chuck, USD: decimal.RequireFromString("1.5")},
+	}
+	tx, from, err := p.CreateRawTransaction(ctx, log, payouts, 7, decimal.RequireFromString("0.5"))
+	require.NoError(t, err)
+	require.Equal(t, p.signer.Address(), from)
+	require.Equal(t, uint64(7), tx.Nonce)
+
+	require.Equal(t, disperseAddress, *client.estimated[len(client.estimated)-1].To)
+	method, args := client.lastCall(t)
+	require.Equal(t, "disperseTokenSimple", method.Name)
+	require.Equal(t, tokenAddress, args[0])
+	require.Equal(t, []common.Address{alice, bob, chuck}, args[1])
+	require.Equal(t, []*big.Int{big.NewInt(200_000_000), big.NewInt(100_000_000), big.NewInt(300_000_000)}, args[2])
+
+	// The max fee is per payout, so it grows with the group.
+	client.gas = 1_500_001
+	_, _, err = p.CreateRawTransaction(ctx, log, payouts, 7, decimal.RequireFromString("0.5"))
+	require.True(t, payer.ErrPreconditionNotMet.Has(err), err)
+	require.ErrorContains(t, err, "is larger than the max allowed fee for 3 payouts (150000 GWei)")
+
+	// The disperse contract must be allowed to transfer all of the tokens.
+	client.gas = 1_200_000
+	client.allowance = big.NewInt(599_999_999)
+	_, _, err = p.CreateRawTransaction(ctx, log, payouts, 7, decimal.RequireFromString("0.5"))
+	require.EqualError(t, err, "not enough token allowance for disperse contract "+disperseAddress.String()+" to cover transfer (599999999 < 600000000)")
+
+	// Without a disperse contract, payout groups hold a single payout.
+	p.disperseAddress = nil
+	_, _, err = p.CreateRawTransaction(ctx, log, payouts, 7, decimal.RequireFromString("0.5"))
+	require.ErrorContains(t, err, "multitransfer requires a disperse contract address")
+}
+
+func TestPrintEstimate(t *testing.T) {
+	ctx := context.Background()
+
+	client := newFakeClient()
+	p := newTestPayer(t, client, big.NewInt(60_000_000_000_000))
+
+	out := captureStdout(t, func() {
+		require.NoError(t, p.PrintEstimate(ctx, 10, 10))
+	})
+	require.Equal(t, ""+
+		"Estimated Gas Per Tx........: 500000\n"+
+		"Current gas price...........: 0.1 GWei\n"+
+		"Estimated Fee Per Tx........: 50000 GWei\n"+
+		"Max Fee Per Payout..........: 60000 GWei\n"+
+		"Remaining Fee...............: 500000 GWei\n", out)
+}
+
+func TestPrintEstimateDisperse(t *testing.T) {
+	ctx := context.Background()
+
+	client := newFakeClient()
+	client.gas = 1_200_000
+	client.allowance = big.NewInt(3)
+	p := newTestPayer(t, client, nil)
+	p.disperseAddress = &disperseAddress
+
+	out := captureStdout(t, func() {
+		require.NoError(t, p.PrintEstimate(ctx, 10, 30))
+	})
+	require.Equal(t, ""+
+		"Estimated Gas Per Tx........: 1200000\n"+
+		"Current gas price...........: 0.1 GWei\n"+
+		"Estimated Fee Per Tx........: 120000 GWei\n"+
+		"Remaining Fee...............: 0.0012 ETH\n", out)
+
+	// The sample group pays out fresh payees, with amounts the disperse
+	// contract is allowed to transfer.
+	method, args := client.lastCall(t)
+	require.Equal(t, "disperseTokenSimple", method.Name)
+	payees := args[1].([]common.Address)
+	require.Len(t, payees, 3)
+	require.NotContains(t, payees, p.signer.Address())
+	require.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)}, args[2])
+}
+
+func TestCheckNonceGroup(t *testing.T) {
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+
+	client := newFakeClient()
+	p := newTestPayer(t, client, nil)
+
+	// The payouts of a group paid out through the disperse contract share
+	// the hash of the one transaction, which the receipt is recorded for.
+	hash := common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444")
+	nonceGroup := &pipelinedb.NonceGroup{
+		Nonce:         7,
+		PayoutGroupID: 1,
+		Txs:           []pipelinedb.Transaction{{Hash: hash.String()}},
+	}
+
+	// The node knows neither a receipt nor the transaction once it has
+	// been dropped.
+	state, statuses, err := p.CheckNonceGroup(ctx, log, nonceGroup, false)
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxDropped, state)
+	require.Equal(t, []*pipelinedb.TxStatus{{Hash: hash.String(), State: pipelinedb.TxDropped}}, statuses)
+
+	client.pending[hash] = true
+	state, statuses, err = p.CheckNonceGroup(ctx, log, nonceGroup, false)
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxPending, state)
+	require.Equal(t, []*pipelinedb.TxStatus{{Hash: hash.String(), State: pipelinedb.TxPending}}, statuses)
+
+	client.receipts[hash] = &zktypes.Receipt{Receipt: types.Receipt{
+		TxHash: hash,
+		Status: types.ReceiptStatusSuccessful,
+	}}
+	state, statuses, err = p.CheckNonceGroup(ctx, log, nonceGroup, false)
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxConfirmed, state)
+	require.Len(t, statuses, 1)
+	require.Equal(t, hash.String(), statuses[0].Hash)
+	require.Equal(t, pipelinedb.TxConfirmed, statuses[0].State)
+	require.Equal(t, hash, statuses[0].Receipt.TxHash)
+
+	client.receipts[hash].Status = types.ReceiptStatusFailed
+	state, statuses, err = p.CheckNonceGroup(ctx, log, nonceGroup, false)
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxFailed, state)
+	require.Equal(t, pipelinedb.TxFailed, statuses[0].State)
+}
+
+func TestAuditor(t *testing.T) {
+	ctx := context.Background()
+
+	client := newFakeClient()
+	auditor := &Auditor{client: client}
+	hash := common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444")
+
+	state, err := auditor.CheckTransactionState(ctx, hash.String())
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxDropped, state)
+
+	for status, expected := range map[string]pipelinedb.TxState{
+		"included": pipelinedb.TxPending,
+		"verified": pipelinedb.TxConfirmed,
+		"failed":   pipelinedb.TxFailed,
+	} {
+		client.details[hash] = &zktypes.TransactionDetails{Status: status}
+		state, err := auditor.CheckTransactionState(ctx, hash.String())
+		require.NoError(t, err)
+		require.Equal(t, expected, state, status)
+	}
+
+	// Confirmed transactions are final once their L1 batch is.
+	state, err = auditor.CheckConfirmedTransactionState(ctx, hash.String())
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxPending, state)
+
+	client.receipts[hash] = &zktypes.Receipt{
+		Receipt:       types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful},
+		L1BatchNumber: (*hexutil.Big)(big.NewInt(42)),
+	}
+	client.batches[42] = &zktypes.BatchDetails{Status: "sealed"}
+	_, err = auditor.CheckConfirmedTransactionState(ctx, hash.String())
+	require.EqualError(t, err, `unknown zksync-era tx status "sealed"`)
+
+	client.batches[42].Status = "verified"
+	state, err = auditor.CheckConfirmedTransactionState(ctx, hash.String())
+	require.NoError(t, err)
+	require.Equal(t, pipelinedb.TxConfirmed, state)
+}
+
+func newTestPayer(t *testing.T, client *fakeClient, maxFee *big.Int) *Payer {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer, err := accounts.NewBaseSignerFromRawPrivateKey(key.D.Bytes(), 300)
+	require.NoError(t, err)
+	return &Payer{
+		zk:              client,
+		signer:          signer,
+		contractAddress: tokenAddress,
+		decimals:        8,
+		maxFee:          maxFee,
+	}
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+
+	require.NoError(t, w.Close())
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+// fakeClient stands in for a zkSync Era node. Calls of the methods it does
+// not implement panic.
+type fakeClient struct {
+	clients.EthereumClient
+	clients.ZkSyncEraClient
+
+	gasPrice *big.Int
+	gas      uint64
+	balance  *big.Int
+
+	// tokens and allowance are the token balance of the spender and the
+	// allowance of the disperse contract.
+	tokens    *big.Int
+	allowance *big.Int
+
+	receipts map[common.Hash]*zktypes.Receipt
+	pending  map[common.Hash]bool
+	details  map[common.Hash]*zktypes.TransactionDetails
+	batches  map[int64]*zktypes.BatchDetails
+
+	// estimated are the calls the gas was estimated for.
+	estimated []zktypes.CallMsg
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		gasPrice:  big.NewInt(100_000_000),
+		gas:       500_000,
+		balance:   new(big.Int),
+		tokens:    big.NewInt(1_000_000_000_000),
+		allowance: new(big.Int),
+		receipts:  make(map[common.Hash]*zktypes.Receipt),
+		pending:   make(map[common.Hash]bool),
+		details:   make(map[common.Hash]*zktypes.TransactionDetails),
+		batches:   make(map[int64]*zktypes.BatchDetails),
+	}
+}
+
+// lastCall returns the method and arguments of the last call the gas was
+// estimated for.
+func (c *fakeClient) lastCall(t *testing.T) (*abi.Method, []any) {
+	require.NotEmpty(t, c.estimated)
+	data := c.estimated[len(c.estimated)-1].Data
+
+	for _, metaData := range []*bind.MetaData{contract.TokenMetaData, contract.DisperseMetaData} {
+		contractABI, err := metaData.GetAbi()
+		require.NoError(t, err)
+		if method, err := contractABI.MethodById(data[:4]); err == nil {
+			args, err := method.Inputs.Unpack(data[4:])
+			require.NoError(t, err)
+			return method, args
+		}
+	}
+	require.FailNow(t, "unknown method")
+	return nil, nil
+}
+
+func (c *fakeClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(300), nil
+}
+
+func (c *fakeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return c.gasPrice, nil
+}
+
+func (c *fakeClient) EstimateGasL2(ctx context.Context, msg zktypes.CallMsg) (uint64, error) {
+	c.estimated = append(c.estimated, msg)
+	return c.gas, nil
+}
+
+func (c *fakeClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return c.balance, nil
+}
+
+func (c *fakeClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	tokenABI, err := contract.TokenMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	method, err := tokenABI.MethodById(msg.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "balanceOf":
+		return method.Outputs.Pack(c.tokens)
+	case "allowance":
+		return method.Outputs.Pack(c.allowance)
+	default:
+		return nil, errs.New("unexpected call of %s", method.Name)
+	}
+}
+
+func (c *fakeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*zktypes.Receipt, error) {
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *fakeClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*zktypes.TransactionResponse, bool, error) {
+	if _, ok := c.receipts[txHash]; ok {
+		return &zktypes.TransactionResponse{Hash: txHash}, false, nil
+	}
+	if c.pending[txHash] {
+		return &zktypes.TransactionResponse{Hash: txHash}, true, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+func (c *fakeClient) TransactionDetails(ctx context.Context, txHash common.Hash) (*zktypes.TransactionDetails, error) {
+	details, ok := c.details[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return details, nil
+}
+
+func (c *fakeClient) L1BatchDetails(ctx context.Context, l1BatchNumber *big.Int) (*zktypes.BatchDetails, error) {
+	return c.batches[l1BatchNumber.Int64()], nil
+}